@@ -0,0 +1,133 @@
+package ast
+
+import (
+	"fmt"
+	"mirth/compiler"
+)
+
+// Span is the range of source code a node is parsed from.
+// Start is the position of the first rune, and End is right after the last rune.
+type Span struct {
+	Start *compiler.Position
+	End   *compiler.Position
+}
+
+func (s Span) NodeSpan() Span {
+	return s
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%s-%s", s.Start, s.End)
+}
+
+func SpanOfToken(token *compiler.Token) Span {
+	return Span{token.Pos, token.End}
+}
+
+// SpanBetween returns the span from the start of the first node to the end of the last node.
+func SpanBetween(first, last Node) Span {
+	return Span{first.NodeSpan().Start, last.NodeSpan().End}
+}
+
+// Node is implemented by all the AST nodes.
+type Node interface {
+	NodeSpan() Span
+}
+
+// Expr is implemented by all the expression nodes.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Identifier is a name refers to a declaration.
+type Identifier struct {
+	Span
+	Name string
+}
+
+// BasicLiteral is a literal of a number, a rune, a string, a byte or a boolean.
+// Kind is the token type of the literal, and Value is its scanned content.
+type BasicLiteral struct {
+	Span
+	Kind  compiler.TokenType
+	Value string
+}
+
+// ParenExpr is an expression wrapped by parentheses.
+type ParenExpr struct {
+	Span
+	Expr Expr
+}
+
+// UnaryExpr is a prefix operation, like `-x`, `!ok`, `~mask` and `++i`.
+type UnaryExpr struct {
+	Span
+	Operator *compiler.Token
+	Operand  Expr
+}
+
+// PostfixExpr is a postfix increment `i++` or decrement `i--`.
+type PostfixExpr struct {
+	Span
+	Operator *compiler.Token
+	Operand  Expr
+}
+
+// BinaryExpr is an infix operation, including the nullish coalescing `a ?? b`
+// and the custom operators declared in an operator table.
+type BinaryExpr struct {
+	Span
+	Operator *compiler.Token
+	Left     Expr
+	Right    Expr
+}
+
+// AssignExpr is an assignment `a = b`, or a compound assignment like `a += b`.
+type AssignExpr struct {
+	Span
+	Operator *compiler.Token
+	Target   Expr
+	Value    Expr
+}
+
+// CallExpr is a function call `callee(args...)`.
+type CallExpr struct {
+	Span
+	Callee    Expr
+	Arguments []Expr
+}
+
+// IndexExpr is an index access `target[index]`.
+type IndexExpr struct {
+	Span
+	Target Expr
+	Index  Expr
+}
+
+// MemberExpr is a member access `target.name`,
+// or an optional chaining `target?.name` if Optional is true.
+type MemberExpr struct {
+	Span
+	Target   Expr
+	Name     *Identifier
+	Optional bool
+}
+
+// BadExpr is a placeholder for an expression with syntax errors,
+// so that the enclosing node is kept in the tree.
+type BadExpr struct {
+	Span
+}
+
+func (*Identifier) exprNode()   {}
+func (*BasicLiteral) exprNode() {}
+func (*ParenExpr) exprNode()    {}
+func (*UnaryExpr) exprNode()    {}
+func (*PostfixExpr) exprNode()  {}
+func (*BinaryExpr) exprNode()   {}
+func (*AssignExpr) exprNode()   {}
+func (*CallExpr) exprNode()     {}
+func (*IndexExpr) exprNode()    {}
+func (*MemberExpr) exprNode()   {}
+func (*BadExpr) exprNode()      {}
@@ -0,0 +1,196 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// isNil tells whether the node is nil, including a nil pointer stored in the interface.
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	value := reflect.ValueOf(node)
+	return value.Kind() == reflect.Pointer && value.IsNil()
+}
+
+type childList []Node
+
+func (c *childList) add(nodes ...Node) {
+	for _, node := range nodes {
+		if !isNil(node) {
+			*c = append(*c, node)
+		}
+	}
+}
+
+func addAll[N Node](c *childList, nodes []N) {
+	for _, node := range nodes {
+		c.add(node)
+	}
+}
+
+// Children returns the direct children of a node in the order of its fields,
+// leaving out the absent optional ones. Tokens like operators are not nodes,
+// so they are not included.
+func Children(node Node) []Node {
+	var c childList
+	switch n := node.(type) {
+	case *Identifier, *BasicLiteral, *BadExpr, *BadStmt, *TemplateText, *WildcardPattern:
+		// Leaves
+	case *ParenExpr:
+		c.add(n.Expr)
+	case *UnaryExpr:
+		c.add(n.Operand)
+	case *PostfixExpr:
+		c.add(n.Operand)
+	case *BinaryExpr:
+		c.add(n.Left, n.Right)
+	case *AssignExpr:
+		c.add(n.Target, n.Value)
+	case *CallExpr:
+		c.add(n.Callee)
+		addAll(&c, n.Arguments)
+	case *IndexExpr:
+		c.add(n.Target, n.Index)
+	case *MemberExpr:
+		c.add(n.Target, n.Name)
+	case *ArrayLit:
+		addAll(&c, n.Elements)
+	case *TupleLit:
+		addAll(&c, n.Elements)
+	case *RangeExpr:
+		c.add(n.Start, n.End)
+	case *SpreadExpr:
+		c.add(n.Expr)
+	case *TemplateString:
+		// Fragments and expressions alternate in the source
+		for i, fragment := range n.Fragments {
+			c.add(fragment)
+			if i < len(n.Exprs) {
+				c.add(n.Exprs[i])
+			}
+		}
+	case *TaggedTemplate:
+		c.add(n.Tag, n.Template)
+	case *StructLit:
+		c.add(n.Type)
+		addAll(&c, n.Fields)
+	case *FieldValue:
+		c.add(n.Name, n.Value)
+	case *TypeAssertion:
+		c.add(n.Value, n.Type)
+
+	case *MatchExpr:
+		c.add(n.Subject)
+		addAll(&c, n.Arms)
+	case *MatchArm:
+		c.add(n.Pattern, n.Guard, n.Body)
+	case *LiteralPattern:
+		c.add(n.Value)
+	case *RangePattern:
+		c.add(n.Start, n.End)
+	case *BindingPattern:
+		c.add(n.Name, n.Pattern)
+	case *TypePattern:
+		c.add(n.Name, n.Type)
+	case *TuplePattern:
+		addAll(&c, n.Elements)
+	case *StructPattern:
+		c.add(n.Type)
+		addAll(&c, n.Fields)
+	case *FieldPattern:
+		c.add(n.Name, n.Pattern)
+	case *VariantPattern:
+		c.add(n.Variant)
+		addAll(&c, n.Elements)
+
+	case *Param:
+		c.add(n.Name, n.Type, n.Default)
+	case *TypeParam:
+		c.add(n.Name, n.Constraint)
+	case *InstantiateExpr:
+		c.add(n.Target)
+		addAll(&c, n.TypeArgs)
+	case *FuncDecl:
+		c.add(n.Receiver, n.Name)
+		addAll(&c, n.TypeParams)
+		addAll(&c, n.Params)
+		c.add(n.ReturnType, n.Body)
+	case *FuncLit:
+		addAll(&c, n.Params)
+		c.add(n.ReturnType, n.Body)
+	case *ArrowFunc:
+		addAll(&c, n.Params)
+		c.add(n.Body)
+	case *FuncType:
+		addAll(&c, n.Params)
+		c.add(n.ReturnType)
+
+	case *File:
+		addAll(&c, n.Statements)
+	case *VarDecl:
+		c.add(n.Name, n.Type, n.Value)
+	case *ExprStmt:
+		c.add(n.Expr)
+	case *BlockStmt:
+		addAll(&c, n.Statements)
+	case *IfStmt:
+		c.add(n.Condition, n.Then, n.Else)
+	case *ForStmt:
+		c.add(n.Label, n.Init, n.Condition, n.Post, n.Body)
+	case *ForInStmt:
+		c.add(n.Label, n.Binding, n.Iterable, n.Body)
+	case *LoopStmt:
+		c.add(n.Label, n.Body)
+	case *BreakStmt:
+		c.add(n.Label)
+	case *ContinueStmt:
+		c.add(n.Label)
+	case *ReturnStmt:
+		c.add(n.Value)
+
+	case *Field:
+		c.add(n.Name, n.Type, n.Default)
+	case *StructDecl:
+		c.add(n.Name)
+		addAll(&c, n.TypeParams)
+		addAll(&c, n.Fields)
+	case *ImportDecl:
+		addAll(&c, n.Path)
+		c.add(n.Alias)
+		addAll(&c, n.Items)
+	case *ImportItem:
+		c.add(n.Name, n.Alias)
+	case *EnumDecl:
+		c.add(n.Name)
+		addAll(&c, n.TypeParams)
+		addAll(&c, n.Variants)
+	case *Variant:
+		c.add(n.Name)
+		addAll(&c, n.Tuple)
+		addAll(&c, n.Fields)
+		c.add(n.Discriminant)
+	case *MethodSignature:
+		c.add(n.Name)
+		addAll(&c, n.Params)
+		c.add(n.ReturnType)
+	case *InterfaceDecl:
+		c.add(n.Name)
+		addAll(&c, n.Embedded)
+		addAll(&c, n.Methods)
+
+	case *NamedType:
+		c.add(n.Name)
+		addAll(&c, n.TypeArgs)
+	case *ArrayType:
+		c.add(n.Length, n.Element)
+	case *TupleType:
+		addAll(&c, n.Elements)
+	case *OptionalType:
+		c.add(n.Elem)
+	default:
+		panic(fmt.Sprintf("ast.Children: unexpected node %T", node))
+	}
+	return c
+}
@@ -0,0 +1,50 @@
+package ast
+
+import "mirth/compiler"
+
+// ArrayLit is an array literal `[a, b, ...rest]`.
+// Elements may be *SpreadExpr, which insert all the elements of another array.
+type ArrayLit struct {
+	Span
+	Elements []Expr
+}
+
+// TupleLit is a tuple `(a, b)` of values with possibly different types,
+// a tuple of a single element is written with a trailing comma `(a,)`.
+type TupleLit struct {
+	Span
+	Elements []Expr
+}
+
+// RangeExpr is an exclusive range `a..b` or an inclusive range `a..=b`.
+// Start or End is nil for an open-ended range, like `xs[2..]` and `xs[..n]`,
+// but an inclusive range always has an End.
+//
+// Both bounds must have the same integer type, and the range is typed
+// as a range of that type. It yields the integers from Start, counting
+// up while they are less than End (or equal to End if inclusive), so
+// `for i in 0..n` iterates n times and `5..0` is empty. A range without
+// Start begins at 0, and a range without End is unbounded.
+// Indexing an array or a string with a range takes the slice between
+// the bounds, where an open End extends to the length.
+type RangeExpr struct {
+	Span
+	Operator  *compiler.Token
+	Start     Expr
+	End       Expr
+	Inclusive bool
+}
+
+// SpreadExpr is a spread `...xs` in call arguments and array literals.
+// It expands the elements of an array in place: in a call they fill
+// the remaining parameters or the variadic parameter, and in an array
+// literal they are concatenated with the surrounding elements.
+type SpreadExpr struct {
+	Span
+	Expr Expr
+}
+
+func (*ArrayLit) exprNode()   {}
+func (*TupleLit) exprNode()   {}
+func (*RangeExpr) exprNode()  {}
+func (*SpreadExpr) exprNode() {}
@@ -0,0 +1,37 @@
+package ast
+
+// EnumDecl is an enum declaration `enum Name { variants }`, or `enum Name<T> { variants }`
+// for a generic enum. A value of the enum is one of its variants, with the payload
+// of that variant.
+type EnumDecl struct {
+	Span
+	Name       *Identifier
+	TypeParams []*TypeParam
+	Variants   []*Variant
+	Public     bool
+}
+
+// Variant is a variant of an enum. Its payload is a tuple `Name(A, B)` if Tuple
+// isn't nil, or a struct `Name { a: A }` if Fields isn't nil, and there's no
+// payload otherwise. A variant without payload may have an explicit integer
+// discriminant `Name = 1`, the others are numbered from the previous one.
+type Variant struct {
+	Span
+	Name         *Identifier
+	Tuple        []TypeExpr
+	Fields       []*Field
+	Discriminant Expr
+}
+
+// VariantPattern matches a variant of an enum, like `Option.None`, and destructures
+// a tuple payload like `Option.Some(value)`. Variant is an *Identifier or a *MemberExpr,
+// and Elements is nil if the pattern has no parentheses. A struct payload is
+// destructured with a StructPattern, like `Shape.Circle { radius }`.
+type VariantPattern struct {
+	Span
+	Variant  Expr
+	Elements []Pattern
+}
+
+func (*EnumDecl) stmtNode()          {}
+func (*VariantPattern) patternNode() {}
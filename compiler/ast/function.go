@@ -0,0 +1,72 @@
+package ast
+
+// Function is implemented by the nodes which introduce a function scope:
+// named declarations, anonymous functions and arrow lambdas.
+// Later phases like closure capture analysis treat them uniformly.
+type Function interface {
+	Node
+	FuncParams() []*Param
+	// FuncBody is a *BlockStmt, or an Expr for an arrow lambda like `(a) => a + 1`.
+	FuncBody() Node
+}
+
+// Param is a function parameter `name: Type = default`, or a variadic `...name: Type`.
+// Type is nil for the parameters of arrow lambdas without annotations.
+type Param struct {
+	Span
+	Name     *Identifier
+	Type     TypeExpr
+	Default  Expr
+	Variadic bool
+}
+
+// FuncDecl is a named function declaration, or a method declaration
+// `func (receiver: Struct) name()` if Receiver isn't nil.
+// A generic function has type parameters `func name<T>()`.
+// ReturnType is nil if the function doesn't return a value.
+type FuncDecl struct {
+	Span
+	Receiver   *Param
+	Name       *Identifier
+	TypeParams []*TypeParam
+	Params     []*Param
+	ReturnType TypeExpr
+	Body       *BlockStmt
+	Public     bool
+}
+
+// FuncLit is an anonymous function expression `func(params) ReturnType { }`.
+type FuncLit struct {
+	Span
+	Params     []*Param
+	ReturnType TypeExpr
+	Body       *BlockStmt
+}
+
+// ArrowFunc is an arrow lambda `(a, b) => a + b`.
+// Body is an Expr, or a *BlockStmt for `(a) => { ... }`.
+type ArrowFunc struct {
+	Span
+	Params []*Param
+	Body   Node
+}
+
+// FuncType is the type of a function, like `func(int, ...string) bool`.
+// Its parameters have no names and no default values.
+type FuncType struct {
+	Span
+	Params     []*Param
+	ReturnType TypeExpr
+}
+
+func (d *FuncDecl) FuncParams() []*Param  { return d.Params }
+func (d *FuncDecl) FuncBody() Node        { return d.Body }
+func (f *FuncLit) FuncParams() []*Param   { return f.Params }
+func (f *FuncLit) FuncBody() Node         { return f.Body }
+func (f *ArrowFunc) FuncParams() []*Param { return f.Params }
+func (f *ArrowFunc) FuncBody() Node       { return f.Body }
+
+func (*FuncDecl) stmtNode()  {}
+func (*FuncLit) exprNode()   {}
+func (*ArrowFunc) exprNode() {}
+func (*FuncType) typeNode()  {}
@@ -0,0 +1,20 @@
+package ast
+
+// TypeParam is a type parameter `T` of a generic function or struct,
+// or `T: Constraint` if the type arguments must satisfy an interface.
+type TypeParam struct {
+	Span
+	Name       *Identifier
+	Constraint TypeExpr
+}
+
+// InstantiateExpr gives explicit type arguments to a generic function or struct,
+// like `make<int>` in `make<int>(8)` and `Box<string>` in `Box<string> { value: "" }`.
+// Type arguments of calls are usually inferred from the arguments instead.
+type InstantiateExpr struct {
+	Span
+	Target   Expr
+	TypeArgs []TypeExpr
+}
+
+func (*InstantiateExpr) exprNode() {}
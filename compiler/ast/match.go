@@ -0,0 +1,99 @@
+package ast
+
+import "mirth/compiler"
+
+// Pattern is implemented by all the nodes of match patterns.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// MatchExpr compares the value of Subject with the pattern of each arm in order,
+// and yields the value of the first arm which matches. The arms must cover all
+// the values of the subject type.
+type MatchExpr struct {
+	Span
+	Subject Expr
+	Arms    []*MatchArm
+}
+
+// MatchArm is `pattern if guard => body`, the guard is optional.
+// Body is an expression, or a *BlockStmt whose value is the value
+// of its last statement, if that statement is an expression.
+type MatchArm struct {
+	Span
+	Pattern Pattern
+	Guard   Expr
+	Body    Node
+}
+
+// WildcardPattern `_` matches any value, without binding it.
+type WildcardPattern struct {
+	Span
+}
+
+// LiteralPattern matches the values equal to a literal. Value is a *BasicLiteral,
+// or a *UnaryExpr for a negative number like `-1`.
+type LiteralPattern struct {
+	Span
+	Value Expr
+}
+
+// RangePattern `1..10` or `'a'..='z'` matches the values between its bounds,
+// which are literals like the value of a LiteralPattern. Start or End is nil
+// for an open-ended range like `10..`, but an inclusive range always has an End.
+type RangePattern struct {
+	Span
+	Operator  *compiler.Token
+	Start     Expr
+	End       Expr
+	Inclusive bool
+}
+
+// BindingPattern binds the matched value to a new variable. With a sub-pattern,
+// like `n @ 1..10`, the value must also match the sub-pattern.
+type BindingPattern struct {
+	Span
+	Name    *Identifier
+	Pattern Pattern
+}
+
+// TypePattern `c: Circle` matches the interface values holding a value of the type,
+// and binds the held value with that type. The name may be `_` to not bind it.
+type TypePattern struct {
+	Span
+	Name *Identifier
+	Type TypeExpr
+}
+
+// TuplePattern `(a, _, 0)` matches the elements of a tuple one by one.
+type TuplePattern struct {
+	Span
+	Elements []Pattern
+}
+
+// StructPattern `Point { x: 0, y }` destructures a struct. The fields which are
+// not listed match any value.
+type StructPattern struct {
+	Span
+	Type   Expr
+	Fields []*FieldPattern
+}
+
+// FieldPattern is `name: pattern` in a struct pattern. Pattern is nil
+// for the shorthand `name`, which binds the field to a variable of the same name.
+type FieldPattern struct {
+	Span
+	Name    *Identifier
+	Pattern Pattern
+}
+
+func (*MatchExpr) exprNode() {}
+
+func (*WildcardPattern) patternNode() {}
+func (*LiteralPattern) patternNode()  {}
+func (*RangePattern) patternNode()    {}
+func (*BindingPattern) patternNode()  {}
+func (*TypePattern) patternNode()     {}
+func (*TuplePattern) patternNode()    {}
+func (*StructPattern) patternNode()   {}
@@ -0,0 +1,73 @@
+package ast
+
+// Decl is implemented by the statements which declare a name. At the top level
+// of a file, a public declaration `pub func f()` is visible from the modules
+// importing the module of the file.
+type Decl interface {
+	Stmt
+	DeclName() *Identifier
+	IsPublic() bool
+}
+
+// ImportDecl imports a module by its path from the project root, like `import geometry.shapes`.
+// The module is bound to the last name of its path, or to Alias for `import geometry.shapes as geo`.
+// A selective import `import geometry.shapes.{Circle, area as circleArea}` binds the listed
+// declarations of the module instead, and has no Alias.
+type ImportDecl struct {
+	Span
+	Path  []*Identifier
+	Alias *Identifier
+	Items []*ImportItem
+}
+
+// ImportItem is a declaration `name` or `name as alias` imported by a selective import.
+type ImportItem struct {
+	Span
+	Name  *Identifier
+	Alias *Identifier
+}
+
+// PathString returns the module path like `geometry.shapes`.
+func (d *ImportDecl) PathString() string {
+	path := ""
+	for i, name := range d.Path {
+		if i > 0 {
+			path += "."
+		}
+		path += name.Name
+	}
+	return path
+}
+
+// Binding returns the name the module is bound to, it's nil for a selective import.
+func (d *ImportDecl) Binding() *Identifier {
+	if d.Items != nil {
+		return nil
+	}
+	if d.Alias != nil {
+		return d.Alias
+	}
+	return d.Path[len(d.Path)-1]
+}
+
+// Binding returns the name the imported declaration is bound to.
+func (i *ImportItem) Binding() *Identifier {
+	if i.Alias != nil {
+		return i.Alias
+	}
+	return i.Name
+}
+
+func (d *VarDecl) DeclName() *Identifier       { return d.Name }
+func (d *FuncDecl) DeclName() *Identifier      { return d.Name }
+func (d *StructDecl) DeclName() *Identifier    { return d.Name }
+func (d *InterfaceDecl) DeclName() *Identifier { return d.Name }
+func (d *EnumDecl) DeclName() *Identifier      { return d.Name }
+
+func (d *VarDecl) IsPublic() bool       { return d.Public }
+func (d *FuncDecl) IsPublic() bool      { return d.Public }
+func (d *StructDecl) IsPublic() bool    { return d.Public }
+func (d *InterfaceDecl) IsPublic() bool { return d.Public }
+func (d *EnumDecl) IsPublic() bool      { return d.Public }
+
+func (*ImportDecl) stmtNode() {}
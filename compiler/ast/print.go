@@ -0,0 +1,156 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mirth/compiler"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	nodeType  = reflect.TypeOf((*Node)(nil)).Elem()
+	spanType  = reflect.TypeOf(Span{})
+	tokenType = reflect.TypeOf((*compiler.Token)(nil))
+)
+
+// Fprint dumps the tree as indented text, one node per line with its span and
+// its attributes, under the name of the field holding it:
+//
+//	File 1:1-1:10
+//	  Statements[0]: VarDecl 1:1-1:10 Keyword="let"
+//	    Name: Identifier 1:5-1:6 Name="a"
+//	    Value: BasicLiteral 1:9-1:10 Kind=TokenTypeDecimalInteger Value="1"
+func Fprint(w io.Writer, node Node) error {
+	p := &printer{w: w}
+	p.printNode("", node, 0)
+	return p.err
+}
+
+// Sprint is like Fprint, but returns the text.
+func Sprint(node Node) string {
+	var builder strings.Builder
+	Fprint(&builder, node)
+	return builder.String()
+}
+
+type printer struct {
+	w   io.Writer
+	err error
+}
+
+func (p *printer) printf(format string, args ...any) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+func (p *printer) printNode(label string, node Node, depth int) {
+	value := reflect.ValueOf(node).Elem()
+	p.printf("%s%s%s %s", strings.Repeat("  ", depth), label, value.Type().Name(), node.NodeSpan())
+	var children []func()
+	forEachField(value, func(name string, field reflect.Value) {
+		switch {
+		case field.Type().Implements(nodeType):
+			if !field.IsNil() {
+				children = append(children, func() { p.printNode(name+": ", field.Interface().(Node), depth+1) })
+			}
+		case field.Kind() == reflect.Slice && field.Type().Elem().Implements(nodeType):
+			for i := 0; i < field.Len(); i++ {
+				element := field.Index(i).Interface().(Node)
+				label := fmt.Sprintf("%s[%d]: ", name, i)
+				children = append(children, func() { p.printNode(label, element, depth+1) })
+			}
+		default:
+			if attribute, present := attributeOf(field); present {
+				if text, isText := attribute.(string); isText {
+					attribute = strconv.Quote(text)
+				}
+				p.printf(" %s=%v", name, attribute)
+			}
+		}
+	})
+	p.printf("\n")
+	for _, printChild := range children {
+		printChild()
+	}
+}
+
+// forEachField calls f with the fields of a node struct, except the span.
+func forEachField(value reflect.Value, f func(name string, field reflect.Value)) {
+	for i := 0; i < value.NumField(); i++ {
+		if value.Type().Field(i).Type == spanType {
+			continue
+		}
+		f(value.Type().Field(i).Name, value.Field(i))
+	}
+}
+
+// attributeOf returns the printable value of a field which is not a node,
+// tokens are represented by their content.
+func attributeOf(field reflect.Value) (any, bool) {
+	switch {
+	case field.Type() == tokenType:
+		if field.IsNil() {
+			return nil, false
+		}
+		return field.Interface().(*compiler.Token).Content, true
+	case field.Type() == reflect.TypeOf(compiler.TokenType(0)):
+		return field.Interface().(compiler.TokenType).String(), true
+	case field.Kind() == reflect.Bool:
+		// Only the flags which are set, to keep the dump short
+		return field.Bool(), field.Bool()
+	default:
+		return field.Interface(), true
+	}
+}
+
+// JSON dumps the tree as indented JSON. Each node is an object with its type,
+// its span and its fields, with the field names in lower camel case:
+//
+//	{"type": "Identifier", "span": {"start": "1:5", "end": "1:6"}, "name": "a"}
+func JSON(node Node) ([]byte, error) {
+	return json.MarshalIndent(jsonOf(node), "", "  ")
+}
+
+func jsonOf(node Node) map[string]any {
+	value := reflect.ValueOf(node).Elem()
+	span := node.NodeSpan()
+	object := map[string]any{
+		"type": value.Type().Name(),
+		"span": map[string]any{"start": positionString(span.Start), "end": positionString(span.End)},
+	}
+	forEachField(value, func(name string, field reflect.Value) {
+		key := strings.ToLower(name[:1]) + name[1:]
+		switch {
+		case field.Type().Implements(nodeType):
+			if field.IsNil() {
+				object[key] = nil
+			} else {
+				object[key] = jsonOf(field.Interface().(Node))
+			}
+		case field.Kind() == reflect.Slice && field.Type().Elem().Implements(nodeType):
+			elements := make([]any, field.Len())
+			for i := range elements {
+				elements[i] = jsonOf(field.Index(i).Interface().(Node))
+			}
+			object[key] = elements
+		default:
+			attribute, _ := attributeOf(field)
+			if field.Kind() == reflect.Bool {
+				attribute = field.Bool()
+			}
+			object[key] = attribute
+		}
+	})
+	return object
+}
+
+func positionString(position *compiler.Position) string {
+	if position == nil {
+		return ""
+	}
+	return position.String()
+}
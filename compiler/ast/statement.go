@@ -0,0 +1,113 @@
+package ast
+
+import "mirth/compiler"
+
+// Stmt is implemented by all the statement nodes.
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// File is the root node of a source file.
+type File struct {
+	Span
+	Statements []Stmt
+}
+
+// VarDecl declares a variable with `let`, or a constant with `const`.
+// Type is nil if there's no type annotation, and Value is nil for `let x: int`.
+type VarDecl struct {
+	Span
+	Keyword *compiler.Token
+	Name    *Identifier
+	Type    TypeExpr
+	Value   Expr
+	Public  bool
+}
+
+func (d *VarDecl) IsConst() bool {
+	return d.Keyword.Type == compiler.TokenTypeConst
+}
+
+// BadStmt is a placeholder for a statement with syntax errors,
+// covering the source skipped by the parser to recover from them.
+type BadStmt struct {
+	Span
+}
+
+// ExprStmt is an expression evaluated for its side effects, like a call or an assignment.
+type ExprStmt struct {
+	Span
+	Expr Expr
+}
+
+// BlockStmt is a list of statements wrapped by curly braces.
+type BlockStmt struct {
+	Span
+	Statements []Stmt
+}
+
+// IfStmt is an `if` statement. Else is nil, an *IfStmt for `else if`, or a *BlockStmt.
+type IfStmt struct {
+	Span
+	Condition Expr
+	Then      *BlockStmt
+	Else      Stmt
+}
+
+// ForStmt is a C-style `for init; condition; post { }` loop.
+// `for condition { }` only has a condition, and `for { }` has none of them.
+type ForStmt struct {
+	Span
+	Label     *Identifier
+	Init      Stmt
+	Condition Expr
+	Post      Expr
+	Body      *BlockStmt
+}
+
+// ForInStmt is a range-based `for item in iterable { }` loop.
+type ForInStmt struct {
+	Span
+	Label    *Identifier
+	Binding  *Identifier
+	Iterable Expr
+	Body     *BlockStmt
+}
+
+// LoopStmt is an infinite `loop { }`, it can only be exited by `break` or `return`.
+type LoopStmt struct {
+	Span
+	Label *Identifier
+	Body  *BlockStmt
+}
+
+// BreakStmt exits the innermost loop, or the loop with the label.
+type BreakStmt struct {
+	Span
+	Label *Identifier
+}
+
+// ContinueStmt starts the next iteration of the innermost loop, or the loop with the label.
+type ContinueStmt struct {
+	Span
+	Label *Identifier
+}
+
+// ReturnStmt returns from the function, Value is nil for a bare `return`.
+type ReturnStmt struct {
+	Span
+	Value Expr
+}
+
+func (*BadStmt) stmtNode()      {}
+func (*VarDecl) stmtNode()      {}
+func (*ExprStmt) stmtNode()     {}
+func (*BlockStmt) stmtNode()    {}
+func (*IfStmt) stmtNode()       {}
+func (*ForStmt) stmtNode()      {}
+func (*ForInStmt) stmtNode()    {}
+func (*LoopStmt) stmtNode()     {}
+func (*BreakStmt) stmtNode()    {}
+func (*ContinueStmt) stmtNode() {}
+func (*ReturnStmt) stmtNode()   {}
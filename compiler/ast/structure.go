@@ -0,0 +1,68 @@
+package ast
+
+// Field is a struct field `name: Type = default`.
+// An embedded struct has no name, and its Type is the embedded struct.
+type Field struct {
+	Span
+	Name     *Identifier
+	Type     TypeExpr
+	Default  Expr
+	Embedded bool
+}
+
+// StructDecl is a struct declaration `struct Name { fields }`,
+// or `struct Name<T> { fields }` for a generic struct.
+type StructDecl struct {
+	Span
+	Name       *Identifier
+	TypeParams []*TypeParam
+	Fields     []*Field
+	Public     bool
+}
+
+// MethodSignature is a method required by an interface, `name(params) ReturnType`.
+type MethodSignature struct {
+	Span
+	Name       *Identifier
+	Params     []*Param
+	ReturnType TypeExpr
+}
+
+// InterfaceDecl is an interface declaration `interface Name { methods }`.
+// Embedded holds the interfaces whose methods are included in this one.
+type InterfaceDecl struct {
+	Span
+	Name     *Identifier
+	Methods  []*MethodSignature
+	Embedded []TypeExpr
+	Public   bool
+}
+
+// FieldValue is a field initializer `name: value` in a struct literal.
+type FieldValue struct {
+	Span
+	Name  *Identifier
+	Value Expr
+}
+
+// StructLit is a struct literal `Name { field: value }`.
+// Type is an *Identifier, a *MemberExpr for a struct from another module,
+// or an *InstantiateExpr for a generic struct.
+type StructLit struct {
+	Span
+	Type   Expr
+	Fields []*FieldValue
+}
+
+// TypeAssertion `value.(Type)` asserts that an interface value holds a value of the type,
+// and yields that value. Type may also be an interface, that the held value implements.
+type TypeAssertion struct {
+	Span
+	Value Expr
+	Type  TypeExpr
+}
+
+func (*StructDecl) stmtNode()    {}
+func (*InterfaceDecl) stmtNode() {}
+func (*StructLit) exprNode()     {}
+func (*TypeAssertion) exprNode() {}
@@ -0,0 +1,44 @@
+package ast
+
+// TemplateText is a text fragment of a template string, with escapes already decoded.
+type TemplateText struct {
+	Span
+	Value string
+}
+
+// TemplateString is a template literal `text ${expr} text`.
+// Fragments and Exprs alternate, starting and ending with a fragment,
+// so there is always one more fragment than expressions. Fragments
+// are empty where two interpolations are adjacent.
+type TemplateString struct {
+	Span
+	Fragments []*TemplateText
+	Exprs     []Expr
+}
+
+// TaggedTemplate is a template string prefixed by a tag function, like sql`...`.
+// Instead of concatenating the template, the tag function is called with the
+// fragments and the evaluated expressions separately:
+//
+//	sql`SELECT * FROM users WHERE id = ${id}`
+//	// is evaluated as
+//	sql(["SELECT * FROM users WHERE id = ", ""], [id])
+//
+// so that it can escape the values for safe query and HTML building.
+type TaggedTemplate struct {
+	Span
+	Tag      Expr
+	Template *TemplateString
+}
+
+// FragmentValues returns the text of all the fragments, which the tag function receives.
+func (t *TemplateString) FragmentValues() []string {
+	values := make([]string, len(t.Fragments))
+	for i, fragment := range t.Fragments {
+		values[i] = fragment.Value
+	}
+	return values
+}
+
+func (*TemplateString) exprNode() {}
+func (*TaggedTemplate) exprNode() {}
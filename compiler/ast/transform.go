@@ -0,0 +1,253 @@
+package ast
+
+import "fmt"
+
+type spanSetter interface {
+	setSpan(span Span)
+}
+
+func (s *Span) setSpan(span Span) {
+	*s = span
+}
+
+// Transform rewrites the tree bottom-up: the children of a node are transformed
+// first, then rewrite(node) returns the node to put in its place, which may be
+// the node itself. The tree is modified in place, and the new root is returned.
+//
+// A replacement without span takes the span of the node it replaces, so
+// diagnostics about the rewritten tree still point at the source. Returning
+// nil removes the node from a list, or clears an optional field. A replacement
+// must fit the field it's put in, an expression can't replace a statement.
+func Transform(node Node, rewrite func(Node) Node) Node {
+	if isNil(node) {
+		return node
+	}
+	t := transformer(rewrite)
+	switch n := node.(type) {
+	case *Identifier, *BasicLiteral, *BadExpr, *BadStmt, *TemplateText, *WildcardPattern:
+		// Leaves
+	case *ParenExpr:
+		n.Expr = transformField(t, n.Expr)
+	case *UnaryExpr:
+		n.Operand = transformField(t, n.Operand)
+	case *PostfixExpr:
+		n.Operand = transformField(t, n.Operand)
+	case *BinaryExpr:
+		n.Left = transformField(t, n.Left)
+		n.Right = transformField(t, n.Right)
+	case *AssignExpr:
+		n.Target = transformField(t, n.Target)
+		n.Value = transformField(t, n.Value)
+	case *CallExpr:
+		n.Callee = transformField(t, n.Callee)
+		n.Arguments = transformList(t, n.Arguments)
+	case *IndexExpr:
+		n.Target = transformField(t, n.Target)
+		n.Index = transformField(t, n.Index)
+	case *MemberExpr:
+		n.Target = transformField(t, n.Target)
+		n.Name = transformField(t, n.Name)
+	case *ArrayLit:
+		n.Elements = transformList(t, n.Elements)
+	case *TupleLit:
+		n.Elements = transformList(t, n.Elements)
+	case *RangeExpr:
+		n.Start = transformField(t, n.Start)
+		n.End = transformField(t, n.End)
+	case *SpreadExpr:
+		n.Expr = transformField(t, n.Expr)
+	case *TemplateString:
+		// Fragments are kept, so that they still alternate with the expressions
+		for i := range n.Exprs {
+			n.Exprs[i] = transformField(t, n.Exprs[i])
+		}
+	case *TaggedTemplate:
+		n.Tag = transformField(t, n.Tag)
+		n.Template = transformField(t, n.Template)
+	case *StructLit:
+		n.Type = transformField(t, n.Type)
+		n.Fields = transformList(t, n.Fields)
+	case *FieldValue:
+		n.Name = transformField(t, n.Name)
+		n.Value = transformField(t, n.Value)
+	case *TypeAssertion:
+		n.Value = transformField(t, n.Value)
+		n.Type = transformField(t, n.Type)
+
+	case *MatchExpr:
+		n.Subject = transformField(t, n.Subject)
+		n.Arms = transformList(t, n.Arms)
+	case *MatchArm:
+		n.Pattern = transformField(t, n.Pattern)
+		n.Guard = transformField(t, n.Guard)
+		n.Body = transformField(t, n.Body)
+	case *LiteralPattern:
+		n.Value = transformField(t, n.Value)
+	case *RangePattern:
+		n.Start = transformField(t, n.Start)
+		n.End = transformField(t, n.End)
+	case *BindingPattern:
+		n.Name = transformField(t, n.Name)
+		n.Pattern = transformField(t, n.Pattern)
+	case *TypePattern:
+		n.Name = transformField(t, n.Name)
+		n.Type = transformField(t, n.Type)
+	case *TuplePattern:
+		n.Elements = transformList(t, n.Elements)
+	case *StructPattern:
+		n.Type = transformField(t, n.Type)
+		n.Fields = transformList(t, n.Fields)
+	case *FieldPattern:
+		n.Name = transformField(t, n.Name)
+		n.Pattern = transformField(t, n.Pattern)
+	case *VariantPattern:
+		n.Variant = transformField(t, n.Variant)
+		n.Elements = transformList(t, n.Elements)
+
+	case *Param:
+		n.Name = transformField(t, n.Name)
+		n.Type = transformField(t, n.Type)
+		n.Default = transformField(t, n.Default)
+	case *TypeParam:
+		n.Name = transformField(t, n.Name)
+		n.Constraint = transformField(t, n.Constraint)
+	case *InstantiateExpr:
+		n.Target = transformField(t, n.Target)
+		n.TypeArgs = transformList(t, n.TypeArgs)
+	case *FuncDecl:
+		n.Receiver = transformField(t, n.Receiver)
+		n.Name = transformField(t, n.Name)
+		n.TypeParams = transformList(t, n.TypeParams)
+		n.Params = transformList(t, n.Params)
+		n.ReturnType = transformField(t, n.ReturnType)
+		n.Body = transformField(t, n.Body)
+	case *FuncLit:
+		n.Params = transformList(t, n.Params)
+		n.ReturnType = transformField(t, n.ReturnType)
+		n.Body = transformField(t, n.Body)
+	case *ArrowFunc:
+		n.Params = transformList(t, n.Params)
+		n.Body = transformField(t, n.Body)
+	case *FuncType:
+		n.Params = transformList(t, n.Params)
+		n.ReturnType = transformField(t, n.ReturnType)
+
+	case *File:
+		n.Statements = transformList(t, n.Statements)
+	case *VarDecl:
+		n.Name = transformField(t, n.Name)
+		n.Type = transformField(t, n.Type)
+		n.Value = transformField(t, n.Value)
+	case *ExprStmt:
+		n.Expr = transformField(t, n.Expr)
+	case *BlockStmt:
+		n.Statements = transformList(t, n.Statements)
+	case *IfStmt:
+		n.Condition = transformField(t, n.Condition)
+		n.Then = transformField(t, n.Then)
+		n.Else = transformField(t, n.Else)
+	case *ForStmt:
+		n.Label = transformField(t, n.Label)
+		n.Init = transformField(t, n.Init)
+		n.Condition = transformField(t, n.Condition)
+		n.Post = transformField(t, n.Post)
+		n.Body = transformField(t, n.Body)
+	case *ForInStmt:
+		n.Label = transformField(t, n.Label)
+		n.Binding = transformField(t, n.Binding)
+		n.Iterable = transformField(t, n.Iterable)
+		n.Body = transformField(t, n.Body)
+	case *LoopStmt:
+		n.Label = transformField(t, n.Label)
+		n.Body = transformField(t, n.Body)
+	case *BreakStmt:
+		n.Label = transformField(t, n.Label)
+	case *ContinueStmt:
+		n.Label = transformField(t, n.Label)
+	case *ReturnStmt:
+		n.Value = transformField(t, n.Value)
+
+	case *Field:
+		n.Name = transformField(t, n.Name)
+		n.Type = transformField(t, n.Type)
+		n.Default = transformField(t, n.Default)
+	case *StructDecl:
+		n.Name = transformField(t, n.Name)
+		n.TypeParams = transformList(t, n.TypeParams)
+		n.Fields = transformList(t, n.Fields)
+	case *ImportDecl:
+		n.Path = transformList(t, n.Path)
+		n.Alias = transformField(t, n.Alias)
+		n.Items = transformList(t, n.Items)
+	case *ImportItem:
+		n.Name = transformField(t, n.Name)
+		n.Alias = transformField(t, n.Alias)
+	case *EnumDecl:
+		n.Name = transformField(t, n.Name)
+		n.TypeParams = transformList(t, n.TypeParams)
+		n.Variants = transformList(t, n.Variants)
+	case *Variant:
+		n.Name = transformField(t, n.Name)
+		n.Tuple = transformList(t, n.Tuple)
+		n.Fields = transformList(t, n.Fields)
+		n.Discriminant = transformField(t, n.Discriminant)
+	case *MethodSignature:
+		n.Name = transformField(t, n.Name)
+		n.Params = transformList(t, n.Params)
+		n.ReturnType = transformField(t, n.ReturnType)
+	case *InterfaceDecl:
+		n.Name = transformField(t, n.Name)
+		n.Embedded = transformList(t, n.Embedded)
+		n.Methods = transformList(t, n.Methods)
+
+	case *NamedType:
+		n.Name = transformField(t, n.Name)
+		n.TypeArgs = transformList(t, n.TypeArgs)
+	case *ArrayType:
+		n.Length = transformField(t, n.Length)
+		n.Element = transformField(t, n.Element)
+	case *TupleType:
+		n.Elements = transformList(t, n.Elements)
+	case *OptionalType:
+		n.Elem = transformField(t, n.Elem)
+	default:
+		panic(fmt.Sprintf("ast.Transform: unexpected node %T", node))
+	}
+
+	replacement := rewrite(node)
+	if replacement != node && !isNil(replacement) && replacement.NodeSpan().Start == nil {
+		if setter, ok := replacement.(spanSetter); ok {
+			setter.setSpan(node.NodeSpan())
+		}
+	}
+	return replacement
+}
+
+type transformer func(Node) Node
+
+// transformField transforms the node of a field, and checks that the replacement fits the field.
+func transformField[T Node](t transformer, node T) T {
+	var zero T
+	if isNil(node) {
+		return node
+	}
+	replacement := Transform(node, t)
+	if isNil(replacement) {
+		return zero
+	}
+	result, fits := replacement.(T)
+	if !fits {
+		panic(fmt.Sprintf("ast.Transform: %T can't be replaced by %T", node, replacement))
+	}
+	return result
+}
+
+func transformList[T Node](t transformer, nodes []T) []T {
+	result := nodes[:0]
+	for _, node := range nodes {
+		if replacement := transformField(t, node); !isNil(replacement) {
+			result = append(result, replacement)
+		}
+	}
+	return result
+}
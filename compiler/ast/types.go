@@ -0,0 +1,39 @@
+package ast
+
+// TypeExpr is implemented by all the nodes of type annotations.
+type TypeExpr interface {
+	Node
+	typeNode()
+}
+
+// NamedType refers to a type by its name, like `int` and `string`,
+// with the type arguments of a generic type, like `Map<string, int>`.
+type NamedType struct {
+	Span
+	Name     *Identifier
+	TypeArgs []TypeExpr
+}
+
+// ArrayType is `[]T` for a dynamically sized array, or `[N]T` if Length isn't nil.
+type ArrayType struct {
+	Span
+	Length  Expr
+	Element TypeExpr
+}
+
+// TupleType is `(A, B)`, the type of tuples whose elements have the types A and B.
+type TupleType struct {
+	Span
+	Elements []TypeExpr
+}
+
+// OptionalType is `T?`, the type of the values which are either a T or nil.
+type OptionalType struct {
+	Span
+	Elem TypeExpr
+}
+
+func (*NamedType) typeNode()    {}
+func (*ArrayType) typeNode()    {}
+func (*TupleType) typeNode()    {}
+func (*OptionalType) typeNode() {}
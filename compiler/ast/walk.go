@@ -0,0 +1,36 @@
+package ast
+
+// Visitor's Visit method is called for each node met by Walk.
+// If the returned visitor w is not nil, Walk visits each child of the node
+// with w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree in depth-first order: it starts by calling v.Visit(node),
+// then walks the children of the node with the returned visitor, in field order.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range Children(node) {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree in depth-first order, calling f(node) for each node.
+// The children of a node are visited only if f(node) returns true, and f(nil)
+// is called after all the children of a node have been visited.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,139 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/parser"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func parseFile(source string) *ast.File {
+	return parser.CreateParser(source).ParseFile().Unwrap()
+}
+
+type depthCounter struct {
+	depth    int
+	maxDepth *int
+}
+
+func (c depthCounter) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		return nil
+	}
+	if c.depth > *c.maxDepth {
+		*c.maxDepth = c.depth
+	}
+	return depthCounter{c.depth + 1, c.maxDepth}
+}
+
+func TestWalk(t *testing.T) {
+	Convey("Test inspect nodes in depth-first order", t, func() {
+		file := parseFile("let a = f(x, 1)\nif a { return }")
+		var names []string
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.Identifier:
+				names = append(names, n.Name)
+			case *ast.BlockStmt:
+				return false
+			}
+			return true
+		})
+		So(names, ShouldResemble, []string{"a", "f", "x", "a"})
+	})
+
+	Convey("Test walk with a visitor per level", t, func() {
+		maxDepth := 0
+		ast.Walk(depthCounter{0, &maxDepth}, parseFile("let a = -(1 + b)"))
+		// File, VarDecl, UnaryExpr, ParenExpr, BinaryExpr, Identifier
+		So(maxDepth, ShouldEqual, 5)
+	})
+}
+
+func TestTransform(t *testing.T) {
+	Convey("Test fold constants and keep spans", t, func() {
+		file := parseFile("let a = x * (2 + 3)")
+		ast.Transform(file, func(node ast.Node) ast.Node {
+			binary, isBinary := node.(*ast.BinaryExpr)
+			if !isBinary {
+				if paren, isParen := node.(*ast.ParenExpr); isParen {
+					if literal, isLiteral := paren.Expr.(*ast.BasicLiteral); isLiteral {
+						return literal
+					}
+				}
+				return node
+			}
+			left, isLeftLiteral := binary.Left.(*ast.BasicLiteral)
+			right, isRightLiteral := binary.Right.(*ast.BasicLiteral)
+			if !isLeftLiteral || !isRightLiteral || binary.Operator.Content != "+" {
+				return node
+			}
+			a, _ := strconv.Atoi(left.Value)
+			b, _ := strconv.Atoi(right.Value)
+			return &ast.BasicLiteral{Kind: compiler.TokenTypeDecimalInteger, Value: strconv.Itoa(a + b)}
+		})
+		value := file.Statements[0].(*ast.VarDecl).Value.(*ast.BinaryExpr)
+		folded := value.Right.(*ast.BasicLiteral)
+		So(folded.Value, ShouldEqual, "5")
+		// The folded literal took the span of `2 + 3`
+		So(folded.Start.Offset, ShouldEqual, 13)
+		So(folded.End.Offset, ShouldEqual, 18)
+	})
+
+	Convey("Test remove statements", t, func() {
+		file := parseFile("debug(1)\nlet a = 1\ndebug(2)")
+		ast.Transform(file, func(node ast.Node) ast.Node {
+			if stmt, isExprStmt := node.(*ast.ExprStmt); isExprStmt {
+				if call, isCall := stmt.Expr.(*ast.CallExpr); isCall && call.Callee.(*ast.Identifier).Name == "debug" {
+					return nil
+				}
+			}
+			return node
+		})
+		So(len(file.Statements), ShouldEqual, 1)
+	})
+
+	Convey("Test reject a replacement which doesn't fit", t, func() {
+		file := parseFile("let a = 1")
+		So(func() {
+			ast.Transform(file, func(node ast.Node) ast.Node {
+				if _, isLiteral := node.(*ast.BasicLiteral); isLiteral {
+					return &ast.BreakStmt{}
+				}
+				return node
+			})
+		}, ShouldPanicWith, "ast.Transform: *ast.BasicLiteral can't be replaced by *ast.BreakStmt")
+	})
+}
+
+func TestPrint(t *testing.T) {
+	Convey("Test print the tree as indented text", t, func() {
+		So(ast.Sprint(parseFile("let a = -b\nf(...xs)")), ShouldEqual, `File 1:1-2:9
+  Statements[0]: VarDecl 1:1-1:11 Keyword="let"
+    Name: Identifier 1:5-1:6 Name="a"
+    Value: UnaryExpr 1:9-1:11 Operator="-"
+      Operand: Identifier 1:10-1:11 Name="b"
+  Statements[1]: ExprStmt 2:1-2:9
+    Expr: CallExpr 2:1-2:9
+      Callee: Identifier 2:1-2:2 Name="f"
+      Arguments[0]: SpreadExpr 2:3-2:8
+        Expr: Identifier 2:6-2:8 Name="xs"
+`)
+	})
+
+	Convey("Test print the tree as JSON", t, func() {
+		output, err := ast.JSON(parseFile("x?.y"))
+		So(err, ShouldBeNil)
+		var decoded map[string]any
+		So(json.Unmarshal(output, &decoded), ShouldBeNil)
+		member := decoded["statements"].([]any)[0].(map[string]any)["expr"].(map[string]any)
+		So(member["type"], ShouldEqual, "MemberExpr")
+		So(member["optional"], ShouldEqual, true)
+		So(member["span"], ShouldResemble, map[string]any{"start": "1:1", "end": "1:5"})
+		So(member["name"].(map[string]any)["name"], ShouldEqual, "y")
+	})
+}
@@ -1,8 +1,11 @@
 package compiler
 
 import (
+	"bytes"
 	"fmt"
 	"mirth/shared"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/fatih/color"
 )
@@ -40,10 +43,54 @@ const (
 	// Scanner errors, mostly related to syntax issues
 	UnexpectedToken
 	FailedToRetrieveToken
+	UnexpectedEndOfFile
+
+	// Parser errors
+	InvalidAssignmentTarget
+	NonAssociativeOperator
+	InvalidParameter
+	InvalidRange
+
+	// Semantic errors
+	NonExhaustiveMatch
+	DuplicateDeclaration
+	InvalidDiscriminant
+	UndefinedName
+
+	// Module errors
+	ModuleNotFound
+	UnresolvedImport
+	ImportCycle
+
+	// Type errors
+	TypeMismatch
+	InvalidOperation
+	ArgumentCount
+	UnknownMember
+	CannotInferType
+	PossiblyNil
+
+	// Constant errors
+	NotConstant
+	ConstantOverflow
+
+	// Control flow errors
+	MissingReturn
+	JumpOutsideLoop
+	UnassignedVariable
+	ImmutableAssignment
 
 	// ---- 2. Warning Codes:
 	// UnknownWarning is an fallback warning code for warnings that don't have a clear specification.
 	UnknownWarning
+
+	UnreachablePattern
+	ShadowedDeclaration
+	UnreachableCode
+	InfiniteLoop
+	// LintFinding is the code of the findings of the lint rules, it's an error or a warning
+	// depending on the severity of the rule.
+	LintFinding
 )
 
 // Error type represents something unexpected in the source code.
@@ -51,7 +98,20 @@ type Diagnostic struct {
 	Type DiagnosticType
 	Code DiagnosticCode
 	Pos  *Position
-	Msg  string
+	// End is right after the offending source, like the end of an unexpected token.
+	// It's nil if the diagnostic points at a single position.
+	End *Position
+	Msg string
+	// Notes point at the other sources the diagnostic relates to,
+	// like the type annotation a mismatching value is checked against.
+	Notes []*Note
+}
+
+// Note is a secondary message of a diagnostic, about another span of the same source.
+type Note struct {
+	Pos *Position
+	End *Position
+	Msg string
 }
 
 func (d *Diagnostic) String() string {
@@ -70,9 +130,69 @@ func (d *Diagnostic) Error() string {
 	return d.String()
 }
 
+// Render formats the diagnostic with the source line it points at,
+// and a caret underlining the offending span on that line:
+//
+//	Error  1:9: Unexpected token: expected expression, found ')'
+//	  1 | let a = )
+//	    |         ^
+//
+// The notes follow, with the spans they point at underlined by dashes.
+func (d *Diagnostic) Render(source []byte) string {
+	if d.Pos == nil || d.Pos.Offset > len(source) {
+		return d.String()
+	}
+	markerColor := shared.Ternary(d.Type == DiagnosticError, color.FgRed, color.FgYellow)
+	var rendered strings.Builder
+	rendered.WriteString(d.String())
+	rendered.WriteString(renderSpan(source, d.Pos, d.End, '^', markerColor))
+	for _, note := range d.Notes {
+		rendered.WriteString(fmt.Sprintf("\n %s: %s", note.Pos, note.Msg))
+		if note.Pos.Offset <= len(source) {
+			rendered.WriteString(renderSpan(source, note.Pos, note.End, '-', color.FgCyan))
+		}
+	}
+	return rendered.String()
+}
+
+// renderSpan formats the source line of a span, with the span underlined by the marker.
+func renderSpan(source []byte, pos, end *Position, marker rune, markerColor color.Attribute) string {
+	lineStart := bytes.LastIndexByte(source[:pos.Offset], '\n') + 1
+	lineEnd := len(source)
+	if index := bytes.IndexByte(source[pos.Offset:], '\n'); index >= 0 {
+		lineEnd = pos.Offset + index
+	}
+	line := strings.TrimRight(string(source[lineStart:lineEnd]), "\r")
+
+	// Tabs are kept in the padding so that the caret stays aligned with the line
+	var padding strings.Builder
+	for _, r := range string(source[lineStart:pos.Offset]) {
+		padding.WriteRune(shared.Ternary(r == '\t', '\t', ' '))
+	}
+	width := 1
+	if end != nil && end.Offset > pos.Offset {
+		// A span over several lines is underlined until the end of the first one
+		spanEnd := shared.Ternary(end.Offset < lineEnd, end.Offset, lineEnd)
+		if count := utf8.RuneCount(source[pos.Offset:spanEnd]); count > 1 {
+			width = count
+		}
+	}
+
+	lineNumber := fmt.Sprint(pos.Line)
+	gutter := strings.Repeat(" ", len(lineNumber))
+	return fmt.Sprintf(
+		"\n %s | %s\n %s | %s%s",
+		lineNumber, line,
+		gutter, padding.String(), shared.ColorString(
+			strings.Repeat(string(marker), width),
+			[]color.Attribute{markerColor, color.Bold},
+		),
+	)
+}
+
 func CreateErrorDiagnostic(code DiagnosticCode, pos *Position, msg string) *Diagnostic {
-	return &Diagnostic{DiagnosticError, code, pos, msg}
+	return &Diagnostic{Type: DiagnosticError, Code: code, Pos: pos, Msg: msg}
 }
 func CreateWarningDiagnostic(code DiagnosticCode, pos *Position, msg string) *Diagnostic {
-	return &Diagnostic{DiagnosticWarning, code, pos, msg}
+	return &Diagnostic{Type: DiagnosticWarning, Code: code, Pos: pos, Msg: msg}
 }
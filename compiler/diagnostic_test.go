@@ -0,0 +1,36 @@
+package compiler
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRenderDiagnostic(t *testing.T) {
+	Convey("Test render the caret under the offending span", t, func() {
+		source := []byte("let a = 1\n\tlet b = ret(\n")
+		diagnostic := CreateErrorDiagnostic(UnexpectedToken, CreatePositon(19, 2, 10), "Unexpected token")
+		diagnostic.End = CreatePositon(22, 2, 13)
+		So(diagnostic.Render(source), ShouldEqual, " Error  2:10: Unexpected token\n"+
+			" 2 | \tlet b = ret(\n"+
+			"   | \t        ^^^")
+	})
+
+	Convey("Test render a single position", t, func() {
+		diagnostic := CreateWarningDiagnostic(UnknownWarning, CreatePositon(3, 1, 4), "Nothing")
+		So(diagnostic.Render([]byte("abc")), ShouldEqual, " Warning  1:4: Nothing\n 1 | abc\n   |    ^")
+	})
+
+	Convey("Test render the notes under the diagnostic", t, func() {
+		source := []byte("let a: int = \"one\"")
+		diagnostic := CreateErrorDiagnostic(TypeMismatch, CreatePositon(13, 1, 14), "Type mismatch")
+		diagnostic.End = CreatePositon(18, 1, 19)
+		diagnostic.Notes = []*Note{{Pos: CreatePositon(7, 1, 8), End: CreatePositon(10, 1, 11), Msg: "Expected because of this"}}
+		So(diagnostic.Render(source), ShouldEqual, " Error  1:14: Type mismatch\n"+
+			" 1 | let a: int = \"one\"\n"+
+			"   |              ^^^^^\n"+
+			" 1:8: Expected because of this\n"+
+			" 1 | let a: int = \"one\"\n"+
+			"   |        ---")
+	})
+}
@@ -0,0 +1,186 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/parser"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Options controls how the driver processes source files.
+type Options struct {
+	// Maximum number of files processed at the same time,
+	// defaults to the number of usable CPUs.
+	Workers int
+	// Treat the first error diagnostic as fatal and cancel the remaining work.
+	StopOnError bool
+	// Operators recognized by the scanners, defaults to the builtin ones.
+	Operators *compiler.OperatorTable
+}
+
+// FileDiagnostic is a diagnostic attached to the file it was reported in.
+type FileDiagnostic struct {
+	Path string
+	*compiler.Diagnostic
+}
+
+func (d *FileDiagnostic) String() string {
+	return fmt.Sprintf("%s:%s", d.Path, d.Diagnostic.String())
+}
+
+// SourceFile is the processing result of a single file.
+type SourceFile struct {
+	Path        string
+	Source      []byte
+	Tokens      []*compiler.Token
+	AST         *ast.File // Syntax errors are replaced by bad nodes
+	Diagnostics []*compiler.Diagnostic
+	// Skipped is true if the work was cancelled before the file was processed.
+	Skipped bool
+
+	ReadDuration  time.Duration
+	ScanDuration  time.Duration
+	ParseDuration time.Duration
+}
+
+func (f *SourceFile) hasError() bool {
+	for _, diagnostic := range f.Diagnostics {
+		if diagnostic.Type == compiler.DiagnosticError {
+			return true
+		}
+	}
+	return false
+}
+
+// Report gathers the results of all the files, in the same order as the given paths.
+type Report struct {
+	Files []*SourceFile
+	// Diagnostics of all the files, merged in file order.
+	Diagnostics []*FileDiagnostic
+	// Fatal is the error which cancelled the remaining work.
+	Fatal    error
+	Duration time.Duration
+}
+
+type fatalError struct {
+	fileIndex int
+	err       error
+}
+
+// Run scans and parses the given files on a bounded pool of workers.
+// Cancelling the context, or meeting a fatal error, stops the workers
+// from picking up new files. The report is deterministic: it doesn't
+// depend on the order in which the workers finish.
+func Run(ctx context.Context, paths []string, options *Options) *Report {
+	if options == nil {
+		options = &Options{}
+	}
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	startTime := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	files := make([]*SourceFile, len(paths))
+	for i, path := range paths {
+		files[i] = &SourceFile{Path: path, Skipped: true}
+	}
+
+	var fatalMutex sync.Mutex
+	var fatal *fatalError
+	reportFatal := func(fileIndex int, err error) {
+		fatalMutex.Lock()
+		defer fatalMutex.Unlock()
+		// Keep the fatal error of the first file, so the report stays deterministic
+		// when several workers fail at the same time.
+		if fatal == nil || fileIndex < fatal.fileIndex {
+			fatal = &fatalError{fileIndex, err}
+		}
+		cancel()
+	}
+
+	jobs := make(chan int)
+	var waitGroup sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for fileIndex := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				file := files[fileIndex]
+				if err := processFile(ctx, file, options); err != nil {
+					reportFatal(fileIndex, err)
+				} else if options.StopOnError && file.hasError() {
+					reportFatal(fileIndex, fmt.Errorf("%s: stopped on the first error", file.Path))
+				}
+			}
+		}()
+	}
+
+sendJobs:
+	for i := range paths {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break sendJobs
+		}
+	}
+	close(jobs)
+	waitGroup.Wait()
+
+	report := &Report{Files: files}
+	for _, file := range files {
+		for _, diagnostic := range file.Diagnostics {
+			report.Diagnostics = append(report.Diagnostics, &FileDiagnostic{file.Path, diagnostic})
+		}
+	}
+	if fatal != nil {
+		report.Fatal = fatal.err
+	} else if ctx.Err() != nil {
+		report.Fatal = ctx.Err()
+	}
+	report.Duration = time.Since(startTime)
+	return report
+}
+
+func processFile(ctx context.Context, file *SourceFile, options *Options) error {
+	file.Skipped = false
+
+	readStartTime := time.Now()
+	source, err := os.ReadFile(file.Path)
+	file.ReadDuration = time.Since(readStartTime)
+	if err != nil {
+		return err
+	}
+	file.Source = source
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	scanStartTime := time.Now()
+	scanner := compiler.CreateScanner(source)
+	if options.Operators != nil {
+		scanner.UseOperatorTable(options.Operators)
+	}
+	file.Tokens, file.Diagnostics = scanner.Tokenize()
+	file.ScanDuration = time.Since(scanStartTime)
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	parseStartTime := time.Now()
+	fileParser := parser.CreateParserFromTokens(file.Tokens, options.Operators)
+	file.AST = fileParser.ParseFile().Unwrap()
+	file.Diagnostics = append(file.Diagnostics, fileParser.Diagnostics()...)
+	file.ParseDuration = time.Since(parseStartTime)
+	return nil
+}
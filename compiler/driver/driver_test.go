@@ -0,0 +1,107 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func writeSourceFiles(t *testing.T, sources []string) []string {
+	dir := t.TempDir()
+	var paths []string
+	for i, source := range sources {
+		path := filepath.Join(dir, fmt.Sprintf("file_%03d.mirth", i))
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestRunDriver(t *testing.T) {
+	Convey("Test scan files in input order", t, func() {
+		var sources []string
+		for i := 0; i < 64; i++ {
+			sources = append(sources, fmt.Sprintf("let x%d = %d\n", i, i))
+		}
+		paths := writeSourceFiles(t, sources)
+		report := Run(context.Background(), paths, &Options{Workers: 4})
+
+		So(report.Fatal, ShouldBeNil)
+		So(len(report.Files), ShouldEqual, 64)
+		for i, file := range report.Files {
+			So(file.Path, ShouldEqual, paths[i])
+			So(file.Skipped, ShouldBeFalse)
+			So(file.Tokens[1].Content, ShouldEqual, fmt.Sprintf("x%d", i))
+			So(file.ScanDuration, ShouldBeGreaterThan, 0)
+			So(file.ParseDuration, ShouldBeGreaterThan, 0)
+			So(file.AST.Statements[0].(*ast.VarDecl).Name.Name, ShouldEqual, fmt.Sprintf("x%d", i))
+		}
+	})
+
+	Convey("Test merge diagnostics in file order", t, func() {
+		paths := writeSourceFiles(t, []string{
+			"let a = 0o3e2\nlet b = 123e\n",
+			"let c = 1\n",
+			"let d = \"unterminated",
+			"let e = (1 +\n",
+		})
+		for round := 0; round < 10; round++ {
+			report := Run(context.Background(), paths, &Options{Workers: 4})
+			So(report.Fatal, ShouldBeNil)
+			var diagnosticPaths []string
+			for _, diagnostic := range report.Diagnostics {
+				diagnosticPaths = append(diagnosticPaths, filepath.Base(diagnostic.Path))
+			}
+			// Both of the scanning errors in the first file make the parser fail as well
+			So(diagnosticPaths, ShouldResemble, []string{
+				"file_000.mirth", "file_000.mirth", "file_000.mirth", "file_000.mirth",
+				"file_002.mirth", "file_002.mirth",
+				"file_003.mirth",
+			})
+			So(report.Diagnostics[0].Code, ShouldEqual, compiler.UnexpectedToken)
+			So(report.Diagnostics[4].Code, ShouldEqual, compiler.UnexpectedEndOfFile)
+			So(report.Diagnostics[6].Msg, ShouldEqual, "Unexpected token: expected expression, found end of file")
+			So(report.Files[1].AST, ShouldNotBeNil)
+			// The parser recovers from syntax errors, so every file has a tree
+			So(report.Files[3].AST, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Test cancel remaining work on fatal error", t, func() {
+		paths := writeSourceFiles(t, []string{"let a = 1\n", "let b = 2\n"})
+		paths = append([]string{filepath.Join(t.TempDir(), "missing.mirth")}, paths...)
+		report := Run(context.Background(), paths, &Options{Workers: 1})
+
+		So(report.Fatal, ShouldNotBeNil)
+		So(os.IsNotExist(report.Fatal), ShouldBeTrue)
+		So(report.Files[1].Skipped, ShouldBeTrue)
+		So(report.Files[2].Skipped, ShouldBeTrue)
+	})
+
+	Convey("Test stop on the first error", t, func() {
+		paths := writeSourceFiles(t, []string{"let a = 123e\n", "let b = 2\n"})
+		report := Run(context.Background(), paths, &Options{Workers: 1, StopOnError: true})
+
+		So(report.Fatal, ShouldNotBeNil)
+		So(report.Files[0].Skipped, ShouldBeFalse)
+		So(report.Files[1].Skipped, ShouldBeTrue)
+	})
+
+	Convey("Test cancelled context", t, func() {
+		paths := writeSourceFiles(t, []string{"let a = 1\n"})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		report := Run(ctx, paths, nil)
+
+		So(report.Fatal, ShouldEqual, context.Canceled)
+		So(report.Files[0].Skipped, ShouldBeTrue)
+	})
+}
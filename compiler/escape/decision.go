@@ -0,0 +1,74 @@
+package escape
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"sort"
+)
+
+// Decision is a decision of the analysis on a node of a file, printed by the `-m` flag.
+type Decision struct {
+	Pos *compiler.Position
+	Msg string
+}
+
+func (d *Decision) String() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.Msg)
+}
+
+// Decisions returns the decisions on the allocations, the closures, the captured variables and
+// the parameters of an analyzed file, sorted by position.
+func (info *Info) Decisions(file *ast.File) []*Decision {
+	var decisions []*Decision
+	decide := func(node ast.Node, format string, args ...any) {
+		decisions = append(decisions, &Decision{Pos: node.NodeSpan().Start, Msg: fmt.Sprintf(format, args...)})
+	}
+	escapes := func(node ast.Node, what string) {
+		escapes, isAnalyzed := info.Escapes[node]
+		switch {
+		case !isAnalyzed:
+		case escapes:
+			decide(node, "%s escapes to the heap", what)
+		default:
+			decide(node, "%s doesn't escape", what)
+		}
+	}
+	moved := map[*ast.Identifier]bool{} // The names declaring the moved variables
+	for symbol := range info.Moved {
+		moved[symbol.Ident] = true
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.StructLit:
+			escapes(n, "Struct literal")
+		case *ast.ArrayLit:
+			escapes(n, "Array literal")
+		case *ast.FuncLit, *ast.ArrowFunc, *ast.FuncDecl:
+			if _, isClosure := info.Captures[n]; !isClosure {
+				return true
+			}
+			escapes(n, "Closure")
+			for _, captured := range info.Captures[n] {
+				how := "value"
+				if captured.ByRef {
+					how = "reference"
+				}
+				decide(n, "Closure captures '%s' by %s", captured.Symbol.Name, how)
+			}
+		case *ast.Param:
+			if info.Leaks[n] {
+				decide(n, "Parameter '%s' leaks, the values passed to it escape", n.Name.Name)
+			}
+		case *ast.Identifier:
+			if moved[n] {
+				decide(n, "'%s' is moved to the heap, an escaping closure captures it by reference", n.Name)
+			}
+		}
+		return true
+	})
+	sort.SliceStable(decisions, func(i, j int) bool {
+		return decisions[i].Pos.Offset < decisions[j].Pos.Offset
+	})
+	return decisions
+}
@@ -0,0 +1,193 @@
+// Package escape analyzes the closures and the allocations of the resolved files for the
+// backends: which variables the closures capture and how, and which allocations escape the
+// stack frame of the function making them, and must be made on the heap.
+//
+// The analysis doesn't follow the control flow. The values are tracked through a graph of
+// locations: the local variables, the parameters and the allocations, where each location
+// lists the locations whose values may be stored in it. A value escapes if it reaches the
+// heap: when it's returned, stored in a global variable or in a value the function doesn't
+// own, like a parameter, or passed to a function which may keep it. The calls to the functions
+// declared in the analyzed modules use the summaries of their parameters, telling whether they
+// leak, the other calls leak their arguments.
+package escape
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/resolve"
+	"mirth/compiler/types"
+)
+
+// Capture is a variable of an enclosing function which a closure uses.
+type Capture struct {
+	Symbol *resolve.Symbol
+	// ByRef is true if the variable is assigned besides its declaration, the closure and
+	// the function then share the variable. Otherwise the closure holds a copy of its value.
+	ByRef bool
+}
+
+// Info holds the results of the analysis of the modules of a program.
+type Info struct {
+	// Captures maps the closures, the *ast.FuncLit, the *ast.ArrowFunc and the local
+	// *ast.FuncDecl, to the variables they capture, in the order of their first use.
+	Captures map[ast.Node][]*Capture
+	// Escapes maps the allocations, the closures, the *ast.StructLit and the *ast.ArrayLit,
+	// to true if they escape and false if they can be made on the stack.
+	Escapes map[ast.Node]bool
+	// Moved are the variables captured by reference by an escaping closure, which are
+	// made on the heap to outlive the stack frame of their function.
+	Moved map[*resolve.Symbol]bool
+	// Leaks maps the parameters to true if the values passed to them may escape.
+	Leaks map[*ast.Param]bool
+}
+
+func CreateInfo() *Info {
+	return &Info{
+		Captures: map[ast.Node][]*Capture{},
+		Escapes:  map[ast.Node]bool{},
+		Moved:    map[*resolve.Symbol]bool{},
+		Leaks:    map[*ast.Param]bool{},
+	}
+}
+
+// Analyze analyzes the files of a module whose names are resolved by table and whose types
+// are typed, the results are added to info. The modules it imports should be analyzed
+// beforehand with the same info, the arguments passed to the functions of the other modules
+// leak otherwise. typed may be nil, the scalar fields and elements are then taken for values
+// which may reference their container.
+func Analyze(files []*ast.File, table *resolve.Table, typed *types.Info, info *Info) {
+	assigned := assignedVariables(files, table)
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if isClosure(node, table) {
+				info.Captures[node] = captures(node, table, assigned)
+			}
+			return true
+		})
+	}
+
+	// The parameters of the module don't leak until a pass finds they do, the summaries
+	// only change from false to true so the passes end.
+	var params []*ast.Param
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if param, isParam := node.(*ast.Param); isParam {
+				params = append(params, param)
+				info.Leaks[param] = false
+			}
+			return true
+		})
+	}
+	for {
+		g := build(files, table, typed, info, assigned)
+		g.propagate()
+		changed := false
+		for _, param := range params {
+			if loc := g.params[param]; loc != nil && loc.escapes && !info.Leaks[param] {
+				info.Leaks[param] = true
+				changed = true
+			}
+		}
+		if !changed {
+			g.record(info)
+			return
+		}
+	}
+}
+
+// isClosure tells whether a node is a function which may capture variables: a function
+// literal, an arrow lambda, or a function declared in a block.
+func isClosure(node ast.Node, table *resolve.Table) bool {
+	switch n := node.(type) {
+	case *ast.FuncLit, *ast.ArrowFunc:
+		return true
+	case *ast.FuncDecl:
+		return n.Receiver == nil && isLocalVar(table.Defs[n.Name])
+	}
+	return false
+}
+
+// isLocalVar tells whether a symbol is a variable of a function, which a closure may capture.
+func isLocalVar(symbol *resolve.Symbol) bool {
+	if symbol == nil || symbol.Scope == nil {
+		return false
+	}
+	switch symbol.Scope.Kind {
+	case resolve.UniverseScope, resolve.ModuleScope, resolve.FileScope, resolve.TypeScope:
+		return false
+	}
+	switch symbol.Kind {
+	case resolve.VarSymbol, resolve.ParamSymbol, resolve.BindingSymbol, resolve.FuncSymbol:
+		return true
+	}
+	return false
+}
+
+// rootName returns the name an assignment target is rooted at, like `p` for `p.xs[0]`, or nil.
+func rootName(target ast.Expr) *ast.Identifier {
+	for {
+		switch t := target.(type) {
+		case *ast.Identifier:
+			return t
+		case *ast.ParenExpr:
+			target = t.Expr
+		case *ast.MemberExpr:
+			target = t.Target
+		case *ast.IndexExpr:
+			target = t.Target
+		default:
+			return nil
+		}
+	}
+}
+
+// assignedVariables returns the variables which are assigned, incremented or decremented
+// besides their declaration, or whose fields or elements are.
+func assignedVariables(files []*ast.File, table *resolve.Table) map[*resolve.Symbol]bool {
+	assigned := map[*resolve.Symbol]bool{}
+	mark := func(target ast.Expr) {
+		if root := rootName(target); root != nil && table.Uses[root] != nil {
+			assigned[table.Uses[root]] = true
+		}
+	}
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.AssignExpr:
+				mark(n.Target)
+			case *ast.PostfixExpr:
+				mark(n.Operand)
+			case *ast.UnaryExpr:
+				if n.Operator.Type == compiler.TokenTypeDoublePlus || n.Operator.Type == compiler.TokenTypeDoubleMinus {
+					mark(n.Operand)
+				}
+			}
+			return true
+		})
+	}
+	return assigned
+}
+
+// captures returns the local variables a closure uses which are declared outside of it.
+func captures(closure ast.Node, table *resolve.Table, assigned map[*resolve.Symbol]bool) []*Capture {
+	span := closure.NodeSpan()
+	inside := func(ident *ast.Identifier) bool {
+		return span.Start.Offset <= ident.Start.Offset && ident.Start.Offset < span.End.Offset
+	}
+	var captured []*Capture
+	seen := map[*resolve.Symbol]bool{}
+	ast.Inspect(closure, func(node ast.Node) bool {
+		ident, isName := node.(*ast.Identifier)
+		if !isName {
+			return true
+		}
+		symbol := table.Uses[ident]
+		if !isLocalVar(symbol) || inside(symbol.Ident) || seen[symbol] {
+			return true
+		}
+		seen[symbol] = true
+		captured = append(captured, &Capture{Symbol: symbol, ByRef: assigned[symbol]})
+		return true
+	})
+	return captured
+}
@@ -0,0 +1,222 @@
+package escape
+
+import (
+	"fmt"
+	"mirth/compiler/ast"
+	"mirth/compiler/parser"
+	"mirth/compiler/resolve"
+	"mirth/compiler/types"
+	"mirth/shared"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// analyze parses, resolves, type checks and analyzes a source.
+func analyze(source string) (*ast.File, *Info) {
+	file := parser.CreateParser(source).ParseFile().Unwrap()
+	table, _ := resolve.ResolveFile(file)
+	typed, _ := types.CheckFile(file)
+	info := CreateInfo()
+	Analyze([]*ast.File{file}, table, typed, info)
+	return file, info
+}
+
+func decisions(source string) []string {
+	file, info := analyze(source)
+	var msgs []string
+	for _, decision := range info.Decisions(file) {
+		msgs = append(msgs, decision.String())
+	}
+	return msgs
+}
+
+// capturesOf returns the captures of the closures of a source, like `count by reference`.
+func capturesOf(source string) [][]string {
+	file, info := analyze(source)
+	var captures [][]string
+	ast.Inspect(file, func(node ast.Node) bool {
+		closure, isClosure := info.Captures[node]
+		if !isClosure {
+			return true
+		}
+		described := []string{}
+		for _, captured := range closure {
+			described = append(described, captured.Symbol.Name+shared.Ternary(captured.ByRef, " by reference", " by value"))
+		}
+		captures = append(captures, described)
+		return true
+	})
+	return captures
+}
+
+func TestCaptures(t *testing.T) {
+	Convey("Test record the variables the closures capture", t, func() {
+		So(capturesOf(`
+let global = 1
+
+func f(n: int, m: int) {
+  let total = 0
+  let scale = 2
+  let add = (x: int) => {
+    total += x * scale
+    let inner = () => total + n + global
+  }
+  let read = func() int { return scale + m }
+  m = 3
+  func helper(k: int) int { return k * scale }
+  let pure = (x: int) => x + 1
+}`), ShouldResemble, [][]string{
+			{"total by reference", "scale by value", "n by value"},
+			{"total by reference", "n by value"},
+			{"scale by value", "m by reference"},
+			{"scale by value"},
+			{},
+		})
+	})
+}
+
+func TestEscapes(t *testing.T) {
+	Convey("Test decide which allocations escape", t, func() {
+		So(decisions(`
+struct Point { x: int, y: int }
+
+let origin = Point { x: 0, y: 0 }
+
+func make() Point {
+  let p = Point { x: 1, y: 2 }
+  return p
+}
+
+func length(p: Point) int {
+  return p.x + p.y
+}
+
+func main() {
+  let local = Point { x: 1, y: 2 }
+  let xs = [local]
+  let n = length(xs[0])
+  let kept = Point { x: 3, y: 4 }
+  origin = kept
+  let opt = Point { x: n, y: n }
+  let maybe = [opt] ?? [kept]
+}`), ShouldResemble, []string{
+			"4:14: Struct literal escapes to the heap",
+			"7:11: Struct literal escapes to the heap",
+			"16:15: Struct literal doesn't escape",
+			"17:12: Array literal doesn't escape",
+			"19:14: Struct literal escapes to the heap",
+			"21:13: Struct literal doesn't escape",
+			"22:15: Array literal doesn't escape",
+			"22:24: Array literal doesn't escape",
+		})
+	})
+
+	Convey("Test read the scalar fields and elements without their container", t, func() {
+		So(decisions(`
+struct Point { x: int, y: int }
+
+func getX(p: Point) int {
+  return p.x
+}
+
+func first(ps: []Point) Point {
+  return ps[0]
+}
+
+func main() int {
+  let p = Point { x: 1, y: 2 }
+  let ps = [Point { x: 3, y: 4 }]
+  return getX(p) + ps[0].x + first(ps).y
+}`), ShouldResemble, []string{
+			"8:12: Parameter 'ps' leaks, the values passed to it escape",
+			"13:11: Struct literal doesn't escape",
+			"14:12: Array literal escapes to the heap",
+			"14:13: Struct literal escapes to the heap",
+		})
+	})
+
+	Convey("Test keep the returned scalars off the heap", t, func() {
+		So(decisions(`
+func fib(n: int) int {
+  if n < 2 { return n }
+  let a = n - 1
+  return fib(a) + fib(n - 2)
+}
+
+func name(s: string) string {
+  let copy = s
+  return copy
+}`), ShouldBeEmpty)
+	})
+
+	Convey("Test move to the heap the variables captured by reference by the escaping closures", t, func() {
+		So(decisions(`
+func counter() func() int {
+  let count = 0
+  let step = 1
+  return func() int {
+    count += step
+    return count
+  }
+}
+
+func sum(xs: []int) int {
+  let total = 0
+  let add = (x: int) => { total += x }
+  for x in xs { add(x) }
+  return total
+}`), ShouldResemble, []string{
+			"3:7: 'count' is moved to the heap, an escaping closure captures it by reference",
+			"5:10: Closure escapes to the heap",
+			"5:10: Closure captures 'count' by reference",
+			"5:10: Closure captures 'step' by value",
+			"13:13: Closure doesn't escape",
+			"13:13: Closure captures 'total' by reference",
+		})
+	})
+
+	Convey("Test summarize whether the parameters leak", t, func() {
+		file, info := analyze(`
+struct Node { value: int, next: Node? }
+
+func link(n: Node, holder: Node) {
+  holder.next = n
+}
+
+func depth(n: Node, d: int) int {
+  if d == 0 { return 0 }
+  return depth(n, d - 1)
+}
+
+func first(n: Node, ...rest: Node) Node {
+  return rest[0]
+}
+
+func main(other: Node) {
+  let a = Node { value: 1, next: nil }
+  let b = Node { value: 2, next: nil }
+  b.next = a
+  link(b, other)
+  depth(Node { value: 3, next: nil }, 2)
+  first(Node { value: 4, next: nil }, Node { value: 5, next: nil })
+}`)
+		leaking := []string{}
+		ast.Inspect(file, func(node ast.Node) bool {
+			if param, isParam := node.(*ast.Param); isParam && info.Leaks[param] {
+				leaking = append(leaking, param.Name.Name)
+			}
+			return true
+		})
+		So(leaking, ShouldResemble, []string{"n", "rest"})
+
+		escaping := []string{}
+		ast.Inspect(file, func(node ast.Node) bool {
+			if literal, isStruct := node.(*ast.StructLit); isStruct {
+				escaping = append(escaping, fmt.Sprint(literal.Fields[0].Value.(*ast.BasicLiteral).Value, " ", info.Escapes[literal]))
+			}
+			return true
+		})
+		So(escaping, ShouldResemble, []string{"1 true", "2 true", "3 false", "4 false", "5 true"})
+	})
+}
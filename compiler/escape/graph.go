@@ -0,0 +1,478 @@
+package escape
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/resolve"
+	"mirth/compiler/types"
+)
+
+// location is a place holding values: a variable, a parameter, an allocation, or the heap.
+type location struct {
+	// sources are the locations whose values may be stored in this one, or referenced by it.
+	sources []*location
+	escapes bool
+	// isVar is true for the variables and the parameters, which may be aliased.
+	isVar bool
+	// external is true for the heap and the parameters, whose values the function doesn't own.
+	external bool
+}
+
+// store is an assignment to a field or an element of a value held by a variable.
+type store struct {
+	target *location
+	values []*location
+}
+
+// graph is the graph of the locations of a module, built with the current summaries of the
+// parameters.
+type graph struct {
+	table *resolve.Table
+	// typed are the types of the expressions, or nil if the module isn't type checked.
+	typed *types.Info
+	info  *Info
+	// assigned are the variables assigned besides their declaration.
+	assigned map[*resolve.Symbol]bool
+	heap     *location
+	vars     map[*resolve.Symbol]*location
+	// allocs are the locations of the allocations, by node.
+	allocs map[ast.Node]*location
+	params map[*ast.Param]*location
+	stores []*store
+}
+
+func build(files []*ast.File, table *resolve.Table, typed *types.Info, info *Info, assigned map[*resolve.Symbol]bool) *graph {
+	g := &graph{
+		table:    table,
+		typed:    typed,
+		info:     info,
+		assigned: assigned,
+		heap:     &location{escapes: true, external: true},
+		vars:     map[*resolve.Symbol]*location{},
+		allocs:   map[ast.Node]*location{},
+		params:   map[*ast.Param]*location{},
+	}
+	for _, file := range files {
+		for _, stmt := range file.Statements {
+			g.stmt(stmt)
+		}
+	}
+	return g
+}
+
+// propagate marks the locations whose values reach the heap. The values stored in the fields
+// or the elements of the values which the function doesn't own escape first.
+func (g *graph) propagate() {
+	for _, store := range g.stores {
+		if reachesExternal(store.target) {
+			g.flow(g.heap, store.values)
+		}
+	}
+	stack := []*location{g.heap}
+	for len(stack) > 0 {
+		loc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, source := range loc.sources {
+			if !source.escapes {
+				source.escapes = true
+				stack = append(stack, source)
+			}
+		}
+	}
+}
+
+// record adds the decisions of the propagated graph to info.
+func (g *graph) record(info *Info) {
+	for node, loc := range g.allocs {
+		info.Escapes[node] = loc.escapes
+		if !loc.escapes {
+			continue
+		}
+		for _, captured := range info.Captures[node] {
+			if captured.ByRef {
+				info.Moved[captured.Symbol] = true
+			}
+		}
+	}
+}
+
+// reachesExternal tells whether a location may hold a value the function doesn't own.
+func reachesExternal(loc *location) bool {
+	seen := map[*location]bool{loc: true}
+	stack := []*location{loc}
+	for len(stack) > 0 {
+		loc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if loc.external {
+			return true
+		}
+		for _, source := range loc.sources {
+			if !seen[source] {
+				seen[source] = true
+				stack = append(stack, source)
+			}
+		}
+	}
+	return false
+}
+
+// flow makes the values of sources flow into a location, which holds them.
+func (g *graph) flow(to *location, sources []*location) {
+	to.sources = append(to.sources, sources...)
+}
+
+// alias makes the values of sources flow into a variable. A variable flowing into another one
+// makes them aliases, so the flow goes both ways: the values stored through one are seen
+// through the other.
+func (g *graph) alias(to *location, sources []*location) {
+	g.flow(to, sources)
+	for _, source := range sources {
+		if source.isVar && source != to {
+			source.sources = append(source.sources, to)
+		}
+	}
+}
+
+// symbolOf returns the symbol a name refers to, the declaration of another module for an import.
+func (g *graph) symbolOf(ident *ast.Identifier) *resolve.Symbol {
+	symbol := g.table.Uses[ident]
+	if symbol != nil && symbol.Kind == resolve.ImportSymbol {
+		return symbol.Target
+	}
+	return symbol
+}
+
+// variable returns the location of a local variable, or nil for a global one.
+func (g *graph) variable(symbol *resolve.Symbol) *location {
+	if !isLocalVar(symbol) {
+		return nil
+	}
+	loc := g.vars[symbol]
+	if loc == nil {
+		loc = &location{isVar: true}
+		g.vars[symbol] = loc
+	}
+	return loc
+}
+
+// declare makes the values of sources flow into the variable a name declares, or to the
+// heap for a global variable.
+func (g *graph) declare(ident *ast.Identifier, sources []*location) {
+	if loc := g.variable(g.table.Defs[ident]); loc != nil {
+		g.alias(loc, sources)
+		return
+	}
+	g.flow(g.heap, sources)
+}
+
+// function adds the parameters and the body of a function. Its returned values escape.
+func (g *graph) function(params []*ast.Param, body ast.Node) {
+	for _, param := range params {
+		loc := g.variable(g.table.Defs[param.Name])
+		if loc == nil {
+			continue
+		}
+		g.params[param] = loc
+		loc.external = true
+		if param.Default != nil {
+			g.flow(loc, g.expr(param.Default))
+		}
+	}
+	switch b := body.(type) {
+	case *ast.BlockStmt:
+		g.stmt(b)
+	case ast.Expr:
+		g.flow(g.heap, g.expr(b))
+	}
+}
+
+// closure adds a closure, which is an allocation holding the variables it captures.
+func (g *graph) closure(node ast.Node, params []*ast.Param, body ast.Node) *location {
+	alloc := &location{}
+	g.allocs[node] = alloc
+	for _, captured := range g.info.Captures[node] {
+		g.flow(alloc, []*location{g.variable(captured.Symbol)})
+	}
+	g.function(params, body)
+	return alloc
+}
+
+func (g *graph) stmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.VarDecl:
+		if s.Value != nil {
+			g.declare(s.Name, g.expr(s.Value))
+		}
+	case *ast.FuncDecl:
+		if s.Body == nil {
+			return
+		}
+		params := s.Params
+		if s.Receiver != nil {
+			params = append([]*ast.Param{s.Receiver}, params...)
+		}
+		if isClosure(s, g.table) {
+			g.declare(s.Name, []*location{g.closure(s, params, s.Body)})
+			return
+		}
+		g.function(params, s.Body)
+	case *ast.ExprStmt:
+		g.expr(s.Expr)
+	case *ast.BlockStmt:
+		for _, stmt := range s.Statements {
+			g.stmt(stmt)
+		}
+	case *ast.IfStmt:
+		g.expr(s.Condition)
+		g.stmt(s.Then)
+		if s.Else != nil {
+			g.stmt(s.Else)
+		}
+	case *ast.ForStmt:
+		if s.Init != nil {
+			g.stmt(s.Init)
+		}
+		if s.Condition != nil {
+			g.expr(s.Condition)
+		}
+		if s.Post != nil {
+			g.expr(s.Post)
+		}
+		g.stmt(s.Body)
+	case *ast.ForInStmt:
+		g.declare(s.Binding, g.expr(s.Iterable))
+		g.stmt(s.Body)
+	case *ast.LoopStmt:
+		g.stmt(s.Body)
+	case *ast.ReturnStmt:
+		if s.Value != nil {
+			g.flow(g.heap, g.expr(s.Value))
+		}
+	}
+}
+
+// exprs adds expressions, and returns the locations their values may come from.
+func (g *graph) exprs(exprs []ast.Expr) []*location {
+	var sources []*location
+	for _, expr := range exprs {
+		sources = append(sources, g.expr(expr)...)
+	}
+	return sources
+}
+
+// expr adds an expression, and returns the locations its value may come from: the variables
+// it reads and the allocations it makes, which the value may be or reference. The values
+// computed from other values, like the sums, and the scalars, like the ints and the strings,
+// which reference no allocation, come from no location.
+func (g *graph) expr(expr ast.Expr) []*location {
+	sources := g.exprSources(expr)
+	if g.typed == nil {
+		return sources
+	}
+	if basic, isBasic := g.typed.Types[expr].(*types.Basic); isBasic && basic != types.Invalid {
+		return nil
+	}
+	return sources
+}
+
+func (g *graph) exprSources(expr ast.Expr) []*location {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		symbol := g.symbolOf(e)
+		if loc := g.variable(symbol); loc != nil {
+			return []*location{loc}
+		}
+		if symbol != nil && symbol.Kind == resolve.VarSymbol {
+			return []*location{g.heap} // The global variables hold values on the heap
+		}
+	case *ast.ParenExpr:
+		return g.expr(e.Expr)
+	case *ast.SpreadExpr:
+		return g.expr(e.Expr)
+	case *ast.UnaryExpr:
+		g.expr(e.Operand)
+	case *ast.PostfixExpr:
+		g.expr(e.Operand)
+	case *ast.BinaryExpr:
+		left, right := g.expr(e.Left), g.expr(e.Right)
+		if e.Operator.Type == compiler.TokenTypeDoubleQuestion {
+			return append(left, right...)
+		}
+	case *ast.AssignExpr:
+		return g.assign(e)
+	case *ast.CallExpr:
+		return g.call(e)
+	case *ast.IndexExpr:
+		g.expr(e.Index)
+		return g.expr(e.Target) // An element may reference the values stored in its container
+	case *ast.MemberExpr:
+		return g.expr(e.Target)
+	case *ast.TypeAssertion:
+		return g.expr(e.Value)
+	case *ast.InstantiateExpr:
+		return g.expr(e.Target)
+	case *ast.RangeExpr:
+		if e.Start != nil {
+			g.expr(e.Start)
+		}
+		if e.End != nil {
+			g.expr(e.End)
+		}
+	case *ast.TupleLit:
+		return g.exprs(e.Elements)
+	case *ast.ArrayLit:
+		alloc := &location{}
+		g.allocs[e] = alloc
+		g.flow(alloc, g.exprs(e.Elements))
+		return []*location{alloc}
+	case *ast.StructLit:
+		alloc := &location{}
+		g.allocs[e] = alloc
+		for _, field := range e.Fields {
+			g.flow(alloc, g.expr(field.Value))
+		}
+		return []*location{alloc}
+	case *ast.FuncLit:
+		return []*location{g.closure(e, e.Params, e.Body)}
+	case *ast.ArrowFunc:
+		return []*location{g.closure(e, e.Params, e.Body)}
+	case *ast.MatchExpr:
+		return g.match(e)
+	case *ast.TemplateString:
+		g.exprs(e.Exprs)
+	case *ast.TaggedTemplate:
+		g.expr(e.Tag)
+		g.flow(g.heap, g.exprs(e.Template.Exprs)) // The tag function may keep the values
+	}
+	return nil
+}
+
+// assign makes the value of an assignment flow into the variable its target is rooted at,
+// like `p` for `p.next = node`, or to the heap if the target isn't rooted at a local variable.
+// A value stored in a field or an element escapes if the variable may hold a value the
+// function doesn't own.
+func (g *graph) assign(e *ast.AssignExpr) []*location {
+	value := g.expr(e.Value)
+	g.expr(e.Target) // The indexes are evaluated
+	if e.Operator.Type != compiler.TokenTypeEqual {
+		return nil
+	}
+	var target *location
+	if root := rootName(e.Target); root != nil {
+		target = g.variable(g.symbolOf(root))
+	}
+	if target == nil {
+		g.flow(g.heap, value)
+		return value
+	}
+	if _, isName := e.Target.(*ast.Identifier); isName {
+		g.alias(target, value)
+		return value
+	}
+	g.flow(target, value)
+	g.stores = append(g.stores, &store{target: target, values: value})
+	return value
+}
+
+// call adds a call. The arguments passed to a parameter which leaks escape, the parameters of
+// the functions which aren't analyzed, like the methods and the closures held by fields, are
+// assumed to leak. The result of a call is on the heap, the values a function returns escape.
+func (g *graph) call(e *ast.CallExpr) []*location {
+	params, isKnown := g.callee(e.Callee)
+	if !isKnown {
+		if member, isMember := e.Callee.(*ast.MemberExpr); isMember {
+			g.flow(g.heap, g.expr(member.Target)) // A method may keep its receiver
+		} else {
+			g.expr(e.Callee)
+		}
+		g.flow(g.heap, g.exprs(e.Arguments))
+		return []*location{g.heap}
+	}
+	for i, arg := range e.Arguments {
+		sources := g.expr(arg)
+		if len(params) == 0 {
+			g.flow(g.heap, sources)
+			continue
+		}
+		param := params[len(params)-1]
+		if i < len(params) {
+			param = params[i]
+		}
+		if leaks, isAnalyzed := g.info.Leaks[param]; leaks || !isAnalyzed {
+			g.flow(g.heap, sources)
+		}
+	}
+	return []*location{g.heap}
+}
+
+// callee returns the parameters of the function a call calls by name: a function like `f` and
+// `module.f`, or a closure held by a variable which isn't assigned again. isKnown is false if
+// the callee is another value.
+func (g *graph) callee(callee ast.Expr) (params []*ast.Param, isKnown bool) {
+	var name *ast.Identifier
+	switch c := callee.(type) {
+	case *ast.Identifier:
+		name = c
+	case *ast.MemberExpr:
+		name = c.Name
+	case *ast.InstantiateExpr:
+		return g.callee(c.Target)
+	default:
+		return nil, false
+	}
+	symbol := g.symbolOf(name)
+	if symbol == nil {
+		return nil, false
+	}
+	switch decl := symbol.Decl.(type) {
+	case *ast.FuncDecl:
+		return decl.Params, symbol.Kind == resolve.FuncSymbol && decl.Receiver == nil && decl.Body != nil
+	case *ast.VarDecl:
+		if symbol.Kind != resolve.VarSymbol || g.assigned[symbol] {
+			return nil, false
+		}
+		switch value := decl.Value.(type) {
+		case *ast.FuncLit:
+			return value.Params, true
+		case *ast.ArrowFunc:
+			return value.Params, true
+		}
+	}
+	return nil, false
+}
+
+// match adds a match expression, whose subject flows into the names bound by the patterns.
+// Its value comes from the values of its arms.
+func (g *graph) match(e *ast.MatchExpr) []*location {
+	subject := g.expr(e.Subject)
+	var sources []*location
+	for _, arm := range e.Arms {
+		ast.Inspect(arm.Pattern, func(node ast.Node) bool {
+			if ident, isName := node.(*ast.Identifier); isName && g.table.Defs[ident] != nil {
+				g.declare(ident, subject)
+			}
+			return true
+		})
+		if arm.Guard != nil {
+			g.expr(arm.Guard)
+		}
+		switch body := arm.Body.(type) {
+		case ast.Expr:
+			sources = append(sources, g.expr(body)...)
+		case *ast.BlockStmt:
+			if len(body.Statements) == 0 {
+				continue
+			}
+			last := len(body.Statements) - 1
+			for _, stmt := range body.Statements[:last] {
+				g.stmt(stmt)
+			}
+			if value, isExpr := body.Statements[last].(*ast.ExprStmt); isExpr {
+				sources = append(sources, g.expr(value.Expr)...)
+			} else {
+				g.stmt(body.Statements[last])
+			}
+		}
+	}
+	return sources
+}
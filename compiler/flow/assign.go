@@ -0,0 +1,347 @@
+package flow
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/resolve"
+	"sort"
+)
+
+// CheckAssignments reports the variables which are read before they're assigned on
+// every path, and the assignments to the immutable names, like the constants and the
+// functions. The names are resolved by table. The reads are checked on the control-flow
+// graph of each function, a variable declared without value like `let x: int` must
+// be assigned on all the paths leading to a read.
+func CheckAssignments(file *ast.File, table *resolve.Table) []*compiler.Diagnostic {
+	a := &assignments{table: table, reported: map[*resolve.Symbol]bool{}}
+	a.body(file.Statements)
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.AssignExpr:
+			a.checkMutable(n.Target, n.Operator)
+		case *ast.UnaryExpr:
+			a.checkIncrement(n.Operand, n.Operator)
+		case *ast.PostfixExpr:
+			a.checkIncrement(n.Operand, n.Operator)
+		}
+		return true
+	})
+	sort.SliceStable(a.diagnostics, func(i, j int) bool {
+		return a.diagnostics[i].Pos.Offset < a.diagnostics[j].Pos.Offset
+	})
+	return a.diagnostics
+}
+
+type assignments struct {
+	table       *resolve.Table
+	diagnostics []*compiler.Diagnostic
+	// reported are the variables already reported, a variable is only reported at its first read.
+	reported map[*resolve.Symbol]bool
+}
+
+func (a *assignments) errorf(node ast.Node, code compiler.DiagnosticCode, format string, args ...any) {
+	span := node.NodeSpan()
+	a.diagnostics = append(a.diagnostics, &compiler.Diagnostic{
+		Type: compiler.DiagnosticError, Code: code, Pos: span.Start, End: span.End, Msg: fmt.Sprintf(format, args...),
+	})
+}
+
+// symbolOf returns the symbol a name refers to, the declaration of another module for an import.
+func (a *assignments) symbolOf(ident *ast.Identifier) *resolve.Symbol {
+	symbol := a.table.Uses[ident]
+	if symbol != nil && symbol.Kind == resolve.ImportSymbol {
+		return symbol.Target
+	}
+	return symbol
+}
+
+// checkMutable reports an assignment, or a compound assignment like `+=`, whose target is
+// an immutable name or a member of one. The variables, the parameters and the bindings are mutable.
+func (a *assignments) checkMutable(target ast.Expr, operator *compiler.Token) {
+	root, isMember := target, false
+	for {
+		switch t := root.(type) {
+		case *ast.ParenExpr:
+			root = t.Expr
+			continue
+		case *ast.MemberExpr:
+			root, isMember = t.Target, true
+			continue
+		case *ast.IndexExpr:
+			root, isMember = t.Target, true
+			continue
+		}
+		break
+	}
+	ident, isName := root.(*ast.Identifier)
+	if !isName {
+		return
+	}
+	symbol := a.symbolOf(ident)
+	if symbol == nil {
+		return
+	}
+	switch symbol.Kind {
+	case resolve.VarSymbol, resolve.ParamSymbol, resolve.BindingSymbol:
+		return
+	case resolve.ModuleSymbol:
+		if isMember {
+			return // A variable of an imported module, like `config.level = 2`
+		}
+	}
+	what := fmt.Sprintf("%s '%s'", symbol.Kind, ident.Name)
+	if isMember {
+		what = "a member of " + what
+	}
+	if operator.Type == compiler.TokenTypeEqual {
+		a.errorf(target, compiler.ImmutableAssignment, "Can't assign to %s, it's immutable", what)
+	} else {
+		a.errorf(target, compiler.ImmutableAssignment, "Can't apply '%s' to %s, it's immutable", operator.Content, what)
+	}
+}
+
+func (a *assignments) checkIncrement(operand ast.Expr, operator *compiler.Token) {
+	if operator.Type == compiler.TokenTypeDoublePlus || operator.Type == compiler.TokenTypeDoubleMinus {
+		a.checkMutable(operand, operator)
+	}
+}
+
+// event is a read or a write of a variable, in the order of the evaluation.
+type event struct {
+	symbol *resolve.Symbol
+	ident  *ast.Identifier // The name read, nil for a write
+	// assigned tells whether a write assigns the variable, or declares it again without value.
+	assigned bool
+}
+
+// state is the set of the variables assigned at a point of the graph: definitely on every
+// path, and possibly on some paths.
+type state struct {
+	definitely map[*resolve.Symbol]bool
+	possibly   map[*resolve.Symbol]bool
+}
+
+func (s state) copy() state {
+	copied := state{definitely: map[*resolve.Symbol]bool{}, possibly: map[*resolve.Symbol]bool{}}
+	for symbol := range s.definitely {
+		copied.definitely[symbol] = true
+	}
+	for symbol := range s.possibly {
+		copied.possibly[symbol] = true
+	}
+	return copied
+}
+
+func (s state) equal(other state) bool {
+	return len(s.definitely) == len(other.definitely) && len(s.possibly) == len(other.possibly) &&
+		subset(s.definitely, other.definitely) && subset(s.possibly, other.possibly)
+}
+
+func subset(a, b map[*resolve.Symbol]bool) bool {
+	for symbol := range a {
+		if !b[symbol] {
+			return false
+		}
+	}
+	return true
+}
+
+// apply updates the state with an event.
+func (s state) apply(e event) {
+	if e.ident != nil {
+		return
+	}
+	if e.assigned {
+		s.definitely[e.symbol], s.possibly[e.symbol] = true, true
+	} else {
+		delete(s.definitely, e.symbol)
+		delete(s.possibly, e.symbol)
+	}
+}
+
+// body checks the reads of the variables declared without value in a list of statements,
+// and then the nested functions.
+func (a *assignments) body(statements []ast.Stmt) {
+	graph := Build(statements, nil)
+	tracked := map[*resolve.Symbol]bool{}
+	var nested []ast.Function
+	for _, stmt := range statements {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case ast.Function:
+				nested = append(nested, n)
+				return false
+			case *ast.VarDecl:
+				if symbol := a.table.Defs[n.Name]; symbol != nil && n.Value == nil && !n.IsConst() {
+					tracked[symbol] = true
+				}
+			}
+			return true
+		})
+	}
+	if len(tracked) > 0 {
+		a.checkReads(graph, tracked)
+	}
+	for _, function := range nested {
+		if body, isBlock := function.FuncBody().(*ast.BlockStmt); isBlock && body != nil {
+			a.body(body.Statements)
+		}
+	}
+}
+
+// checkReads solves the assigned variables at the start of each block, from the states at
+// the end of its predecessors, until they don't change. A variable is definitely assigned
+// if it's assigned at the end of all the predecessors.
+func (a *assignments) checkReads(graph *Graph, tracked map[*resolve.Symbol]bool) {
+	events := map[*Block][]event{}
+	for _, block := range graph.Blocks {
+		for _, node := range block.Nodes {
+			events[block] = append(events[block], a.events(node, tracked)...)
+		}
+	}
+
+	all := map[*resolve.Symbol]bool{}
+	for symbol := range tracked {
+		all[symbol] = true
+	}
+	out := map[*Block]state{}
+	for _, block := range graph.Blocks {
+		// The blocks not solved yet don't restrict the variables definitely assigned
+		out[block] = state{definitely: all, possibly: map[*resolve.Symbol]bool{}}
+	}
+	in := map[*Block]state{}
+	for changed := true; changed; {
+		changed = false
+		for _, block := range graph.Blocks {
+			if !block.Live {
+				continue
+			}
+			start := a.entryState(graph, block, out)
+			in[block] = start
+			end := start.copy()
+			for _, e := range events[block] {
+				end.apply(e)
+			}
+			if !end.equal(out[block]) {
+				out[block], changed = end, true
+			}
+		}
+	}
+
+	for _, block := range graph.Blocks {
+		if !block.Live {
+			continue
+		}
+		current := in[block].copy()
+		for _, e := range events[block] {
+			if e.ident != nil && !current.definitely[e.symbol] && !a.reported[e.symbol] {
+				a.reported[e.symbol] = true
+				if current.possibly[e.symbol] {
+					a.errorf(e.ident, compiler.UnassignedVariable, "Variable '%s' might not be assigned on every path before it's read", e.ident.Name)
+				} else {
+					a.errorf(e.ident, compiler.UnassignedVariable, "Variable '%s' is read before it's assigned", e.ident.Name)
+				}
+			}
+			current.apply(e)
+		}
+	}
+}
+
+// entryState returns the variables assigned at the start of a block, nothing is assigned at the entry.
+func (a *assignments) entryState(graph *Graph, block *Block, out map[*Block]state) state {
+	start := state{definitely: map[*resolve.Symbol]bool{}, possibly: map[*resolve.Symbol]bool{}}
+	if block == graph.Entry {
+		return start
+	}
+	first := true
+	for _, pred := range block.Preds {
+		if !pred.Live {
+			continue
+		}
+		for symbol := range out[pred].possibly {
+			start.possibly[symbol] = true
+		}
+		if first {
+			for symbol := range out[pred].definitely {
+				start.definitely[symbol] = true
+			}
+			first = false
+			continue
+		}
+		for symbol := range start.definitely {
+			if !out[pred].definitely[symbol] {
+				delete(start.definitely, symbol)
+			}
+		}
+	}
+	return start
+}
+
+// events returns the reads and the writes of the tracked variables by a node of a block,
+// in the order of the evaluation. The values of the declarations and of the returns, and
+// the arms of the match expressions of the statements, are nodes of their own.
+func (a *assignments) events(node ast.Node, tracked map[*resolve.Symbol]bool) []event {
+	w := &eventWalker{assignments: a, tracked: tracked}
+	switch n := node.(type) {
+	case *ast.VarDecl:
+		if symbol := a.table.Defs[n.Name]; tracked[symbol] {
+			w.events = append(w.events, event{symbol: symbol, assigned: n.Value != nil})
+		}
+	case *ast.MatchExpr, *ast.ReturnStmt, *ast.BreakStmt, *ast.ContinueStmt, *ast.FuncDecl:
+		// The declared functions are called later, their reads aren't checked
+	case ast.Expr:
+		w.expr(n)
+	}
+	return w.events
+}
+
+type eventWalker struct {
+	*assignments
+	tracked map[*resolve.Symbol]bool
+	events  []event
+	// readsOnly is true in the code which may not run, like the arms of a nested match,
+	// whose assignments don't count.
+	readsOnly bool
+}
+
+func (w *eventWalker) expr(node ast.Node) {
+	switch n := node.(type) {
+	case *ast.Identifier:
+		if symbol := w.table.Uses[n]; w.tracked[symbol] {
+			w.events = append(w.events, event{symbol: symbol, ident: n})
+		}
+		return
+	case *ast.AssignExpr:
+		target, isName := n.Target.(*ast.Identifier)
+		if n.Operator.Type != compiler.TokenTypeEqual || !isName {
+			break // A compound assignment reads its target
+		}
+		w.expr(n.Value)
+		if symbol := w.table.Uses[target]; w.tracked[symbol] && !w.readsOnly {
+			w.events = append(w.events, event{symbol: symbol, assigned: true})
+		}
+		return
+	case *ast.MatchExpr:
+		w.expr(n.Subject)
+		readsOnly := w.readsOnly
+		w.readsOnly = true
+		for _, arm := range n.Arms {
+			w.expr(arm)
+		}
+		w.readsOnly = readsOnly
+		return
+	case ast.Function:
+		// A lambda reads the variables it captures when it's created
+		readsOnly := w.readsOnly
+		w.readsOnly = true
+		for _, child := range ast.Children(n) {
+			w.expr(child)
+		}
+		w.readsOnly = readsOnly
+		return
+	}
+	for _, child := range ast.Children(node) {
+		w.expr(child)
+	}
+}
@@ -0,0 +1,102 @@
+package flow
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/parser"
+	"mirth/compiler/resolve"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func checkAssignments(source string) []*compiler.Diagnostic {
+	file := parser.CreateParser(source).ParseFile().Unwrap()
+	table, _ := resolve.ResolveFile(file)
+	return CheckAssignments(file, table)
+}
+
+func TestCheckAssignments(t *testing.T) {
+	Convey("Test accept the variables assigned on every path", t, func() {
+		diagnostics := checkAssignments(`
+func f(ok: bool) int {
+  let x: int
+  if ok { x = 1 } else { x = 2 }
+  let y: int
+  match ok {
+    true => { y = 1 }
+    _ => { return 0 }
+  }
+  let z: int
+  loop {
+    z = x + y
+    break
+  }
+  return z
+}`)
+		So(diagnostics, ShouldBeEmpty)
+	})
+
+	Convey("Test report the variables read before they're assigned", t, func() {
+		diagnostics := checkAssignments(`
+func f(ok: bool, xs: []int) int {
+  let a: int
+  let b: int
+  if ok { b = 1 }
+  let c: int
+  for x in xs { c = x }
+  print(a, b, c)
+  print(a)
+  return 0
+}`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Variable 'a' is read before it's assigned",
+			"Variable 'b' might not be assigned on every path before it's read",
+			"Variable 'c' might not be assigned on every path before it's read",
+		})
+		So(diagnostics[0].Code, ShouldEqual, compiler.UnassignedVariable)
+		So(diagnostics[0].Pos.Line, ShouldEqual, 8)
+
+		diagnostics = checkAssignments(`
+func g() {
+  let total: int
+  total += 1
+  let n: int
+  let read = () => n
+  let m: int
+  m = m + 1
+}`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Variable 'total' is read before it's assigned",
+			"Variable 'n' is read before it's assigned",
+			"Variable 'm' is read before it's assigned",
+		})
+	})
+
+	Convey("Test report the writes of the immutable names", t, func() {
+		diagnostics := checkAssignments(`
+const LIMIT = 10
+const NAME = "mirth"
+func f() {}
+struct Point { x: int }
+func g(p: Point) {
+  LIMIT = 20
+  LIMIT += 1
+  LIMIT <<= 2
+  LIMIT++
+  NAME[0] = 'x'
+  f = g
+  p.x = 1
+  let n = 0
+  n += LIMIT
+}`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Can't assign to constant 'LIMIT', it's immutable",
+			"Can't apply '+=' to constant 'LIMIT', it's immutable",
+			"Can't apply '<<=' to constant 'LIMIT', it's immutable",
+			"Can't apply '++' to constant 'LIMIT', it's immutable",
+			"Can't assign to a member of constant 'NAME', it's immutable",
+			"Can't assign to function 'f', it's immutable",
+		})
+		So(diagnostics[0].Code, ShouldEqual, compiler.ImmutableAssignment)
+	})
+}
@@ -0,0 +1,130 @@
+package flow
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"sort"
+)
+
+type checker struct {
+	diagnostics []*compiler.Diagnostic
+}
+
+// Check builds the control-flow graphs of the top-level statements of a file and of all
+// its functions, and reports the unreachable code, the functions which can end without
+// returning a value, the `break` and `continue` outside of a loop, and the loops which
+// can never be exited. The diagnostics are sorted by position.
+func Check(file *ast.File) []*compiler.Diagnostic {
+	c := &checker{}
+	c.body(file.Statements, nil)
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		return c.diagnostics[i].Pos.Offset < c.diagnostics[j].Pos.Offset
+	})
+	return c.diagnostics
+}
+
+func (c *checker) report(node ast.Node, diagnosticType compiler.DiagnosticType, code compiler.DiagnosticCode, msg string) {
+	span := node.NodeSpan()
+	c.diagnostics = append(c.diagnostics, &compiler.Diagnostic{Type: diagnosticType, Code: code, Pos: span.Start, End: span.End, Msg: msg})
+}
+
+// body checks the statements of a function body, or the top-level statements if function is nil,
+// and then the nested functions.
+func (c *checker) body(statements []ast.Stmt, function ast.Function) {
+	graph := Build(statements, func(jump ast.Stmt) {
+		keyword := "break"
+		if _, isContinue := jump.(*ast.ContinueStmt); isContinue {
+			keyword = "continue"
+		}
+		c.report(jump, compiler.DiagnosticError, compiler.JumpOutsideLoop, "'"+keyword+"' outside of a loop")
+	})
+
+	var nested []ast.Function
+	c.unreachable(graph, statements)
+	for _, stmt := range statements {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case ast.Function:
+				nested = append(nested, n)
+				return false
+			case *ast.BlockStmt:
+				// The blocks of the compound statements and of the match arms
+				c.unreachable(graph, n.Statements)
+			}
+			return true
+		})
+	}
+	c.loops(graph)
+	if function != nil && returnsValue(function) && graph.FallsOff() {
+		c.missingReturn(function)
+	}
+
+	for _, function := range nested {
+		if body, isBlock := function.FuncBody().(*ast.BlockStmt); isBlock && body != nil {
+			c.body(body.Statements, function)
+		}
+	}
+}
+
+// unreachable reports the first statement of a list which can't be reached, unlike the statement
+// before it. The following statements are unreachable too, but they're not reported again.
+func (c *checker) unreachable(graph *Graph, statements []ast.Stmt) {
+	for i := 1; i < len(statements); i++ {
+		previous, block := graph.BlockOf(statements[i-1]), graph.BlockOf(statements[i])
+		if previous != nil && block != nil && previous.Live && !block.Live {
+			c.report(statements[i], compiler.DiagnosticWarning, compiler.UnreachableCode, "Unreachable code")
+			return
+		}
+	}
+}
+
+// loops reports the reachable infinite loops, `loop` and `for` without condition,
+// which no break, return or jump to an enclosing loop can leave.
+func (c *checker) loops(graph *Graph) {
+	for _, loop := range graph.Loops() {
+		keyword := "loop"
+		if forStmt, isFor := loop.Stmt.(*ast.ForStmt); isFor {
+			if forStmt.Condition != nil {
+				continue
+			}
+			keyword = "for"
+		} else if _, isLoop := loop.Stmt.(*ast.LoopStmt); !isLoop {
+			continue
+		}
+		if !graph.BlockOf(loop.Stmt).Live {
+			continue
+		}
+		exits := false
+		for _, exit := range loop.Exits {
+			exits = exits || exit.Live
+		}
+		if !exits {
+			c.report(loop.Stmt, compiler.DiagnosticWarning, compiler.InfiniteLoop,
+				"Infinite loop, this '"+keyword+"' has no 'break' or 'return' to exit it")
+		}
+	}
+}
+
+// returnsValue tells whether a function declares a result type. The arrow lambdas infer it.
+func returnsValue(function ast.Function) bool {
+	switch f := function.(type) {
+	case *ast.FuncDecl:
+		return f.ReturnType != nil
+	case *ast.FuncLit:
+		return f.ReturnType != nil
+	}
+	return false
+}
+
+// missingReturn reports a function which can reach the closing brace of its body, without a return.
+func (c *checker) missingReturn(function ast.Function) {
+	end := function.FuncBody().NodeSpan().End
+	brace := &compiler.Position{Offset: end.Offset - 1, Line: end.Line, Column: end.Column - 1}
+	msg := "Missing return at the end of the function"
+	if decl, isDecl := function.(*ast.FuncDecl); isDecl {
+		msg = "Missing return at the end of function '" + decl.Name.Name + "'"
+	}
+	c.diagnostics = append(c.diagnostics, &compiler.Diagnostic{
+		Type: compiler.DiagnosticError, Code: compiler.MissingReturn, Pos: brace, End: end, Msg: msg,
+	})
+}
@@ -0,0 +1,114 @@
+package flow
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/parser"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func checkSource(source string) []*compiler.Diagnostic {
+	return Check(parser.CreateParser(source).ParseFile().Unwrap())
+}
+
+func messages(diagnostics []*compiler.Diagnostic) []string {
+	var msgs []string
+	for _, diagnostic := range diagnostics {
+		msgs = append(msgs, diagnostic.Msg)
+	}
+	return msgs
+}
+
+func TestCheck(t *testing.T) {
+	Convey("Test report the unreachable code once per list", t, func() {
+		diagnostics := checkSource(`
+func f(x: int) int {
+  if x > 0 {
+    return 1
+    print("positive")
+    print("again")
+  }
+  loop {
+    break
+    x += 1
+  }
+  return 0
+  print("done")
+}`)
+		So(messages(diagnostics), ShouldResemble, []string{"Unreachable code", "Unreachable code", "Unreachable code"})
+		So(diagnostics[0].Type, ShouldEqual, compiler.DiagnosticWarning)
+		So(diagnostics[0].Code, ShouldEqual, compiler.UnreachableCode)
+		So(diagnostics[0].Pos.Line, ShouldEqual, 5)
+		So(diagnostics[1].Pos.Line, ShouldEqual, 10)
+		So(diagnostics[2].Pos.Line, ShouldEqual, 13)
+
+		diagnostics = checkSource("func f(x: int) {\n  if x > 0 { return } else { return }\n  print(x)\n}")
+		So(messages(diagnostics), ShouldResemble, []string{"Unreachable code"})
+	})
+
+	Convey("Test report the functions which can end without a return", t, func() {
+		diagnostics := checkSource(`
+func sign(x: int) int {
+  if x > 0 {
+    return 1
+  } else if x < 0 {
+    return -1
+  }
+}
+func always(x: int) int {
+  if x > 0 { return 1 } else { return 0 }
+}
+func forever() int {
+  loop {}
+}
+func choose(x: int) int {
+  match x {
+    0 => { return 0 }
+    _ => { return 1 }
+  }
+}
+let f = func() int { print(1) }
+let g = (x) => { print(x) }`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Missing return at the end of function 'sign'",
+			"Infinite loop, this 'loop' has no 'break' or 'return' to exit it",
+			"Missing return at the end of the function",
+		})
+		So(diagnostics[0].Code, ShouldEqual, compiler.MissingReturn)
+		So(diagnostics[0].Pos.Line, ShouldEqual, 8)
+		So(diagnostics[0].Pos.Column, ShouldEqual, 1)
+	})
+
+	Convey("Test report the jumps outside of a loop", t, func() {
+		diagnostics := checkSource(`
+func f(xs: []int) {
+  break
+  for x in xs {
+    let g = () => { continue }
+    if x > 0 { continue }
+  }
+}`)
+		So(messages(diagnostics), ShouldResemble, []string{"'break' outside of a loop", "'continue' outside of a loop"})
+		So(diagnostics[0].Code, ShouldEqual, compiler.JumpOutsideLoop)
+		So(diagnostics[1].Pos.Line, ShouldEqual, 5)
+	})
+
+	Convey("Test report the loops without exit", t, func() {
+		diagnostics := checkSource(`
+func f(xs: []int) int {
+  loop { print(1) }
+}
+func g() {
+  outer: loop {
+    for { break outer }
+  }
+  for {
+    loop { return }
+  }
+}`)
+		So(messages(diagnostics), ShouldResemble, []string{"Infinite loop, this 'loop' has no 'break' or 'return' to exit it"})
+		So(diagnostics[0].Code, ShouldEqual, compiler.InfiniteLoop)
+		So(diagnostics[0].Pos.Line, ShouldEqual, 3)
+	})
+}
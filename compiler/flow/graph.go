@@ -0,0 +1,337 @@
+// Package flow builds the control-flow graphs of the function bodies, and checks
+// the control flow with them: unreachable code, missing returns, jumps outside of
+// the loops and loops without exit.
+package flow
+
+import (
+	"fmt"
+	"mirth/compiler/ast"
+	"strings"
+)
+
+// Block is a basic block, the nodes it evaluates in sequence followed by a jump to
+// one of its successors. The nodes are the simple statements, and the conditions
+// and the values which decide of the jumps of the compound statements.
+type Block struct {
+	Index int
+	// Comment tells where the block comes from, like "if.then" or "loop.body".
+	Comment string
+	Nodes   []ast.Node
+	Succs   []*Block
+	Preds   []*Block
+	// Live is true if the block can be reached from the entry of the graph.
+	Live bool
+}
+
+func (b *Block) String() string {
+	return fmt.Sprintf("%d.%s", b.Index, b.Comment)
+}
+
+// Graph is the control-flow graph of a function body, or of the top-level statements of a file.
+type Graph struct {
+	// Entry is the first block. Exit is the last block, reached by the returns and by the
+	// end of the body, it has no nodes.
+	Entry  *Block
+	Exit   *Block
+	Blocks []*Block
+	// End is the block which falls off the end of the body to the exit, without a return.
+	End *Block
+	// blockOf maps the statements to the block they start in.
+	blockOf map[ast.Node]*Block
+	// loops are the loops of the body, in the order of the source.
+	loops []*Loop
+}
+
+// Loop is a loop statement of a graph, with the blocks which leave it: the breaks,
+// the jumps to an enclosing loop, and the returns.
+type Loop struct {
+	Stmt  ast.Stmt
+	Exits []*Block
+}
+
+// BlockOf returns the block in which a statement starts, or nil if it's not a statement of the graph.
+func (g *Graph) BlockOf(stmt ast.Node) *Block {
+	return g.blockOf[stmt]
+}
+
+// Loops returns the loops of the graph, in the order of the source.
+func (g *Graph) Loops() []*Loop {
+	return g.loops
+}
+
+// FallsOff tells whether the end of the body can be reached, and so the function
+// can return without a return statement.
+func (g *Graph) FallsOff() bool {
+	return g.End.Live
+}
+
+// String prints the blocks with their successors, one per line, for debugging and tests.
+func (g *Graph) String() string {
+	var lines []string
+	for _, block := range g.Blocks {
+		succs := make([]string, len(block.Succs))
+		for i, succ := range block.Succs {
+			succs[i] = succ.String()
+		}
+		line := block.String()
+		if !block.Live {
+			line += " (dead)"
+		}
+		if len(succs) > 0 {
+			line += " -> " + strings.Join(succs, ", ")
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Build builds the control-flow graph of a list of statements, like a function body.
+// The nested functions are not part of the graph, they have their own. The jumps
+// outside of a loop don't jump in the graph, they're passed to onJump if it's not nil.
+func Build(statements []ast.Stmt, onJump func(jump ast.Stmt)) *Graph {
+	b := &builder{graph: &Graph{blockOf: map[ast.Node]*Block{}}, onJump: onJump}
+	b.graph.Entry = b.newBlock("entry")
+	b.current = b.graph.Entry
+	b.statements(statements)
+	b.graph.End = b.current
+	b.graph.Exit = b.newBlock("exit")
+	b.jump(b.graph.Exit)
+	for _, block := range b.returns {
+		b.edge(block, b.graph.Exit)
+	}
+	b.graph.markLive()
+	return b.graph
+}
+
+// markLive marks the blocks which can be reached from the entry.
+func (g *Graph) markLive() {
+	stack := []*Block{g.Entry}
+	g.Entry.Live = true
+	for len(stack) > 0 {
+		block := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, succ := range block.Succs {
+			if !succ.Live {
+				succ.Live = true
+				stack = append(stack, succ)
+			}
+		}
+	}
+}
+
+// target is an enclosing loop of the statement being built, with the blocks its
+// `break` and `continue` jump to.
+type target struct {
+	loop       *Loop
+	label      string
+	breakTo    *Block
+	continueTo *Block
+}
+
+type builder struct {
+	graph   *Graph
+	current *Block
+	targets []*target
+	// returns are the blocks ending with a return, which jump to the exit.
+	returns []*Block
+	onJump  func(jump ast.Stmt)
+}
+
+func (b *builder) newBlock(comment string) *Block {
+	block := &Block{Index: len(b.graph.Blocks), Comment: comment}
+	b.graph.Blocks = append(b.graph.Blocks, block)
+	return block
+}
+
+func (b *builder) edge(from, to *Block) {
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
+
+// jump ends the current block with a jump to a block, and continues in that block.
+func (b *builder) jump(to *Block) {
+	b.edge(b.current, to)
+	b.current = to
+}
+
+// deadEnd ends the current block without a successor, the statements following
+// it are built in an unreachable block.
+func (b *builder) deadEnd() {
+	b.current = b.newBlock("unreachable")
+}
+
+func (b *builder) add(node ast.Node) {
+	b.current.Nodes = append(b.current.Nodes, node)
+}
+
+func (b *builder) statements(statements []ast.Stmt) {
+	for _, stmt := range statements {
+		b.stmt(stmt)
+	}
+}
+
+func (b *builder) stmt(stmt ast.Stmt) {
+	b.graph.blockOf[stmt] = b.current
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		b.statements(s.Statements)
+	case *ast.ExprStmt:
+		b.expr(s.Expr)
+	case *ast.VarDecl:
+		if s.Value != nil {
+			b.expr(s.Value)
+		}
+		b.add(s)
+	case *ast.IfStmt:
+		b.ifStmt(s)
+	case *ast.ForStmt:
+		if s.Init != nil {
+			b.stmt(s.Init)
+		}
+		head := b.newBlock("for.head")
+		b.jump(head)
+		if s.Condition != nil {
+			b.add(s.Condition)
+		}
+		post, done := b.newBlock("for.post"), b.newBlock("for.done")
+		if s.Condition != nil {
+			b.edge(head, done)
+		}
+		b.loopBody(s, s.Label, s.Body, head, post, done)
+		b.current = post
+		if s.Post != nil {
+			b.add(s.Post)
+		}
+		b.edge(post, head)
+		b.current = done
+	case *ast.ForInStmt:
+		b.add(s.Iterable)
+		head, done := b.newBlock("for.head"), b.newBlock("for.done")
+		b.jump(head)
+		b.add(s.Binding)
+		b.edge(head, done)
+		b.loopBody(s, s.Label, s.Body, head, head, done)
+		b.current = done
+	case *ast.LoopStmt:
+		head, done := b.newBlock("loop.head"), b.newBlock("loop.done")
+		b.jump(head)
+		b.loopBody(s, s.Label, s.Body, head, head, done)
+		b.current = done
+	case *ast.BreakStmt:
+		b.add(s)
+		b.branch(s, s.Label, func(t *target) *Block { return t.breakTo })
+	case *ast.ContinueStmt:
+		b.add(s)
+		b.branch(s, s.Label, func(t *target) *Block { return t.continueTo })
+	case *ast.ReturnStmt:
+		if s.Value != nil {
+			b.expr(s.Value)
+		}
+		b.add(s)
+		for _, t := range b.targets {
+			t.loop.Exits = append(t.loop.Exits, b.current)
+		}
+		b.returns = append(b.returns, b.current)
+		b.deadEnd()
+	default:
+		// The declarations, whose bodies have their own graphs
+		b.add(stmt)
+	}
+}
+
+func (b *builder) ifStmt(s *ast.IfStmt) {
+	b.add(s.Condition)
+	condition := b.current
+	done := b.newBlock("if.done")
+
+	b.current = b.newBlock("if.then")
+	b.edge(condition, b.current)
+	b.stmt(s.Then)
+	b.edge(b.current, done)
+
+	if s.Else != nil {
+		b.current = b.newBlock("if.else")
+		b.edge(condition, b.current)
+		b.stmt(s.Else)
+		b.edge(b.current, done)
+	} else {
+		b.edge(condition, done)
+	}
+	b.current = done
+}
+
+// loopBody builds the body of a loop, which starts from the head of the loop, and
+// which jumps to next for the next iteration and to done to break out of the loop.
+func (b *builder) loopBody(loop ast.Stmt, label *ast.Identifier, body *ast.BlockStmt, head, next, done *Block) {
+	t := &target{loop: &Loop{Stmt: loop}, breakTo: done, continueTo: next}
+	if label != nil {
+		t.label = label.Name
+	}
+	b.graph.loops = append(b.graph.loops, t.loop)
+	b.targets = append(b.targets, t)
+	b.current = b.newBlock(strings.TrimSuffix(head.Comment, ".head") + ".body")
+	b.edge(head, b.current)
+	b.stmt(body)
+	b.edge(b.current, next)
+	b.targets = b.targets[:len(b.targets)-1]
+}
+
+// branch ends the current block with a `break` or a `continue` jumping to the block
+// of the enclosing loop with the label, or of the innermost loop.
+func (b *builder) branch(jump ast.Stmt, label *ast.Identifier, to func(*target) *Block) {
+	for i := len(b.targets) - 1; i >= 0; i-- {
+		t := b.targets[i]
+		if label != nil && t.label != label.Name {
+			continue
+		}
+		b.edge(b.current, to(t))
+		// The loops nested in the target are left
+		for _, inner := range b.targets[i+1:] {
+			inner.loop.Exits = append(inner.loop.Exits, b.current)
+		}
+		if _, isBreak := jump.(*ast.BreakStmt); isBreak {
+			t.loop.Exits = append(t.loop.Exits, b.current)
+		}
+		b.deadEnd()
+		return
+	}
+	// Outside of a loop, or an undefined label which the resolver reports. The jump is
+	// invalid and ignored, so that it doesn't make the following code unreachable.
+	if label == nil && b.onJump != nil {
+		b.onJump(jump)
+	}
+}
+
+// expr adds an expression to the current block. A match expression branches to its arms,
+// whose blocks may jump, the other expressions don't change the flow.
+func (b *builder) expr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		b.expr(e.Expr)
+	case *ast.MatchExpr:
+		b.expr(e.Subject)
+		subject := b.current
+		done := b.newBlock("match.done")
+		for _, arm := range e.Arms {
+			b.current = b.newBlock("match.arm")
+			b.edge(subject, b.current)
+			if arm.Guard != nil {
+				b.add(arm.Guard)
+			}
+			switch body := arm.Body.(type) {
+			case *ast.BlockStmt:
+				b.stmt(body)
+			case ast.Expr:
+				b.expr(body)
+			}
+			b.edge(b.current, done)
+		}
+		if len(e.Arms) == 0 {
+			b.edge(subject, done)
+		}
+		b.current = done
+		b.add(e)
+	default:
+		b.add(expr)
+	}
+}
@@ -0,0 +1,76 @@
+package flow
+
+import (
+	"mirth/compiler/ast"
+	"mirth/compiler/parser"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func buildGraph(source string) *Graph {
+	file := parser.CreateParser(source).ParseFile().Unwrap()
+	return Build(file.Statements, nil)
+}
+
+func TestBuild(t *testing.T) {
+	Convey("Test build the branches of an if statement", t, func() {
+		graph := buildGraph("if a { b() } else { return }\nc()")
+		So(graph.String(), ShouldEqual, strings.Join([]string{
+			"0.entry -> 2.if.then, 3.if.else",
+			"1.if.done -> 5.exit",
+			"2.if.then -> 1.if.done",
+			"3.if.else -> 5.exit",
+			"4.unreachable (dead) -> 1.if.done",
+			"5.exit",
+		}, "\n"))
+		So(graph.FallsOff(), ShouldBeTrue)
+		So(graph.Entry.Nodes, ShouldHaveLength, 1)
+	})
+
+	Convey("Test build the loops with their jumps", t, func() {
+		graph := buildGraph("for let i = 0; i < 3; i++ {\n  if i == 1 { continue }\n  f()\n}")
+		So(graph.String(), ShouldEqual, strings.Join([]string{
+			"0.entry -> 1.for.head",
+			"1.for.head -> 3.for.done, 4.for.body",
+			"2.for.post -> 1.for.head",
+			"3.for.done -> 8.exit",
+			"4.for.body -> 6.if.then, 5.if.done",
+			"5.if.done -> 2.for.post",
+			"6.if.then -> 2.for.post",
+			"7.unreachable (dead) -> 5.if.done",
+			"8.exit",
+		}, "\n"))
+
+		graph = buildGraph("outer: loop {\n  for x in xs { break outer }\n}")
+		So(graph.Loops(), ShouldHaveLength, 2)
+		So(graph.Loops()[0].Exits, ShouldHaveLength, 1)
+		So(graph.Loops()[1].Exits, ShouldHaveLength, 1)
+		So(graph.FallsOff(), ShouldBeTrue)
+	})
+
+	Convey("Test build the arms of a match expression", t, func() {
+		graph := buildGraph("let v = match x {\n  1 => { return }\n  _ => 2\n}\nf(v)")
+		So(graph.String(), ShouldEqual, strings.Join([]string{
+			"0.entry -> 2.match.arm, 4.match.arm",
+			"1.match.done -> 5.exit",
+			"2.match.arm -> 5.exit",
+			"3.unreachable (dead) -> 1.match.done",
+			"4.match.arm -> 1.match.done",
+			"5.exit",
+		}, "\n"))
+		file := parser.CreateParser("return\nf()").ParseFile().Unwrap()
+		graph = Build(file.Statements, nil)
+		So(graph.BlockOf(file.Statements[0]).Live, ShouldBeTrue)
+		So(graph.BlockOf(file.Statements[1]).Live, ShouldBeFalse)
+		So(graph.FallsOff(), ShouldBeFalse)
+	})
+
+	Convey("Test pass the jumps outside of a loop", t, func() {
+		var jumps []ast.Stmt
+		file := parser.CreateParser("break\nloop { break }\ncontinue").ParseFile().Unwrap()
+		Build(file.Statements, func(jump ast.Stmt) { jumps = append(jumps, jump) })
+		So(jumps, ShouldResemble, []ast.Stmt{file.Statements[0], file.Statements[2]})
+	})
+}
@@ -7,7 +7,7 @@ type UniRune struct {
 	byteLength int    // length of rune in bytes
 }
 
-var identifierTerminatorRegExp = regexp.MustCompile(`[ \t\n;:,(){}\[\].=?!*/%^|&~><+\-'"]`)
+var identifierTerminatorRegExp = regexp.MustCompile(`[ \t\n;:,(){}\[\].=?!*/%^|&~@><+\-'"` + "`" + `]`)
 var singleEscapeSymbolsRuneMap = map[string]string{
 	"n":  "\n",
 	"t":  "\t",
@@ -56,6 +56,21 @@ func isHexDigit(r *UniRune) bool {
 		(rawRune >= 'A' && rawRune <= 'F')
 }
 
+func hexDigitValue(r rune) byte {
+	switch {
+	case r >= '0' && r <= '9':
+		return byte(r - '0')
+	case r >= 'a' && r <= 'f':
+		return byte(r-'a') + 10
+	default:
+		return byte(r-'A') + 10
+	}
+}
+
+func isASCII(r *UniRune) bool {
+	return len(r.raw) == 1 && r.raw[0] < 0x80
+}
+
 func isOctalDigit(r *UniRune) bool {
 	return r.hasOnlyOneRune() && r.firstRune() >= '0' && r.firstRune() <= '7'
 }
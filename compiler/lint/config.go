@@ -0,0 +1,83 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"mirth/shared"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ConfigFile is the name of the lint configuration file at the root of a project, like:
+//
+//	{
+//	  "rules": {
+//	    "unused-param": "off",
+//	    "float-equality": "error"
+//	  }
+//	}
+//
+// The rules it doesn't list keep their default severity.
+const ConfigFile = "mirth-lint.json"
+
+// Config sets the severities of the rules of a project.
+type Config struct {
+	Severities map[string]Severity
+}
+
+// SeverityOf returns the severity of a rule, the config may be nil.
+func (c *Config) SeverityOf(rule *Rule) Severity {
+	if c != nil {
+		if severity, exists := c.Severities[rule.ID]; exists {
+			return severity
+		}
+	}
+	return rule.Severity
+}
+
+// ParseConfig parses the content of a configuration file, whose rules must be in the registry.
+func ParseConfig(data []byte, registry *Registry) (*Config, error) {
+	var file struct {
+		Rules map[string]string `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid lint configuration: %w", err)
+	}
+	ids := make([]string, 0, len(file.Rules))
+	for id := range file.Rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids) // The first error doesn't depend on the order of the map
+	config := &Config{Severities: map[string]Severity{}}
+	for _, id := range ids {
+		name := file.Rules[id]
+		if registry.Rule(id) == nil {
+			return nil, fmt.Errorf("unknown lint rule '%s' in the configuration", id)
+		}
+		severity, valid := ParseSeverity(name)
+		if !valid {
+			return nil, fmt.Errorf("invalid severity '%s' of the lint rule '%s', expected 'off', 'warning' or 'error'", name, id)
+		}
+		config.Severities[id] = severity
+	}
+	return config, nil
+}
+
+// LoadConfig loads the configuration file of the project at root, the default
+// configuration is empty if there's no file.
+func LoadConfig(root string, registry *Registry) *shared.Result[*Config, error] {
+	path := filepath.Join(root, ConfigFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return shared.ResultOk[*Config, error](&Config{Severities: map[string]Severity{}})
+	}
+	if err != nil {
+		return shared.ResultErr[*Config](err)
+	}
+	config, err := ParseConfig(data, registry)
+	if err != nil {
+		return shared.ResultErr[*Config](fmt.Errorf("%s: %w", path, err))
+	}
+	return shared.ResultOk[*Config, error](config)
+}
@@ -0,0 +1,114 @@
+package lint
+
+import (
+	"bytes"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"sort"
+	"unicode/utf8"
+)
+
+// ApplyFixes applies fixes to a source, and returns the fixed source with the number of fixes
+// applied. A fix whose edits overlap the edits of a fix applied before it is left out, linting
+// the fixed source again finds it again if it's still needed.
+func ApplyFixes(source []byte, fixes []*Fix) ([]byte, int) {
+	var edits []*Edit
+	applied := 0
+	for _, fix := range fixes {
+		if overlaps(fix.Edits, edits) {
+			continue
+		}
+		edits = append(edits, fix.Edits...)
+		applied++
+	}
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Pos.Offset < edits[j].Pos.Offset
+	})
+	var fixed bytes.Buffer
+	offset := 0
+	for _, edit := range edits {
+		fixed.Write(source[offset:edit.Pos.Offset])
+		fixed.WriteString(edit.Text)
+		offset = edit.End.Offset
+	}
+	fixed.Write(source[offset:])
+	return fixed.Bytes(), applied
+}
+
+func overlaps(edits, applied []*Edit) bool {
+	for _, edit := range edits {
+		for _, other := range applied {
+			if edit.Pos.Offset < other.End.Offset && other.Pos.Offset < edit.End.Offset {
+				return true
+			}
+			if edit.Pos.Offset == other.Pos.Offset {
+				return true // Two insertions at the same place, or an insertion at the start of a replacement
+			}
+		}
+	}
+	return false
+}
+
+// positionAt returns the position of an offset of the source.
+func positionAt(source []byte, offset int) *compiler.Position {
+	start := lineStart(source, offset)
+	line := bytes.Count(source[:start], []byte("\n")) + 1
+	return &compiler.Position{Offset: offset, Line: line, Column: utf8.RuneCount(source[start:offset]) + 1}
+}
+
+// lineStart returns the offset of the start of the line of an offset.
+func lineStart(source []byte, offset int) int {
+	return bytes.LastIndexByte(source[:offset], '\n') + 1
+}
+
+// lineEnd returns the offset after the line break ending the line of an offset.
+func lineEnd(source []byte, offset int) int {
+	if end := bytes.IndexByte(source[offset:], '\n'); end >= 0 {
+		return offset + end + 1
+	}
+	return len(source)
+}
+
+// indentOf returns the indentation of the line of an offset.
+func indentOf(source []byte, offset int) string {
+	start := lineStart(source, offset)
+	end := start
+	for end < len(source) && (source[end] == ' ' || source[end] == '\t') {
+		end++
+	}
+	return string(source[start:end])
+}
+
+func isBlank(text []byte) bool {
+	return len(bytes.TrimSpace(text)) == 0
+}
+
+// replaceNode replaces the source of a node by a text.
+func replaceNode(node ast.Node, text string) *Edit {
+	span := node.NodeSpan()
+	return &Edit{Pos: span.Start, End: span.End, Text: text}
+}
+
+// deleteNode deletes the source of a node, with its lines if nothing else is on them. The
+// spaces between the node and the rest of its line are deleted with it, like the ones before
+// a trailing comment.
+func deleteNode(source []byte, node ast.Node) *Edit {
+	span := node.NodeSpan()
+	start, end := lineStart(source, span.Start.Offset), lineEnd(source, span.End.Offset)
+	before, after := isBlank(source[start:span.Start.Offset]), isBlank(source[span.End.Offset:end])
+	switch {
+	case before && after:
+		return &Edit{Pos: positionAt(source, start), End: positionAt(source, end)}
+	case before:
+		end = span.End.Offset
+		for source[end] == ' ' || source[end] == '\t' {
+			end++
+		}
+		return &Edit{Pos: span.Start, End: positionAt(source, end)}
+	}
+	start = span.Start.Offset
+	for source[start-1] == ' ' || source[start-1] == '\t' {
+		start--
+	}
+	return &Edit{Pos: positionAt(source, start), End: span.End}
+}
@@ -0,0 +1,179 @@
+// Package lint runs lint rules on the resolved files: rules which report the code which
+// compiles but is likely a mistake, like unused variables. The rules are registered in a
+// registry with an ID and a default severity, a project configures their severities and
+// a `//mirth:ignore` pragma comment suppresses their findings. A rule may propose a fix.
+package lint
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/resolve"
+	"mirth/compiler/types"
+	"sort"
+)
+
+// Severity tells how a finding is reported, the rules which are off don't run.
+type Severity int
+
+const (
+	Off Severity = iota
+	Warning
+	Error
+)
+
+var severityNames = []string{"off", "warning", "error"}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// ParseSeverity returns the severity with the name like `warning`.
+func ParseSeverity(name string) (Severity, bool) {
+	for i, severityName := range severityNames {
+		if severityName == name {
+			return Severity(i), true
+		}
+	}
+	return Off, false
+}
+
+// Rule is a lint rule, identified by an ID like `unused-variable`.
+type Rule struct {
+	ID string
+	// Doc describes what the rule reports in a sentence.
+	Doc string
+	// Severity is the severity of the findings if the configuration doesn't change it.
+	Severity Severity
+	Run      func(pass *Pass)
+}
+
+// File is a file to lint, whose names are resolved by Table. Types holds the types of
+// the checked expressions, it's nil if the file isn't type checked, then the rules
+// which need the types find less. The tokens hold the comments with the pragmas.
+type File struct {
+	AST    *ast.File
+	Source []byte
+	Tokens []*compiler.Token
+	Table  *resolve.Table
+	Types  *types.Info
+}
+
+// Finding is a diagnostic reported by a rule, with its fix if the rule has one.
+type Finding struct {
+	*compiler.Diagnostic
+	Rule string
+	Fix  *Fix
+}
+
+func (f *Finding) String() string {
+	return fmt.Sprintf("%s [%s]", f.Diagnostic.String(), f.Rule)
+}
+
+// Fix is a change of the source fixing a finding, made of edits which don't overlap.
+type Fix struct {
+	Msg   string
+	Edits []*Edit
+}
+
+// Edit replaces the source from Pos to End by Text, it inserts Text if they're the same.
+type Edit struct {
+	Pos  *compiler.Position
+	End  *compiler.Position
+	Text string
+}
+
+// Pass is the run of a rule on a file.
+type Pass struct {
+	*File
+	rule     *Rule
+	severity Severity
+	findings []*Finding
+}
+
+// Report reports a finding of the rule on a node, fix is nil if the rule can't fix it.
+func (p *Pass) Report(node ast.Node, msg string, fix *Fix) {
+	span := node.NodeSpan()
+	diagnosticType := compiler.DiagnosticWarning
+	if p.severity == Error {
+		diagnosticType = compiler.DiagnosticError
+	}
+	p.findings = append(p.findings, &Finding{
+		Diagnostic: &compiler.Diagnostic{Type: diagnosticType, Code: compiler.LintFinding, Pos: span.Start, End: span.End, Msg: msg},
+		Rule:       p.rule.ID,
+		Fix:        fix,
+	})
+}
+
+// Registry holds the rules which can run, by ID.
+type Registry struct {
+	rules map[string]*Rule
+}
+
+func CreateRegistry() *Registry {
+	return &Registry{rules: map[string]*Rule{}}
+}
+
+// Builtin returns a new registry with the built-in rules, more rules can be registered in it.
+func Builtin() *Registry {
+	registry := CreateRegistry()
+	for _, rule := range builtinRules {
+		if err := registry.Register(rule); err != nil {
+			panic(err)
+		}
+	}
+	return registry
+}
+
+// Register adds a rule to the registry, its ID must be unique.
+func (r *Registry) Register(rule *Rule) error {
+	if rule.ID == "" || rule.Run == nil {
+		return fmt.Errorf("lint rule '%s' needs an ID and a Run function", rule.ID)
+	}
+	if _, exists := r.rules[rule.ID]; exists {
+		return fmt.Errorf("lint rule '%s' is already registered", rule.ID)
+	}
+	r.rules[rule.ID] = rule
+	return nil
+}
+
+// Rule returns the rule with the ID, or nil.
+func (r *Registry) Rule(id string) *Rule {
+	return r.rules[id]
+}
+
+// Rules returns the rules sorted by ID.
+func (r *Registry) Rules() []*Rule {
+	rules := make([]*Rule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// Run runs the rules enabled by config on a file, config is nil for the default severities.
+// The findings suppressed by a pragma are left out, the invalid pragmas are reported. The
+// findings are sorted by position.
+func (r *Registry) Run(file *File, config *Config) []*Finding {
+	pragmas, findings := r.parsePragmas(file.Tokens)
+	for _, rule := range r.Rules() {
+		severity := config.SeverityOf(rule)
+		if severity == Off || pragmas.suppressesFile(rule.ID) {
+			continue
+		}
+		pass := &Pass{File: file, rule: rule, severity: severity}
+		rule.Run(pass)
+		for _, finding := range pass.findings {
+			if !pragmas.suppresses(finding.Pos.Line, rule.ID) {
+				findings = append(findings, finding)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Pos.Offset < findings[j].Pos.Offset
+	})
+	return findings
+}
@@ -0,0 +1,162 @@
+package lint
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/parser"
+	"mirth/compiler/resolve"
+	"mirth/compiler/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// checkedFile scans, parses, resolves and type checks a source into a file to lint.
+func checkedFile(source string) *File {
+	tokens, _ := compiler.CreateScanner(source).Tokenize()
+	file := parser.CreateParserFromTokens(tokens, nil).ParseFile().Unwrap()
+	table, _ := resolve.ResolveFile(file)
+	info := types.CreateInfo()
+	types.Check([]*ast.File{file}, table, info)
+	return &File{AST: file, Source: []byte(source), Tokens: tokens, Table: table, Types: info}
+}
+
+// run lints a source with the built-in rules.
+func run(source string, config *Config) []*Finding {
+	return Builtin().Run(checkedFile(source), config)
+}
+
+func messages(findings []*Finding) []string {
+	var msgs []string
+	for _, finding := range findings {
+		msgs = append(msgs, finding.Msg+" ["+finding.Rule+"]")
+	}
+	return msgs
+}
+
+// fix applies the fixes of all the findings of a source.
+func fix(source string) string {
+	var fixes []*Fix
+	for _, finding := range run(source, nil) {
+		if finding.Fix != nil {
+			fixes = append(fixes, finding.Fix)
+		}
+	}
+	fixed, _ := ApplyFixes([]byte(source), fixes)
+	return string(fixed)
+}
+
+func TestRegistry(t *testing.T) {
+	Convey("Test register the rules by ID", t, func() {
+		registry := Builtin()
+		So(registry.Rule("unused-variable"), ShouldEqual, unusedVariable)
+		So(registry.Rule("missing"), ShouldBeNil)
+		So(len(registry.Rules()), ShouldEqual, len(builtinRules))
+		So(registry.Rules()[0].ID, ShouldEqual, "constant-condition")
+
+		custom := &Rule{ID: "no-print", Severity: Error, Run: func(p *Pass) {}}
+		So(registry.Register(custom), ShouldBeNil)
+		So(registry.Register(custom).Error(), ShouldEqual, "lint rule 'no-print' is already registered")
+		So(registry.Register(&Rule{ID: "no-run"}).Error(), ShouldEqual, "lint rule 'no-run' needs an ID and a Run function")
+	})
+
+	Convey("Test run a custom rule with its severity", t, func() {
+		registry := CreateRegistry()
+		registry.Register(&Rule{ID: "no-todo", Severity: Error, Run: func(p *Pass) {
+			for _, stmt := range p.AST.Statements {
+				p.Report(stmt, "Statement found", nil)
+			}
+		}})
+		findings := registry.Run(checkedFile("let a = 1\nlet b = 2"), nil)
+		So(messages(findings), ShouldResemble, []string{"Statement found [no-todo]", "Statement found [no-todo]"})
+		So(findings[0].Type, ShouldEqual, compiler.DiagnosticError)
+		So(findings[0].Code, ShouldEqual, compiler.LintFinding)
+		So(findings[1].Pos.Line, ShouldEqual, 2)
+	})
+}
+
+func TestConfig(t *testing.T) {
+	Convey("Test configure the severities of the rules", t, func() {
+		config, err := ParseConfig([]byte(`{"rules": {"unused-variable": "off", "float-equality": "error"}}`), Builtin())
+		So(err, ShouldBeNil)
+		So(config.SeverityOf(unusedVariable), ShouldEqual, Off)
+		So(config.SeverityOf(floatEquality), ShouldEqual, Error)
+		So(config.SeverityOf(selfAssignment), ShouldEqual, Warning)
+
+		findings := run(`
+func f(x: float) bool {
+  let unused = 1
+  return x == 0.5
+}`, config)
+		So(messages(findings), ShouldResemble, []string{
+			"Floats compared with '==' may differ by a rounding error, compare their difference with a tolerance [float-equality]",
+		})
+		So(findings[0].Type, ShouldEqual, compiler.DiagnosticError)
+	})
+
+	Convey("Test report the invalid configurations", t, func() {
+		_, err := ParseConfig([]byte(`{"rules": {"unused-everything": "off"}}`), Builtin())
+		So(err.Error(), ShouldEqual, "unknown lint rule 'unused-everything' in the configuration")
+		_, err = ParseConfig([]byte(`{"rules": {"unused-variable": "fatal"}}`), Builtin())
+		So(err.Error(), ShouldContainSubstring, "invalid severity 'fatal'")
+	})
+
+	Convey("Test load the configuration of a project", t, func() {
+		root := t.TempDir()
+		config := LoadConfig(root, Builtin())
+		So(config.Ok, ShouldBeTrue)
+		So(config.Unwrap().SeverityOf(unusedImport), ShouldEqual, Warning)
+
+		os.WriteFile(filepath.Join(root, ConfigFile), []byte(`{"rules": {"unused-import": "error"}}`), 0o644)
+		So(LoadConfig(root, Builtin()).Unwrap().SeverityOf(unusedImport), ShouldEqual, Error)
+	})
+}
+
+func TestPragmas(t *testing.T) {
+	Convey("Test suppress the findings on a line", t, func() {
+		findings := run(`
+func f(x: float) {
+  let a = 1 //mirth:ignore unused-variable
+  //mirth:ignore
+  let b = x == 0.5
+  let c = 2 //mirth:ignore float-equality
+}`, nil)
+		So(messages(findings), ShouldResemble, []string{"Variable 'c' is never used [unused-variable]"})
+	})
+
+	Convey("Test suppress the findings in a file", t, func() {
+		findings := run(`//mirth:ignore-file unused-variable, unused-param
+func f(x: int) {
+  let a = 1
+  a = a
+}`, nil)
+		So(messages(findings), ShouldResemble, []string{"Assignment of a value to itself [self-assignment]"})
+	})
+
+	Convey("Test report the invalid pragmas", t, func() {
+		findings := run(`
+//mirth:silence
+//mirth:ignore unused-everything
+`, nil)
+		So(messages(findings), ShouldResemble, []string{
+			"Unknown pragma '//mirth:silence', expected '//mirth:ignore' or '//mirth:ignore-file' [pragma]",
+			"Unknown lint rule 'unused-everything' in the pragma [pragma]",
+		})
+	})
+}
+
+func TestApplyFixes(t *testing.T) {
+	Convey("Test apply the fixes which don't overlap", t, func() {
+		source := []byte("abcdef")
+		at := func(offset int) *compiler.Position { return positionAt(source, offset) }
+		fixed, applied := ApplyFixes(source, []*Fix{
+			{Edits: []*Edit{{Pos: at(4), End: at(6), Text: "XY"}}},
+			{Edits: []*Edit{{Pos: at(0), End: at(1)}, {Pos: at(2), End: at(2), Text: "-"}}},
+			{Edits: []*Edit{{Pos: at(5), End: at(6), Text: "Z"}}},
+		})
+		So(string(fixed), ShouldEqual, "b-cdXY")
+		So(applied, ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,87 @@
+package lint
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"strings"
+)
+
+// pragmaPrefix starts the pragma comments:
+//
+//	//mirth:ignore unused-variable, self-assignment
+//	//mirth:ignore-file float-equality
+//
+// `ignore` suppresses the findings of the rules on its line, or on the next line if the
+// comment is alone on its line. `ignore-file` suppresses them in the whole file. Without
+// rule IDs, they suppress the findings of all the rules.
+const pragmaPrefix = "//mirth:"
+
+// invalidPragma is the rule of the findings about the pragmas themselves, which can't be suppressed.
+const invalidPragma = "pragma"
+
+// pragmas are the suppressions of a file, by rule ID. The empty ID stands for all the rules.
+type pragmas struct {
+	file  map[string]bool
+	lines map[int]map[string]bool
+}
+
+func (p *pragmas) suppressesFile(rule string) bool {
+	return p.file[""] || p.file[rule]
+}
+
+func (p *pragmas) suppresses(line int, rule string) bool {
+	return p.suppressesFile(rule) || p.lines[line][""] || p.lines[line][rule]
+}
+
+// parsePragmas collects the pragmas of the comments of a file, and reports the invalid ones.
+func (r *Registry) parsePragmas(tokens []*compiler.Token) (*pragmas, []*Finding) {
+	p := &pragmas{file: map[string]bool{}, lines: map[int]map[string]bool{}}
+	var findings []*Finding
+	invalid := func(token *compiler.Token, msg string) {
+		findings = append(findings, &Finding{
+			Diagnostic: &compiler.Diagnostic{Type: compiler.DiagnosticWarning, Code: compiler.LintFinding, Pos: token.Pos, End: token.End, Msg: msg},
+			Rule:       invalidPragma,
+		})
+	}
+
+	codeLine := 0 // The last line with code before the comment
+	for _, token := range tokens {
+		if token.Type != compiler.TokenTypeLineComment {
+			if token.Type != compiler.TokenTypeLineBreak && token.Type != compiler.TokenTypeEndOfFile {
+				codeLine = token.Pos.Line
+			}
+			continue
+		}
+		if !strings.HasPrefix(token.Content, pragmaPrefix) {
+			continue
+		}
+		fields := strings.Fields(strings.ReplaceAll(strings.TrimPrefix(token.Content, pragmaPrefix), ",", " "))
+		if len(fields) == 0 || (fields[0] != "ignore" && fields[0] != "ignore-file") {
+			invalid(token, fmt.Sprintf("Unknown pragma '%s', expected '%signore' or '%signore-file'", token.Content, pragmaPrefix, pragmaPrefix))
+			continue
+		}
+		rules := fields[1:]
+		if len(rules) == 0 {
+			rules = []string{""}
+		}
+		suppressed := p.file
+		if fields[0] == "ignore" {
+			line := token.Pos.Line
+			if codeLine != line {
+				line++ // The comment is alone on its line
+			}
+			if p.lines[line] == nil {
+				p.lines[line] = map[string]bool{}
+			}
+			suppressed = p.lines[line]
+		}
+		for _, rule := range rules {
+			if rule != "" && r.Rule(rule) == nil {
+				invalid(token, fmt.Sprintf("Unknown lint rule '%s' in the pragma", rule))
+				continue
+			}
+			suppressed[rule] = true
+		}
+	}
+	return p, findings
+}
@@ -0,0 +1,260 @@
+package lint
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/resolve"
+	"mirth/compiler/types"
+	"strings"
+)
+
+// builtinRules are the rules of the registry returned by Builtin.
+var builtinRules = []*Rule{
+	unusedVariable,
+	unusedParam,
+	unusedImport,
+	redundantElse,
+	constantCondition,
+	selfAssignment,
+	floatEquality,
+}
+
+var redundantElse = &Rule{
+	ID:       "redundant-else",
+	Doc:      "Reports the else branches following a branch which always jumps away, their code can follow the if statement.",
+	Severity: Warning,
+	Run:      runRedundantElse,
+}
+
+var constantCondition = &Rule{
+	ID:       "constant-condition",
+	Doc:      "Reports the conditions of the if statements, the for loops and the match guards which are always true or always false.",
+	Severity: Warning,
+	Run:      runConstantCondition,
+}
+
+var selfAssignment = &Rule{
+	ID:       "self-assignment",
+	Doc:      "Reports the assignments of a variable, a field or an element to itself.",
+	Severity: Warning,
+	Run:      runSelfAssignment,
+}
+
+var floatEquality = &Rule{
+	ID:       "float-equality",
+	Doc:      "Reports the comparisons of floats with '==' and '!=', which fail on rounding errors.",
+	Severity: Warning,
+	Run:      runFloatEquality,
+}
+
+// jumps tells whether a block ends with a return, a break or a continue.
+func jumps(block *ast.BlockStmt) bool {
+	if len(block.Statements) == 0 {
+		return false
+	}
+	switch s := block.Statements[len(block.Statements)-1].(type) {
+	case *ast.ReturnStmt, *ast.BreakStmt, *ast.ContinueStmt:
+		return true
+	case *ast.BlockStmt:
+		return jumps(s)
+	case *ast.IfStmt:
+		elseBlock, isBlock := s.Else.(*ast.BlockStmt)
+		return jumps(s.Then) && isBlock && jumps(elseBlock)
+	}
+	return false
+}
+
+// runRedundantElse reports `if c { return } else { f() }`, which is fixed by moving the code
+// of the else branch after the if statement. An `else if` becomes an if statement of its own.
+func runRedundantElse(p *Pass) {
+	ast.Inspect(p.AST, func(node ast.Node) bool {
+		s, isIf := node.(*ast.IfStmt)
+		if !isIf || s.Else == nil || !jumps(s.Then) {
+			return true
+		}
+		indent := indentOf(p.Source, s.Start.Offset)
+		edit := &Edit{Pos: s.Then.End, End: s.Else.NodeSpan().Start, Text: "\n" + indent}
+		if block, isBlock := s.Else.(*ast.BlockStmt); isBlock {
+			edit = &Edit{Pos: s.Then.End, End: block.End, Text: dedentBlock(p.Source, block, indent)}
+		}
+		p.Report(s.Else, "The else branch is redundant, the if branch always jumps away", &Fix{
+			Msg:   "Move the code of the else branch after the if statement",
+			Edits: []*Edit{edit},
+		})
+		return true
+	})
+}
+
+// dedentBlock returns the lines of the code of a block between its braces, comments included,
+// each starting with a line break and reindented from the indentation of its first line below
+// the opening brace to indent. The blank lines around the code are left out.
+func dedentBlock(source []byte, block *ast.BlockStmt, indent string) string {
+	lines := strings.Split(string(source[block.Start.Offset+1:block.End.Offset-1]), "\n")
+	inner := ""
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) != "" {
+			inner = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			break
+		}
+	}
+	lines[0] = strings.TrimLeft(lines[0], " \t") // The code on the line of the opening brace
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var text strings.Builder
+	for _, line := range lines {
+		text.WriteString("\n")
+		if line = strings.TrimRight(line, " \t"); line != "" {
+			text.WriteString(indent + strings.TrimPrefix(line, inner))
+		}
+	}
+	return text.String()
+}
+
+// runConstantCondition reports the conditions whose value is known at compile time. The
+// conditions which are a named constant, like `if DEBUG`, or its negation are left alone,
+// they're meant to be changed.
+func runConstantCondition(p *Pass) {
+	check := func(cond ast.Expr, hint string) {
+		if cond == nil || isNamedConstant(cond) {
+			return
+		}
+		value, _ := types.EvalConstant(cond, p.constantLookup(map[*resolve.Symbol]bool{}))
+		if value == nil || value.Kind != types.BoolConstant {
+			return
+		}
+		msg := "Condition is always false"
+		if value.Bool {
+			msg = "Condition is always true" + hint
+		}
+		p.Report(cond, msg, nil)
+	}
+	ast.Inspect(p.AST, func(node ast.Node) bool {
+		switch s := node.(type) {
+		case *ast.IfStmt:
+			check(s.Condition, "")
+		case *ast.ForStmt:
+			check(s.Condition, ", use 'loop' for an infinite loop")
+		case *ast.MatchArm:
+			check(s.Guard, "")
+		}
+		return true
+	})
+}
+
+func isNamedConstant(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return true
+	case *ast.ParenExpr:
+		return isNamedConstant(e.Expr)
+	case *ast.UnaryExpr:
+		return e.Operator.Type == compiler.TokenTypeBang && isNamedConstant(e.Operand)
+	}
+	return false
+}
+
+// constantLookup returns the values of the constants, computed by the type checker if the
+// file is checked, or folded from their declarations otherwise. seen guards against the
+// constants declared in a cycle.
+func (p *Pass) constantLookup(seen map[*resolve.Symbol]bool) types.ConstantLookup {
+	return func(ident *ast.Identifier) (*types.Constant, *compiler.Diagnostic) {
+		symbol := p.Table.Uses[ident]
+		if symbol == nil || symbol.Kind != resolve.ConstSymbol || seen[symbol] {
+			return nil, nil
+		}
+		if p.Types != nil {
+			if value, isChecked := p.Types.Constants[symbol]; isChecked {
+				return value, nil
+			}
+		}
+		decl, isVar := symbol.Decl.(*ast.VarDecl)
+		if !isVar || decl.Value == nil {
+			return nil, nil
+		}
+		seen[symbol] = true
+		defer delete(seen, symbol)
+		return types.EvalConstant(decl.Value, p.constantLookup(seen))
+	}
+}
+
+// runSelfAssignment reports `x = x` and `p.x = p.x`, which are removed.
+func runSelfAssignment(p *Pass) {
+	ast.Inspect(p.AST, func(node ast.Node) bool {
+		stmt, isExpr := node.(*ast.ExprStmt)
+		if !isExpr {
+			return true
+		}
+		assign, isAssign := stmt.Expr.(*ast.AssignExpr)
+		if isAssign && assign.Operator.Type == compiler.TokenTypeEqual && p.sameExpr(assign.Target, assign.Value) {
+			p.Report(assign, "Assignment of a value to itself", &Fix{
+				Msg:   "Remove the assignment",
+				Edits: []*Edit{deleteNode(p.Source, stmt)},
+			})
+		}
+		return true
+	})
+}
+
+// sameExpr tells whether two expressions name the same variable, field or element, which
+// are made of names, member accesses, and indexes which are names or literals.
+func (p *Pass) sameExpr(a, b ast.Expr) bool {
+	if paren, isParen := b.(*ast.ParenExpr); isParen {
+		return p.sameExpr(a, paren.Expr)
+	}
+	switch x := a.(type) {
+	case *ast.ParenExpr:
+		return p.sameExpr(x.Expr, b)
+	case *ast.Identifier:
+		y, isName := b.(*ast.Identifier)
+		if !isName || x.Name != y.Name {
+			return false
+		}
+		return p.Table.Uses[x] == p.Table.Uses[y]
+	case *ast.MemberExpr:
+		y, isMember := b.(*ast.MemberExpr)
+		return isMember && !x.Optional && !y.Optional && x.Name.Name == y.Name.Name && p.sameExpr(x.Target, y.Target)
+	case *ast.IndexExpr:
+		y, isIndex := b.(*ast.IndexExpr)
+		return isIndex && p.sameExpr(x.Target, y.Target) && p.sameExpr(x.Index, y.Index)
+	case *ast.BasicLiteral:
+		y, isLiteral := b.(*ast.BasicLiteral)
+		return isLiteral && x.Kind == y.Kind && x.Value == y.Value
+	}
+	return false
+}
+
+// runFloatEquality reports the float comparisons with `==` and `!=`. The type of an operand
+// is known if the file is checked, otherwise only the float literals are recognized.
+func runFloatEquality(p *Pass) {
+	ast.Inspect(p.AST, func(node ast.Node) bool {
+		e, isBinary := node.(*ast.BinaryExpr)
+		if !isBinary || (e.Operator.Type != compiler.TokenTypeDoubleEqual && e.Operator.Type != compiler.TokenTypeBangEqual) {
+			return true
+		}
+		if p.isFloat(e.Left) || p.isFloat(e.Right) {
+			p.Report(e, "Floats compared with '"+e.Operator.Content+"' may differ by a rounding error, compare their difference with a tolerance", nil)
+		}
+		return true
+	})
+}
+
+func (p *Pass) isFloat(expr ast.Expr) bool {
+	if p.Types != nil {
+		if basic, isBasic := p.Types.TypeOf(expr).(*types.Basic); isBasic {
+			return basic.Kind == types.Float32Kind || basic.Kind == types.Float64Kind
+		}
+	}
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return p.isFloat(e.Expr)
+	case *ast.UnaryExpr:
+		return p.isFloat(e.Operand)
+	case *ast.BasicLiteral:
+		return e.Kind == compiler.TokenTypeFloat || e.Kind == compiler.TokenTypeExponent
+	}
+	return false
+}
@@ -0,0 +1,249 @@
+package lint
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUnusedRules(t *testing.T) {
+	Convey("Test report the unused variables and constants", t, func() {
+		source := `
+let top = 1
+
+func f() int {
+  let a = compute()
+  let b: int
+  const C = 2
+  let d = 1
+  d = 2
+  let _ignored = 3
+  let e = 4
+  return e + top
+}`
+		So(messages(run(source, nil)), ShouldResemble, []string{
+			"Variable 'a' is never used [unused-variable]",
+			"Variable 'b' is never used [unused-variable]",
+			"Constant 'C' is never used [unused-variable]",
+			"Variable 'd' is assigned but never read [unused-variable]",
+		})
+		So(fix(source), ShouldEqual, `
+let top = 1
+
+func f() int {
+  let _ = compute()
+  let d = 1
+  d = 2
+  let _ignored = 3
+  let e = 4
+  return e + top
+}`)
+	})
+
+	Convey("Test report the unused parameters", t, func() {
+		source := `
+func f(a: int, b: int, _c: int) int {
+  let g = (x: int) => 1
+  return a + g(0)
+}`
+		So(messages(run(source, nil)), ShouldResemble, []string{
+			"Parameter 'b' is never used [unused-param]",
+			"Parameter 'x' is never used [unused-param]",
+		})
+		So(fix(source), ShouldEqual, `
+func f(a: int, _b: int, _c: int) int {
+  let g = (_x: int) => 1
+  return a + g(0)
+}`)
+	})
+
+	Convey("Test report the unused imports", t, func() {
+		source := `
+import geometry.shapes
+import geometry.vector as vec
+import std.math.{ sqrt, pow, abs }
+import std.text.{ trim }
+
+func f() float {
+  return vec.length(pow(2.0, 2.0))
+}`
+		findings := run(source, nil)
+		So(messages(findings), ShouldResemble, []string{
+			"Module 'geometry.shapes' is imported but never used [unused-import]",
+			"'sqrt' is imported from module 'std.math' but never used [unused-import]",
+			"'abs' is imported from module 'std.math' but never used [unused-import]",
+			"None of the declarations imported from module 'std.text' is used [unused-import]",
+		})
+		So(fix(source), ShouldEqual, `
+import geometry.vector as vec
+import std.math.{ pow }
+
+func f() float {
+  return vec.length(pow(2.0, 2.0))
+}`)
+	})
+}
+
+func TestRedundantElse(t *testing.T) {
+	Convey("Test move the else branches after the if statements which jump away", t, func() {
+		source := `
+func sign(x: int) int {
+  if x < 0 {
+    return -1
+  } else if x == 0 {
+    return 0
+  }
+  for {
+    if x > 100 {
+      break
+    } else {
+      x = x * 2
+      print(x)
+    }
+  }
+  if x > 10 { return 2 } else { return 1 }
+}`
+		So(messages(run(source, nil)), ShouldResemble, []string{
+			"The else branch is redundant, the if branch always jumps away [redundant-else]",
+			"The else branch is redundant, the if branch always jumps away [redundant-else]",
+			"The else branch is redundant, the if branch always jumps away [redundant-else]",
+		})
+		So(fix(source), ShouldEqual, `
+func sign(x: int) int {
+  if x < 0 {
+    return -1
+  }
+  if x == 0 {
+    return 0
+  }
+  for {
+    if x > 100 {
+      break
+    }
+    x = x * 2
+    print(x)
+  }
+  if x > 10 { return 2 }
+  return 1
+}`)
+	})
+
+	Convey("Test keep the comments of the else branches when moving them", t, func() {
+		source := `
+func f(x: int) int {
+  if x < 0 {
+    return -1
+  } else { // x is positive
+    // Double it
+    x = x * 2
+
+    return x // Doubled
+    // Unreachable
+  }
+}`
+		So(fix(source), ShouldEqual, `
+func f(x: int) int {
+  if x < 0 {
+    return -1
+  }
+  // x is positive
+  // Double it
+  x = x * 2
+
+  return x // Doubled
+  // Unreachable
+}`)
+	})
+
+	Convey("Test accept the else branches after the if statements which fall through", t, func() {
+		So(run(`
+func f(x: int) int {
+  if x < 0 {
+    if x < -10 { return -2 }
+  } else {
+    return 1
+  }
+  return 0
+}`, nil), ShouldBeEmpty)
+	})
+}
+
+func TestConstantCondition(t *testing.T) {
+	Convey("Test report the conditions which are always true or false", t, func() {
+		findings := run(`
+const DEBUG = false
+const LIMIT = 10
+
+func f(x: int) {
+  if DEBUG { print("debug") }
+  if !DEBUG { print("release") }
+  if LIMIT > 5 { print(x) }
+  if 1 == 2 || DEBUG { print(x) }
+  for true { break }
+  for x < LIMIT { break }
+  match x {
+    n if LIMIT < 0 => print(n)
+    _ => print(0)
+  }
+}`, nil)
+		So(messages(findings), ShouldResemble, []string{
+			"Condition is always true [constant-condition]",
+			"Condition is always false [constant-condition]",
+			"Condition is always true, use 'loop' for an infinite loop [constant-condition]",
+			"Condition is always false [constant-condition]",
+		})
+		So(findings[0].Pos.Line, ShouldEqual, 8)
+	})
+}
+
+func TestSelfAssignment(t *testing.T) {
+	Convey("Test report the assignments of a value to itself", t, func() {
+		source := `
+struct Point { x: int, y: int }
+
+func f(p: Point, xs: []int, i: int) {
+  let x = 1
+  x = x
+  p.x = p.x
+  p.x = p.y
+  xs[i] = xs[i]
+  xs[i] = xs[i + 1]
+  x = x // keep me
+  print(x)
+}`
+		So(messages(run(source, nil)), ShouldResemble, []string{
+			"Assignment of a value to itself [self-assignment]",
+			"Assignment of a value to itself [self-assignment]",
+			"Assignment of a value to itself [self-assignment]",
+			"Assignment of a value to itself [self-assignment]",
+		})
+		So(fix(source), ShouldEqual, `
+struct Point { x: int, y: int }
+
+func f(p: Point, xs: []int, i: int) {
+  let x = 1
+  p.x = p.y
+  xs[i] = xs[i + 1]
+  // keep me
+  print(x)
+}`)
+	})
+}
+
+func TestFloatEquality(t *testing.T) {
+	Convey("Test report the float comparisons with == and !=", t, func() {
+		findings := run(`
+func f(a: float, b: f32, n: int) bool {
+  let c = a == 0.1
+  let d = b != b
+  let e = n == 1
+  let g = (a + 1.0) < 2.0
+  return c && d && e && g
+}`, nil)
+		So(messages(findings), ShouldResemble, []string{
+			"Floats compared with '==' may differ by a rounding error, compare their difference with a tolerance [float-equality]",
+			"Floats compared with '!=' may differ by a rounding error, compare their difference with a tolerance [float-equality]",
+		})
+		So(findings[0].Fix, ShouldBeNil)
+	})
+}
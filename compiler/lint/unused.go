@@ -0,0 +1,175 @@
+package lint
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/resolve"
+	"strings"
+)
+
+var unusedVariable = &Rule{
+	ID:       "unused-variable",
+	Doc:      "Reports the local variables and constants which are never read.",
+	Severity: Warning,
+	Run:      runUnusedVariable,
+}
+
+var unusedParam = &Rule{
+	ID:       "unused-param",
+	Doc:      "Reports the parameters of the functions and the lambdas which are never read.",
+	Severity: Warning,
+	Run:      runUnusedParam,
+}
+
+var unusedImport = &Rule{
+	ID:       "unused-import",
+	Doc:      "Reports the imported modules and declarations which are never used.",
+	Severity: Warning,
+	Run:      runUnusedImport,
+}
+
+// isIgnored tells whether a name is meant to be unused, like `_` and `_index`.
+func isIgnored(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+// reads returns the number of uses of a symbol which read it, the targets of the assignments
+// `x = value` don't.
+func reads(symbol *resolve.Symbol, writes map[*ast.Identifier]bool) int {
+	count := 0
+	for _, use := range symbol.Uses {
+		if !writes[use] {
+			count++
+		}
+	}
+	return count
+}
+
+// writesOf returns the names assigned by the assignments `x = value` of a file.
+func writesOf(file *ast.File) map[*ast.Identifier]bool {
+	writes := map[*ast.Identifier]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if assign, isAssign := node.(*ast.AssignExpr); isAssign && assign.Operator.Type == compiler.TokenTypeEqual {
+			if target, isName := assign.Target.(*ast.Identifier); isName {
+				writes[target] = true
+			}
+		}
+		return true
+	})
+	return writes
+}
+
+// runUnusedVariable reports the unused local declarations, the top-level ones may be used
+// by the other files of the module. An unused variable is renamed `_` to keep the evaluation
+// of its value, and an unused constant is removed.
+func runUnusedVariable(p *Pass) {
+	writes := writesOf(p.AST)
+	ast.Inspect(p.AST, func(node ast.Node) bool {
+		decl, isVar := node.(*ast.VarDecl)
+		if !isVar || isIgnored(decl.Name.Name) {
+			return true
+		}
+		symbol := p.Table.Defs[decl.Name]
+		if symbol == nil || symbol.Scope == nil || symbol.Scope.Kind == resolve.ModuleScope || reads(symbol, writes) > 0 {
+			return true
+		}
+		what := "Variable"
+		if decl.IsConst() {
+			what = "Constant"
+		}
+		switch {
+		case len(symbol.Uses) > 0:
+			p.Report(decl.Name, fmt.Sprintf("%s '%s' is assigned but never read", what, decl.Name.Name), nil)
+		case decl.Value != nil && !decl.IsConst():
+			p.Report(decl.Name, fmt.Sprintf("%s '%s' is never used", what, decl.Name.Name), &Fix{
+				Msg:   "Rename it '_'",
+				Edits: []*Edit{replaceNode(decl.Name, "_")},
+			})
+		default:
+			p.Report(decl.Name, fmt.Sprintf("%s '%s' is never used", what, decl.Name.Name), &Fix{
+				Msg:   "Remove the declaration",
+				Edits: []*Edit{deleteNode(p.Source, decl)},
+			})
+		}
+		return true
+	})
+}
+
+// runUnusedParam reports the unused parameters, which are renamed with a leading `_`. The
+// parameters of the methods aren't reported, an interface may need them.
+func runUnusedParam(p *Pass) {
+	writes := writesOf(p.AST)
+	ast.Inspect(p.AST, func(node ast.Node) bool {
+		var params []*ast.Param
+		switch f := node.(type) {
+		case *ast.FuncDecl:
+			if f.Receiver != nil || f.Body == nil {
+				return true
+			}
+			params = f.Params
+		case *ast.FuncLit:
+			params = f.Params
+		case *ast.ArrowFunc:
+			params = f.Params
+		}
+		for _, param := range params {
+			symbol := p.Table.Defs[param.Name]
+			if symbol == nil || isIgnored(param.Name.Name) || reads(symbol, writes) > 0 {
+				continue
+			}
+			var fix *Fix
+			if len(symbol.Uses) == 0 {
+				fix = &Fix{
+					Msg:   fmt.Sprintf("Rename it '_%s'", param.Name.Name),
+					Edits: []*Edit{replaceNode(param.Name, "_"+param.Name.Name)},
+				}
+			}
+			p.Report(param.Name, fmt.Sprintf("Parameter '%s' is never used", param.Name.Name), fix)
+		}
+		return true
+	})
+}
+
+// runUnusedImport reports the unused imports of a file. The unused items of a selective
+// import are reported one by one, unless none of them is used.
+func runUnusedImport(p *Pass) {
+	for _, stmt := range p.AST.Statements {
+		decl, isImport := stmt.(*ast.ImportDecl)
+		if !isImport {
+			continue
+		}
+		removeImport := &Fix{Msg: "Remove the import", Edits: []*Edit{deleteNode(p.Source, decl)}}
+		if decl.Items == nil {
+			if symbol := p.Table.Defs[decl.Binding()]; symbol != nil && len(symbol.Uses) == 0 {
+				p.Report(decl, fmt.Sprintf("Module '%s' is imported but never used", decl.PathString()), removeImport)
+			}
+			continue
+		}
+
+		var unused []int
+		for i, item := range decl.Items {
+			if symbol := p.Table.Defs[item.Binding()]; symbol != nil && len(symbol.Uses) == 0 {
+				unused = append(unused, i)
+			}
+		}
+		if len(unused) > 0 && len(unused) == len(decl.Items) {
+			p.Report(decl, fmt.Sprintf("None of the declarations imported from module '%s' is used", decl.PathString()), removeImport)
+			continue
+		}
+		for _, i := range unused {
+			item := decl.Items[i]
+			// The item is removed with the comma separating it from the next item, or from the previous one for the last item
+			edit := &Edit{Pos: item.Start, End: item.End}
+			if i+1 < len(decl.Items) {
+				edit.End = decl.Items[i+1].Start
+			} else {
+				edit.Pos = decl.Items[i-1].End
+			}
+			p.Report(item, fmt.Sprintf("'%s' is imported from module '%s' but never used", item.Binding().Name, decl.PathString()), &Fix{
+				Msg:   "Remove it from the import",
+				Edits: []*Edit{edit},
+			})
+		}
+	}
+}
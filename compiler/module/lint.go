@@ -0,0 +1,38 @@
+package module
+
+import (
+	"mirth/compiler/driver"
+	"mirth/compiler/lint"
+)
+
+// LintedFile is a file of the program with the findings of the lint rules.
+type LintedFile struct {
+	File     *driver.SourceFile
+	Findings []*lint.Finding
+}
+
+// Lint runs the rules of the registry enabled by config on the files of all the modules, in
+// module and file order. The files of the modules which weren't resolved, because the loading
+// stopped, are left out.
+func (p *Program) Lint(registry *lint.Registry, config *lint.Config) []*LintedFile {
+	var linted []*LintedFile
+	for _, module := range p.Order {
+		if module.Symbols == nil {
+			continue
+		}
+		for _, file := range module.Files {
+			if file.AST == nil {
+				continue
+			}
+			findings := registry.Run(&lint.File{
+				AST:    file.AST,
+				Source: file.Source,
+				Tokens: file.Tokens,
+				Table:  module.Symbols,
+				Types:  p.Types,
+			}, config)
+			linted = append(linted, &LintedFile{File: file, Findings: findings})
+		}
+	}
+	return linted
+}
@@ -0,0 +1,285 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/driver"
+	"mirth/compiler/escape"
+	"mirth/compiler/flow"
+	"mirth/compiler/resolve"
+	"mirth/compiler/types"
+	"sort"
+	"strings"
+)
+
+// Module is a unit of code, made of one source file or of all the source files of a directory.
+type Module struct {
+	Path    string // Like `geometry.shapes`
+	Files   []*driver.SourceFile
+	Imports []*Import
+	// Symbols is the result of the name resolution of the module.
+	Symbols *resolve.Table
+}
+
+// Import is an import declaration at the top level of a file of the module.
+type Import struct {
+	Decl *ast.ImportDecl
+	File *driver.SourceFile
+	// Module is the imported module, it's nil if it's not found.
+	Module *Module
+}
+
+// Declaration returns the top-level declaration of the module with the name, or nil.
+func (m *Module) Declaration(name string) ast.Decl {
+	for _, file := range m.Files {
+		if file.AST == nil {
+			continue
+		}
+		for _, stmt := range file.AST.Statements {
+			if decl, isDecl := stmt.(ast.Decl); isDecl && decl.DeclName().Name == name {
+				return decl
+			}
+		}
+	}
+	return nil
+}
+
+// Program is the entry module with all the modules it imports, directly or not.
+type Program struct {
+	Entry   *Module
+	Modules map[string]*Module
+	// Order lists the modules with the imported modules before the importing ones,
+	// the modules of an import cycle are listed in the order they are met.
+	Order []*Module
+	// Diagnostics of all the files, merged in module and file order, sorted by position in each file.
+	Diagnostics []*driver.FileDiagnostic
+	// Types holds the types of the checked expressions and declarations of all the modules.
+	Types *types.Info
+	// Escapes holds the captures of the closures and the escape decisions of all the modules.
+	Escapes *escape.Info
+	// Fatal is the error which stopped the loading.
+	Fatal error
+}
+
+// Load loads the entry module with the path like `app.main`, then the modules it
+// imports, level by level, each level is processed by the driver at once. The
+// imports are checked once all the modules are loaded: missing modules, missing
+// or private declarations of selective imports, and import cycles are reported.
+// Then the names of the modules are resolved, their types are checked and their
+// closures and allocations are analyzed, the imported modules first.
+func Load(ctx context.Context, resolver *Resolver, entry string, options *driver.Options) *Program {
+	program := &Program{Modules: map[string]*Module{}, Types: types.CreateInfo(), Escapes: escape.CreateInfo()}
+	entryFiles := resolver.Locate(SplitPath(entry))
+	if !entryFiles.Ok {
+		program.Fatal = entryFiles.Err
+		return program
+	}
+
+	var loadOrder []*Module
+	level := []*Module{{Path: entry}}
+	levelFiles := [][]string{entryFiles.Unwrap()}
+	program.Entry = level[0]
+	program.Modules[entry] = level[0]
+	for len(level) > 0 {
+		var paths []string
+		for _, files := range levelFiles {
+			paths = append(paths, files...)
+		}
+		report := driver.Run(ctx, paths, options)
+		loadOrder = append(loadOrder, level...)
+		fileIndex := 0
+		for i, module := range level {
+			module.Files = report.Files[fileIndex : fileIndex+len(levelFiles[i])]
+			fileIndex += len(levelFiles[i])
+		}
+		if report.Fatal != nil {
+			program.Fatal = report.Fatal
+			break
+		}
+
+		var nextLevel []*Module
+		var nextLevelFiles [][]string
+		for _, module := range level {
+			for _, file := range module.Files {
+				for _, stmt := range file.AST.Statements {
+					decl, isImport := stmt.(*ast.ImportDecl)
+					if !isImport {
+						continue
+					}
+					imported := &Import{Decl: decl, File: file}
+					module.Imports = append(module.Imports, imported)
+
+					path := decl.PathString()
+					if importedModule, isLoaded := program.Modules[path]; isLoaded {
+						imported.Module = importedModule
+						continue
+					}
+					filesResult := resolver.Locate(SplitPath(path))
+					if !filesResult.Ok {
+						file.Diagnostics = append(file.Diagnostics, errorAt(decl, compiler.ModuleNotFound,
+							fmt.Sprintf("Can't import module '%s': %s", path, filesResult.Err),
+						))
+						continue
+					}
+					importedModule := &Module{Path: path}
+					program.Modules[path] = importedModule
+					imported.Module = importedModule
+					nextLevel = append(nextLevel, importedModule)
+					nextLevelFiles = append(nextLevelFiles, filesResult.Unwrap())
+				}
+			}
+		}
+		level, levelFiles = nextLevel, nextLevelFiles
+	}
+
+	if program.Fatal == nil {
+		for _, module := range loadOrder {
+			checkImportedItems(module)
+		}
+		program.Order = sortModules(loadOrder)
+		for _, module := range program.Order {
+			resolveModule(module)
+			checkModule(module, program.Types)
+			analyzeModule(module, program.Types, program.Escapes)
+		}
+	}
+	for _, module := range loadOrder {
+		for _, file := range module.Files {
+			// The diagnostics of the later phases are appended after the ones of the file
+			sort.SliceStable(file.Diagnostics, func(i, j int) bool {
+				return file.Diagnostics[i].Pos.Offset < file.Diagnostics[j].Pos.Offset
+			})
+			for _, diagnostic := range file.Diagnostics {
+				program.Diagnostics = append(program.Diagnostics, &driver.FileDiagnostic{Path: file.Path, Diagnostic: diagnostic})
+			}
+		}
+	}
+	return program
+}
+
+// checkImportedItems reports the items of the selective imports of a module
+// which are not declared by the imported module, or are not public.
+func checkImportedItems(module *Module) {
+	for _, imported := range module.Imports {
+		if imported.Module == nil {
+			continue
+		}
+		for _, item := range imported.Decl.Items {
+			decl := imported.Module.Declaration(item.Name.Name)
+			switch {
+			case decl == nil:
+				imported.File.Diagnostics = append(imported.File.Diagnostics, errorAt(item.Name, compiler.UnresolvedImport, fmt.Sprintf(
+					"Module '%s' has no declaration named '%s'", imported.Module.Path, item.Name.Name,
+				)))
+			case !decl.IsPublic():
+				imported.File.Diagnostics = append(imported.File.Diagnostics, errorAt(item.Name, compiler.UnresolvedImport, fmt.Sprintf(
+					"'%s' is not public in module '%s', declare it with 'pub'", item.Name.Name, imported.Module.Path,
+				)))
+			}
+		}
+	}
+}
+
+// resolveModule resolves the names of a module, the modules it imports should be resolved
+// beforehand. The modules of an import cycle may not be, their declarations are then unknown.
+func resolveModule(module *Module) {
+	importedModules := map[*ast.ImportDecl]*Module{}
+	for _, imported := range module.Imports {
+		importedModules[imported.Decl] = imported.Module
+	}
+	importer := func(decl *ast.ImportDecl) *resolve.Scope {
+		if imported := importedModules[decl]; imported != nil && imported.Symbols != nil {
+			return imported.Symbols.Module
+		}
+		return nil
+	}
+
+	files := make([]*ast.File, len(module.Files))
+	for i, file := range module.Files {
+		files[i] = file.AST
+	}
+	var diagnostics [][]*compiler.Diagnostic
+	module.Symbols, diagnostics = resolve.ResolveModule(files, importer)
+	for i, file := range module.Files {
+		file.Diagnostics = append(file.Diagnostics, diagnostics[i]...)
+	}
+}
+
+// checkModule checks the types, the control flow and the assignments of a module, the
+// modules it imports should be checked beforehand with the same info.
+func checkModule(module *Module, info *types.Info) {
+	files := make([]*ast.File, len(module.Files))
+	for i, file := range module.Files {
+		files[i] = file.AST
+	}
+	diagnostics := types.Check(files, module.Symbols, info)
+	for i, file := range module.Files {
+		file.Diagnostics = append(file.Diagnostics, diagnostics[i]...)
+		file.Diagnostics = append(file.Diagnostics, flow.Check(file.AST)...)
+		file.Diagnostics = append(file.Diagnostics, flow.CheckAssignments(file.AST, module.Symbols)...)
+	}
+}
+
+// analyzeModule analyzes the captures and the escapes of a checked module, the modules it
+// imports should be analyzed beforehand with the same info.
+func analyzeModule(module *Module, typed *types.Info, info *escape.Info) {
+	files := make([]*ast.File, len(module.Files))
+	for i, file := range module.Files {
+		files[i] = file.AST
+	}
+	escape.Analyze(files, module.Symbols, typed, info)
+}
+
+// sortModules sorts the modules so that the imported modules come first, with a depth-first
+// search which reports every import closing a cycle, along with the full cycle path.
+func sortModules(modules []*Module) []*Module {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := map[*Module]int{}
+	var order, stack []*Module
+	var visit func(module *Module)
+	visit = func(module *Module) {
+		states[module] = visiting
+		stack = append(stack, module)
+		for _, imported := range module.Imports {
+			switch {
+			case imported.Module == nil:
+			case states[imported.Module] == unvisited:
+				visit(imported.Module)
+			case states[imported.Module] == visiting:
+				var cycle []string
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == imported.Module {
+						for _, cycleModule := range stack[i:] {
+							cycle = append(cycle, cycleModule.Path)
+						}
+						break
+					}
+				}
+				cycle = append(cycle, imported.Module.Path)
+				imported.File.Diagnostics = append(imported.File.Diagnostics, errorAt(imported.Decl, compiler.ImportCycle,
+					"Import cycle: "+strings.Join(cycle, " -> "),
+				))
+			}
+		}
+		stack = stack[:len(stack)-1]
+		states[module] = visited
+		order = append(order, module)
+	}
+	for _, module := range modules {
+		if states[module] == unvisited {
+			visit(module)
+		}
+	}
+	return order
+}
+
+func errorAt(node ast.Node, code compiler.DiagnosticCode, msg string) *compiler.Diagnostic {
+	span := node.NodeSpan()
+	return &compiler.Diagnostic{Type: compiler.DiagnosticError, Code: code, Pos: span.Start, End: span.End, Msg: msg}
+}
@@ -0,0 +1,245 @@
+package module
+
+import (
+	"context"
+	"mirth/compiler"
+	"mirth/compiler/lint"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// writeProject writes the source files by their path relative to a new project root.
+func writeProject(t *testing.T, sources map[string]string) string {
+	root := t.TempDir()
+	for path, source := range sources {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func modulePaths(modules []*Module) []string {
+	var paths []string
+	for _, module := range modules {
+		paths = append(paths, module.Path)
+	}
+	return paths
+}
+
+func TestResolver(t *testing.T) {
+	Convey("Test locate file and directory modules", t, func() {
+		root := writeProject(t, map[string]string{
+			"main.mirth":                  "",
+			"geometry/shapes.mirth":       "",
+			"geometry/vector/add.mirth":   "",
+			"geometry/vector/scale.mirth": "",
+			"geometry/vector/README.md":   "",
+			"both.mirth":                  "",
+			"both/a.mirth":                "",
+		})
+		resolver := CreateResolver(root)
+
+		So(resolver.Locate([]string{"geometry", "shapes"}).Unwrap(), ShouldResemble,
+			[]string{filepath.Join(root, "geometry", "shapes.mirth")})
+		So(resolver.Locate(SplitPath("geometry.vector")).Unwrap(), ShouldResemble, []string{
+			filepath.Join(root, "geometry", "vector", "add.mirth"),
+			filepath.Join(root, "geometry", "vector", "scale.mirth"),
+		})
+
+		missing := resolver.Locate(SplitPath("geometry.lines"))
+		So(missing.Ok, ShouldBeFalse)
+		So(missing.Err.Error(), ShouldEqual,
+			"module 'geometry.lines' is not found, there's no file 'geometry/lines.mirth' nor source files in the directory 'geometry/lines'")
+
+		ambiguous := resolver.Locate(SplitPath("both"))
+		So(ambiguous.Err.Error(), ShouldEqual, "module 'both' is both the file 'both.mirth' and the directory 'both'")
+	})
+}
+
+func TestLoad(t *testing.T) {
+	Convey("Test load the imported modules", t, func() {
+		root := writeProject(t, map[string]string{
+			"main.mirth":                "import geometry.shapes.{Circle, area as circleArea}\nimport geometry.vector as vec\nlet a = circleArea\nlet b = vec.add\n",
+			"geometry/shapes.mirth":     "import geometry.vector\npub struct Circle { radius: f64 }\npub func area(c: Circle) f64 { return c.radius }\n",
+			"geometry/vector/add.mirth": "pub func add() {}\n",
+			"geometry/vector/sub.mirth": "func sub() {}\n",
+		})
+		program := Load(context.Background(), CreateResolver(root), "main", nil)
+
+		So(program.Fatal, ShouldBeNil)
+		So(program.Diagnostics, ShouldBeEmpty)
+		So(program.Entry.Path, ShouldEqual, "main")
+		So(len(program.Modules), ShouldEqual, 3)
+		So(modulePaths(program.Order), ShouldResemble, []string{"geometry.vector", "geometry.shapes", "main"})
+
+		vector := program.Modules["geometry.vector"]
+		So(len(vector.Files), ShouldEqual, 2)
+		So(vector.Declaration("sub").IsPublic(), ShouldBeFalse)
+		So(vector.Declaration("mul"), ShouldBeNil)
+		So(program.Entry.Imports[1].Module, ShouldEqual, vector)
+		So(program.Entry.Imports[1].Decl.Binding().Name, ShouldEqual, "vec")
+
+		area := program.Modules["geometry.shapes"].Symbols.Module.LookupLocal("area")
+		So(len(area.Uses), ShouldEqual, 1)
+		circleArea := program.Entry.Symbols.Scopes[program.Entry.Files[0].AST].LookupLocal("circleArea")
+		So(circleArea.Target, ShouldEqual, area)
+		So(len(vector.Symbols.Module.LookupLocal("add").Uses), ShouldEqual, 1)
+	})
+
+	Convey("Test report unresolved imports", t, func() {
+		root := writeProject(t, map[string]string{
+			"main.mirth":   "import lib.{helper, secret, missing}\nimport nowhere\n",
+			"lib/a.mirth":  "pub func helper() {}\n",
+			"lib/b.mirth":  "func secret() {}\n",
+			"unused.mirth": "import nowhere\n",
+		})
+		program := Load(context.Background(), CreateResolver(root), "main", nil)
+
+		So(program.Fatal, ShouldBeNil)
+		So(len(program.Modules), ShouldEqual, 2)
+		var codes []compiler.DiagnosticCode
+		var messages []string
+		for _, diagnostic := range program.Diagnostics {
+			So(diagnostic.Path, ShouldEqual, filepath.Join(root, "main.mirth"))
+			codes = append(codes, diagnostic.Code)
+			messages = append(messages, diagnostic.Msg)
+		}
+		So(codes, ShouldResemble, []compiler.DiagnosticCode{compiler.UnresolvedImport, compiler.UnresolvedImport, compiler.ModuleNotFound})
+		So(messages, ShouldResemble, []string{
+			"'secret' is not public in module 'lib', declare it with 'pub'",
+			"Module 'lib' has no declaration named 'missing'",
+			"Can't import module 'nowhere': module 'nowhere' is not found, there's no file 'nowhere.mirth' nor source files in the directory 'nowhere'",
+		})
+		So(program.Diagnostics[2].Pos.Line, ShouldEqual, 2)
+	})
+
+	Convey("Test report import cycles with the full path", t, func() {
+		root := writeProject(t, map[string]string{
+			"main.mirth": "import a\n",
+			"a.mirth":    "import b\n",
+			"b.mirth":    "import c\n",
+			"c.mirth":    "import a\nimport c\n",
+		})
+		program := Load(context.Background(), CreateResolver(root), "main", nil)
+
+		So(program.Fatal, ShouldBeNil)
+		So(len(program.Diagnostics), ShouldEqual, 2)
+		for _, diagnostic := range program.Diagnostics {
+			So(diagnostic.Path, ShouldEqual, filepath.Join(root, "c.mirth"))
+			So(diagnostic.Code, ShouldEqual, compiler.ImportCycle)
+		}
+		So(program.Diagnostics[0].Msg, ShouldEqual, "Import cycle: a -> b -> c -> a")
+		So(program.Diagnostics[1].Msg, ShouldEqual, "Import cycle: c -> c")
+		So(modulePaths(program.Order), ShouldResemble, []string{"c", "b", "a", "main"})
+	})
+
+	Convey("Test check the types across modules", t, func() {
+		root := writeProject(t, map[string]string{
+			"main.mirth":   "import shapes.{Circle, area}\nimport shapes as s\nlet c = Circle { radius: 2.0 }\nlet a = area(c)\nlet b: string = s.area(c)\n",
+			"shapes.mirth": "pub struct Circle { radius: f64 }\npub func area(c: Circle) f64 { return c.radius * c.radius }\n",
+		})
+		program := Load(context.Background(), CreateResolver(root), "main", nil)
+
+		So(program.Fatal, ShouldBeNil)
+		So(len(program.Diagnostics), ShouldEqual, 1)
+		So(program.Diagnostics[0].Path, ShouldEqual, filepath.Join(root, "main.mirth"))
+		So(program.Diagnostics[0].Msg, ShouldEqual, "Type mismatch: expected 'string', found 'f64'")
+		scope := program.Entry.Symbols.Module
+		So(program.Types.Symbols[scope.LookupLocal("c")].String(), ShouldEqual, "Circle")
+		So(program.Types.Symbols[scope.LookupLocal("a")].String(), ShouldEqual, "f64")
+	})
+
+	Convey("Test report the control flow diagnostics once, sorted by position", t, func() {
+		root := writeProject(t, map[string]string{
+			"main.mirth": "func sign(x: int) int {\n  if x < 0 { return -1 }\n}\nfunc f() {\n  return\n  let y = 1\n}\nlet s: string = 1\n",
+		})
+		program := Load(context.Background(), CreateResolver(root), "main", nil)
+
+		So(program.Fatal, ShouldBeNil)
+		var messages []string
+		for _, diagnostic := range program.Diagnostics {
+			messages = append(messages, diagnostic.Msg)
+		}
+		So(messages, ShouldResemble, []string{
+			"Missing return at the end of function 'sign'",
+			"Unreachable code",
+			"Type mismatch: expected 'string', found 'int'",
+		})
+	})
+
+	Convey("Test check the matches on the imported enums", t, func() {
+		root := writeProject(t, map[string]string{
+			"main.mirth":      "import lib.{Kind, Color as C}\nfunc f(k: Kind, c: C) int {\n  let n = match k {\n    Kind.A => 1\n    Kind.B => 2\n  }\n  return n + match c {\n    C.Red => 1\n  }\n}\n",
+			"lib/kind.mirth":  "pub enum Kind { A, B }\n",
+			"lib/color.mirth": "pub enum Color { Red, Green }\n",
+		})
+		program := Load(context.Background(), CreateResolver(root), "main", nil)
+
+		So(program.Fatal, ShouldBeNil)
+		var messages []string
+		for _, diagnostic := range program.Diagnostics {
+			messages = append(messages, diagnostic.Msg)
+		}
+		So(messages, ShouldResemble, []string{
+			"Non-exhaustive match, pattern 'Color.Green' is not covered by any arm, add an arm for it or a '_' arm",
+		})
+		So(program.Diagnostics[0].Pos.Line, ShouldEqual, 7)
+	})
+
+	Convey("Test fail without the entry module", t, func() {
+		program := Load(context.Background(), CreateResolver(t.TempDir()), "main", nil)
+		So(program.Fatal, ShouldNotBeNil)
+		So(program.Entry, ShouldBeNil)
+	})
+}
+
+func TestEscapes(t *testing.T) {
+	Convey("Test analyze the escapes with the summaries of the imported modules", t, func() {
+		root := writeProject(t, map[string]string{
+			"main.mirth":   "import shapes.{Circle, area, keep}\nfunc main() {\n  let a = area(Circle { radius: 1.0 })\n  keep(Circle { radius: 2.0 })\n}\n",
+			"shapes.mirth": "pub struct Circle { radius: f64 }\nlet kept: Circle? = nil\npub func area(c: Circle) f64 { return c.radius * c.radius }\npub func keep(c: Circle) { kept = c }\n",
+		})
+		program := Load(context.Background(), CreateResolver(root), "main", nil)
+		So(program.Diagnostics, ShouldBeEmpty)
+
+		var decisions []string
+		for _, decision := range program.Escapes.Decisions(program.Entry.Files[0].AST) {
+			decisions = append(decisions, decision.String())
+		}
+		So(decisions, ShouldResemble, []string{
+			"3:16: Struct literal doesn't escape",
+			"4:8: Struct literal escapes to the heap",
+		})
+	})
+}
+
+func TestLint(t *testing.T) {
+	Convey("Test lint the files of all the modules", t, func() {
+		root := writeProject(t, map[string]string{
+			"main.mirth":   "import shapes.{area, Circle}\nfunc main() {\n  let unused = 1\n  let a = area(2.0)\n  a = a\n}\n",
+			"shapes.mirth": "pub func area(r: f64) f64 { return r * r }\npub struct Circle { radius: f64 }\nfunc same(x: f64) bool { return x == x }\n",
+		})
+		program := Load(context.Background(), CreateResolver(root), "main", nil)
+		So(program.Diagnostics, ShouldBeEmpty)
+
+		registry := lint.Builtin()
+		linted := program.Lint(registry, lint.LoadConfig(root, registry).Unwrap())
+		So(len(linted), ShouldEqual, 2)
+		So(linted[0].File.Path, ShouldEqual, filepath.Join(root, "shapes.mirth"))
+		So(len(linted[0].Findings), ShouldEqual, 1)
+		So(linted[0].Findings[0].Rule, ShouldEqual, "float-equality")
+		var rules []string
+		for _, finding := range linted[1].Findings {
+			rules = append(rules, finding.Rule)
+		}
+		So(rules, ShouldResemble, []string{"unused-import", "unused-variable", "self-assignment"})
+	})
+}
@@ -0,0 +1,85 @@
+package module
+
+import (
+	"fmt"
+	"mirth/shared"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileExtension is the extension of the Mirth source files.
+const FileExtension = ".mirth"
+
+// Resolver finds the modules on disk relative to the project root. The module
+// `geometry.shapes` is either the file `geometry/shapes.mirth`, or all the
+// source files directly in the directory `geometry/shapes`.
+type Resolver struct {
+	Root string
+}
+
+func CreateResolver(root string) *Resolver {
+	return &Resolver{Root: root}
+}
+
+// SplitPath splits a module path like `geometry.shapes` into its names.
+func SplitPath(path string) []string {
+	return strings.Split(path, ".")
+}
+
+// Locate returns the source files of the module with the path, sorted by name.
+func (r *Resolver) Locate(path []string) *shared.Result[[]string, error] {
+	modulePath := strings.Join(path, ".")
+	filePath := filepath.Join(r.Root, filepath.Join(path...)) + FileExtension
+	dirPath := filepath.Join(r.Root, filepath.Join(path...))
+
+	fileInfo, fileErr := os.Stat(filePath)
+	isFile := fileErr == nil && fileInfo.Mode().IsRegular()
+	dirFiles, dirErr := sourceFilesIn(dirPath)
+	if dirErr != nil {
+		return shared.ResultErr[[]string](dirErr)
+	}
+
+	switch {
+	case isFile && len(dirFiles) > 0:
+		return shared.ResultErr[[]string](fmt.Errorf(
+			"module '%s' is both the file '%s' and the directory '%s'", modulePath, r.relative(filePath), r.relative(dirPath),
+		))
+	case isFile:
+		return shared.ResultOk[[]string, error]([]string{filePath})
+	case len(dirFiles) > 0:
+		return shared.ResultOk[[]string, error](dirFiles)
+	}
+	return shared.ResultErr[[]string](fmt.Errorf(
+		"module '%s' is not found, there's no file '%s' nor source files in the directory '%s'",
+		modulePath, r.relative(filePath), r.relative(dirPath),
+	))
+}
+
+// sourceFilesIn returns the source files directly in a directory,
+// there are none if the directory doesn't exist.
+func sourceFilesIn(dirPath string) ([]string, error) {
+	if dirInfo, err := os.Stat(dirPath); err != nil || !dirInfo.IsDir() {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) == FileExtension {
+			files = append(files, filepath.Join(dirPath, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (r *Resolver) relative(path string) string {
+	if relativePath, err := filepath.Rel(r.Root, path); err == nil {
+		return relativePath
+	}
+	return path
+}
@@ -0,0 +1,228 @@
+package compiler
+
+import (
+	"fmt"
+	"mirth/shared"
+	"strings"
+)
+
+// Precedence is the binding power of a binary operator.
+// Levels are ordered from the loosest to the tightest binding.
+type Precedence int
+
+const (
+	PrecedenceNone           Precedence = iota
+	PrecedenceAssignment                // = += -= *= **= /= %= <<= >>= &= |= ^=
+	PrecedenceNullish                   // ??
+	PrecedenceRange                     // .. ..=
+	PrecedenceLogicalOr                 // ||
+	PrecedenceLogicalAnd                // &&
+	PrecedenceEquality                  // == !=
+	PrecedenceComparison                // < > <= >=
+	PrecedenceBitwiseOr                 // |
+	PrecedenceBitwiseXor                // ^
+	PrecedenceBitwiseAnd                // &
+	PrecedenceShift                     // << >>
+	PrecedenceAdditive                  // + -
+	PrecedenceMultiplicative            // * / %
+	PrecedenceExponent                  // **
+	PrecedencePrefix                    // ! ~ - ++ --
+	PrecedencePostfix                   // ++ -- () [] . ?.
+)
+
+type Associativity int
+
+const (
+	AssociativityLeft Associativity = iota
+	AssociativityRight
+	AssociativityNone
+)
+
+// OperatorTokenMap is the declarative table of every punctuation and operator
+// symbol known to the scanner. The scanner always picks the longest symbol
+// matching the source, so `<<=` wins over `<<` and `<`.
+var OperatorTokenMap = map[string]TokenType{
+	";":   TokenTypeSemi,
+	",":   TokenTypeComma,
+	":":   TokenTypeColon,
+	"(":   TokenTypeLeftParen,
+	")":   TokenTypeRightParen,
+	"{":   TokenTypeLeftCurly,
+	"}":   TokenTypeRightCurly,
+	"[":   TokenTypeLeftBracket,
+	"]":   TokenTypeRightBracket,
+	".":   TokenTypeDot,
+	"..":  TokenTypeDoubleDots,
+	"..=": TokenTypeDoubleDotsEqual,
+	"...": TokenTypeEllipsis,
+	"=":   TokenTypeEqual,
+	"==":  TokenTypeDoubleEqual,
+	"!=":  TokenTypeBangEqual,
+	"=>":  TokenTypeArrow,
+	"+":   TokenTypePlus,
+	"-":   TokenTypeMinus,
+	"*":   TokenTypeStar,
+	"**":  TokenTypeDoubleStar,
+	"/":   TokenTypeSlash,
+	"%":   TokenTypePercent,
+	"@":   TokenTypeAlpha,
+	"~":   TokenTypeWavy,
+	"^":   TokenTypeCaret,
+	"&":   TokenTypeAmpersand,
+	"!":   TokenTypeBang,
+	"|":   TokenTypeVertical,
+	"<":   TokenTypeLeftAngle,
+	">":   TokenTypeRightAngle,
+	"<<":  TokenTypeDoubleLeftAngle,
+	">>":  TokenTypeDoubleRightAngle,
+	"&&":  TokenTypeDoubleAmpersand,
+	"||":  TokenTypeDoubleVertical,
+	"<=":  TokenTypeLeftAngleEqual,
+	">=":  TokenTypeRightAngleEqual,
+	"++":  TokenTypeDoublePlus,
+	"--":  TokenTypeDoubleMinus,
+	"+=":  TokenTypePlusEqual,
+	"-=":  TokenTypeMinusEqual,
+	"*=":  TokenTypeStarEqual,
+	"**=": TokenTypeDoubleStarEqual,
+	"/=":  TokenTypeSlashEqual,
+	"%=":  TokenTypePercentEqual,
+	"<<=": TokenTypeDoubleLeftAngleEqual,
+	">>=": TokenTypeDoubleRightAngleEqual,
+	"&=":  TokenTypeAmpersandEqual,
+	"|=":  TokenTypeVerticalEqual,
+	"^=":  TokenTypeCaretEqual,
+	"?":   TokenTypeQuestion,
+	"?.":  TokenTypeQuestionDot,
+	"??":  TokenTypeDoubleQuestion,
+}
+
+// Runes which a custom operator symbol may be composed of.
+// All of them terminate identifiers, so `a|>b` is scanned as three tokens.
+const customOperatorRunes = "!%&*+-./:<=>?@^|~"
+
+// OperatorDefinition describes a symbol recognized by the scanner.
+// Precedence and Associativity are only meaningful for custom operators,
+// the parser keeps its own table for the builtin ones.
+type OperatorDefinition struct {
+	Symbol        string
+	Type          TokenType
+	Precedence    Precedence
+	Associativity Associativity
+}
+
+type operatorTrieNode struct {
+	children map[byte]*operatorTrieNode
+	operator *OperatorDefinition // Non-nil if a symbol ends at this node
+}
+
+func createOperatorTrieNode() *operatorTrieNode {
+	return &operatorTrieNode{children: map[byte]*operatorTrieNode{}}
+}
+
+func (n *operatorTrieNode) insert(operator *OperatorDefinition) {
+	node := n
+	for i := 0; i < len(operator.Symbol); i++ {
+		child, exists := node.children[operator.Symbol[i]]
+		if !exists {
+			child = createOperatorTrieNode()
+			node.children[operator.Symbol[i]] = child
+		}
+		node = child
+	}
+	node.operator = operator
+}
+
+// longestMatch walks the trie along the source and returns
+// the longest operator which the source starts with.
+func (n *operatorTrieNode) longestMatch(source []byte) *OperatorDefinition {
+	var matched *OperatorDefinition
+	node := n
+	for i := 0; i < len(source); i++ {
+		child, exists := node.children[source[i]]
+		if !exists {
+			break
+		}
+		node = child
+		if node.operator != nil {
+			matched = node.operator
+		}
+	}
+	return matched
+}
+
+// OperatorTable holds the builtin operators and the custom operators
+// declared by libraries. A scanner uses it to recognize operator tokens,
+// and the parser uses it to look up precedence of custom operators.
+type OperatorTable struct {
+	operators map[string]*OperatorDefinition
+	trie      *operatorTrieNode
+}
+
+var defaultOperatorTable = CreateOperatorTable()
+
+func CreateOperatorTable() *OperatorTable {
+	table := &OperatorTable{
+		operators: map[string]*OperatorDefinition{},
+		trie:      createOperatorTrieNode(),
+	}
+	for symbol, tokenType := range OperatorTokenMap {
+		table.add(&OperatorDefinition{Symbol: symbol, Type: tokenType})
+	}
+	return table
+}
+
+func (t *OperatorTable) add(operator *OperatorDefinition) {
+	t.operators[operator.Symbol] = operator
+	t.trie.insert(operator)
+}
+
+// Declare registers a custom binary operator.
+// Tokens scanned for it have the type TokenTypeCustomOperator and the symbol as content.
+func (t *OperatorTable) Declare(symbol string, precedence Precedence, associativity Associativity) *shared.Result[*OperatorDefinition, error] {
+	if len(symbol) == 0 {
+		return shared.ResultErr[*OperatorDefinition](
+			fmt.Errorf("custom operator symbol should not be empty"),
+		)
+	}
+	for _, r := range symbol {
+		if !strings.ContainsRune(customOperatorRunes, r) {
+			return shared.ResultErr[*OperatorDefinition](
+				fmt.Errorf("invalid rune '%c' in custom operator '%s', only '%s' are allowed", r, symbol, customOperatorRunes),
+			)
+		}
+	}
+	if strings.HasPrefix(symbol, "//") {
+		return shared.ResultErr[*OperatorDefinition](
+			fmt.Errorf("custom operator '%s' should not start with a line comment", symbol),
+		)
+	}
+	if _, exists := t.operators[symbol]; exists {
+		return shared.ResultErr[*OperatorDefinition](
+			fmt.Errorf("operator '%s' is already declared", symbol),
+		)
+	}
+	if precedence <= PrecedenceNone || precedence >= PrecedencePrefix {
+		return shared.ResultErr[*OperatorDefinition](
+			fmt.Errorf("invalid precedence %d for binary operator '%s'", precedence, symbol),
+		)
+	}
+
+	operator := &OperatorDefinition{
+		Symbol:        symbol,
+		Type:          TokenTypeCustomOperator,
+		Precedence:    precedence,
+		Associativity: associativity,
+	}
+	t.add(operator)
+	return shared.ResultOk[*OperatorDefinition, error](operator)
+}
+
+func (t *OperatorTable) longestMatch(source []byte) *OperatorDefinition {
+	return t.trie.longestMatch(source)
+}
+
+func (t *OperatorTable) Lookup(symbol string) (*OperatorDefinition, bool) {
+	operator, ok := t.operators[symbol]
+	return operator, ok
+}
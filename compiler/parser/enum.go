@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/shared"
+)
+
+// parseEnumDecl parses an enum declaration, the type parameters are optional:
+//
+//	enum Name<T> {
+//	  Unit
+//	  Tuple(T, int)
+//	  Struct { field: Type }
+//	  Discriminant = 1
+//	}
+func (p *Parser) parseEnumDecl() *StmtResult {
+	enumToken := p.advance()
+	nameResult := p.parseIdentifier("enum name")
+	if !nameResult.Ok {
+		return stmtErr(nameResult.Err)
+	}
+	typeParamsResult := p.parseTypeParams()
+	if !typeParamsResult.Ok {
+		return stmtErr(typeParamsResult.Err)
+	}
+	openResult := p.expect(compiler.TokenTypeLeftCurly, "'{' before enum variants")
+	if !openResult.Ok {
+		return stmtErr(openResult.Err)
+	}
+
+	enumDecl := &ast.EnumDecl{Name: nameResult.Unwrap(), TypeParams: typeParamsResult.Unwrap()}
+	closeResult := p.parseMemberList(func() *compiler.Diagnostic {
+		variantResult := p.parseVariant()
+		if !variantResult.Ok {
+			return variantResult.Err
+		}
+		enumDecl.Variants = append(enumDecl.Variants, variantResult.Unwrap())
+		return nil
+	})
+	if !closeResult.Ok {
+		return stmtErr(closeResult.Err)
+	}
+	enumDecl.Span = ast.Span{Start: enumToken.Pos, End: closeResult.Unwrap().End}
+	return stmtOk(enumDecl)
+}
+
+func (p *Parser) parseVariant() *shared.Result[*ast.Variant, *compiler.Diagnostic] {
+	nameResult := p.parseIdentifier("variant name")
+	if !nameResult.Ok {
+		return shared.ResultErr[*ast.Variant](nameResult.Err)
+	}
+	variant := &ast.Variant{Span: nameResult.Unwrap().Span, Name: nameResult.Unwrap()}
+
+	switch {
+	case p.check(compiler.TokenTypeLeftParen):
+		p.advance()
+		p.pushLineBreakSensitive(false)
+		variant.Tuple = []ast.TypeExpr{}
+		for !p.check(compiler.TokenTypeRightParen) {
+			typeResult := p.parseType()
+			if !typeResult.Ok {
+				p.popLineBreakSensitive()
+				return shared.ResultErr[*ast.Variant](typeResult.Err)
+			}
+			variant.Tuple = append(variant.Tuple, typeResult.Unwrap())
+			if _, hasComma := p.match(compiler.TokenTypeComma); !hasComma {
+				break
+			}
+		}
+		closeResult := p.expect(compiler.TokenTypeRightParen, "',' or ')' after payload type")
+		p.popLineBreakSensitive()
+		if !closeResult.Ok {
+			return shared.ResultErr[*ast.Variant](closeResult.Err)
+		}
+		variant.End = closeResult.Unwrap().End
+	case p.check(compiler.TokenTypeLeftCurly):
+		p.advance()
+		variant.Fields = []*ast.Field{}
+		closeResult := p.parseMemberList(func() *compiler.Diagnostic {
+			fieldResult := p.parseField()
+			if !fieldResult.Ok {
+				return fieldResult.Err
+			}
+			variant.Fields = append(variant.Fields, fieldResult.Unwrap())
+			return nil
+		})
+		if !closeResult.Ok {
+			return shared.ResultErr[*ast.Variant](closeResult.Err)
+		}
+		variant.End = closeResult.Unwrap().End
+	case p.check(compiler.TokenTypeEqual):
+		p.advance()
+		discriminantResult := p.ParseExpression()
+		if !discriminantResult.Ok {
+			return shared.ResultErr[*ast.Variant](discriminantResult.Err)
+		}
+		variant.Discriminant = discriminantResult.Unwrap()
+		variant.End = variant.Discriminant.NodeSpan().End
+	}
+	return shared.ResultOk[*ast.Variant, *compiler.Diagnostic](variant)
+}
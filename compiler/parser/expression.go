@@ -0,0 +1,464 @@
+package parser
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+)
+
+// ParseExpression parses a whole expression, including assignments.
+func (p *Parser) ParseExpression() *ExprResult {
+	return p.parseExpressionWithPrecedence(compiler.PrecedenceAssignment)
+}
+
+func (p *Parser) infixOperatorOf(token *compiler.Token) (infixOperator, bool) {
+	if token.Type == compiler.TokenTypeCustomOperator {
+		if custom, declared := p.operators.Lookup(token.Content); declared {
+			return infixOperator{custom.Precedence, custom.Associativity}, true
+		}
+		return infixOperator{}, false
+	}
+	operator, isInfix := infixOperators[token.Type]
+	return operator, isInfix
+}
+
+// parseExpressionWithPrecedence is the core of the Pratt parser. It parses
+// an operand, then keeps folding infix operators into the left hand side
+// as long as they bind at least as tight as minPrecedence.
+func (p *Parser) parseExpressionWithPrecedence(minPrecedence compiler.Precedence) *ExprResult {
+	leftResult := p.parsePrefix()
+	if !leftResult.Ok {
+		return leftResult
+	}
+	left := leftResult.Unwrap()
+
+	for {
+		operatorToken := p.peek()
+		operator, isInfix := p.infixOperatorOf(operatorToken)
+		if !isInfix || operator.precedence < minPrecedence {
+			break
+		}
+		p.advance()
+
+		if isRangeOperator(operatorToken.Type) {
+			var end ast.Expr
+			if !p.isRangeEndOmitted() {
+				endResult := p.parseExpressionWithPrecedence(compiler.PrecedenceRange + 1)
+				if !endResult.Ok {
+					return endResult
+				}
+				end = endResult.Unwrap()
+			}
+			rangeResult := p.createRangeExpr(left, operatorToken, end)
+			if !rangeResult.Ok {
+				return rangeResult
+			}
+			left = rangeResult.Unwrap()
+		} else {
+			// An operator at the end of a line continues the expression on the next line.
+			p.skipLineBreaks()
+
+			nextMinPrecedence := operator.precedence + 1
+			if operator.associativity == compiler.AssociativityRight {
+				nextMinPrecedence = operator.precedence
+			}
+			rightResult := p.parseExpressionWithPrecedence(nextMinPrecedence)
+			if !rightResult.Ok {
+				return rightResult
+			}
+			right := rightResult.Unwrap()
+
+			if isAssignmentOperator(operatorToken.Type) {
+				if !isAssignable(left) {
+					return exprErr(compiler.CreateErrorDiagnostic(
+						compiler.InvalidAssignmentTarget,
+						left.NodeSpan().Start,
+						fmt.Sprintf("Invalid assignment target on the left of '%s'", operatorToken.Content),
+					))
+				}
+				left = &ast.AssignExpr{
+					Span:     ast.SpanBetween(left, right),
+					Operator: operatorToken,
+					Target:   left,
+					Value:    right,
+				}
+				continue
+			}
+			left = &ast.BinaryExpr{
+				Span:     ast.SpanBetween(left, right),
+				Operator: operatorToken,
+				Left:     left,
+				Right:    right,
+			}
+		}
+
+		if operator.associativity == compiler.AssociativityNone {
+			if next, isNextInfix := p.infixOperatorOf(p.peek()); isNextInfix && next.precedence == operator.precedence {
+				return exprErr(p.createParseErr(
+					compiler.NonAssociativeOperator,
+					p.peek(),
+					fmt.Sprintf(
+						"Operator '%s' is non-associative and can't be chained with '%s', wrap one side in parentheses",
+						p.peek().Content,
+						operatorToken.Content,
+					),
+				))
+			}
+		}
+	}
+	return exprOk(left)
+}
+
+func isAssignable(expr ast.Expr) bool {
+	switch target := expr.(type) {
+	case *ast.Identifier, *ast.IndexExpr:
+		return true
+	case *ast.MemberExpr:
+		// `a?.b = c` has nothing to assign to if `a` is nil
+		return !target.Optional
+	case *ast.ParenExpr:
+		return isAssignable(target.Expr)
+	default:
+		return false
+	}
+}
+
+func (p *Parser) parsePrefix() *ExprResult {
+	operatorToken := p.peek()
+	switch {
+	case prefixOperators[operatorToken.Type]:
+		p.advance()
+		operandResult := p.parseExpressionWithPrecedence(compiler.PrecedenceExponent)
+		if !operandResult.Ok {
+			return operandResult
+		}
+		operand := operandResult.Unwrap()
+		return exprOk(&ast.UnaryExpr{
+			Span:     ast.Span{Start: operatorToken.Pos, End: operand.NodeSpan().End},
+			Operator: operatorToken,
+			Operand:  operand,
+		})
+	case operatorToken.Type == compiler.TokenTypeDoublePlus || operatorToken.Type == compiler.TokenTypeDoubleMinus:
+		p.advance()
+		operandResult := p.parsePrefix()
+		if !operandResult.Ok {
+			return operandResult
+		}
+		operand := operandResult.Unwrap()
+		if !isAssignable(operand) {
+			return exprErr(compiler.CreateErrorDiagnostic(
+				compiler.InvalidAssignmentTarget,
+				operand.NodeSpan().Start,
+				fmt.Sprintf("Invalid operand of '%s', expected a variable, an index or a member", operatorToken.Content),
+			))
+		}
+		return exprOk(&ast.UnaryExpr{
+			Span:     ast.Span{Start: operatorToken.Pos, End: operand.NodeSpan().End},
+			Operator: operatorToken,
+			Operand:  operand,
+		})
+	case isRangeOperator(operatorToken.Type):
+		p.advance()
+		var end ast.Expr
+		if !p.isRangeEndOmitted() {
+			endResult := p.parseExpressionWithPrecedence(compiler.PrecedenceRange + 1)
+			if !endResult.Ok {
+				return endResult
+			}
+			end = endResult.Unwrap()
+		}
+		return p.createRangeExpr(nil, operatorToken, end)
+	default:
+		return p.parsePostfix()
+	}
+}
+
+// isRangeEndOmitted tells whether the range operator just consumed
+// has no end bound, like `xs[2..]` or `for i in 0.. {`.
+func (p *Parser) isRangeEndOmitted() bool {
+	return p.check(
+		compiler.TokenTypeRightBracket,
+		compiler.TokenTypeRightParen,
+		compiler.TokenTypeRightCurly,
+		compiler.TokenTypeLeftCurly,
+		compiler.TokenTypeComma,
+		compiler.TokenTypeSemi,
+		compiler.TokenTypeLineBreak,
+		compiler.TokenTypeEndOfFile,
+	)
+}
+
+// createRangeExpr creates a range from the bounds around the operator, both bounds may be nil.
+func (p *Parser) createRangeExpr(start ast.Expr, operatorToken *compiler.Token, end ast.Expr) *ExprResult {
+	inclusive := operatorToken.Type == compiler.TokenTypeDoubleDotsEqual
+	if inclusive && end == nil {
+		return exprErr(p.createParseErr(
+			compiler.InvalidRange,
+			operatorToken,
+			"Inclusive range '..=' requires an end bound",
+		))
+	}
+	span := ast.SpanOfToken(operatorToken)
+	if start != nil {
+		span.Start = start.NodeSpan().Start
+	}
+	if end != nil {
+		span.End = end.NodeSpan().End
+	}
+	return exprOk(&ast.RangeExpr{
+		Span:      span,
+		Operator:  operatorToken,
+		Start:     start,
+		End:       end,
+		Inclusive: inclusive,
+	})
+}
+
+// parsePostfix parses a primary expression followed by calls, index accesses,
+// member accesses, tagged templates, type arguments and postfix `++`/`--`.
+func (p *Parser) parsePostfix() *ExprResult {
+	primaryResult := p.parsePrimary()
+	if !primaryResult.Ok {
+		return primaryResult
+	}
+	expr := primaryResult.Unwrap()
+
+	for {
+		token := p.peek()
+		switch token.Type {
+		case compiler.TokenTypeLeftParen:
+			p.advance()
+			argumentsResult := p.parseExpressionList(compiler.TokenTypeRightParen, "argument")
+			if !argumentsResult.Ok {
+				return exprErr(argumentsResult.Err)
+			}
+			expr = &ast.CallExpr{
+				Span:      ast.Span{Start: expr.NodeSpan().Start, End: p.previous.End},
+				Callee:    expr,
+				Arguments: argumentsResult.Unwrap(),
+			}
+		case compiler.TokenTypeLeftBracket:
+			p.advance()
+			p.pushLineBreakSensitive(false)
+			restoreStructLiteral := p.allowStructLiteral(true)
+			indexResult := p.ParseExpression()
+			restoreStructLiteral()
+			if !indexResult.Ok {
+				p.popLineBreakSensitive()
+				return indexResult
+			}
+			closeResult := p.expect(compiler.TokenTypeRightBracket, "']'")
+			p.popLineBreakSensitive()
+			if !closeResult.Ok {
+				return exprErr(closeResult.Err)
+			}
+			expr = &ast.IndexExpr{
+				Span:   ast.Span{Start: expr.NodeSpan().Start, End: closeResult.Unwrap().End},
+				Target: expr,
+				Index:  indexResult.Unwrap(),
+			}
+		case compiler.TokenTypeDot, compiler.TokenTypeQuestionDot:
+			p.advance()
+			p.skipLineBreaks()
+			if token.Type == compiler.TokenTypeDot && p.check(compiler.TokenTypeLeftParen) {
+				assertionResult := p.parseTypeAssertion(expr)
+				if !assertionResult.Ok {
+					return assertionResult
+				}
+				expr = assertionResult.Unwrap()
+				continue
+			}
+			nameResult := p.expect(compiler.TokenTypeIdentifier, "member name")
+			if !nameResult.Ok {
+				return exprErr(nameResult.Err)
+			}
+			nameToken := nameResult.Unwrap()
+			expr = &ast.MemberExpr{
+				Span:     ast.Span{Start: expr.NodeSpan().Start, End: nameToken.End},
+				Target:   expr,
+				Name:     &ast.Identifier{Span: ast.SpanOfToken(nameToken), Name: nameToken.Content},
+				Optional: token.Type == compiler.TokenTypeQuestionDot,
+			}
+		case compiler.TokenTypeLeftCurly:
+			if !p.structLiteralAllowed || !isTypeName(expr) || !p.isStructLiteralBodyAhead() {
+				return exprOk(expr)
+			}
+			structLitResult := p.parseStructLit(expr)
+			if !structLitResult.Ok {
+				return structLitResult
+			}
+			expr = structLitResult.Unwrap()
+		case compiler.TokenTypeTemplateStringQuote:
+			// A tag must be directly followed by the template, on the same line
+			if p.tokens[p.position] != token {
+				return exprOk(expr)
+			}
+			templateResult := p.parseTemplateString()
+			if !templateResult.Ok {
+				return exprErr(templateResult.Err)
+			}
+			template := templateResult.Unwrap()
+			expr = &ast.TaggedTemplate{
+				Span:     ast.Span{Start: expr.NodeSpan().Start, End: template.End},
+				Tag:      expr,
+				Template: template,
+			}
+		case compiler.TokenTypeLeftAngle:
+			if !isTypeName(expr) {
+				return exprOk(expr)
+			}
+			instantiation := p.tryParseInstantiation(expr)
+			if instantiation == nil {
+				return exprOk(expr)
+			}
+			expr = instantiation
+		case compiler.TokenTypeDoublePlus, compiler.TokenTypeDoubleMinus:
+			if !isAssignable(expr) {
+				return exprErr(p.createParseErr(
+					compiler.InvalidAssignmentTarget,
+					token,
+					fmt.Sprintf("Invalid operand of '%s', expected a variable, an index or a member", token.Content),
+				))
+			}
+			p.advance()
+			expr = &ast.PostfixExpr{
+				Span:     ast.Span{Start: expr.NodeSpan().Start, End: token.End},
+				Operator: token,
+				Operand:  expr,
+			}
+		default:
+			return exprOk(expr)
+		}
+	}
+}
+
+// parseTypeAssertion parses the type of a type assertion `value.(Type)`, after the dot.
+func (p *Parser) parseTypeAssertion(value ast.Expr) *ExprResult {
+	p.advance()
+	typeResult := p.parseType()
+	if !typeResult.Ok {
+		return exprErr(typeResult.Err)
+	}
+	closeResult := p.expect(compiler.TokenTypeRightParen, "')' after the asserted type")
+	if !closeResult.Ok {
+		return exprErr(closeResult.Err)
+	}
+	return exprOk(&ast.TypeAssertion{
+		Span:  ast.Span{Start: value.NodeSpan().Start, End: closeResult.Unwrap().End},
+		Value: value,
+		Type:  typeResult.Unwrap(),
+	})
+}
+
+// parseExpressionList parses comma separated expressions until the closing token,
+// the opening token should have been consumed. A trailing comma is allowed,
+// and each expression may be a spread `...xs`.
+func (p *Parser) parseExpressionList(closing compiler.TokenType, description string) *ExprListResult {
+	p.pushLineBreakSensitive(false)
+	defer p.popLineBreakSensitive()
+	defer p.allowStructLiteral(true)()
+
+	var exprs []ast.Expr
+	for !p.check(closing) {
+		spreadToken, isSpread := p.match(compiler.TokenTypeEllipsis)
+		exprResult := p.ParseExpression()
+		if !exprResult.Ok {
+			return &ExprListResult{Err: exprResult.Err}
+		}
+		expr := exprResult.Unwrap()
+		if isSpread {
+			expr = &ast.SpreadExpr{
+				Span: ast.Span{Start: spreadToken.Pos, End: expr.NodeSpan().End},
+				Expr: expr,
+			}
+		}
+		exprs = append(exprs, expr)
+		if _, hasComma := p.match(compiler.TokenTypeComma); !hasComma {
+			break
+		}
+	}
+	closeResult := p.expect(closing, describeTokenTypes(compiler.TokenTypeComma, closing)+" after "+description)
+	if !closeResult.Ok {
+		return &ExprListResult{Err: closeResult.Err}
+	}
+	return &ExprListResult{Value: exprs, Ok: true}
+}
+
+func (p *Parser) parsePrimary() *ExprResult {
+	token := p.peek()
+	switch token.Type {
+	case compiler.TokenTypeIdentifier:
+		p.advance()
+		return exprOk(&ast.Identifier{Span: ast.SpanOfToken(token), Name: token.Content})
+	case compiler.TokenTypeDecimalInteger,
+		compiler.TokenTypeOctalInteger,
+		compiler.TokenTypeHexadecimalInteger,
+		compiler.TokenTypeBinaryInteger,
+		compiler.TokenTypeExponent,
+		compiler.TokenTypeFloat,
+		compiler.TokenTypeRune,
+		compiler.TokenTypeString,
+		compiler.TokenTypeByte,
+		compiler.TokenTypeByteString,
+		compiler.TokenTypeTrue,
+		compiler.TokenTypeFalse,
+		compiler.TokenTypeNil:
+		p.advance()
+		return exprOk(&ast.BasicLiteral{Span: ast.SpanOfToken(token), Kind: token.Type, Value: token.Content})
+	case compiler.TokenTypeFunc:
+		return p.parseFuncLit()
+	case compiler.TokenTypeMatch:
+		return p.parseMatch()
+	case compiler.TokenTypeLeftBracket:
+		p.advance()
+		elementsResult := p.parseExpressionList(compiler.TokenTypeRightBracket, "element")
+		if !elementsResult.Ok {
+			return exprErr(elementsResult.Err)
+		}
+		return exprOk(&ast.ArrayLit{
+			Span:     ast.Span{Start: token.Pos, End: p.previous.End},
+			Elements: elementsResult.Unwrap(),
+		})
+	case compiler.TokenTypeTemplateStringQuote:
+		templateResult := p.parseTemplateString()
+		if !templateResult.Ok {
+			return exprErr(templateResult.Err)
+		}
+		return exprOk(templateResult.Unwrap())
+	case compiler.TokenTypeLeftParen:
+		if p.isArrowFuncAhead() {
+			return p.parseArrowFunc()
+		}
+		p.advance()
+		p.pushLineBreakSensitive(false)
+		restoreStructLiteral := p.allowStructLiteral(true)
+		exprResult := p.ParseExpression()
+		restoreStructLiteral()
+		if !exprResult.Ok {
+			p.popLineBreakSensitive()
+			return exprResult
+		}
+		if _, isTuple := p.match(compiler.TokenTypeComma); isTuple {
+			restResult := p.parseExpressionList(compiler.TokenTypeRightParen, "tuple element")
+			p.popLineBreakSensitive()
+			if !restResult.Ok {
+				return exprErr(restResult.Err)
+			}
+			return exprOk(&ast.TupleLit{
+				Span:     ast.Span{Start: token.Pos, End: p.previous.End},
+				Elements: append([]ast.Expr{exprResult.Unwrap()}, restResult.Unwrap()...),
+			})
+		}
+		closeResult := p.expect(compiler.TokenTypeRightParen, "')'")
+		p.popLineBreakSensitive()
+		if !closeResult.Ok {
+			return exprErr(closeResult.Err)
+		}
+		return exprOk(&ast.ParenExpr{
+			Span: ast.Span{Start: token.Pos, End: closeResult.Unwrap().End},
+			Expr: exprResult.Unwrap(),
+		})
+	default:
+		return exprErr(p.createUnexpectedTokenErr("expression"))
+	}
+}
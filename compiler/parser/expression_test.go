@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// toSExpr prints an expression as an S-expression, to make the tree shape easy to assert.
+func toSExpr(expr ast.Expr) string {
+	switch node := expr.(type) {
+	case *ast.Identifier:
+		return node.Name
+	case *ast.BasicLiteral:
+		return node.Value
+	case *ast.ParenExpr:
+		return toSExpr(node.Expr)
+	case *ast.UnaryExpr:
+		return fmt.Sprintf("(%s %s)", node.Operator.Content, toSExpr(node.Operand))
+	case *ast.PostfixExpr:
+		return fmt.Sprintf("(%s %s)", toSExpr(node.Operand), node.Operator.Content)
+	case *ast.BinaryExpr:
+		return fmt.Sprintf("(%s %s %s)", node.Operator.Content, toSExpr(node.Left), toSExpr(node.Right))
+	case *ast.AssignExpr:
+		return fmt.Sprintf("(%s %s %s)", node.Operator.Content, toSExpr(node.Target), toSExpr(node.Value))
+	case *ast.CallExpr:
+		var arguments []string
+		for _, argument := range node.Arguments {
+			arguments = append(arguments, toSExpr(argument))
+		}
+		return fmt.Sprintf("(call %s [%s])", toSExpr(node.Callee), strings.Join(arguments, " "))
+	case *ast.IndexExpr:
+		return fmt.Sprintf("(index %s %s)", toSExpr(node.Target), toSExpr(node.Index))
+	case *ast.MemberExpr:
+		return fmt.Sprintf("(%s %s %s)", map[bool]string{true: "?.", false: "."}[node.Optional], toSExpr(node.Target), node.Name.Name)
+	case *ast.ArrayLit:
+		var elements []string
+		for _, element := range node.Elements {
+			elements = append(elements, toSExpr(element))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(elements, " "))
+	case *ast.RangeExpr:
+		bounds := []string{"_", "_"}
+		if node.Start != nil {
+			bounds[0] = toSExpr(node.Start)
+		}
+		if node.End != nil {
+			bounds[1] = toSExpr(node.End)
+		}
+		return fmt.Sprintf("(%s %s %s)", node.Operator.Content, bounds[0], bounds[1])
+	case *ast.SpreadExpr:
+		return fmt.Sprintf("(... %s)", toSExpr(node.Expr))
+	case *ast.TypeAssertion:
+		return fmt.Sprintf("(.() %s %s)", toSExpr(node.Value), typeString(node.Type))
+	default:
+		return fmt.Sprintf("<%T>", expr)
+	}
+}
+
+func TestParseOperatorPrecedence(t *testing.T) {
+	cases := []struct {
+		source string
+		expect string
+	}{
+		{"1 + 2 * 3", "(+ 1 (* 2 3))"},
+		{"(1 + 2) * 3", "(* (+ 1 2) 3)"},
+		{"a - b - c", "(- (- a b) c)"},
+		{"a ** b ** c", "(** a (** b c))"},
+		{"-x ** 2", "(- (** x 2))"},
+		{"-a * b", "(* (- a) b)"},
+		{"!a && b || c", "(|| (&& (! a) b) c)"},
+		{"a | b ^ c & d", "(| a (^ b (& c d)))"},
+		{"a << 1 + 2", "(<< a (+ 1 2))"},
+		{"a == b < c", "(== a (< b c))"},
+		{"~mask & 0xFF", "(& (~ mask) 0xFF)"},
+		{"a ?? b ?? c", "(?? a (?? b c))"},
+		{"a?.b ?? c.d", "(?? (?. a b) (. c d))"},
+		{"a = b += c", "(= a (+= b c))"},
+		{"x **= 2", "(**= x 2)"},
+		{"a <<= b >>= 1", "(<<= a (>>= b 1))"},
+		{"i++ + ++j", "(+ (i ++) (++ j))"},
+		{"--a.b[0]", "(-- (index (. a b) 0))"},
+		{"f(a, b + 1)(c)", "(call (call f [a (+ b 1)]) [c])"},
+		{"xs[i % n].len()", "(call (. (index xs (% i n)) len) [])"},
+		{"a +\n  b", "(+ a b)"},
+		{"f(\n  a,\n  b,\n)", "(call f [a b])"},
+		{"1..10", "(.. 1 10)"},
+		{"0..n + 1", "(.. 0 (+ n 1))"},
+		{"a..=b * 2", "(..= a (* b 2))"},
+		{"r = lo..hi", "(= r (.. lo hi))"},
+		{"xs[2..]", "(index xs (.. 2 _))"},
+		{"xs[..n]", "(index xs (.. _ n))"},
+		{"xs[..]", "(index xs (.. _ _))"},
+		{"f(a, ...rest)", "(call f [a (... rest)])"},
+		{"[1, ...xs, f(...ys)]", "[1 (... xs) (call f [(... ys)])]"},
+		{"[\n  1,\n  2,\n][0]", "(index [1 2] 0)"},
+		{"[]", "[]"},
+	}
+	for _, testCase := range cases {
+		Convey(fmt.Sprintf("Test parse expression `%s`", testCase.source), t, func() {
+			parser := CreateParser(testCase.source)
+			expr := parser.ParseExpression().Unwrap()
+			So(toSExpr(expr), ShouldEqual, testCase.expect)
+			So(parser.peek().Type, ShouldEqual, compiler.TokenTypeEndOfFile)
+		})
+	}
+}
+
+func TestParseCustomOperators(t *testing.T) {
+	Convey("Test parse custom operators with declared precedence", t, func() {
+		operators := compiler.CreateOperatorTable()
+		operators.Declare("|>", compiler.PrecedenceNullish, compiler.AssociativityLeft).Unwrap()
+		scanner := compiler.CreateScanner("xs |> map + 1 |> sum")
+		scanner.UseOperatorTable(operators)
+		tokens, _ := scanner.Tokenize()
+
+		expr := CreateParserFromTokens(tokens, operators).ParseExpression().Unwrap()
+		So(toSExpr(expr), ShouldEqual, "(|> (|> xs (+ map 1)) sum)")
+	})
+}
+
+func TestParseExpressionSpans(t *testing.T) {
+	Convey("Test expression spans", t, func() {
+		expr := CreateParser("foo(1) +\n  bar[2]").ParseExpression().Unwrap()
+		binary := expr.(*ast.BinaryExpr)
+		So(binary.Start.Offset, ShouldEqual, 0)
+		So(binary.End.Offset, ShouldEqual, 17)
+		So(binary.Left.NodeSpan().End.Offset, ShouldEqual, 6)
+		So(binary.Right.NodeSpan().Start.Line, ShouldEqual, 2)
+		So(binary.Right.NodeSpan().Start.Column, ShouldEqual, 3)
+	})
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	cases := []struct {
+		source  string
+		errCode compiler.DiagnosticCode
+		errMsg  string
+	}{
+		{"1 +", compiler.UnexpectedToken, "Unexpected token: expected expression, found end of file"},
+		{"(a + b", compiler.UnexpectedToken, "Unexpected token: expected ')', found end of file"},
+		{"a < b < c", compiler.NonAssociativeOperator, "Operator '<' is non-associative and can't be chained with '<', wrap one side in parentheses"},
+		{"a + b = c", compiler.InvalidAssignmentTarget, "Invalid assignment target on the left of '='"},
+		{"a?.b = c", compiler.InvalidAssignmentTarget, "Invalid assignment target on the left of '='"},
+		{"1++", compiler.InvalidAssignmentTarget, "Invalid operand of '++', expected a variable, an index or a member"},
+		{"f(a b)", compiler.UnexpectedToken, "Unexpected token: expected ',' or ')' after argument, found 'b'"},
+		{"xs[1..=]", compiler.InvalidRange, "Inclusive range '..=' requires an end bound"},
+		{"a..b..c", compiler.NonAssociativeOperator, "Operator '..' is non-associative and can't be chained with '..', wrap one side in parentheses"},
+		{"...xs", compiler.UnexpectedToken, "Unexpected token: expected expression, found '...'"},
+	}
+	for _, testCase := range cases {
+		Convey(fmt.Sprintf("Test parse invalid expression `%s`", testCase.source), t, func() {
+			result := CreateParser(testCase.source).ParseExpression()
+			So(result.Err, ShouldNotBeNil)
+			So(result.Err.Code, ShouldEqual, testCase.errCode)
+			So(result.Err.Msg, ShouldEqual, testCase.errMsg)
+		})
+	}
+}
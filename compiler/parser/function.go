@@ -0,0 +1,341 @@
+package parser
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/shared"
+)
+
+type ParamListResult = shared.Result[[]*ast.Param, *compiler.Diagnostic]
+
+// parseFuncDecl parses `func name(params) ReturnType { body }`,
+// generic functions `func name<T: Constraint>(params) ReturnType { body }`,
+// and methods `func (receiver: Struct) name(params) ReturnType { body }`.
+func (p *Parser) parseFuncDecl() *StmtResult {
+	funcToken := p.advance()
+	var receiver *ast.Param
+	if _, isMethod := p.match(compiler.TokenTypeLeftParen); isMethod {
+		p.pushLineBreakSensitive(false)
+		receiverResult := p.parseParam(true)
+		if !receiverResult.Ok {
+			p.popLineBreakSensitive()
+			return stmtErr(receiverResult.Err)
+		}
+		closeResult := p.expect(compiler.TokenTypeRightParen, "')' after method receiver")
+		p.popLineBreakSensitive()
+		if !closeResult.Ok {
+			return stmtErr(closeResult.Err)
+		}
+		receiver = receiverResult.Unwrap()
+		if receiver.Variadic || receiver.Default != nil {
+			return stmtErr(compiler.CreateErrorDiagnostic(
+				compiler.InvalidParameter,
+				receiver.Start,
+				fmt.Sprintf("Method receiver '%s' can't be variadic or have a default value", receiver.Name.Name),
+			))
+		}
+	}
+	nameResult := p.parseIdentifier("function name")
+	if !nameResult.Ok {
+		return stmtErr(nameResult.Err)
+	}
+	typeParamsResult := p.parseTypeParams()
+	if !typeParamsResult.Ok {
+		return stmtErr(typeParamsResult.Err)
+	}
+	signatureResult := p.parseSignature()
+	if !signatureResult.Ok {
+		return stmtErr(signatureResult.Err)
+	}
+	funcSignature := signatureResult.Unwrap()
+	bodyResult := p.parseBlock()
+	if !bodyResult.Ok {
+		return stmtErr(bodyResult.Err)
+	}
+	return stmtOk(&ast.FuncDecl{
+		Span:       ast.Span{Start: funcToken.Pos, End: bodyResult.Unwrap().End},
+		Receiver:   receiver,
+		Name:       nameResult.Unwrap(),
+		TypeParams: typeParamsResult.Unwrap(),
+		Params:     funcSignature.params,
+		ReturnType: funcSignature.returnType,
+		Body:       bodyResult.Unwrap(),
+	})
+}
+
+// parseFuncLit parses an anonymous function `func(params) ReturnType { body }`.
+func (p *Parser) parseFuncLit() *ExprResult {
+	funcToken := p.advance()
+	signatureResult := p.parseSignature()
+	if !signatureResult.Ok {
+		return exprErr(signatureResult.Err)
+	}
+	funcSignature := signatureResult.Unwrap()
+	bodyResult := p.parseBlock()
+	if !bodyResult.Ok {
+		return exprErr(bodyResult.Err)
+	}
+	return exprOk(&ast.FuncLit{
+		Span:       ast.Span{Start: funcToken.Pos, End: bodyResult.Unwrap().End},
+		Params:     funcSignature.params,
+		ReturnType: funcSignature.returnType,
+		Body:       bodyResult.Unwrap(),
+	})
+}
+
+type signature struct {
+	params     []*ast.Param
+	returnType ast.TypeExpr
+}
+
+// parseSignature parses the parameters and the optional return type of a function.
+// The return type is absent if the body starts right after the parameters.
+func (p *Parser) parseSignature() *shared.Result[*signature, *compiler.Diagnostic] {
+	openResult := p.expect(compiler.TokenTypeLeftParen, "'(' before parameters")
+	if !openResult.Ok {
+		return shared.ResultErr[*signature](openResult.Err)
+	}
+	paramsResult := p.parseParamList(true)
+	if !paramsResult.Ok {
+		return shared.ResultErr[*signature](paramsResult.Err)
+	}
+	funcSignature := &signature{params: paramsResult.Unwrap()}
+	if !p.check(compiler.TokenTypeLeftCurly) {
+		returnTypeResult := p.parseType()
+		if !returnTypeResult.Ok {
+			return shared.ResultErr[*signature](returnTypeResult.Err)
+		}
+		funcSignature.returnType = returnTypeResult.Unwrap()
+	}
+	return shared.ResultOk[*signature, *compiler.Diagnostic](funcSignature)
+}
+
+// parseParamList parses parameters until the closing parenthesis, the opening one
+// should have been consumed. Parameters of declarations require type annotations,
+// while those of arrow lambdas may omit them.
+func (p *Parser) parseParamList(requireTypes bool) *ParamListResult {
+	p.pushLineBreakSensitive(false)
+	defer p.popLineBreakSensitive()
+
+	var params []*ast.Param
+	for !p.check(compiler.TokenTypeRightParen) {
+		paramResult := p.parseParam(requireTypes)
+		if !paramResult.Ok {
+			return &ParamListResult{Err: paramResult.Err}
+		}
+		params = append(params, paramResult.Unwrap())
+		if _, hasComma := p.match(compiler.TokenTypeComma); !hasComma {
+			break
+		}
+	}
+	closeResult := p.expect(compiler.TokenTypeRightParen, "',' or ')' after parameter")
+	if !closeResult.Ok {
+		return &ParamListResult{Err: closeResult.Err}
+	}
+
+	if err := checkParamOrder(params); err != nil {
+		return &ParamListResult{Err: err}
+	}
+	return &ParamListResult{Value: params, Ok: true}
+}
+
+func (p *Parser) parseParam(requireType bool) *shared.Result[*ast.Param, *compiler.Diagnostic] {
+	param := &ast.Param{}
+	start := p.peek().Pos
+	if _, isVariadic := p.match(compiler.TokenTypeEllipsis); isVariadic {
+		param.Variadic = true
+	}
+	nameResult := p.parseIdentifier("parameter name")
+	if !nameResult.Ok {
+		return shared.ResultErr[*ast.Param](nameResult.Err)
+	}
+	param.Name = nameResult.Unwrap()
+
+	if _, hasType := p.match(compiler.TokenTypeColon); hasType {
+		typeResult := p.parseType()
+		if !typeResult.Ok {
+			return shared.ResultErr[*ast.Param](typeResult.Err)
+		}
+		param.Type = typeResult.Unwrap()
+	} else if requireType {
+		return shared.ResultErr[*ast.Param](
+			p.createUnexpectedTokenErr(fmt.Sprintf("':' and the type of parameter '%s'", param.Name.Name)),
+		)
+	}
+
+	if equalToken, hasDefault := p.match(compiler.TokenTypeEqual); hasDefault {
+		if param.Variadic {
+			return shared.ResultErr[*ast.Param](p.createParseErr(
+				compiler.InvalidParameter,
+				equalToken,
+				fmt.Sprintf("Variadic parameter '%s' can't have a default value", param.Name.Name),
+			))
+		}
+		defaultResult := p.ParseExpression()
+		if !defaultResult.Ok {
+			return shared.ResultErr[*ast.Param](defaultResult.Err)
+		}
+		param.Default = defaultResult.Unwrap()
+	}
+
+	param.Span = ast.Span{Start: start, End: p.previous.End}
+	return shared.ResultOk[*ast.Param, *compiler.Diagnostic](param)
+}
+
+// checkParamOrder makes sure that the variadic parameter comes last,
+// and that no required parameter follows a parameter with a default value.
+func checkParamOrder(params []*ast.Param) *compiler.Diagnostic {
+	var firstDefault *ast.Param
+	for i, param := range params {
+		if param.Variadic && i != len(params)-1 {
+			return compiler.CreateErrorDiagnostic(
+				compiler.InvalidParameter,
+				param.Start,
+				fmt.Sprintf("Variadic parameter '%s' should be the last parameter", param.Name.Name),
+			)
+		}
+		if param.Default != nil && firstDefault == nil {
+			firstDefault = param
+		} else if param.Default == nil && !param.Variadic && firstDefault != nil {
+			return compiler.CreateErrorDiagnostic(
+				compiler.InvalidParameter,
+				param.Start,
+				fmt.Sprintf(
+					"Parameter '%s' should have a default value, because it follows '%s' which has one",
+					param.Name.Name,
+					firstDefault.Name.Name,
+				),
+			)
+		}
+	}
+	return nil
+}
+
+// tokensAfterParens returns the tokens following the parenthesis which closes the one
+// starting at the given index, skipping comments but not line breaks.
+// It returns nil if the parenthesis is never closed.
+func (p *Parser) tokensAfterParens(index int) []*compiler.Token {
+	depth := 0
+	for i := index; i < len(p.tokens); i++ {
+		switch p.tokens[i].Type {
+		case compiler.TokenTypeLeftParen:
+			depth++
+		case compiler.TokenTypeRightParen:
+			depth--
+			if depth == 0 {
+				var following []*compiler.Token
+				for _, token := range p.tokens[i+1:] {
+					if token.Type != compiler.TokenTypeLineComment {
+						following = append(following, token)
+					}
+				}
+				return following
+			}
+		case compiler.TokenTypeEndOfFile:
+			return nil
+		}
+	}
+	return nil
+}
+
+// isArrowFuncAhead checks whether the parentheses at the current position are followed by `=>`.
+func (p *Parser) isArrowFuncAhead() bool {
+	following := p.tokensAfterParens(p.nextIndex(p.position))
+	return len(following) > 0 && following[0].Type == compiler.TokenTypeArrow
+}
+
+// isMethodDeclAhead checks whether the `func` at the current position is followed by
+// a receiver and a method name, like `func (p: Point) name(`.
+func (p *Parser) isMethodDeclAhead() bool {
+	if p.peekAt(1).Type != compiler.TokenTypeLeftParen {
+		return false
+	}
+	following := p.tokensAfterParens(p.nextIndex(p.nextIndex(p.position) + 1))
+	return len(following) > 1 &&
+		following[0].Type == compiler.TokenTypeIdentifier &&
+		following[1].Type == compiler.TokenTypeLeftParen
+}
+
+// parseArrowFunc parses `(params) => expression` and `(params) => { statements }`.
+func (p *Parser) parseArrowFunc() *ExprResult {
+	openToken := p.advance()
+	paramsResult := p.parseParamList(false)
+	if !paramsResult.Ok {
+		return exprErr(paramsResult.Err)
+	}
+	p.advance() // Moving over the `=>`
+	p.skipLineBreaks()
+
+	arrowFunc := &ast.ArrowFunc{Params: paramsResult.Unwrap()}
+	if p.check(compiler.TokenTypeLeftCurly) {
+		bodyResult := p.parseBlock()
+		if !bodyResult.Ok {
+			return exprErr(bodyResult.Err)
+		}
+		arrowFunc.Body = bodyResult.Unwrap()
+	} else {
+		bodyResult := p.ParseExpression()
+		if !bodyResult.Ok {
+			return bodyResult
+		}
+		arrowFunc.Body = bodyResult.Unwrap()
+	}
+	arrowFunc.Span = ast.Span{Start: openToken.Pos, End: arrowFunc.Body.NodeSpan().End}
+	return exprOk(arrowFunc)
+}
+
+// parseFuncType parses `func(Type, ...Type) ReturnType` in type annotations.
+func (p *Parser) parseFuncType() *TypeResult {
+	funcToken := p.advance()
+	openResult := p.expect(compiler.TokenTypeLeftParen, "'(' before parameter types")
+	if !openResult.Ok {
+		return typeErr(openResult.Err)
+	}
+
+	funcType := &ast.FuncType{}
+	p.pushLineBreakSensitive(false)
+	for !p.check(compiler.TokenTypeRightParen) {
+		param := &ast.Param{}
+		start := p.peek().Pos
+		if _, isVariadic := p.match(compiler.TokenTypeEllipsis); isVariadic {
+			param.Variadic = true
+		}
+		typeResult := p.parseType()
+		if !typeResult.Ok {
+			p.popLineBreakSensitive()
+			return typeResult
+		}
+		param.Type = typeResult.Unwrap()
+		param.Span = ast.Span{Start: start, End: param.Type.NodeSpan().End}
+		funcType.Params = append(funcType.Params, param)
+		if _, hasComma := p.match(compiler.TokenTypeComma); !hasComma {
+			break
+		}
+	}
+	closeResult := p.expect(compiler.TokenTypeRightParen, "',' or ')' after parameter type")
+	p.popLineBreakSensitive()
+	if !closeResult.Ok {
+		return typeErr(closeResult.Err)
+	}
+	for i, param := range funcType.Params {
+		if param.Variadic && i != len(funcType.Params)-1 {
+			return typeErr(compiler.CreateErrorDiagnostic(
+				compiler.InvalidParameter,
+				param.Start,
+				"Variadic parameter type should be the last one",
+			))
+		}
+	}
+
+	funcType.Span = ast.Span{Start: funcToken.Pos, End: closeResult.Unwrap().End}
+	if p.canStartType() {
+		returnTypeResult := p.parseType()
+		if !returnTypeResult.Ok {
+			return returnTypeResult
+		}
+		funcType.ReturnType = returnTypeResult.Unwrap()
+		funcType.End = funcType.ReturnType.NodeSpan().End
+	}
+	return typeOk(funcType)
+}
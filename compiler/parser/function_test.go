@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseFuncDecl(t *testing.T) {
+	Convey("Test parse function declaration", t, func() {
+		file := parseFile(`
+func join(sep: string = ", ", ...parts: string) string {
+  return reduce(parts, (acc, part) => acc + sep + part)
+}
+func log(message: string) {
+  print(message)
+}`)
+		join := file.Statements[0].(*ast.FuncDecl)
+		So(join.Name.Name, ShouldEqual, "join")
+		So(len(join.Params), ShouldEqual, 2)
+		So(join.Params[0].Name.Name, ShouldEqual, "sep")
+		So(join.Params[0].Default.(*ast.BasicLiteral).Value, ShouldEqual, ", ")
+		So(join.Params[1].Variadic, ShouldBeTrue)
+		So(join.Params[1].Type.(*ast.NamedType).Name.Name, ShouldEqual, "string")
+		So(join.ReturnType.(*ast.NamedType).Name.Name, ShouldEqual, "string")
+
+		returnValue := join.Body.Statements[0].(*ast.ReturnStmt).Value.(*ast.CallExpr)
+		lambda := returnValue.Arguments[1].(*ast.ArrowFunc)
+		So(len(lambda.Params), ShouldEqual, 2)
+		So(lambda.Params[0].Type, ShouldBeNil)
+		So(toSExpr(lambda.Body.(ast.Expr)), ShouldEqual, "(+ (+ acc sep) part)")
+
+		log := file.Statements[1].(*ast.FuncDecl)
+		So(log.ReturnType, ShouldBeNil)
+		So(log.Start.Line, ShouldEqual, 5)
+		So(log.End.Line, ShouldEqual, 7)
+
+		var function ast.Function = log
+		So(function.FuncBody(), ShouldEqual, log.Body)
+	})
+}
+
+func TestParseAnonymousFunctions(t *testing.T) {
+	Convey("Test parse anonymous function expression", t, func() {
+		decl := parseFile("let add = func(a: int, b: int) int { return a + b }").Statements[0].(*ast.VarDecl)
+		funcLit := decl.Value.(*ast.FuncLit)
+		So(len(funcLit.Params), ShouldEqual, 2)
+		So(funcLit.ReturnType.(*ast.NamedType).Name.Name, ShouldEqual, "int")
+		So(len(funcLit.Body.Statements), ShouldEqual, 1)
+	})
+
+	Convey("Test parse arrow lambdas", t, func() {
+		file := parseFile("let f = () => 0\nlet g = (x: int) => {\n  return x * 2\n}\nlet h = (a) + b")
+		f := file.Statements[0].(*ast.VarDecl).Value.(*ast.ArrowFunc)
+		So(f.Params, ShouldBeEmpty)
+		So(toSExpr(f.Body.(ast.Expr)), ShouldEqual, "0")
+
+		g := file.Statements[1].(*ast.VarDecl).Value.(*ast.ArrowFunc)
+		So(g.Params[0].Type.(*ast.NamedType).Name.Name, ShouldEqual, "int")
+		So(g.Body.(*ast.BlockStmt).Statements, ShouldHaveLength, 1)
+
+		h := file.Statements[2].(*ast.VarDecl).Value
+		So(toSExpr(h), ShouldEqual, "(+ a b)")
+	})
+
+	Convey("Test parse function types", t, func() {
+		decl := parseFile("let callback: func(int, ...string) bool = nop").Statements[0].(*ast.VarDecl)
+		funcType := decl.Type.(*ast.FuncType)
+		So(len(funcType.Params), ShouldEqual, 2)
+		So(funcType.Params[1].Variadic, ShouldBeTrue)
+		So(funcType.ReturnType.(*ast.NamedType).Name.Name, ShouldEqual, "bool")
+	})
+}
+
+func TestParseFunctionErrors(t *testing.T) {
+	cases := []struct {
+		source  string
+		errCode compiler.DiagnosticCode
+		errMsg  string
+	}{
+		{"func f(a) {}", compiler.UnexpectedToken, "Unexpected token: expected ':' and the type of parameter 'a', found ')'"},
+		{"func f(...a: int, b: int) {}", compiler.InvalidParameter, "Variadic parameter 'a' should be the last parameter"},
+		{"func f(a: int = 1, b: int) {}", compiler.InvalidParameter, "Parameter 'b' should have a default value, because it follows 'a' which has one"},
+		{"func f(...a: int = 1) {}", compiler.InvalidParameter, "Variadic parameter 'a' can't have a default value"},
+		{"func f(a: int) int", compiler.UnexpectedToken, "Unexpected token: expected '{', found end of file"},
+	}
+	for _, testCase := range cases {
+		Convey(fmt.Sprintf("Test parse invalid function `%s`", testCase.source), t, func() {
+			diagnostics := parseErrors(testCase.source)
+			So(diagnostics, ShouldNotBeEmpty)
+			So(diagnostics[0].Code, ShouldEqual, testCase.errCode)
+			So(diagnostics[0].Msg, ShouldEqual, testCase.errMsg)
+		})
+	}
+}
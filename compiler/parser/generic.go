@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/shared"
+	"strings"
+)
+
+type TypeParamListResult = shared.Result[[]*ast.TypeParam, *compiler.Diagnostic]
+type TypeListResult = shared.Result[[]ast.TypeExpr, *compiler.Diagnostic]
+
+// parseTypeParams parses the type parameters `<T, U: Constraint>` of a generic declaration,
+// it returns no parameters if the declaration isn't generic.
+func (p *Parser) parseTypeParams() *TypeParamListResult {
+	if _, isGeneric := p.match(compiler.TokenTypeLeftAngle); !isGeneric {
+		return &TypeParamListResult{Ok: true}
+	}
+	p.pushLineBreakSensitive(false)
+	defer p.popLineBreakSensitive()
+
+	var typeParams []*ast.TypeParam
+	for {
+		nameResult := p.parseIdentifier("type parameter name")
+		if !nameResult.Ok {
+			return &TypeParamListResult{Err: nameResult.Err}
+		}
+		typeParam := &ast.TypeParam{Span: nameResult.Unwrap().Span, Name: nameResult.Unwrap()}
+		if _, hasConstraint := p.match(compiler.TokenTypeColon); hasConstraint {
+			constraintResult := p.parseType()
+			if !constraintResult.Ok {
+				return &TypeParamListResult{Err: constraintResult.Err}
+			}
+			typeParam.Constraint = constraintResult.Unwrap()
+			typeParam.End = typeParam.Constraint.NodeSpan().End
+		}
+		typeParams = append(typeParams, typeParam)
+		if _, hasComma := p.match(compiler.TokenTypeComma); !hasComma || p.check(compiler.TokenTypeRightAngle) {
+			break
+		}
+	}
+	closeResult := p.expectClosingAngle("',' or '>' after type parameter")
+	if !closeResult.Ok {
+		return &TypeParamListResult{Err: closeResult.Err}
+	}
+	return &TypeParamListResult{Value: typeParams, Ok: true}
+}
+
+// parseTypeArgs parses the type arguments `<int, []T>` of a generic type,
+// the opening '<' should have been consumed.
+func (p *Parser) parseTypeArgs() *TypeListResult {
+	p.pushLineBreakSensitive(false)
+	defer p.popLineBreakSensitive()
+
+	var typeArgs []ast.TypeExpr
+	for {
+		typeResult := p.parseType()
+		if !typeResult.Ok {
+			return &TypeListResult{Err: typeResult.Err}
+		}
+		typeArgs = append(typeArgs, typeResult.Unwrap())
+		if _, hasComma := p.match(compiler.TokenTypeComma); !hasComma || p.check(compiler.TokenTypeRightAngle) {
+			break
+		}
+	}
+	closeResult := p.expectClosingAngle("',' or '>' after type argument")
+	if !closeResult.Ok {
+		return &TypeListResult{Err: closeResult.Err}
+	}
+	return &TypeListResult{Value: typeArgs, Ok: true}
+}
+
+// expectClosingAngle consumes the '>' closing type parameters or arguments.
+// The scanner reads `>>` in `Box<Box<int>>` as a shift operator, so a token
+// starting with '>' is split in two, and only the first '>' is consumed.
+func (p *Parser) expectClosingAngle(description string) *TokenResult {
+	token := p.peek()
+	if token.Type != compiler.TokenTypeRightAngle && len(token.Content) > 1 && strings.HasPrefix(token.Content, ">") {
+		rest := token.Content[1:]
+		if restType, isOperator := compiler.OperatorTokenMap[rest]; isOperator {
+			middle := compiler.CreatePositon(token.Pos.Offset+1, token.Pos.Line, token.Pos.Column+1)
+			index := p.nextIndex(p.position)
+			// The token list is copied, so that a backtracking parse can restore it
+			tokens := make([]*compiler.Token, 0, len(p.tokens)+1)
+			tokens = append(tokens, p.tokens[:index]...)
+			tokens = append(tokens,
+				&compiler.Token{Type: compiler.TokenTypeRightAngle, Pos: token.Pos, End: middle, Content: ">"},
+				&compiler.Token{Type: restType, Pos: middle, End: token.End, Content: rest},
+			)
+			p.tokens = append(tokens, p.tokens[index+1:]...)
+		}
+	}
+	return p.expect(compiler.TokenTypeRightAngle, description)
+}
+
+// checkpoint is a parser position to backtrack to.
+type checkpoint struct {
+	position int
+	tokens   []*compiler.Token
+	previous *compiler.Token
+	state    parserState
+}
+
+func (p *Parser) saveCheckpoint() checkpoint {
+	return checkpoint{p.position, p.tokens, p.previous, p.saveState()}
+}
+
+func (p *Parser) backtrack(c checkpoint) {
+	p.position = c.position
+	p.tokens = c.tokens
+	p.previous = c.previous
+	p.restoreState(c.state)
+}
+
+// tryParseInstantiation tells `f<T>(x)` and `Box<T> { }` apart from comparisons
+// like `a < b`. It tries to parse type arguments after the '<', and keeps them
+// only if they are followed by a call, a struct literal or a member access. Otherwise, it
+// backtracks and returns nil, so that the '<' is parsed as a comparison.
+// Chained comparisons like `a < b > (c)` are rejected anyway, so they can't be
+// confused with an instantiation.
+func (p *Parser) tryParseInstantiation(target ast.Expr) *ast.InstantiateExpr {
+	saved := p.saveCheckpoint()
+	p.advance() // Moving over the '<'
+	typeArgsResult := p.parseTypeArgs()
+	if typeArgsResult.Ok {
+		closeToken := p.previous
+		isCall := p.tokens[p.position].Type == compiler.TokenTypeLeftParen
+		isStructLiteral := p.check(compiler.TokenTypeLeftCurly) && p.structLiteralAllowed && p.isStructLiteralBodyAhead()
+		// A variant of a generic enum, like `Option<int>.None`
+		isMember := p.tokens[p.position].Type == compiler.TokenTypeDot
+		if isCall || isStructLiteral || isMember {
+			return &ast.InstantiateExpr{
+				Span:     ast.Span{Start: target.NodeSpan().Start, End: closeToken.End},
+				Target:   target,
+				TypeArgs: typeArgsResult.Unwrap(),
+			}
+		}
+	}
+	p.backtrack(saved)
+	return nil
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func typeString(typeExpr ast.TypeExpr) string {
+	switch node := typeExpr.(type) {
+	case *ast.NamedType:
+		if len(node.TypeArgs) == 0 {
+			return node.Name.Name
+		}
+		args := ""
+		for i, arg := range node.TypeArgs {
+			args += map[bool]string{true: ", ", false: ""}[i > 0] + typeString(arg)
+		}
+		return fmt.Sprintf("%s<%s>", node.Name.Name, args)
+	case *ast.ArrayType:
+		return "[]" + typeString(node.Element)
+	case *ast.OptionalType:
+		return typeString(node.Elem) + "?"
+	default:
+		return fmt.Sprintf("<%T>", typeExpr)
+	}
+}
+
+func TestParseGenerics(t *testing.T) {
+	Convey("Test parse generic declarations", t, func() {
+		file := parseFile(`
+struct Pair<K: Hashable, V> { key: K; value: V }
+func first<T>(xs: []T) T { return xs[0] }
+func (p: Pair<K, V>) get() V { return p.value }`)
+		pair := file.Statements[0].(*ast.StructDecl)
+		So(len(pair.TypeParams), ShouldEqual, 2)
+		So(pair.TypeParams[0].Name.Name, ShouldEqual, "K")
+		So(typeString(pair.TypeParams[0].Constraint), ShouldEqual, "Hashable")
+		So(pair.TypeParams[1].Constraint, ShouldBeNil)
+
+		first := file.Statements[1].(*ast.FuncDecl)
+		So(first.TypeParams[0].Name.Name, ShouldEqual, "T")
+		So(typeString(first.Params[0].Type), ShouldEqual, "[]T")
+
+		method := file.Statements[2].(*ast.FuncDecl)
+		So(typeString(method.Receiver.Type), ShouldEqual, "Pair<K, V>")
+	})
+
+	Convey("Test split '>>' closing nested type arguments", t, func() {
+		file := parseFile("let m: Map<string, List<List<int>>> = empty\nlet n: Box<Box<int>>= x\nlet s = a >> 2")
+		So(typeString(file.Statements[0].(*ast.VarDecl).Type), ShouldEqual, "Map<string, List<List<int>>>")
+		inner := file.Statements[0].(*ast.VarDecl).Type.(*ast.NamedType).TypeArgs[1]
+		So(inner.NodeSpan().End.Offset, ShouldEqual, 34)
+		So(typeString(file.Statements[1].(*ast.VarDecl).Type), ShouldEqual, "Box<Box<int>>")
+		So(toSExpr(file.Statements[2].(*ast.VarDecl).Value), ShouldEqual, "(>> a 2)")
+	})
+
+	Convey("Test tell type arguments apart from comparisons", t, func() {
+		cases := []struct {
+			source string
+			expect string
+		}{
+			{"a < b", "(< a b)"},
+			{"a < b && c > d", "(&& (< a b) (> c d))"},
+			{"a < b == c > (d)", "(== (< a b) (> c d))"},
+			{"xs.len() < max", "(< (call (. xs len) []) max)"},
+			{"i < n >> 1", "(< i (>> n 1))"},
+		}
+		for _, testCase := range cases {
+			expr := CreateParser(testCase.source).ParseExpression().Unwrap()
+			So(toSExpr(expr), ShouldEqual, testCase.expect)
+		}
+
+		call := CreateParser("make<Map<string, int>>(16)").ParseExpression().Unwrap().(*ast.CallExpr)
+		instantiation := call.Callee.(*ast.InstantiateExpr)
+		So(toSExpr(instantiation.Target), ShouldEqual, "make")
+		So(typeString(instantiation.TypeArgs[0]), ShouldEqual, "Map<string, int>")
+		So(toSExpr(call.Arguments[0]), ShouldEqual, "16")
+
+		literal := CreateParser("Box<int> { value: 1 }").ParseExpression().Unwrap().(*ast.StructLit)
+		So(typeString(literal.Type.(*ast.InstantiateExpr).TypeArgs[0]), ShouldEqual, "int")
+	})
+
+	Convey("Test report invalid type parameters", t, func() {
+		diagnostics := parseErrors("func f<T U>() {}")
+		So(diagnostics[0].Code, ShouldEqual, compiler.UnexpectedToken)
+		So(diagnostics[0].Msg, ShouldEqual, "Unexpected token: expected ',' or '>' after type parameter, found 'U'")
+	})
+}
@@ -0,0 +1,349 @@
+package parser
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/shared"
+)
+
+type PatternResult = shared.Result[ast.Pattern, *compiler.Diagnostic]
+
+func patternOk(pattern ast.Pattern) *PatternResult {
+	return &PatternResult{Value: pattern, Ok: true}
+}
+
+func patternErr(err *compiler.Diagnostic) *PatternResult {
+	return &PatternResult{Err: err}
+}
+
+// parseMatch parses a match expression, arms are separated by line breaks or commas:
+//
+//	match subject {
+//	  pattern => value
+//	  pattern if guard => { statements }
+//	}
+func (p *Parser) parseMatch() *ExprResult {
+	matchToken := p.advance()
+	restoreStructLiteral := p.allowStructLiteral(false)
+	subjectResult := p.ParseExpression()
+	restoreStructLiteral()
+	if !subjectResult.Ok {
+		return subjectResult
+	}
+	openResult := p.expect(compiler.TokenTypeLeftCurly, "'{' before match arms")
+	if !openResult.Ok {
+		return exprErr(openResult.Err)
+	}
+	defer p.allowStructLiteral(true)()
+
+	matchExpr := &ast.MatchExpr{Subject: subjectResult.Unwrap()}
+	closeResult := p.parseMemberList(func() *compiler.Diagnostic {
+		armResult := p.parseMatchArm()
+		if !armResult.Ok {
+			return armResult.Err
+		}
+		matchExpr.Arms = append(matchExpr.Arms, armResult.Unwrap())
+		return nil
+	})
+	if !closeResult.Ok {
+		return exprErr(closeResult.Err)
+	}
+	matchExpr.Span = ast.Span{Start: matchToken.Pos, End: closeResult.Unwrap().End}
+	return exprOk(matchExpr)
+}
+
+func (p *Parser) parseMatchArm() *shared.Result[*ast.MatchArm, *compiler.Diagnostic] {
+	patternResult := p.parsePattern()
+	if !patternResult.Ok {
+		return shared.ResultErr[*ast.MatchArm](patternResult.Err)
+	}
+	arm := &ast.MatchArm{Pattern: patternResult.Unwrap()}
+	if _, hasGuard := p.match(compiler.TokenTypeIf); hasGuard {
+		guardResult := p.ParseExpression()
+		if !guardResult.Ok {
+			return shared.ResultErr[*ast.MatchArm](guardResult.Err)
+		}
+		arm.Guard = guardResult.Unwrap()
+	}
+	arrowResult := p.expect(compiler.TokenTypeArrow, "'=>' after match pattern")
+	if !arrowResult.Ok {
+		return shared.ResultErr[*ast.MatchArm](arrowResult.Err)
+	}
+	p.skipLineBreaks()
+
+	if p.check(compiler.TokenTypeLeftCurly) {
+		blockResult := p.parseBlock()
+		if !blockResult.Ok {
+			return shared.ResultErr[*ast.MatchArm](blockResult.Err)
+		}
+		arm.Body = blockResult.Unwrap()
+	} else {
+		bodyResult := p.ParseExpression()
+		if !bodyResult.Ok {
+			return shared.ResultErr[*ast.MatchArm](bodyResult.Err)
+		}
+		arm.Body = bodyResult.Unwrap()
+	}
+	arm.Span = ast.Span{Start: arm.Pattern.NodeSpan().Start, End: arm.Body.NodeSpan().End}
+	return shared.ResultOk[*ast.MatchArm, *compiler.Diagnostic](arm)
+}
+
+// parsePattern parses a match pattern:
+//
+//	_                  wildcard
+//	42, -1, "text"     literal
+//	0..10, 'a'..='z'   range, the bounds are optional like in range expressions
+//	name, name @ p     binding
+//	name: Type         type pattern, matching the interface values holding a Type
+//	(p1, p2)           tuple
+//	Point { x: p, y }  struct destructuring
+//	Color.Red          enum variant
+//	Option.Some(p)     enum variant, with the payload patterns
+//	Shape.Circle { r } enum variant with a struct payload
+func (p *Parser) parsePattern() *PatternResult {
+	token := p.peek()
+	switch {
+	case token.Type == compiler.TokenTypeIdentifier && p.peekAt(1).Type == compiler.TokenTypeColon:
+		return p.parseTypePattern()
+	case token.Type == compiler.TokenTypeIdentifier && token.Content == "_":
+		p.advance()
+		return patternOk(&ast.WildcardPattern{Span: ast.SpanOfToken(token)})
+	case token.Type == compiler.TokenTypeIdentifier:
+		next := p.peekAt(1).Type
+		if next == compiler.TokenTypeDot || next == compiler.TokenTypeLeftCurly || next == compiler.TokenTypeLeftParen {
+			return p.parsePathPattern()
+		}
+		name := &ast.Identifier{Span: ast.SpanOfToken(p.advance()), Name: token.Content}
+		binding := &ast.BindingPattern{Span: name.Span, Name: name}
+		if _, hasPattern := p.match(compiler.TokenTypeAlpha); hasPattern {
+			patternResult := p.parsePattern()
+			if !patternResult.Ok {
+				return patternResult
+			}
+			binding.Pattern = patternResult.Unwrap()
+			binding.End = binding.Pattern.NodeSpan().End
+		}
+		return patternOk(binding)
+	case token.Type == compiler.TokenTypeLeftParen:
+		return p.parseTuplePattern()
+	case isRangeOperator(token.Type):
+		return p.parseRangePattern(nil)
+	default:
+		literalResult := p.parsePatternLiteral()
+		if !literalResult.Ok {
+			return patternErr(literalResult.Err)
+		}
+		literal := literalResult.Unwrap()
+		if isRangeOperator(p.peek().Type) {
+			return p.parseRangePattern(literal)
+		}
+		return patternOk(&ast.LiteralPattern{Span: literal.NodeSpan(), Value: literal})
+	}
+}
+
+// parseTypePattern parses a type pattern `name: Type`.
+func (p *Parser) parseTypePattern() *PatternResult {
+	nameToken := p.advance()
+	p.advance()
+	typeResult := p.parseType()
+	if !typeResult.Ok {
+		return patternErr(typeResult.Err)
+	}
+	pattern := &ast.TypePattern{
+		Name: &ast.Identifier{Span: ast.SpanOfToken(nameToken), Name: nameToken.Content},
+		Type: typeResult.Unwrap(),
+	}
+	pattern.Span = ast.Span{Start: nameToken.Pos, End: pattern.Type.NodeSpan().End}
+	return patternOk(pattern)
+}
+
+// parsePatternLiteral parses the literal of a literal pattern or a range bound,
+// a number may be negated.
+func (p *Parser) parsePatternLiteral() *ExprResult {
+	token := p.peek()
+	if token.Type == compiler.TokenTypeMinus {
+		p.advance()
+		if !p.check(
+			compiler.TokenTypeDecimalInteger,
+			compiler.TokenTypeOctalInteger,
+			compiler.TokenTypeHexadecimalInteger,
+			compiler.TokenTypeBinaryInteger,
+			compiler.TokenTypeExponent,
+			compiler.TokenTypeFloat,
+		) {
+			return exprErr(p.createUnexpectedTokenErr("number after '-' in pattern"))
+		}
+		operand := p.advance()
+		literal := &ast.BasicLiteral{Span: ast.SpanOfToken(operand), Kind: operand.Type, Value: operand.Content}
+		return exprOk(&ast.UnaryExpr{
+			Span:     ast.Span{Start: token.Pos, End: operand.End},
+			Operator: token,
+			Operand:  literal,
+		})
+	}
+	switch token.Type {
+	case compiler.TokenTypeDecimalInteger,
+		compiler.TokenTypeOctalInteger,
+		compiler.TokenTypeHexadecimalInteger,
+		compiler.TokenTypeBinaryInteger,
+		compiler.TokenTypeExponent,
+		compiler.TokenTypeFloat,
+		compiler.TokenTypeRune,
+		compiler.TokenTypeString,
+		compiler.TokenTypeByte,
+		compiler.TokenTypeByteString,
+		compiler.TokenTypeTrue,
+		compiler.TokenTypeFalse:
+		p.advance()
+		return exprOk(&ast.BasicLiteral{Span: ast.SpanOfToken(token), Kind: token.Type, Value: token.Content})
+	default:
+		return exprErr(p.createUnexpectedTokenErr("pattern"))
+	}
+}
+
+// parseRangePattern parses a range pattern from the range operator, start is nil for `..end`.
+func (p *Parser) parseRangePattern(start ast.Expr) *PatternResult {
+	operatorToken := p.advance()
+	var end ast.Expr
+	if !p.check(compiler.TokenTypeArrow, compiler.TokenTypeIf) && !p.isRangeEndOmitted() {
+		endResult := p.parsePatternLiteral()
+		if !endResult.Ok {
+			return patternErr(endResult.Err)
+		}
+		end = endResult.Unwrap()
+	}
+	rangeResult := p.createRangeExpr(start, operatorToken, end)
+	if !rangeResult.Ok {
+		return patternErr(rangeResult.Err)
+	}
+	rangeExpr := rangeResult.Unwrap().(*ast.RangeExpr)
+	return patternOk(&ast.RangePattern{
+		Span:      rangeExpr.Span,
+		Operator:  operatorToken,
+		Start:     start,
+		End:       end,
+		Inclusive: rangeExpr.Inclusive,
+	})
+}
+
+// parseTuplePattern parses `(p1, p2)`. A single pattern without
+// a trailing comma is only grouped, like in `(n @ 1..10)`.
+func (p *Parser) parseTuplePattern() *PatternResult {
+	openToken := p.advance()
+	p.pushLineBreakSensitive(false)
+	defer p.popLineBreakSensitive()
+
+	var elements []ast.Pattern
+	if !p.check(compiler.TokenTypeRightParen) {
+		firstResult := p.parsePattern()
+		if !firstResult.Ok {
+			return firstResult
+		}
+		if _, hasComma := p.match(compiler.TokenTypeComma); !hasComma {
+			closeResult := p.expect(compiler.TokenTypeRightParen, "',' or ')' after tuple element pattern")
+			if !closeResult.Ok {
+				return patternErr(closeResult.Err)
+			}
+			return firstResult
+		}
+		elements = append(elements, firstResult.Unwrap())
+	}
+	restResult := p.parsePatternList("tuple element pattern")
+	if !restResult.Ok {
+		return patternErr(restResult.Err)
+	}
+	return patternOk(&ast.TuplePattern{
+		Span:     ast.Span{Start: openToken.Pos, End: p.previous.End},
+		Elements: append(elements, restResult.Unwrap()...),
+	})
+}
+
+// parsePatternList parses comma separated patterns until the closing ')', a trailing
+// comma is allowed. The opening '(' should have been consumed.
+func (p *Parser) parsePatternList(description string) *shared.Result[[]ast.Pattern, *compiler.Diagnostic] {
+	p.pushLineBreakSensitive(false)
+	defer p.popLineBreakSensitive()
+
+	patterns := []ast.Pattern{}
+	for !p.check(compiler.TokenTypeRightParen) {
+		patternResult := p.parsePattern()
+		if !patternResult.Ok {
+			return shared.ResultErr[[]ast.Pattern](patternResult.Err)
+		}
+		patterns = append(patterns, patternResult.Unwrap())
+		if _, hasComma := p.match(compiler.TokenTypeComma); !hasComma {
+			break
+		}
+	}
+	closeResult := p.expect(compiler.TokenTypeRightParen, "',' or ')' after "+description)
+	if !closeResult.Ok {
+		return shared.ResultErr[[]ast.Pattern](closeResult.Err)
+	}
+	return shared.ResultOk[[]ast.Pattern, *compiler.Diagnostic](patterns)
+}
+
+// parsePathPattern parses the patterns starting with a name which may be
+// qualified, like `geometry.Point { x }`, `Option.Some(x)` and `Color.Red`.
+func (p *Parser) parsePathPattern() *PatternResult {
+	nameToken := p.advance()
+	var path ast.Expr = &ast.Identifier{Span: ast.SpanOfToken(nameToken), Name: nameToken.Content}
+	for p.check(compiler.TokenTypeDot) {
+		p.advance()
+		memberResult := p.parseIdentifier("variant or type name")
+		if !memberResult.Ok {
+			return patternErr(memberResult.Err)
+		}
+		path = &ast.MemberExpr{
+			Span:   ast.Span{Start: path.NodeSpan().Start, End: memberResult.Unwrap().End},
+			Target: path,
+			Name:   memberResult.Unwrap(),
+		}
+	}
+
+	switch p.peek().Type {
+	case compiler.TokenTypeLeftCurly:
+		return p.parseStructPattern(path)
+	case compiler.TokenTypeLeftParen:
+		p.advance()
+		elementsResult := p.parsePatternList("payload pattern")
+		if !elementsResult.Ok {
+			return patternErr(elementsResult.Err)
+		}
+		return patternOk(&ast.VariantPattern{
+			Span:     ast.Span{Start: path.NodeSpan().Start, End: p.previous.End},
+			Variant:  path,
+			Elements: elementsResult.Unwrap(),
+		})
+	default:
+		return patternOk(&ast.VariantPattern{Span: path.NodeSpan(), Variant: path})
+	}
+}
+
+// parseStructPattern parses the fields `{ field: pattern, shorthand }` of a struct pattern,
+// typeName is the name of the struct or of an enum variant with a struct payload.
+func (p *Parser) parseStructPattern(typeName ast.Expr) *PatternResult {
+	p.advance() // Moving over the '{'
+	structPattern := &ast.StructPattern{Type: typeName}
+	closeResult := p.parseMemberList(func() *compiler.Diagnostic {
+		nameResult := p.parseIdentifier("field name")
+		if !nameResult.Ok {
+			return nameResult.Err
+		}
+		field := &ast.FieldPattern{Span: nameResult.Unwrap().Span, Name: nameResult.Unwrap()}
+		if _, hasPattern := p.match(compiler.TokenTypeColon); hasPattern {
+			patternResult := p.parsePattern()
+			if !patternResult.Ok {
+				return patternResult.Err
+			}
+			field.Pattern = patternResult.Unwrap()
+			field.End = field.Pattern.NodeSpan().End
+		}
+		structPattern.Fields = append(structPattern.Fields, field)
+		return nil
+	})
+	if !closeResult.Ok {
+		return patternErr(closeResult.Err)
+	}
+	structPattern.Span = ast.Span{Start: typeName.NodeSpan().Start, End: closeResult.Unwrap().End}
+	return patternOk(structPattern)
+}
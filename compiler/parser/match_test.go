@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"fmt"
+	"mirth/compiler/ast"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func patternString(pattern ast.Pattern) string {
+	switch node := pattern.(type) {
+	case *ast.WildcardPattern:
+		return "_"
+	case *ast.LiteralPattern:
+		return toSExpr(node.Value)
+	case *ast.RangePattern:
+		bounds := []string{"", ""}
+		if node.Start != nil {
+			bounds[0] = toSExpr(node.Start)
+		}
+		if node.End != nil {
+			bounds[1] = toSExpr(node.End)
+		}
+		return bounds[0] + node.Operator.Content + bounds[1]
+	case *ast.BindingPattern:
+		if node.Pattern == nil {
+			return node.Name.Name
+		}
+		return node.Name.Name + " @ " + patternString(node.Pattern)
+	case *ast.TypePattern:
+		return node.Name.Name + ": " + typeString(node.Type)
+	case *ast.TuplePattern:
+		var elements []string
+		for _, element := range node.Elements {
+			elements = append(elements, patternString(element))
+		}
+		return fmt.Sprintf("(%s)", strings.Join(elements, ", "))
+	case *ast.StructPattern:
+		var fields []string
+		for _, field := range node.Fields {
+			if field.Pattern == nil {
+				fields = append(fields, field.Name.Name)
+			} else {
+				fields = append(fields, field.Name.Name+": "+patternString(field.Pattern))
+			}
+		}
+		return fmt.Sprintf("%s { %s }", toSExpr(node.Type), strings.Join(fields, ", "))
+	default:
+		return fmt.Sprintf("<%T>", pattern)
+	}
+}
+
+func TestParseMatch(t *testing.T) {
+	Convey("Test parse match expression with all the pattern kinds", t, func() {
+		file := parseFile(`let label = match value {
+  0 => "zero"
+  -1 => "minus one"
+  2..10 => "small"
+  'a'..='z' => "letter"
+  n @ 100.. if n % 2 == 0 => "big"
+  (_, x) => x,
+  geometry.Point { x: 0, y } => y
+  _ => { log(value); "other" }
+}`)
+		match := file.Statements[0].(*ast.VarDecl).Value.(*ast.MatchExpr)
+		So(toSExpr(match.Subject), ShouldEqual, "value")
+		var patterns []string
+		for _, arm := range match.Arms {
+			patterns = append(patterns, patternString(arm.Pattern))
+		}
+		So(patterns, ShouldResemble, []string{
+			"0", "(- 1)", "2..10", "a..=z", "n @ 100..", "(_, x)", "(. geometry Point) { x: 0, y }", "_",
+		})
+		So(toSExpr(match.Arms[4].Guard), ShouldEqual, "(== (% n 2) 0)")
+		So(toSExpr(match.Arms[5].Body.(ast.Expr)), ShouldEqual, "x")
+		So(len(match.Arms[7].Body.(*ast.BlockStmt).Statements), ShouldEqual, 2)
+		So(match.Arms[2].Pattern.(*ast.RangePattern).Inclusive, ShouldBeFalse)
+		So(match.Arms[3].Pattern.(*ast.RangePattern).Inclusive, ShouldBeTrue)
+		So(match.Start.Offset, ShouldEqual, 12)
+		So(match.End.Line, ShouldEqual, 10)
+	})
+}
+
+func TestParseTypeSwitch(t *testing.T) {
+	Convey("Test parse type assertions and type patterns", t, func() {
+		file := parseFile(`let area = shape.(Circle).radius
+let n = match shape {
+  c: Circle => c.radius
+  _: Square => 0.0
+  (s: Shape, _) => 1.0
+  other => 2.0
+}`)
+		area := file.Statements[0].(*ast.VarDecl).Value
+		So(toSExpr(area), ShouldEqual, "(. (.() shape Circle) radius)")
+		match := file.Statements[1].(*ast.VarDecl).Value.(*ast.MatchExpr)
+		var patterns []string
+		for _, arm := range match.Arms {
+			patterns = append(patterns, patternString(arm.Pattern))
+		}
+		So(patterns, ShouldResemble, []string{"c: Circle", "_: Square", "(s: Shape, _)", "other"})
+		So(match.Arms[0].Pattern.NodeSpan().End.Column, ShouldEqual, 12)
+
+		errors := parseErrors("let c = shape.(Circle")
+		So(errors[0].Msg, ShouldEqual, "Unexpected token: expected ')' after the asserted type, found end of file")
+	})
+}
+
+func TestParseTuple(t *testing.T) {
+	Convey("Test parse tuples and tuple types", t, func() {
+		file := parseFile("let pair: (int, string) = (1, name)\nlet single = (a,)\nlet grouped = (a)")
+		pair := file.Statements[0].(*ast.VarDecl)
+		So(len(pair.Type.(*ast.TupleType).Elements), ShouldEqual, 2)
+		So(len(pair.Value.(*ast.TupleLit).Elements), ShouldEqual, 2)
+		So(len(file.Statements[1].(*ast.VarDecl).Value.(*ast.TupleLit).Elements), ShouldEqual, 1)
+		So(file.Statements[2].(*ast.VarDecl).Value, ShouldHaveSameTypeAs, &ast.ParenExpr{})
+	})
+
+	Convey("Test parse match as a statement and in a tuple subject", t, func() {
+		file := parseFile("match (a, b) {\n  (true, _) => f()\n  (false, n @ 0) => g(n)\n}\nh()")
+		So(len(file.Statements), ShouldEqual, 2)
+		match := file.Statements[0].(*ast.ExprStmt).Expr.(*ast.MatchExpr)
+		So(match.Subject, ShouldHaveSameTypeAs, &ast.TupleLit{})
+		So(patternString(match.Arms[1].Pattern), ShouldEqual, "(false, n @ 0)")
+	})
+
+	Convey("Test report malformed match arms", t, func() {
+		errors := parseErrors("match a {\n  1 -> b\n}")
+		So(errors[0].Msg, ShouldEqual, "Unexpected token: expected '=>' after match pattern, found '-'")
+		errors = parseErrors("match a {\n  ..= => b\n}")
+		So(errors[0].Msg, ShouldEqual, "Inclusive range '..=' requires an end bound")
+	})
+}
+
+func TestParseEnum(t *testing.T) {
+	Convey("Test parse enum declarations", t, func() {
+		file := parseFile("enum Result<T, E> {\n  Ok(T)\n  Err(E),\n}\nenum Shape { Circle { radius: f64 }, Empty }\nenum Color { Red = 1, Green = 2 }")
+		result := file.Statements[0].(*ast.EnumDecl)
+		So(result.Name.Name, ShouldEqual, "Result")
+		So(len(result.TypeParams), ShouldEqual, 2)
+		So(typeString(result.Variants[1].Tuple[0]), ShouldEqual, "E")
+
+		shape := file.Statements[1].(*ast.EnumDecl)
+		So(shape.Variants[0].Fields[0].Name.Name, ShouldEqual, "radius")
+		So(shape.Variants[1].Tuple, ShouldBeNil)
+		So(shape.Variants[1].Fields, ShouldBeNil)
+
+		color := file.Statements[2].(*ast.EnumDecl)
+		So(toSExpr(color.Variants[1].Discriminant), ShouldEqual, "2")
+	})
+
+	Convey("Test parse variant constructors and patterns", t, func() {
+		file := parseFile(`let a = Option<int>.None
+let b = Shape.Circle { radius: 1.0 }
+match s {
+  Shape.Circle { radius } => radius
+  Option.Some((x, 1)) => x
+  Some(x) => x
+  Color.Red => 0
+}`)
+		member := file.Statements[0].(*ast.VarDecl).Value.(*ast.MemberExpr)
+		So(member.Target, ShouldHaveSameTypeAs, &ast.InstantiateExpr{})
+		So(file.Statements[1].(*ast.VarDecl).Value, ShouldHaveSameTypeAs, &ast.StructLit{})
+
+		arms := file.Statements[2].(*ast.ExprStmt).Expr.(*ast.MatchExpr).Arms
+		So(arms[0].Pattern, ShouldHaveSameTypeAs, &ast.StructPattern{})
+		some := arms[1].Pattern.(*ast.VariantPattern)
+		So(toSExpr(some.Variant), ShouldEqual, "(. Option Some)")
+		So(patternString(some.Elements[0]), ShouldEqual, "(x, 1)")
+		So(len(arms[2].Pattern.(*ast.VariantPattern).Elements), ShouldEqual, 1)
+		So(arms[3].Pattern.(*ast.VariantPattern).Elements, ShouldBeNil)
+	})
+}
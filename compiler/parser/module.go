@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/shared"
+)
+
+// parseImportDecl parses an import of a whole module, with an optional alias,
+// or a selective import of some declarations of the module:
+//
+//	import geometry.shapes
+//	import geometry.shapes as geo
+//	import geometry.shapes.{Circle, area as circleArea}
+func (p *Parser) parseImportDecl() *StmtResult {
+	importToken := p.advance()
+	importDecl := &ast.ImportDecl{}
+	for {
+		nameResult := p.parseIdentifier("module name")
+		if !nameResult.Ok {
+			return stmtErr(nameResult.Err)
+		}
+		importDecl.Path = append(importDecl.Path, nameResult.Unwrap())
+		if !p.check(compiler.TokenTypeDot) {
+			break
+		}
+		p.advance()
+		if p.check(compiler.TokenTypeLeftCurly) {
+			break
+		}
+	}
+	end := importDecl.Path[len(importDecl.Path)-1].End
+
+	switch {
+	case p.check(compiler.TokenTypeLeftCurly):
+		p.advance()
+		importDecl.Items = []*ast.ImportItem{}
+		closeResult := p.parseMemberList(func() *compiler.Diagnostic {
+			itemResult := p.parseImportItem()
+			if !itemResult.Ok {
+				return itemResult.Err
+			}
+			importDecl.Items = append(importDecl.Items, itemResult.Unwrap())
+			return nil
+		})
+		if !closeResult.Ok {
+			return stmtErr(closeResult.Err)
+		}
+		end = closeResult.Unwrap().End
+	case p.check(compiler.TokenTypeAs):
+		p.advance()
+		aliasResult := p.parseIdentifier("module alias")
+		if !aliasResult.Ok {
+			return stmtErr(aliasResult.Err)
+		}
+		importDecl.Alias = aliasResult.Unwrap()
+		end = importDecl.Alias.End
+	}
+	importDecl.Span = ast.Span{Start: importToken.Pos, End: end}
+	return stmtOk(importDecl)
+}
+
+func (p *Parser) parseImportItem() *shared.Result[*ast.ImportItem, *compiler.Diagnostic] {
+	nameResult := p.parseIdentifier("imported name")
+	if !nameResult.Ok {
+		return shared.ResultErr[*ast.ImportItem](nameResult.Err)
+	}
+	item := &ast.ImportItem{Span: nameResult.Unwrap().Span, Name: nameResult.Unwrap()}
+	if _, hasAlias := p.match(compiler.TokenTypeAs); hasAlias {
+		aliasResult := p.parseIdentifier("import alias")
+		if !aliasResult.Ok {
+			return shared.ResultErr[*ast.ImportItem](aliasResult.Err)
+		}
+		item.Alias = aliasResult.Unwrap()
+		item.End = item.Alias.End
+	}
+	return shared.ResultOk[*ast.ImportItem, *compiler.Diagnostic](item)
+}
+
+// parsePubDecl parses a public declaration like `pub func area()`,
+// the span of the declaration starts at `pub`.
+func (p *Parser) parsePubDecl() *StmtResult {
+	pubToken := p.advance()
+	var declResult *StmtResult
+	switch p.peek().Type {
+	case compiler.TokenTypeLet, compiler.TokenTypeConst, compiler.TokenTypeStruct,
+		compiler.TokenTypeInterface, compiler.TokenTypeEnum:
+		declResult = p.parseStatement()
+	case compiler.TokenTypeFunc:
+		if p.peekAt(1).Type == compiler.TokenTypeIdentifier || p.isMethodDeclAhead() {
+			declResult = p.parseFuncDecl()
+		}
+	}
+	if declResult == nil {
+		return stmtErr(p.createUnexpectedTokenErr("declaration after 'pub'"))
+	}
+	if !declResult.Ok {
+		return declResult
+	}
+
+	switch decl := declResult.Unwrap().(type) {
+	case *ast.VarDecl:
+		decl.Public, decl.Start = true, pubToken.Pos
+	case *ast.FuncDecl:
+		decl.Public, decl.Start = true, pubToken.Pos
+	case *ast.StructDecl:
+		decl.Public, decl.Start = true, pubToken.Pos
+	case *ast.InterfaceDecl:
+		decl.Public, decl.Start = true, pubToken.Pos
+	case *ast.EnumDecl:
+		decl.Public, decl.Start = true, pubToken.Pos
+	}
+	return declResult
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseImport(t *testing.T) {
+	Convey("Test parse imports", t, func() {
+		file := parseFile("import geometry.shapes\nimport geometry.shapes as geo\nimport geometry.shapes.{\n  Circle,\n  area as circleArea\n}")
+		whole := file.Statements[0].(*ast.ImportDecl)
+		So(whole.PathString(), ShouldEqual, "geometry.shapes")
+		So(whole.Items, ShouldBeNil)
+		So(whole.Binding().Name, ShouldEqual, "shapes")
+
+		aliased := file.Statements[1].(*ast.ImportDecl)
+		So(aliased.Binding().Name, ShouldEqual, "geo")
+		So(aliased.End.Offset, ShouldEqual, 52)
+
+		selective := file.Statements[2].(*ast.ImportDecl)
+		So(selective.PathString(), ShouldEqual, "geometry.shapes")
+		So(selective.Binding(), ShouldBeNil)
+		So(len(selective.Items), ShouldEqual, 2)
+		So(selective.Items[0].Binding().Name, ShouldEqual, "Circle")
+		So(selective.Items[1].Name.Name, ShouldEqual, "area")
+		So(selective.Items[1].Binding().Name, ShouldEqual, "circleArea")
+	})
+
+	Convey("Test parse import errors", t, func() {
+		diagnostics := parseErrors("import geometry.\nimport a as")
+		So(len(diagnostics), ShouldEqual, 2)
+		So(diagnostics[0].Msg, ShouldEqual, "Unexpected token: expected module name, found line break")
+		So(diagnostics[1].Msg, ShouldEqual, "Unexpected token: expected module alias, found end of file")
+	})
+}
+
+func TestParsePub(t *testing.T) {
+	Convey("Test parse public declarations", t, func() {
+		file := parseFile("pub func area(r: f64) f64 { return r * r }\npub const pi = 3.14\nstruct Circle {}\npub enum Shape { Circle }")
+		area := file.Statements[0].(*ast.FuncDecl)
+		So(area.Public, ShouldBeTrue)
+		So(area.Start.Offset, ShouldEqual, 0)
+
+		decls := []ast.Decl{}
+		for _, stmt := range file.Statements {
+			decls = append(decls, stmt.(ast.Decl))
+		}
+		So(decls[1].IsPublic(), ShouldBeTrue)
+		So(decls[1].DeclName().Name, ShouldEqual, "pi")
+		So(decls[2].IsPublic(), ShouldBeFalse)
+		So(decls[3].IsPublic(), ShouldBeTrue)
+	})
+
+	Convey("Test parse pub without a declaration", t, func() {
+		diagnostics := parseErrors("pub x = 1\npub func() {}")
+		So(len(diagnostics), ShouldEqual, 2)
+		So(diagnostics[0].Code, ShouldEqual, compiler.UnexpectedToken)
+		So(diagnostics[0].Msg, ShouldEqual, "Unexpected token: expected declaration after 'pub', found 'x'")
+		So(diagnostics[1].Msg, ShouldEqual, "Unexpected token: expected declaration after 'pub', found 'func'")
+	})
+}
@@ -0,0 +1,237 @@
+package parser
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/shared"
+	"sort"
+)
+
+type Parser struct {
+	tokens    []*compiler.Token // Always ends with an end-of-file token
+	position  int               // Index of the next token to consume
+	previous  *compiler.Token   // The last consumed token
+	operators *compiler.OperatorTable
+
+	// Line breaks separate statements, but they are ignored inside
+	// parentheses and brackets. The top of this stack tells whether
+	// line breaks are significant at the current position.
+	lineBreakSensitive []bool
+	// Struct literals are not allowed in the headers of `if` and `for`,
+	// so that `if x == y { }` isn't parsed as a struct literal `y { }`.
+	structLiteralAllowed bool
+
+	diagnostics []*compiler.Diagnostic
+	lastError   *compiler.Diagnostic // The last syntax error reported while recovering
+}
+
+type ExprResult = shared.Result[ast.Expr, *compiler.Diagnostic]
+type ExprListResult = shared.Result[[]ast.Expr, *compiler.Diagnostic]
+type TokenResult = shared.Result[*compiler.Token, *compiler.Diagnostic]
+
+// CreateParser scans the whole source with the builtin operators,
+// the scanning diagnostics are kept in the parser's diagnostics.
+func CreateParser[S compiler.AvailableSource](source S) *Parser {
+	tokens, diagnostics := compiler.CreateScanner(source).Tokenize()
+	parser := CreateParserFromTokens(tokens, nil)
+	parser.diagnostics = diagnostics
+	return parser
+}
+
+// CreateParserFromTokens creates a parser over scanned tokens.
+// The operator table should be the one used by the scanner,
+// so that the parser knows the precedence of custom operators.
+func CreateParserFromTokens(tokens []*compiler.Token, operators *compiler.OperatorTable) *Parser {
+	if operators == nil {
+		operators = compiler.CreateOperatorTable()
+	}
+	if len(tokens) == 0 || tokens[len(tokens)-1].Type != compiler.TokenTypeEndOfFile {
+		endPosition := compiler.CreatePositon(0, 1, 1)
+		if len(tokens) > 0 {
+			endPosition = tokens[len(tokens)-1].End
+		}
+		tokens = append(tokens, &compiler.Token{
+			Type:    compiler.TokenTypeEndOfFile,
+			Pos:     endPosition,
+			End:     endPosition,
+			Content: "",
+		})
+	}
+	return &Parser{
+		tokens:             tokens,
+		operators:          operators,
+		lineBreakSensitive: []bool{true},
+
+		structLiteralAllowed: true,
+	}
+}
+
+// Diagnostics returns the scanning diagnostics and the syntax errors
+// the parser has recovered from, sorted by position.
+func (p *Parser) Diagnostics() []*compiler.Diagnostic {
+	sort.SliceStable(p.diagnostics, func(i, j int) bool {
+		return p.diagnostics[i].Pos.Offset < p.diagnostics[j].Pos.Offset
+	})
+	return p.diagnostics
+}
+
+func (p *Parser) isLineBreakSensitive() bool {
+	return p.lineBreakSensitive[len(p.lineBreakSensitive)-1]
+}
+
+func (p *Parser) pushLineBreakSensitive(sensitive bool) {
+	p.lineBreakSensitive = append(p.lineBreakSensitive, sensitive)
+}
+
+func (p *Parser) popLineBreakSensitive() {
+	p.lineBreakSensitive = p.lineBreakSensitive[:len(p.lineBreakSensitive)-1]
+}
+
+// allowStructLiteral sets whether struct literals are allowed, and returns
+// a function restoring the previous setting.
+func (p *Parser) allowStructLiteral(allowed bool) func() {
+	saved := p.structLiteralAllowed
+	p.structLiteralAllowed = allowed
+	return func() {
+		p.structLiteralAllowed = saved
+	}
+}
+
+func (p *Parser) isSkippable(token *compiler.Token) bool {
+	return token.Type == compiler.TokenTypeLineComment ||
+		(token.Type == compiler.TokenTypeLineBreak && !p.isLineBreakSensitive())
+}
+
+// nextIndex returns the index of the next meaningful token, skipping
+// comments, and line breaks where they are not significant.
+func (p *Parser) nextIndex(from int) int {
+	index := from
+	for index < len(p.tokens)-1 && p.isSkippable(p.tokens[index]) {
+		index++
+	}
+	return index
+}
+
+func (p *Parser) peek() *compiler.Token {
+	return p.tokens[p.nextIndex(p.position)]
+}
+
+// peekAt looks forward n meaningful tokens, peekAt(0) is the same as peek().
+func (p *Parser) peekAt(n int) *compiler.Token {
+	index := p.nextIndex(p.position)
+	for i := 0; i < n && index < len(p.tokens)-1; i++ {
+		index = p.nextIndex(index + 1)
+	}
+	return p.tokens[index]
+}
+
+func (p *Parser) advance() *compiler.Token {
+	index := p.nextIndex(p.position)
+	token := p.tokens[index]
+	if token.Type != compiler.TokenTypeEndOfFile {
+		p.position = index + 1
+	}
+	p.previous = token
+	return token
+}
+
+func (p *Parser) check(tokenTypes ...compiler.TokenType) bool {
+	next := p.peek().Type
+	for _, tokenType := range tokenTypes {
+		if next == tokenType {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Parser) match(tokenTypes ...compiler.TokenType) (*compiler.Token, bool) {
+	if p.check(tokenTypes...) {
+		return p.advance(), true
+	}
+	return nil, false
+}
+
+func (p *Parser) skipLineBreaks() {
+	for p.tokens[p.position].Type == compiler.TokenTypeLineBreak ||
+		p.tokens[p.position].Type == compiler.TokenTypeLineComment {
+		p.position++
+	}
+}
+
+func (p *Parser) expect(tokenType compiler.TokenType, description string) *TokenResult {
+	if token, ok := p.match(tokenType); ok {
+		return shared.ResultOk[*compiler.Token, *compiler.Diagnostic](token)
+	}
+	return shared.ResultErr[*compiler.Token](
+		p.createUnexpectedTokenErr(description),
+	)
+}
+
+func describeToken(token *compiler.Token) string {
+	switch token.Type {
+	case compiler.TokenTypeEndOfFile:
+		return "end of file"
+	case compiler.TokenTypeLineBreak:
+		return "line break"
+	case compiler.TokenTypeString, compiler.TokenTypeTemplateStrFragment:
+		return "string literal"
+	case compiler.TokenTypeRune:
+		return "rune literal"
+	case compiler.TokenTypeByte:
+		return "byte literal"
+	case compiler.TokenTypeByteString:
+		return "byte string literal"
+	default:
+		return fmt.Sprintf("'%s'", token.Content)
+	}
+}
+
+// describeTokenType describes a kind of token in diagnostics, like `'{'` and `identifier`.
+func describeTokenType(tokenType compiler.TokenType) string {
+	for symbol, operatorTokenType := range compiler.OperatorTokenMap {
+		if operatorTokenType == tokenType {
+			return fmt.Sprintf("'%s'", symbol)
+		}
+	}
+	for keyword, keywordTokenType := range compiler.KeywordTokenMap {
+		if keywordTokenType == tokenType {
+			return fmt.Sprintf("'%s'", keyword)
+		}
+	}
+	switch tokenType {
+	case compiler.TokenTypeIdentifier:
+		return "identifier"
+	case compiler.TokenTypeLineBreak:
+		return "line break"
+	case compiler.TokenTypeEndOfFile:
+		return "end of file"
+	default:
+		return tokenType.String()
+	}
+}
+
+// createParseErr creates an error spanning the offending token.
+func (p *Parser) createParseErr(code compiler.DiagnosticCode, token *compiler.Token, message string) *compiler.Diagnostic {
+	diagnostic := compiler.CreateErrorDiagnostic(code, token.Pos, message)
+	diagnostic.End = token.End
+	return diagnostic
+}
+
+func (p *Parser) createUnexpectedTokenErr(expected string) *compiler.Diagnostic {
+	found := p.peek()
+	return p.createParseErr(
+		compiler.UnexpectedToken,
+		found,
+		fmt.Sprintf("Unexpected token: expected %s, found %s", expected, describeToken(found)),
+	)
+}
+
+func exprOk(expr ast.Expr) *ExprResult {
+	return &ExprResult{Value: expr, Ok: true}
+}
+
+func exprErr(err *compiler.Diagnostic) *ExprResult {
+	return &ExprResult{Err: err}
+}
@@ -0,0 +1,88 @@
+package parser
+
+import "mirth/compiler"
+
+type infixOperator struct {
+	precedence    compiler.Precedence
+	associativity compiler.Associativity
+}
+
+// infixOperators is the precedence table of the builtin infix operators,
+// from the loosest to the tightest binding:
+//
+//	Precedence                Operators                                Associativity
+//	PrecedenceAssignment      = += -= *= **= /= %= <<= >>= &= |= ^=    right
+//	PrecedenceNullish         ??                                       right
+//	PrecedenceRange           .. ..=                                   none
+//	PrecedenceLogicalOr       ||                                       left
+//	PrecedenceLogicalAnd      &&                                       left
+//	PrecedenceEquality        == !=                                    left
+//	PrecedenceComparison      < > <= >=                                none
+//	PrecedenceBitwiseOr       |                                        left
+//	PrecedenceBitwiseXor      ^                                        left
+//	PrecedenceBitwiseAnd      &                                        left
+//	PrecedenceShift           << >>                                    left
+//	PrecedenceAdditive        + -                                      left
+//	PrecedenceMultiplicative  * / %                                    left
+//	PrecedenceExponent        **                                       right
+//	PrecedencePrefix          ! ~ - ++ --  (prefix)
+//	PrecedencePostfix         ++ --  (postfix), call (), index [], member . ?.
+//
+// Custom operators take the precedence and associativity they are declared with.
+// Non-associative operators can't be chained, so `a < b < c` is rejected.
+// Ranges bind looser than the arithmetic and logical operators, so `0..n + 1`
+// is parsed as `0..(n + 1)`, and their bounds may be omitted, like `xs[2..]`.
+// The prefix operators `!`, `~` and `-` bind looser than `**`,
+// so `-x ** 2` is parsed as `-(x ** 2)`.
+var infixOperators = map[compiler.TokenType]infixOperator{
+	compiler.TokenTypeEqual:                 {compiler.PrecedenceAssignment, compiler.AssociativityRight},
+	compiler.TokenTypePlusEqual:             {compiler.PrecedenceAssignment, compiler.AssociativityRight},
+	compiler.TokenTypeMinusEqual:            {compiler.PrecedenceAssignment, compiler.AssociativityRight},
+	compiler.TokenTypeStarEqual:             {compiler.PrecedenceAssignment, compiler.AssociativityRight},
+	compiler.TokenTypeDoubleStarEqual:       {compiler.PrecedenceAssignment, compiler.AssociativityRight},
+	compiler.TokenTypeSlashEqual:            {compiler.PrecedenceAssignment, compiler.AssociativityRight},
+	compiler.TokenTypePercentEqual:          {compiler.PrecedenceAssignment, compiler.AssociativityRight},
+	compiler.TokenTypeDoubleLeftAngleEqual:  {compiler.PrecedenceAssignment, compiler.AssociativityRight},
+	compiler.TokenTypeDoubleRightAngleEqual: {compiler.PrecedenceAssignment, compiler.AssociativityRight},
+	compiler.TokenTypeAmpersandEqual:        {compiler.PrecedenceAssignment, compiler.AssociativityRight},
+	compiler.TokenTypeVerticalEqual:         {compiler.PrecedenceAssignment, compiler.AssociativityRight},
+	compiler.TokenTypeCaretEqual:            {compiler.PrecedenceAssignment, compiler.AssociativityRight},
+	compiler.TokenTypeDoubleQuestion:        {compiler.PrecedenceNullish, compiler.AssociativityRight},
+	compiler.TokenTypeDoubleDots:            {compiler.PrecedenceRange, compiler.AssociativityNone},
+	compiler.TokenTypeDoubleDotsEqual:       {compiler.PrecedenceRange, compiler.AssociativityNone},
+	compiler.TokenTypeDoubleVertical:        {compiler.PrecedenceLogicalOr, compiler.AssociativityLeft},
+	compiler.TokenTypeDoubleAmpersand:       {compiler.PrecedenceLogicalAnd, compiler.AssociativityLeft},
+	compiler.TokenTypeDoubleEqual:           {compiler.PrecedenceEquality, compiler.AssociativityLeft},
+	compiler.TokenTypeBangEqual:             {compiler.PrecedenceEquality, compiler.AssociativityLeft},
+	compiler.TokenTypeLeftAngle:             {compiler.PrecedenceComparison, compiler.AssociativityNone},
+	compiler.TokenTypeRightAngle:            {compiler.PrecedenceComparison, compiler.AssociativityNone},
+	compiler.TokenTypeLeftAngleEqual:        {compiler.PrecedenceComparison, compiler.AssociativityNone},
+	compiler.TokenTypeRightAngleEqual:       {compiler.PrecedenceComparison, compiler.AssociativityNone},
+	compiler.TokenTypeVertical:              {compiler.PrecedenceBitwiseOr, compiler.AssociativityLeft},
+	compiler.TokenTypeCaret:                 {compiler.PrecedenceBitwiseXor, compiler.AssociativityLeft},
+	compiler.TokenTypeAmpersand:             {compiler.PrecedenceBitwiseAnd, compiler.AssociativityLeft},
+	compiler.TokenTypeDoubleLeftAngle:       {compiler.PrecedenceShift, compiler.AssociativityLeft},
+	compiler.TokenTypeDoubleRightAngle:      {compiler.PrecedenceShift, compiler.AssociativityLeft},
+	compiler.TokenTypePlus:                  {compiler.PrecedenceAdditive, compiler.AssociativityLeft},
+	compiler.TokenTypeMinus:                 {compiler.PrecedenceAdditive, compiler.AssociativityLeft},
+	compiler.TokenTypeStar:                  {compiler.PrecedenceMultiplicative, compiler.AssociativityLeft},
+	compiler.TokenTypeSlash:                 {compiler.PrecedenceMultiplicative, compiler.AssociativityLeft},
+	compiler.TokenTypePercent:               {compiler.PrecedenceMultiplicative, compiler.AssociativityLeft},
+	compiler.TokenTypeDoubleStar:            {compiler.PrecedenceExponent, compiler.AssociativityRight},
+}
+
+// Operators parsed by parsePrefix, their operand binds as tight as `**`.
+var prefixOperators = map[compiler.TokenType]bool{
+	compiler.TokenTypeBang:  true,
+	compiler.TokenTypeWavy:  true,
+	compiler.TokenTypeMinus: true,
+}
+
+func isAssignmentOperator(tokenType compiler.TokenType) bool {
+	operator, isInfix := infixOperators[tokenType]
+	return isInfix && operator.precedence == compiler.PrecedenceAssignment
+}
+
+func isRangeOperator(tokenType compiler.TokenType) bool {
+	return tokenType == compiler.TokenTypeDoubleDots || tokenType == compiler.TokenTypeDoubleDotsEqual
+}
@@ -0,0 +1,140 @@
+package parser
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"strings"
+)
+
+// The parser recovers from syntax errors in panic mode: the error is reported,
+// the tokens are skipped until a synchronization point, and the skipped source
+// is replaced by a BadStmt or a BadExpr, so that later phases still get a tree.
+//
+// Synchronization points are the statement terminators (line breaks and ';'),
+// closing curly braces and the keywords starting a declaration. Brackets opened
+// in the skipped tokens are skipped as a whole, so recovering from `f(a b, {c})`
+// doesn't stop inside the parentheses, and the braces the failed statement opened
+// before the error are skipped up to their closing brace, so that recovering from
+// `S{x: 1 y: 2}` doesn't leave a stray '}'.
+
+// Keywords where a new statement surely starts.
+var syncKeywords = map[compiler.TokenType]bool{
+	compiler.TokenTypeLet:       true,
+	compiler.TokenTypeConst:     true,
+	compiler.TokenTypeFunc:      true,
+	compiler.TokenTypeStruct:    true,
+	compiler.TokenTypeInterface: true,
+	compiler.TokenTypeEnum:      true,
+	compiler.TokenTypeImport:    true,
+	compiler.TokenTypePub:       true,
+}
+
+// parserState is the context which a failed parse may leave unbalanced.
+type parserState struct {
+	lineBreakSensitiveDepth int
+	structLiteralAllowed    bool
+}
+
+func (p *Parser) saveState() parserState {
+	return parserState{len(p.lineBreakSensitive), p.structLiteralAllowed}
+}
+
+func (p *Parser) restoreState(state parserState) {
+	p.lineBreakSensitive = p.lineBreakSensitive[:state.lineBreakSensitiveDepth]
+	p.structLiteralAllowed = state.structLiteralAllowed
+}
+
+// reportError records a syntax error, unless an error was already reported
+// at the same position, which is a cascade of the recovery from that error.
+func (p *Parser) reportError(diagnostic *compiler.Diagnostic) {
+	if p.lastError != nil && p.lastError.Pos.Offset == diagnostic.Pos.Offset {
+		return
+	}
+	p.lastError = diagnostic
+	p.diagnostics = append(p.diagnostics, diagnostic)
+}
+
+// synchronize skips tokens until a synchronization point, which is not consumed,
+// outside the depth of brackets which are already open.
+func (p *Parser) synchronize(depth int) {
+	for ; p.position < len(p.tokens)-1; p.position++ {
+		token := p.tokens[p.position]
+		isSyncPoint := syncKeywords[token.Type] ||
+			token.Type == compiler.TokenTypeLineBreak ||
+			token.Type == compiler.TokenTypeSemi ||
+			token.Type == compiler.TokenTypeRightCurly
+		if depth == 0 && isSyncPoint {
+			return
+		}
+
+		switch token.Type {
+		case compiler.TokenTypeLeftParen, compiler.TokenTypeLeftBracket, compiler.TokenTypeLeftCurly:
+			depth++
+		case compiler.TokenTypeRightParen, compiler.TokenTypeRightBracket, compiler.TokenTypeRightCurly:
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+}
+
+// openedSince returns how many brackets the tokens consumed since the start index
+// left open.
+func (p *Parser) openedSince(start int) int {
+	depth := 0
+	for i := start; i < p.position && i < len(p.tokens); i++ {
+		switch p.tokens[i].Type {
+		case compiler.TokenTypeLeftParen, compiler.TokenTypeLeftBracket, compiler.TokenTypeLeftCurly:
+			depth++
+		case compiler.TokenTypeRightParen, compiler.TokenTypeRightBracket, compiler.TokenTypeRightCurly:
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	return depth
+}
+
+// spanSince returns the span of the tokens consumed since the start index.
+func (p *Parser) spanSince(start int) ast.Span {
+	startPosition := p.tokens[start].Pos
+	if p.position <= start {
+		return ast.Span{Start: startPosition, End: startPosition}
+	}
+	return ast.Span{Start: startPosition, End: p.tokens[p.position-1].End}
+}
+
+// recoverStatement reports the error of the statement which started at the
+// start index, and replaces the statement with a BadStmt up to the next
+// synchronization point after the brackets it opened.
+func (p *Parser) recoverStatement(start int, state parserState, err *compiler.Diagnostic) *ast.BadStmt {
+	p.restoreState(state)
+	p.reportError(err)
+	if p.position <= start && start < len(p.tokens)-1 {
+		// Always skip the first token, even if it's a synchronization point
+		// like a stray '}', so that the parser makes progress
+		p.position = start + 1
+	}
+	p.synchronize(p.openedSince(start))
+	return &ast.BadStmt{Span: p.spanSince(start)}
+}
+
+// recoverExpr is like recoverStatement, but replaces an expression with a BadExpr.
+func (p *Parser) recoverExpr(start int, state parserState, err *compiler.Diagnostic) *ast.BadExpr {
+	p.restoreState(state)
+	p.reportError(err)
+	p.synchronize(p.openedSince(start))
+	return &ast.BadExpr{Span: p.spanSince(start)}
+}
+
+// describeTokenTypes lists a set of expected tokens, like `',', ')' or ']'`.
+func describeTokenTypes(tokenTypes ...compiler.TokenType) string {
+	descriptions := make([]string, len(tokenTypes))
+	for i, tokenType := range tokenTypes {
+		descriptions[i] = describeTokenType(tokenType)
+	}
+	if len(descriptions) <= 1 {
+		return strings.Join(descriptions, "")
+	}
+	return strings.Join(descriptions[:len(descriptions)-1], ", ") + " or " + descriptions[len(descriptions)-1]
+}
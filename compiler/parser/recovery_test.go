@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseErrorRecovery(t *testing.T) {
+	Convey("Test recover from several syntax errors", t, func() {
+		parser := CreateParser(`let a = 1 +
+let b = 2
+foo(a b, {c})
+func f() { let = 1; return 2 }
+}
+let c = 3`)
+		file := parser.ParseFile().Unwrap()
+
+		var messages []string
+		for _, diagnostic := range parser.Diagnostics() {
+			So(diagnostic.Code, ShouldEqual, compiler.UnexpectedToken)
+			messages = append(messages, diagnostic.Pos.String()+" "+diagnostic.Msg)
+		}
+		So(messages, ShouldResemble, []string{
+			"2:1 Unexpected token: expected expression, found 'let'",
+			"3:7 Unexpected token: expected ',' or ')' after argument, found 'b'",
+			"4:16 Unexpected token: expected variable name, found '='",
+			"5:1 Unexpected token: expected expression, found '}'",
+		})
+
+		So(len(file.Statements), ShouldEqual, 6)
+		a := file.Statements[0].(*ast.VarDecl)
+		So(a.Name.Name, ShouldEqual, "a")
+		So(a.Value, ShouldHaveSameTypeAs, &ast.BadExpr{})
+		So(file.Statements[1].(*ast.VarDecl).Name.Name, ShouldEqual, "b")
+
+		badCall := file.Statements[2].(*ast.BadStmt)
+		So(badCall.Start.Line, ShouldEqual, 3)
+		So(badCall.Start.Column, ShouldEqual, 1)
+		So(badCall.End.Column, ShouldEqual, 14)
+
+		body := file.Statements[3].(*ast.FuncDecl).Body
+		So(body.Statements[0], ShouldHaveSameTypeAs, &ast.BadStmt{})
+		So(body.Statements[1], ShouldHaveSameTypeAs, &ast.ReturnStmt{})
+
+		So(file.Statements[4], ShouldHaveSameTypeAs, &ast.BadStmt{})
+		So(file.Statements[5].(*ast.VarDecl).Name.Name, ShouldEqual, "c")
+	})
+
+	Convey("Test list the expected tokens", t, func() {
+		diagnostics := parseErrors("{ a b }\nstruct S { x: int y: int }")
+		So(diagnostics[0].Msg, ShouldEqual, "Unexpected token: expected line break, ';' or '}' after statement, found 'b'")
+		So(diagnostics[1].Msg, ShouldEqual, "Unexpected token: expected line break, ',' or '}' after member, found 'y'")
+		So(parseErrors("struct S { x int }")[0].Msg, ShouldEqual, "Unexpected token: expected ':', line break, ',' or '}' after member, found 'int'")
+	})
+
+	Convey("Test skip the braces the failed statement opened", t, func() {
+		diagnostics := parseErrors(`struct S { x int }
+let a = 1
+let d = S{x: 1 y: 2}
+let e = 3
+func f() {
+  let s = S{x: 1 y: 2}
+  return
+}
+let g = 4`)
+		So(len(diagnostics), ShouldEqual, 3)
+		So(diagnostics[0].Pos.String(), ShouldEqual, "1:14")
+		So(diagnostics[1].Pos.String(), ShouldEqual, "3:16")
+		So(diagnostics[2].Pos.String(), ShouldEqual, "6:18")
+
+		parser := CreateParser("let d = S{x: 1 y: 2}\nlet e = 3")
+		file := parser.ParseFile().Unwrap()
+		So(len(parser.Diagnostics()), ShouldEqual, 1)
+		So(len(file.Statements), ShouldEqual, 2)
+		So(file.Statements[1].(*ast.VarDecl).Name.Name, ShouldEqual, "e")
+	})
+
+	Convey("Test span the offending token", t, func() {
+		diagnostics := parseErrors("let x = 1 + return")
+		So(len(diagnostics), ShouldEqual, 1)
+		So(diagnostics[0].Pos.Offset, ShouldEqual, 12)
+		So(diagnostics[0].End.Offset, ShouldEqual, 18)
+	})
+}
@@ -0,0 +1,412 @@
+package parser
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/shared"
+)
+
+type StmtResult = shared.Result[ast.Stmt, *compiler.Diagnostic]
+type StmtListResult = shared.Result[[]ast.Stmt, *compiler.Diagnostic]
+type BlockResult = shared.Result[*ast.BlockStmt, *compiler.Diagnostic]
+type FileResult = shared.Result[*ast.File, *compiler.Diagnostic]
+
+func stmtOk(stmt ast.Stmt) *StmtResult {
+	return &StmtResult{Value: stmt, Ok: true}
+}
+
+func stmtErr(err *compiler.Diagnostic) *StmtResult {
+	return &StmtResult{Err: err}
+}
+
+// ParseFile parses all the statements of a source file. It always returns
+// the file, the statements with syntax errors are replaced by BadStmt nodes
+// and the errors are recorded in Diagnostics().
+func (p *Parser) ParseFile() *FileResult {
+	start := p.tokens[0].Pos
+	statementsResult := p.parseStatementList(compiler.TokenTypeEndOfFile)
+	if !statementsResult.Ok {
+		return &FileResult{Err: statementsResult.Err}
+	}
+	return &FileResult{
+		Value: &ast.File{
+			Span:       ast.Span{Start: start, End: p.peek().End},
+			Statements: statementsResult.Unwrap(),
+		},
+		Ok: true,
+	}
+}
+
+func (p *Parser) isStatementEnd() bool {
+	return p.check(
+		compiler.TokenTypeLineBreak,
+		compiler.TokenTypeSemi,
+		compiler.TokenTypeRightCurly,
+		compiler.TokenTypeEndOfFile,
+	)
+}
+
+func (p *Parser) skipStatementSeparators() {
+	for p.check(compiler.TokenTypeLineBreak, compiler.TokenTypeSemi) {
+		p.advance()
+	}
+}
+
+// parseStatementList parses statements until the closing token, which is not consumed.
+// Statements are separated by line breaks or semicolons. A statement with syntax
+// errors is reported and replaced by a BadStmt, then parsing goes on with the next one.
+func (p *Parser) parseStatementList(closing compiler.TokenType) *StmtListResult {
+	separators := []compiler.TokenType{compiler.TokenTypeLineBreak, compiler.TokenTypeSemi}
+	if closing != compiler.TokenTypeEndOfFile {
+		separators = append(separators, closing)
+	}
+
+	var statements []ast.Stmt
+	for {
+		p.skipStatementSeparators()
+		if p.check(closing, compiler.TokenTypeEndOfFile) {
+			return &StmtListResult{Value: statements, Ok: true}
+		}
+
+		start := p.nextIndex(p.position)
+		state := p.saveState()
+		stmtResult := p.parseStatement()
+		if !stmtResult.Ok {
+			statements = append(statements, p.recoverStatement(start, state, stmtResult.Err))
+			continue
+		}
+		statements = append(statements, stmtResult.Unwrap())
+
+		if !p.isStatementEnd() {
+			p.reportError(p.createUnexpectedTokenErr(describeTokenTypes(separators...) + " after statement"))
+			p.synchronize(0)
+		}
+	}
+}
+
+func (p *Parser) parseStatement() *StmtResult {
+	token := p.peek()
+	switch token.Type {
+	case compiler.TokenTypeLet, compiler.TokenTypeConst:
+		return p.parseVarDecl()
+	case compiler.TokenTypeIf:
+		return p.parseIf()
+	case compiler.TokenTypeFor:
+		return p.parseFor(nil)
+	case compiler.TokenTypeLoop:
+		return p.parseLoop(nil)
+	case compiler.TokenTypeBreak, compiler.TokenTypeContinue:
+		return p.parseJump()
+	case compiler.TokenTypeReturn:
+		return p.parseReturn()
+	case compiler.TokenTypeFunc:
+		// `func name()` and `func (receiver: Struct) name()` are declarations,
+		// while `func()` is an anonymous function
+		if p.peekAt(1).Type == compiler.TokenTypeIdentifier || p.isMethodDeclAhead() {
+			return p.parseFuncDecl()
+		}
+	case compiler.TokenTypeStruct:
+		return p.parseStructDecl()
+	case compiler.TokenTypeInterface:
+		return p.parseInterfaceDecl()
+	case compiler.TokenTypeEnum:
+		return p.parseEnumDecl()
+	case compiler.TokenTypeImport:
+		return p.parseImportDecl()
+	case compiler.TokenTypePub:
+		return p.parsePubDecl()
+	case compiler.TokenTypeLeftCurly:
+		blockResult := p.parseBlock()
+		if !blockResult.Ok {
+			return stmtErr(blockResult.Err)
+		}
+		return stmtOk(blockResult.Unwrap())
+	case compiler.TokenTypeIdentifier:
+		// A label before a loop, like `outer: for ...`
+		if p.peekAt(1).Type == compiler.TokenTypeColon &&
+			(p.peekAt(2).Type == compiler.TokenTypeFor || p.peekAt(2).Type == compiler.TokenTypeLoop) {
+			return p.parseLabeledLoop()
+		}
+	}
+
+	exprResult := p.ParseExpression()
+	if !exprResult.Ok {
+		return stmtErr(exprResult.Err)
+	}
+	expr := exprResult.Unwrap()
+	return stmtOk(&ast.ExprStmt{Span: expr.NodeSpan(), Expr: expr})
+}
+
+func (p *Parser) parseBlock() *BlockResult {
+	openResult := p.expect(compiler.TokenTypeLeftCurly, "'{'")
+	if !openResult.Ok {
+		return &BlockResult{Err: openResult.Err}
+	}
+	defer p.allowStructLiteral(true)()
+	p.pushLineBreakSensitive(true)
+	statementsResult := p.parseStatementList(compiler.TokenTypeRightCurly)
+	if !statementsResult.Ok {
+		p.popLineBreakSensitive()
+		return &BlockResult{Err: statementsResult.Err}
+	}
+	closeResult := p.expect(compiler.TokenTypeRightCurly, "'}'")
+	p.popLineBreakSensitive()
+	if !closeResult.Ok {
+		return &BlockResult{Err: closeResult.Err}
+	}
+	return &BlockResult{
+		Value: &ast.BlockStmt{
+			Span:       ast.Span{Start: openResult.Unwrap().Pos, End: closeResult.Unwrap().End},
+			Statements: statementsResult.Unwrap(),
+		},
+		Ok: true,
+	}
+}
+
+func (p *Parser) parseIdentifier(description string) *shared.Result[*ast.Identifier, *compiler.Diagnostic] {
+	nameResult := p.expect(compiler.TokenTypeIdentifier, description)
+	if !nameResult.Ok {
+		return shared.ResultErr[*ast.Identifier](nameResult.Err)
+	}
+	nameToken := nameResult.Unwrap()
+	return shared.ResultOk[*ast.Identifier, *compiler.Diagnostic](
+		&ast.Identifier{Span: ast.SpanOfToken(nameToken), Name: nameToken.Content},
+	)
+}
+
+// parseVarDecl parses `let name: Type = value` and `const name: Type = value`.
+// The type annotation is optional, and so is the value of a `let`.
+func (p *Parser) parseVarDecl() *StmtResult {
+	keyword := p.advance()
+	nameResult := p.parseIdentifier("variable name")
+	if !nameResult.Ok {
+		return stmtErr(nameResult.Err)
+	}
+	decl := &ast.VarDecl{
+		Keyword: keyword,
+		Name:    nameResult.Unwrap(),
+	}
+	end := decl.Name.End
+
+	if _, hasType := p.match(compiler.TokenTypeColon); hasType {
+		typeResult := p.parseType()
+		if !typeResult.Ok {
+			return stmtErr(typeResult.Err)
+		}
+		decl.Type = typeResult.Unwrap()
+		end = decl.Type.NodeSpan().End
+	}
+
+	if _, hasValue := p.match(compiler.TokenTypeEqual); hasValue {
+		p.skipLineBreaks()
+		start := p.nextIndex(p.position)
+		state := p.saveState()
+		valueResult := p.ParseExpression()
+		if valueResult.Ok {
+			decl.Value = valueResult.Unwrap()
+		} else {
+			// Keep the declaration, so that its name is still declared for the later phases
+			decl.Value = p.recoverExpr(start, state, valueResult.Err)
+		}
+		end = decl.Value.NodeSpan().End
+	} else if decl.IsConst() {
+		return stmtErr(p.createUnexpectedTokenErr("'=' and the value of constant '" + decl.Name.Name + "'"))
+	}
+
+	decl.Span = ast.Span{Start: keyword.Pos, End: end}
+	return stmtOk(decl)
+}
+
+// peekSkippingLineBreaks looks at the next token after any line breaks, without consuming them.
+func (p *Parser) peekSkippingLineBreaks() *compiler.Token {
+	p.pushLineBreakSensitive(false)
+	defer p.popLineBreakSensitive()
+	return p.peek()
+}
+
+func (p *Parser) parseIf() *StmtResult {
+	ifToken := p.advance()
+	restoreStructLiteral := p.allowStructLiteral(false)
+	conditionResult := p.ParseExpression()
+	restoreStructLiteral()
+	if !conditionResult.Ok {
+		return stmtErr(conditionResult.Err)
+	}
+	thenResult := p.parseBlock()
+	if !thenResult.Ok {
+		return stmtErr(thenResult.Err)
+	}
+	ifStmt := &ast.IfStmt{
+		Span:      ast.Span{Start: ifToken.Pos, End: thenResult.Unwrap().End},
+		Condition: conditionResult.Unwrap(),
+		Then:      thenResult.Unwrap(),
+	}
+
+	// `else` is allowed to start on the line after the closing brace
+	if p.peekSkippingLineBreaks().Type != compiler.TokenTypeElse {
+		return stmtOk(ifStmt)
+	}
+	p.skipLineBreaks()
+	p.advance() // Moving over the `else`
+
+	var elseResult *StmtResult
+	if p.check(compiler.TokenTypeIf) {
+		elseResult = p.parseIf()
+	} else {
+		blockResult := p.parseBlock()
+		if !blockResult.Ok {
+			return stmtErr(blockResult.Err)
+		}
+		elseResult = stmtOk(blockResult.Unwrap())
+	}
+	if !elseResult.Ok {
+		return elseResult
+	}
+	ifStmt.Else = elseResult.Unwrap()
+	ifStmt.End = ifStmt.Else.NodeSpan().End
+	return stmtOk(ifStmt)
+}
+
+func (p *Parser) parseLabeledLoop() *StmtResult {
+	labelResult := p.parseIdentifier("label")
+	if !labelResult.Ok {
+		return stmtErr(labelResult.Err)
+	}
+	p.advance() // Moving over the ':'
+	if p.check(compiler.TokenTypeLoop) {
+		return p.parseLoop(labelResult.Unwrap())
+	}
+	return p.parseFor(labelResult.Unwrap())
+}
+
+func loopStart(label *ast.Identifier, keyword *compiler.Token) *compiler.Position {
+	if label != nil {
+		return label.Start
+	}
+	return keyword.Pos
+}
+
+// parseFor parses all the forms of `for` loops:
+//
+//	for item in iterable { }
+//	for let i = 0; i < n; i++ { }
+//	for condition { }
+//	for { }
+func (p *Parser) parseFor(label *ast.Identifier) *StmtResult {
+	forToken := p.advance()
+	start := loopStart(label, forToken)
+	restoreStructLiteral := p.allowStructLiteral(false)
+	defer restoreStructLiteral()
+
+	if p.check(compiler.TokenTypeIdentifier) && p.peekAt(1).Type == compiler.TokenTypeIn {
+		bindingResult := p.parseIdentifier("loop variable")
+		if !bindingResult.Ok {
+			return stmtErr(bindingResult.Err)
+		}
+		p.advance() // Moving over the `in`
+		iterableResult := p.ParseExpression()
+		if !iterableResult.Ok {
+			return stmtErr(iterableResult.Err)
+		}
+		bodyResult := p.parseBlock()
+		if !bodyResult.Ok {
+			return stmtErr(bodyResult.Err)
+		}
+		return stmtOk(&ast.ForInStmt{
+			Span:     ast.Span{Start: start, End: bodyResult.Unwrap().End},
+			Label:    label,
+			Binding:  bindingResult.Unwrap(),
+			Iterable: iterableResult.Unwrap(),
+			Body:     bodyResult.Unwrap(),
+		})
+	}
+
+	forStmt := &ast.ForStmt{Label: label}
+	if !p.check(compiler.TokenTypeLeftCurly) {
+		var init ast.Stmt
+		if !p.check(compiler.TokenTypeSemi) {
+			initResult := p.parseStatement()
+			if !initResult.Ok {
+				return stmtErr(initResult.Err)
+			}
+			init = initResult.Unwrap()
+		}
+
+		if _, isCStyle := p.match(compiler.TokenTypeSemi); isCStyle {
+			forStmt.Init = init
+			if !p.check(compiler.TokenTypeSemi) {
+				conditionResult := p.ParseExpression()
+				if !conditionResult.Ok {
+					return stmtErr(conditionResult.Err)
+				}
+				forStmt.Condition = conditionResult.Unwrap()
+			}
+			semiResult := p.expect(compiler.TokenTypeSemi, "';' after loop condition")
+			if !semiResult.Ok {
+				return stmtErr(semiResult.Err)
+			}
+			if !p.check(compiler.TokenTypeLeftCurly) {
+				postResult := p.ParseExpression()
+				if !postResult.Ok {
+					return stmtErr(postResult.Err)
+				}
+				forStmt.Post = postResult.Unwrap()
+			}
+		} else if condition, isExpr := init.(*ast.ExprStmt); isExpr {
+			forStmt.Condition = condition.Expr
+		} else {
+			return stmtErr(p.createUnexpectedTokenErr("';' after loop initializer"))
+		}
+	}
+
+	bodyResult := p.parseBlock()
+	if !bodyResult.Ok {
+		return stmtErr(bodyResult.Err)
+	}
+	forStmt.Body = bodyResult.Unwrap()
+	forStmt.Span = ast.Span{Start: start, End: forStmt.Body.End}
+	return stmtOk(forStmt)
+}
+
+func (p *Parser) parseLoop(label *ast.Identifier) *StmtResult {
+	loopToken := p.advance()
+	bodyResult := p.parseBlock()
+	if !bodyResult.Ok {
+		return stmtErr(bodyResult.Err)
+	}
+	return stmtOk(&ast.LoopStmt{
+		Span:  ast.Span{Start: loopStart(label, loopToken), End: bodyResult.Unwrap().End},
+		Label: label,
+		Body:  bodyResult.Unwrap(),
+	})
+}
+
+// parseJump parses `break` and `continue` with an optional label.
+func (p *Parser) parseJump() *StmtResult {
+	keyword := p.advance()
+	span := ast.SpanOfToken(keyword)
+	var label *ast.Identifier
+	if p.check(compiler.TokenTypeIdentifier) {
+		labelResult := p.parseIdentifier("label")
+		label = labelResult.Unwrap()
+		span.End = label.End
+	}
+	if keyword.Type == compiler.TokenTypeBreak {
+		return stmtOk(&ast.BreakStmt{Span: span, Label: label})
+	}
+	return stmtOk(&ast.ContinueStmt{Span: span, Label: label})
+}
+
+func (p *Parser) parseReturn() *StmtResult {
+	returnToken := p.advance()
+	returnStmt := &ast.ReturnStmt{Span: ast.SpanOfToken(returnToken)}
+	if !p.isStatementEnd() {
+		valueResult := p.ParseExpression()
+		if !valueResult.Ok {
+			return stmtErr(valueResult.Err)
+		}
+		returnStmt.Value = valueResult.Unwrap()
+		returnStmt.End = returnStmt.Value.NodeSpan().End
+	}
+	return stmtOk(returnStmt)
+}
@@ -0,0 +1,172 @@
+package parser
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// parseFile parses a source without syntax errors.
+func parseFile(source string) *ast.File {
+	parser := CreateParser(source)
+	file := parser.ParseFile().Unwrap()
+	So(parser.Diagnostics(), ShouldBeEmpty)
+	return file
+}
+
+// parseErrors parses a source and returns the syntax errors.
+func parseErrors(source string) []*compiler.Diagnostic {
+	parser := CreateParser(source)
+	parser.ParseFile().Unwrap()
+	return parser.Diagnostics()
+}
+
+func TestParseVarDecl(t *testing.T) {
+	Convey("Test parse variable declarations", t, func() {
+		file := parseFile("let a = 1\nlet b: int\nconst c: []string = names; let d: [4]u8 = 0")
+		So(len(file.Statements), ShouldEqual, 4)
+
+		a := file.Statements[0].(*ast.VarDecl)
+		So(a.IsConst(), ShouldBeFalse)
+		So(a.Name.Name, ShouldEqual, "a")
+		So(a.Type, ShouldBeNil)
+		So(toSExpr(a.Value), ShouldEqual, "1")
+
+		b := file.Statements[1].(*ast.VarDecl)
+		So(b.Type.(*ast.NamedType).Name.Name, ShouldEqual, "int")
+		So(b.Value, ShouldBeNil)
+
+		c := file.Statements[2].(*ast.VarDecl)
+		So(c.IsConst(), ShouldBeTrue)
+		So(c.Type.(*ast.ArrayType).Length, ShouldBeNil)
+		So(c.Type.(*ast.ArrayType).Element.(*ast.NamedType).Name.Name, ShouldEqual, "string")
+
+		d := file.Statements[3].(*ast.VarDecl)
+		So(toSExpr(d.Type.(*ast.ArrayType).Length), ShouldEqual, "4")
+		So(d.Start.Offset, ShouldEqual, 48)
+	})
+}
+
+func TestParseOptionalTypes(t *testing.T) {
+	Convey("Test parse the optional types and nil", t, func() {
+		file := parseFile("let a: int? = nil\nlet b: []Box<string?>? = b\nlet c = a?.b ?? nil")
+		a := file.Statements[0].(*ast.VarDecl)
+		So(typeString(a.Type), ShouldEqual, "int?")
+		So(a.Type.NodeSpan().End.Offset, ShouldEqual, 11)
+		So(a.Value.(*ast.BasicLiteral).Kind, ShouldEqual, compiler.TokenTypeNil)
+		So(typeString(file.Statements[1].(*ast.VarDecl).Type), ShouldEqual, "[]Box<string?>?")
+		So(toSExpr(file.Statements[2].(*ast.VarDecl).Value), ShouldEqual, "(?? (?. a b) nil)")
+
+		file = parseFile("let f: (func() int)? = nil\nlet t: (int,) = (1,)")
+		So(file.Statements[0].(*ast.VarDecl).Type.(*ast.OptionalType).Elem, ShouldHaveSameTypeAs, &ast.FuncType{})
+		So(file.Statements[1].(*ast.VarDecl).Type, ShouldHaveSameTypeAs, &ast.TupleType{})
+	})
+}
+
+func TestParseIfStmt(t *testing.T) {
+	Convey("Test parse if else chains", t, func() {
+		file := parseFile(`
+if a > b {
+  max = a
+} else if a == b {
+  max = 0
+}
+else {
+  max = b
+}`)
+		So(len(file.Statements), ShouldEqual, 1)
+		ifStmt := file.Statements[0].(*ast.IfStmt)
+		So(toSExpr(ifStmt.Condition), ShouldEqual, "(> a b)")
+		So(len(ifStmt.Then.Statements), ShouldEqual, 1)
+
+		elseIf := ifStmt.Else.(*ast.IfStmt)
+		So(toSExpr(elseIf.Condition), ShouldEqual, "(== a b)")
+		elseBlock := elseIf.Else.(*ast.BlockStmt)
+		So(toSExpr(elseBlock.Statements[0].(*ast.ExprStmt).Expr), ShouldEqual, "(= max b)")
+		So(ifStmt.End, ShouldEqual, elseBlock.End)
+	})
+}
+
+func TestParseLoops(t *testing.T) {
+	Convey("Test parse C-style for loop", t, func() {
+		forStmt := parseFile("for let i = 0; i < n; i++ { sum += i }").Statements[0].(*ast.ForStmt)
+		So(forStmt.Init.(*ast.VarDecl).Name.Name, ShouldEqual, "i")
+		So(toSExpr(forStmt.Condition), ShouldEqual, "(< i n)")
+		So(toSExpr(forStmt.Post), ShouldEqual, "(i ++)")
+		So(len(forStmt.Body.Statements), ShouldEqual, 1)
+	})
+
+	Convey("Test parse for loop with omitted clauses", t, func() {
+		file := parseFile("for ;; { }\nfor running { }\nfor { }")
+		forever := file.Statements[0].(*ast.ForStmt)
+		So(forever.Init, ShouldBeNil)
+		So(forever.Condition, ShouldBeNil)
+		So(forever.Post, ShouldBeNil)
+		So(toSExpr(file.Statements[1].(*ast.ForStmt).Condition), ShouldEqual, "running")
+		So(file.Statements[2].(*ast.ForStmt).Condition, ShouldBeNil)
+	})
+
+	Convey("Test parse range-based for loop", t, func() {
+		forIn := parseFile("for item in items { print(item) }").Statements[0].(*ast.ForInStmt)
+		So(forIn.Binding.Name, ShouldEqual, "item")
+		So(toSExpr(forIn.Iterable), ShouldEqual, "items")
+
+		file := parseFile("for i in 0..n { }\nfor i in 1..=len(xs) - 1 { }\nfor i in 0.. { break }\nlet tail = 2..\n")
+		So(toSExpr(file.Statements[0].(*ast.ForInStmt).Iterable), ShouldEqual, "(.. 0 n)")
+		So(toSExpr(file.Statements[1].(*ast.ForInStmt).Iterable), ShouldEqual, "(..= 1 (- (call len [xs]) 1))")
+		So(toSExpr(file.Statements[2].(*ast.ForInStmt).Iterable), ShouldEqual, "(.. 0 _)")
+		So(toSExpr(file.Statements[3].(*ast.VarDecl).Value), ShouldEqual, "(.. 2 _)")
+	})
+
+	Convey("Test parse labeled loops with break and continue", t, func() {
+		file := parseFile(`
+outer: loop {
+  for x in xs {
+    if x < 0 { continue outer }
+    if x == 0 { break }
+    break outer
+  }
+}
+return`)
+		loop := file.Statements[0].(*ast.LoopStmt)
+		So(loop.Label.Name, ShouldEqual, "outer")
+		So(loop.Start.Line, ShouldEqual, 2)
+
+		forIn := loop.Body.Statements[0].(*ast.ForInStmt)
+		So(forIn.Label, ShouldBeNil)
+		continueStmt := forIn.Body.Statements[0].(*ast.IfStmt).Then.Statements[0].(*ast.ContinueStmt)
+		So(continueStmt.Label.Name, ShouldEqual, "outer")
+		breakStmt := forIn.Body.Statements[1].(*ast.IfStmt).Then.Statements[0].(*ast.BreakStmt)
+		So(breakStmt.Label, ShouldBeNil)
+		So(forIn.Body.Statements[2].(*ast.BreakStmt).Label.Name, ShouldEqual, "outer")
+
+		returnStmt := file.Statements[1].(*ast.ReturnStmt)
+		So(returnStmt.Value, ShouldBeNil)
+	})
+}
+
+func TestParseStatementErrors(t *testing.T) {
+	cases := []struct {
+		source string
+		errMsg string
+	}{
+		{"const a", "Unexpected token: expected '=' and the value of constant 'a', found end of file"},
+		{"let = 1", "Unexpected token: expected variable name, found '='"},
+		{"let a: = 1", "Unexpected token: expected type, found '='"},
+		{"a b", "Unexpected token: expected line break or ';' after statement, found 'b'"},
+		{"if a { b", "Unexpected token: expected '}', found end of file"},
+		{"for let i = 0 { }", "Unexpected token: expected ';' after loop initializer, found '{'"},
+		{"for let i = 0; i < n { }", "Unexpected token: expected ';' after loop condition, found '{'"},
+	}
+	for _, testCase := range cases {
+		Convey(fmt.Sprintf("Test parse invalid statement `%s`", testCase.source), t, func() {
+			diagnostics := parseErrors(testCase.source)
+			So(diagnostics, ShouldNotBeEmpty)
+			So(diagnostics[0].Code, ShouldEqual, compiler.UnexpectedToken)
+			So(diagnostics[0].Msg, ShouldEqual, testCase.errMsg)
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/shared"
+)
+
+// parseMemberList parses the members of a struct, an interface or a struct literal
+// until the closing curly brace. Members are separated by line breaks, semicolons
+// or commas. The opening curly brace should have been consumed.
+func (p *Parser) parseMemberList(parseMember func() *compiler.Diagnostic) *TokenResult {
+	p.pushLineBreakSensitive(true)
+	defer p.popLineBreakSensitive()
+
+	isSeparator := func() bool {
+		return p.check(compiler.TokenTypeLineBreak, compiler.TokenTypeSemi, compiler.TokenTypeComma)
+	}
+	for {
+		for isSeparator() {
+			p.advance()
+		}
+		if p.check(compiler.TokenTypeRightCurly, compiler.TokenTypeEndOfFile) {
+			break
+		}
+		if err := parseMember(); err != nil {
+			return shared.ResultErr[*compiler.Token](err)
+		}
+		if !isSeparator() && !p.check(compiler.TokenTypeRightCurly) {
+			return shared.ResultErr[*compiler.Token](
+				p.createUnexpectedTokenErr(describeTokenTypes(
+					compiler.TokenTypeLineBreak,
+					compiler.TokenTypeComma,
+					compiler.TokenTypeRightCurly,
+				) + " after member"),
+			)
+		}
+	}
+	return p.expect(compiler.TokenTypeRightCurly, "'}'")
+}
+
+// parseStructDecl parses a struct declaration, the type parameters are optional:
+//
+//	struct Name<T: Constraint> {
+//	  field: Type = default
+//	  Embedded
+//	}
+func (p *Parser) parseStructDecl() *StmtResult {
+	structToken := p.advance()
+	nameResult := p.parseIdentifier("struct name")
+	if !nameResult.Ok {
+		return stmtErr(nameResult.Err)
+	}
+	typeParamsResult := p.parseTypeParams()
+	if !typeParamsResult.Ok {
+		return stmtErr(typeParamsResult.Err)
+	}
+	openResult := p.expect(compiler.TokenTypeLeftCurly, "'{' before struct fields")
+	if !openResult.Ok {
+		return stmtErr(openResult.Err)
+	}
+
+	structDecl := &ast.StructDecl{Name: nameResult.Unwrap(), TypeParams: typeParamsResult.Unwrap()}
+	closeResult := p.parseMemberList(func() *compiler.Diagnostic {
+		fieldResult := p.parseField()
+		if !fieldResult.Ok {
+			return fieldResult.Err
+		}
+		structDecl.Fields = append(structDecl.Fields, fieldResult.Unwrap())
+		return nil
+	})
+	if !closeResult.Ok {
+		return stmtErr(closeResult.Err)
+	}
+	structDecl.Span = ast.Span{Start: structToken.Pos, End: closeResult.Unwrap().End}
+	return stmtOk(structDecl)
+}
+
+func (p *Parser) parseField() *shared.Result[*ast.Field, *compiler.Diagnostic] {
+	nameResult := p.parseIdentifier("field name or embedded struct")
+	if !nameResult.Ok {
+		return shared.ResultErr[*ast.Field](nameResult.Err)
+	}
+	name := nameResult.Unwrap()
+
+	if _, hasType := p.match(compiler.TokenTypeColon); !hasType {
+		if !p.check(compiler.TokenTypeLineBreak, compiler.TokenTypeSemi, compiler.TokenTypeComma, compiler.TokenTypeRightCurly) {
+			// A name is either an embedded struct or the name of a field, followed by its type
+			return shared.ResultErr[*ast.Field](p.createUnexpectedTokenErr(describeTokenTypes(
+				compiler.TokenTypeColon,
+				compiler.TokenTypeLineBreak,
+				compiler.TokenTypeComma,
+				compiler.TokenTypeRightCurly,
+			) + " after member"))
+		}
+		return shared.ResultOk[*ast.Field, *compiler.Diagnostic](&ast.Field{
+			Span:     name.Span,
+			Type:     &ast.NamedType{Span: name.Span, Name: name},
+			Embedded: true,
+		})
+	}
+
+	typeResult := p.parseType()
+	if !typeResult.Ok {
+		return shared.ResultErr[*ast.Field](typeResult.Err)
+	}
+	field := &ast.Field{
+		Span: ast.Span{Start: name.Start, End: typeResult.Unwrap().NodeSpan().End},
+		Name: name,
+		Type: typeResult.Unwrap(),
+	}
+	if _, hasDefault := p.match(compiler.TokenTypeEqual); hasDefault {
+		defaultResult := p.ParseExpression()
+		if !defaultResult.Ok {
+			return shared.ResultErr[*ast.Field](defaultResult.Err)
+		}
+		field.Default = defaultResult.Unwrap()
+		field.End = field.Default.NodeSpan().End
+	}
+	return shared.ResultOk[*ast.Field, *compiler.Diagnostic](field)
+}
+
+// parseInterfaceDecl parses an interface declaration:
+//
+//	interface Name {
+//	  method(param: Type) ReturnType
+//	  Embedded
+//	}
+func (p *Parser) parseInterfaceDecl() *StmtResult {
+	interfaceToken := p.advance()
+	nameResult := p.parseIdentifier("interface name")
+	if !nameResult.Ok {
+		return stmtErr(nameResult.Err)
+	}
+	openResult := p.expect(compiler.TokenTypeLeftCurly, "'{' before interface methods")
+	if !openResult.Ok {
+		return stmtErr(openResult.Err)
+	}
+
+	interfaceDecl := &ast.InterfaceDecl{Name: nameResult.Unwrap()}
+	closeResult := p.parseMemberList(func() *compiler.Diagnostic {
+		nameResult := p.parseIdentifier("method name or embedded interface")
+		if !nameResult.Ok {
+			return nameResult.Err
+		}
+		name := nameResult.Unwrap()
+		if _, isMethod := p.match(compiler.TokenTypeLeftParen); !isMethod {
+			interfaceDecl.Embedded = append(interfaceDecl.Embedded, &ast.NamedType{Span: name.Span, Name: name})
+			return nil
+		}
+
+		paramsResult := p.parseParamList(true)
+		if !paramsResult.Ok {
+			return paramsResult.Err
+		}
+		method := &ast.MethodSignature{
+			Span:   ast.Span{Start: name.Start, End: p.previous.End},
+			Name:   name,
+			Params: paramsResult.Unwrap(),
+		}
+		if p.canStartType() {
+			returnTypeResult := p.parseType()
+			if !returnTypeResult.Ok {
+				return returnTypeResult.Err
+			}
+			method.ReturnType = returnTypeResult.Unwrap()
+			method.End = method.ReturnType.NodeSpan().End
+		}
+		interfaceDecl.Methods = append(interfaceDecl.Methods, method)
+		return nil
+	})
+	if !closeResult.Ok {
+		return stmtErr(closeResult.Err)
+	}
+	interfaceDecl.Span = ast.Span{Start: interfaceToken.Pos, End: closeResult.Unwrap().End}
+	return stmtOk(interfaceDecl)
+}
+
+// isTypeName checks whether an expression can name a struct type,
+// like `Point` or `geometry.Point`.
+func isTypeName(expr ast.Expr) bool {
+	switch typeName := expr.(type) {
+	case *ast.Identifier:
+		return true
+	case *ast.MemberExpr:
+		return !typeName.Optional && isTypeName(typeName.Target)
+	case *ast.InstantiateExpr:
+		return isTypeName(typeName.Target)
+	default:
+		return false
+	}
+}
+
+// isStructLiteralBodyAhead tells a struct literal `{ field: value }` or `{}`
+// apart from a block which follows an expression.
+func (p *Parser) isStructLiteralBodyAhead() bool {
+	p.pushLineBreakSensitive(false)
+	defer p.popLineBreakSensitive()
+	if p.peekAt(1).Type == compiler.TokenTypeRightCurly {
+		return true
+	}
+	return p.peekAt(1).Type == compiler.TokenTypeIdentifier && p.peekAt(2).Type == compiler.TokenTypeColon
+}
+
+// parseStructLit parses the body of a struct literal `Type { field: value }`.
+func (p *Parser) parseStructLit(typeName ast.Expr) *ExprResult {
+	p.advance() // Moving over the '{'
+	defer p.allowStructLiteral(true)()
+
+	structLit := &ast.StructLit{Type: typeName}
+	closeResult := p.parseMemberList(func() *compiler.Diagnostic {
+		nameResult := p.parseIdentifier("field name")
+		if !nameResult.Ok {
+			return nameResult.Err
+		}
+		colonResult := p.expect(compiler.TokenTypeColon, fmt.Sprintf("':' after field name '%s'", nameResult.Unwrap().Name))
+		if !colonResult.Ok {
+			return colonResult.Err
+		}
+		p.skipLineBreaks()
+		valueResult := p.ParseExpression()
+		if !valueResult.Ok {
+			return valueResult.Err
+		}
+		structLit.Fields = append(structLit.Fields, &ast.FieldValue{
+			Span:  ast.SpanBetween(nameResult.Unwrap(), valueResult.Unwrap()),
+			Name:  nameResult.Unwrap(),
+			Value: valueResult.Unwrap(),
+		})
+		return nil
+	})
+	if !closeResult.Ok {
+		return exprErr(closeResult.Err)
+	}
+	structLit.Span = ast.Span{Start: typeName.NodeSpan().Start, End: closeResult.Unwrap().End}
+	return exprOk(structLit)
+}
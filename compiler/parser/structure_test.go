@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"mirth/compiler/ast"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseStructDecl(t *testing.T) {
+	Convey("Test parse struct declaration", t, func() {
+		file := parseFile(`
+struct Point3D {
+  Point
+  z: float = 0.0
+  label: string; visible: bool
+}`)
+		structDecl := file.Statements[0].(*ast.StructDecl)
+		So(structDecl.Name.Name, ShouldEqual, "Point3D")
+		So(len(structDecl.Fields), ShouldEqual, 4)
+
+		So(structDecl.Fields[0].Embedded, ShouldBeTrue)
+		So(structDecl.Fields[0].Name, ShouldBeNil)
+		So(structDecl.Fields[0].Type.(*ast.NamedType).Name.Name, ShouldEqual, "Point")
+
+		So(structDecl.Fields[1].Name.Name, ShouldEqual, "z")
+		So(structDecl.Fields[1].Default.(*ast.BasicLiteral).Value, ShouldEqual, "0.0")
+		So(structDecl.Fields[3].Name.Name, ShouldEqual, "visible")
+	})
+
+	Convey("Test parse method declaration", t, func() {
+		file := parseFile("func (p: Point) distance(other: Point) float {\n  return 0\n}\nfunc (a: int) int { return a }(1)")
+		method := file.Statements[0].(*ast.FuncDecl)
+		So(method.Receiver.Name.Name, ShouldEqual, "p")
+		So(method.Receiver.Type.(*ast.NamedType).Name.Name, ShouldEqual, "Point")
+		So(method.Name.Name, ShouldEqual, "distance")
+		So(method.ReturnType.(*ast.NamedType).Name.Name, ShouldEqual, "float")
+
+		call := file.Statements[1].(*ast.ExprStmt).Expr.(*ast.CallExpr)
+		So(call.Callee, ShouldHaveSameTypeAs, &ast.FuncLit{})
+	})
+}
+
+func TestParseInterfaceDecl(t *testing.T) {
+	Convey("Test parse interface declaration", t, func() {
+		file := parseFile(`
+interface Shape {
+  Named
+  area() float
+  scale(factor: float)
+}`)
+		interfaceDecl := file.Statements[0].(*ast.InterfaceDecl)
+		So(interfaceDecl.Name.Name, ShouldEqual, "Shape")
+		So(len(interfaceDecl.Embedded), ShouldEqual, 1)
+		So(interfaceDecl.Embedded[0].(*ast.NamedType).Name.Name, ShouldEqual, "Named")
+		So(len(interfaceDecl.Methods), ShouldEqual, 2)
+		So(interfaceDecl.Methods[0].ReturnType.(*ast.NamedType).Name.Name, ShouldEqual, "float")
+		So(interfaceDecl.Methods[1].Params[0].Name.Name, ShouldEqual, "factor")
+		So(interfaceDecl.Methods[1].ReturnType, ShouldBeNil)
+	})
+}
+
+func TestParseStructLit(t *testing.T) {
+	Convey("Test parse struct literals", t, func() {
+		file := parseFile(`
+let origin = Point {}
+let p = geometry.Point {
+  x: 1,
+  y: origin.y + 2
+}`)
+		origin := file.Statements[0].(*ast.VarDecl).Value.(*ast.StructLit)
+		So(origin.Fields, ShouldBeEmpty)
+
+		p := file.Statements[1].(*ast.VarDecl).Value.(*ast.StructLit)
+		So(toSExpr(p.Type), ShouldEqual, "(. geometry Point)")
+		So(len(p.Fields), ShouldEqual, 2)
+		So(p.Fields[1].Name.Name, ShouldEqual, "y")
+		So(toSExpr(p.Fields[1].Value), ShouldEqual, "(+ (. origin y) 2)")
+	})
+
+	Convey("Test tell struct literals apart from blocks", t, func() {
+		cases := []string{
+			"if p == origin { x = 1 }",
+			"if p == (Point { x: 1 }) { }",
+			"for x in xs { }",
+			"for running { }",
+		}
+		for _, source := range cases {
+			So(parseErrors(source), ShouldBeEmpty)
+		}
+
+		ifStmt := parseFile("if p == origin {}").Statements[0].(*ast.IfStmt)
+		So(toSExpr(ifStmt.Condition), ShouldEqual, "(== p origin)")
+
+		forIn := parseFile("for x in points { x }").Statements[0].(*ast.ForInStmt)
+		So(toSExpr(forIn.Iterable), ShouldEqual, "points")
+		So(len(forIn.Body.Statements), ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/shared"
+)
+
+// parseTemplateString assembles the tokens of a template literal:
+// a quote, text fragments and interpolations `${ expr }` in any order,
+// and a closing quote. Empty fragments are inserted where needed, so that
+// fragments and expressions alternate.
+func (p *Parser) parseTemplateString() *shared.Result[*ast.TemplateString, *compiler.Diagnostic] {
+	openQuote := p.advance()
+	template := &ast.TemplateString{}
+	// Adds an empty fragment at the position if the last segment isn't a fragment
+	ensureFragment := func(position *compiler.Position) {
+		if len(template.Fragments) == len(template.Exprs) {
+			template.Fragments = append(template.Fragments, &ast.TemplateText{
+				Span: ast.Span{Start: position, End: position},
+			})
+		}
+	}
+
+	for {
+		// Template strings are parsed token by token, line breaks
+		// and comments can't appear between the quotes.
+		token := p.tokens[p.position]
+		switch token.Type {
+		case compiler.TokenTypeTemplateStrFragment:
+			p.advance()
+			template.Fragments = append(template.Fragments, &ast.TemplateText{
+				Span:  ast.SpanOfToken(token),
+				Value: token.Content,
+			})
+		case compiler.TokenTypeInterplolationStart:
+			ensureFragment(token.Pos)
+			p.advance()
+			exprResult := p.parseInterpolation()
+			if !exprResult.Ok {
+				return shared.ResultErr[*ast.TemplateString](exprResult.Err)
+			}
+			template.Exprs = append(template.Exprs, exprResult.Unwrap())
+		case compiler.TokenTypeTemplateStringQuote:
+			ensureFragment(token.Pos)
+			p.advance()
+			template.Span = ast.Span{Start: openQuote.Pos, End: token.End}
+			return shared.ResultOk[*ast.TemplateString, *compiler.Diagnostic](template)
+		default:
+			return shared.ResultErr[*ast.TemplateString](p.createParseErr(
+				compiler.UnexpectedToken,
+				token,
+				"Unexpected token: expected template text, '${' or '`', found "+describeToken(token),
+			))
+		}
+	}
+}
+
+// parseInterpolation parses the expression of `${ expr }` and the closing curly brace,
+// the `${` should have been consumed.
+func (p *Parser) parseInterpolation() *ExprResult {
+	p.pushLineBreakSensitive(false)
+	restoreStructLiteral := p.allowStructLiteral(true)
+	exprResult := p.ParseExpression()
+	restoreStructLiteral()
+	if !exprResult.Ok {
+		p.popLineBreakSensitive()
+		return exprResult
+	}
+	closeResult := p.expect(compiler.TokenTypeRightCurly, "'}' after interpolation")
+	p.popLineBreakSensitive()
+	if !closeResult.Ok {
+		return exprErr(closeResult.Err)
+	}
+	return exprResult
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"mirth/compiler/ast"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseTemplateString(t *testing.T) {
+	Convey("Test parse template string segments", t, func() {
+		expr := CreateParser("`Hello ${first} ${last}!`").ParseExpression().Unwrap()
+		template := expr.(*ast.TemplateString)
+		So(template.FragmentValues(), ShouldResemble, []string{"Hello ", " ", "!"})
+		So(len(template.Exprs), ShouldEqual, 2)
+		So(toSExpr(template.Exprs[1]), ShouldEqual, "last")
+		So(template.Start.Offset, ShouldEqual, 0)
+		So(template.End.Offset, ShouldEqual, 25)
+	})
+
+	Convey("Test pad adjacent interpolations with empty fragments", t, func() {
+		template := CreateParser("`${a}${b}`").ParseExpression().Unwrap().(*ast.TemplateString)
+		So(template.FragmentValues(), ShouldResemble, []string{"", "", ""})
+		So(len(template.Exprs), ShouldEqual, 2)
+		So(template.Fragments[1].Start.Offset, ShouldEqual, 5)
+	})
+
+	Convey("Test parse interpolation with curly braces and nested templates", t, func() {
+		template := CreateParser("`sum: ${f(Point { x: 1 })} ${`in ${a + b}`}`").
+			ParseExpression().Unwrap().(*ast.TemplateString)
+		So(template.FragmentValues(), ShouldResemble, []string{"sum: ", " ", ""})
+		So(template.Exprs[0].(*ast.CallExpr).Arguments[0], ShouldHaveSameTypeAs, &ast.StructLit{})
+		nested := template.Exprs[1].(*ast.TemplateString)
+		So(toSExpr(nested.Exprs[0]), ShouldEqual, "(+ a b)")
+	})
+
+	Convey("Test parse tagged template", t, func() {
+		file := parseFile("let query = db.sql`SELECT * FROM users WHERE id = ${id}`\nlet s = tag\n`not tagged`")
+		tagged := file.Statements[0].(*ast.VarDecl).Value.(*ast.TaggedTemplate)
+		So(toSExpr(tagged.Tag), ShouldEqual, "(. db sql)")
+		So(tagged.Template.FragmentValues(), ShouldResemble, []string{"SELECT * FROM users WHERE id = ", ""})
+		So(toSExpr(tagged.Template.Exprs[0]), ShouldEqual, "id")
+		So(tagged.Start.Offset, ShouldEqual, 12)
+
+		So(file.Statements[1].(*ast.VarDecl).Value, ShouldHaveSameTypeAs, &ast.Identifier{})
+		So(file.Statements[2].(*ast.ExprStmt).Expr, ShouldHaveSameTypeAs, &ast.TemplateString{})
+	})
+
+	Convey("Test report unterminated interpolation", t, func() {
+		result := CreateParser("`a ${b c}`").ParseExpression()
+		So(result.Err, ShouldNotBeNil)
+		So(result.Err.Msg, ShouldEqual, "Unexpected token: expected '}' after interpolation, found 'c'")
+	})
+}
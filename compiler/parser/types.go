@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/shared"
+)
+
+type TypeResult = shared.Result[ast.TypeExpr, *compiler.Diagnostic]
+
+func typeOk(typeExpr ast.TypeExpr) *TypeResult {
+	return &TypeResult{Value: typeExpr, Ok: true}
+}
+
+func typeErr(err *compiler.Diagnostic) *TypeResult {
+	return &TypeResult{Err: err}
+}
+
+func (p *Parser) canStartType() bool {
+	return p.check(
+		compiler.TokenTypeIdentifier,
+		compiler.TokenTypeLeftBracket,
+		compiler.TokenTypeLeftParen,
+		compiler.TokenTypeFunc,
+	)
+}
+
+// parseType parses a type annotation:
+//
+//	Name
+//	Generic<Arg, ...>
+//	[]Element
+//	[Length]Element
+//	(Element, ...)
+//	(Type)
+//	func(Param, ...Variadic) Return
+//	Type?
+func (p *Parser) parseType() *TypeResult {
+	typeResult := p.parseNonOptionalType()
+	if !typeResult.Ok {
+		return typeResult
+	}
+	typeExpr := typeResult.Unwrap()
+	for {
+		question, isOptional := p.match(compiler.TokenTypeQuestion)
+		if !isOptional {
+			return typeOk(typeExpr)
+		}
+		typeExpr = &ast.OptionalType{Span: ast.Span{Start: typeExpr.NodeSpan().Start, End: question.End}, Elem: typeExpr}
+	}
+}
+
+func (p *Parser) parseNonOptionalType() *TypeResult {
+	token := p.peek()
+	switch token.Type {
+	case compiler.TokenTypeFunc:
+		return p.parseFuncType()
+	case compiler.TokenTypeIdentifier:
+		p.advance()
+		name := &ast.Identifier{Span: ast.SpanOfToken(token), Name: token.Content}
+		namedType := &ast.NamedType{Span: name.Span, Name: name}
+		if _, isGeneric := p.match(compiler.TokenTypeLeftAngle); isGeneric {
+			typeArgsResult := p.parseTypeArgs()
+			if !typeArgsResult.Ok {
+				return typeErr(typeArgsResult.Err)
+			}
+			namedType.TypeArgs = typeArgsResult.Unwrap()
+			namedType.End = p.previous.End
+		}
+		return typeOk(namedType)
+	case compiler.TokenTypeLeftBracket:
+		p.advance()
+		arrayType := &ast.ArrayType{}
+		if !p.check(compiler.TokenTypeRightBracket) {
+			p.pushLineBreakSensitive(false)
+			lengthResult := p.ParseExpression()
+			p.popLineBreakSensitive()
+			if !lengthResult.Ok {
+				return typeErr(lengthResult.Err)
+			}
+			arrayType.Length = lengthResult.Unwrap()
+		}
+		closeResult := p.expect(compiler.TokenTypeRightBracket, "']'")
+		if !closeResult.Ok {
+			return typeErr(closeResult.Err)
+		}
+		elementResult := p.parseType()
+		if !elementResult.Ok {
+			return elementResult
+		}
+		arrayType.Element = elementResult.Unwrap()
+		arrayType.Span = ast.Span{Start: token.Pos, End: arrayType.Element.NodeSpan().End}
+		return typeOk(arrayType)
+	case compiler.TokenTypeLeftParen:
+		p.advance()
+		p.pushLineBreakSensitive(false)
+		defer p.popLineBreakSensitive()
+		tupleType := &ast.TupleType{}
+		hasComma := false
+		for !p.check(compiler.TokenTypeRightParen) {
+			elementResult := p.parseType()
+			if !elementResult.Ok {
+				return elementResult
+			}
+			tupleType.Elements = append(tupleType.Elements, elementResult.Unwrap())
+			if _, hasComma = p.match(compiler.TokenTypeComma); !hasComma {
+				break
+			}
+		}
+		closeResult := p.expect(compiler.TokenTypeRightParen, "',' or ')' after tuple element type")
+		if !closeResult.Ok {
+			return typeErr(closeResult.Err)
+		}
+		if len(tupleType.Elements) == 1 && !hasComma {
+			// A grouped type, like `(func() int)?`
+			return typeOk(tupleType.Elements[0])
+		}
+		tupleType.Span = ast.Span{Start: token.Pos, End: closeResult.Unwrap().End}
+		return typeOk(tupleType)
+	default:
+		return typeErr(p.createUnexpectedTokenErr("type"))
+	}
+}
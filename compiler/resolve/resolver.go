@@ -0,0 +1,596 @@
+package resolve
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+)
+
+// Importer returns the module scope of the module imported by an import declaration,
+// or nil if the module isn't found or isn't resolved yet.
+type Importer func(decl *ast.ImportDecl) *Scope
+
+type resolver struct {
+	table    *Table
+	scope    *Scope
+	importer Importer
+	// variants are the names of the enum variants, which patterns may use unqualified.
+	variants map[string]bool
+	// labels are the labels of the enclosing loops of the current function.
+	labels      []*Symbol
+	diagnostics *[]*compiler.Diagnostic
+}
+
+// ResolveFile resolves the names of a file which is a module on its own, without imports.
+func ResolveFile(file *ast.File) (*Table, []*compiler.Diagnostic) {
+	table, diagnostics := ResolveModule([]*ast.File{file}, nil)
+	return table, diagnostics[0]
+}
+
+// ResolveModule resolves the names of the files of a module. Every identifier
+// use is bound to its declaration, in the nested scopes of the files, functions,
+// blocks, loops and match arms. The top-level declarations are visible in all
+// the files, while the imports are visible in their file only. The diagnostics
+// are returned by file, in the order of the files.
+func ResolveModule(files []*ast.File, importer Importer) (*Table, [][]*compiler.Diagnostic) {
+	universe := CreateUniverse()
+	r := &resolver{
+		table: &Table{
+			Universe: universe,
+			Module:   newScope(ModuleScope, nil, universe),
+			Defs:     map[*ast.Identifier]*Symbol{},
+			Uses:     map[*ast.Identifier]*Symbol{},
+			Scopes:   map[ast.Node]*Scope{},
+		},
+		importer: importer,
+		variants: map[string]bool{},
+	}
+	for _, name := range predeclaredVariants {
+		r.variants[name] = true
+	}
+	diagnostics := make([][]*compiler.Diagnostic, len(files))
+
+	r.scope = r.table.Module
+	for i, file := range files {
+		r.diagnostics = &diagnostics[i]
+		ast.Inspect(file, func(node ast.Node) bool {
+			if enum, isEnum := node.(*ast.EnumDecl); isEnum {
+				for _, variant := range enum.Variants {
+					r.variants[variant.Name.Name] = true
+				}
+			}
+			return true
+		})
+		for _, stmt := range file.Statements {
+			if decl, isDecl := stmt.(ast.Decl); isDecl {
+				r.declareDecl(decl)
+			}
+		}
+	}
+
+	for i, file := range files {
+		r.diagnostics = &diagnostics[i]
+		r.scope = r.table.Module
+		r.openScope(FileScope, file)
+		for _, stmt := range file.Statements {
+			if importDecl, isImport := stmt.(*ast.ImportDecl); isImport {
+				r.declareImport(importDecl)
+			}
+		}
+		for _, stmt := range file.Statements {
+			r.resolveStmt(stmt)
+		}
+	}
+	return r.table, diagnostics
+}
+
+func (r *resolver) report(diagnostic *compiler.Diagnostic) {
+	*r.diagnostics = append(*r.diagnostics, diagnostic)
+}
+
+func (r *resolver) openScope(kind ScopeKind, node ast.Node) *Scope {
+	r.scope = newScope(kind, node, r.scope)
+	r.table.Scopes[node] = r.scope
+	return r.scope
+}
+
+func (r *resolver) closeScope() {
+	r.scope = r.scope.Parent
+}
+
+// declare declares a symbol in the current scope, reporting a duplicate declaration in the
+// same scope, or the shadowing of a local declaration of an enclosing scope.
+func (r *resolver) declare(kind SymbolKind, ident *ast.Identifier, decl ast.Node) *Symbol {
+	symbol := &Symbol{Name: ident.Name, Kind: kind, Decl: decl, Ident: ident}
+	r.table.Defs[ident] = symbol
+	if ident.Name == "_" {
+		symbol.Scope = r.scope
+		return symbol
+	}
+
+	if previous := r.scope.LookupLocal(ident.Name); previous != nil {
+		r.report(errorAt(ident, compiler.DuplicateDeclaration, fmt.Sprintf(
+			"Duplicate declaration of '%s', it's already declared as a %s at %s", ident.Name, previous.Kind, previous.Ident.Start,
+		)))
+		symbol.Scope = r.scope
+		return symbol
+	}
+	// The imports of a file and the top-level declarations of the module share a namespace
+	if r.scope.Kind == FileScope {
+		if previous := r.table.Module.LookupLocal(ident.Name); previous != nil {
+			r.report(errorAt(ident, compiler.DuplicateDeclaration, fmt.Sprintf(
+				"Import of '%s' conflicts with the %s declared at %s", ident.Name, previous.Kind, previous.Ident.Start,
+			)))
+		}
+	}
+	if outer := r.scope.Parent.Lookup(ident.Name); outer != nil && isLocalScope(outer.Scope) {
+		r.report(warningAt(ident, compiler.ShadowedDeclaration, fmt.Sprintf(
+			"Declaration of '%s' shadows the %s declared at %s", ident.Name, outer.Kind, outer.Ident.Start,
+		)))
+	}
+	r.scope.insert(symbol)
+	return symbol
+}
+
+func isLocalScope(scope *Scope) bool {
+	switch scope.Kind {
+	case UniverseScope, ModuleScope, FileScope:
+		return false
+	}
+	return true
+}
+
+// declareDecl declares the named declarations which are visible in their whole scope:
+// all the top-level ones, and the functions and types of the blocks.
+func (r *resolver) declareDecl(decl ast.Decl) {
+	switch d := decl.(type) {
+	case *ast.VarDecl:
+		r.declare(symbolKindOfVar(d), d.Name, d)
+	case *ast.FuncDecl:
+		if d.Receiver == nil { // Methods belong to their struct
+			r.declare(FuncSymbol, d.Name, d)
+		}
+	case *ast.StructDecl:
+		r.declare(StructSymbol, d.Name, d)
+	case *ast.InterfaceDecl:
+		r.declare(InterfaceSymbol, d.Name, d)
+	case *ast.EnumDecl:
+		r.declare(EnumSymbol, d.Name, d)
+	}
+}
+
+func symbolKindOfVar(decl *ast.VarDecl) SymbolKind {
+	if decl.IsConst() {
+		return ConstSymbol
+	}
+	return VarSymbol
+}
+
+func (r *resolver) declareImport(decl *ast.ImportDecl) {
+	var module *Scope
+	if r.importer != nil {
+		module = r.importer(decl)
+	}
+	if decl.Items == nil {
+		symbol := r.declare(ModuleSymbol, decl.Binding(), decl)
+		symbol.Module = module
+		return
+	}
+	for _, item := range decl.Items {
+		symbol := r.declare(ImportSymbol, item.Binding(), item)
+		if module != nil {
+			symbol.Target = module.LookupLocal(item.Name.Name)
+			if symbol.Target != nil {
+				r.use(item.Name, symbol.Target)
+			}
+		}
+	}
+}
+
+func (r *resolver) use(ident *ast.Identifier, symbol *Symbol) {
+	r.table.Uses[ident] = symbol
+	symbol.Uses = append(symbol.Uses, ident)
+}
+
+// resolveName binds a name to the symbol it refers to, or reports it as undefined
+// with the most similar visible name.
+func (r *resolver) resolveName(ident *ast.Identifier) {
+	if symbol := r.scope.Lookup(ident.Name); symbol != nil {
+		r.use(ident, symbol)
+		return
+	}
+	if r.variants[ident.Name] {
+		return // An unqualified enum variant, resolved by the type checker
+	}
+	msg := fmt.Sprintf("Undefined name '%s'", ident.Name)
+	if suggestion := suggest(ident.Name, r.scope.Names()); suggestion != "" {
+		msg += fmt.Sprintf(", did you mean '%s'?", suggestion)
+	}
+	r.report(errorAt(ident, compiler.UndefinedName, msg))
+}
+
+// resolveStatements resolves a statement list in the current scope,
+// the functions and types declared by the list are visible in the whole list.
+func (r *resolver) resolveStatements(statements []ast.Stmt) {
+	for _, stmt := range statements {
+		if _, isVar := stmt.(*ast.VarDecl); isVar {
+			continue
+		}
+		if decl, isDecl := stmt.(ast.Decl); isDecl {
+			r.declareDecl(decl)
+		}
+	}
+	for _, stmt := range statements {
+		r.resolveStmt(stmt)
+	}
+}
+
+func (r *resolver) resolveBlock(block *ast.BlockStmt) {
+	r.openScope(BlockScope, block)
+	r.resolveStatements(block.Statements)
+	r.closeScope()
+}
+
+func (r *resolver) resolveStmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.VarDecl:
+		r.resolveType(s.Type)
+		r.resolveExpr(s.Value)
+		if r.scope.Kind != FileScope { // The top-level variables are declared beforehand
+			r.declare(symbolKindOfVar(s), s.Name, s)
+		}
+	case *ast.FuncDecl:
+		r.resolveFunction(s, s.TypeParams, s.Receiver, s.Params, s.ReturnType, s.Body)
+	case *ast.StructDecl:
+		r.openScope(TypeScope, s)
+		r.declareTypeParams(s.TypeParams)
+		r.resolveFields(s.Fields)
+		r.closeScope()
+	case *ast.InterfaceDecl:
+		for _, embedded := range s.Embedded {
+			r.resolveType(embedded)
+		}
+		for _, method := range s.Methods {
+			r.resolveParamTypes(method.Params)
+			r.resolveType(method.ReturnType)
+		}
+	case *ast.EnumDecl:
+		r.openScope(TypeScope, s)
+		r.declareTypeParams(s.TypeParams)
+		for _, variant := range s.Variants {
+			for _, fieldType := range variant.Tuple {
+				r.resolveType(fieldType)
+			}
+			r.resolveFields(variant.Fields)
+			r.resolveExpr(variant.Discriminant)
+		}
+		r.closeScope()
+	case *ast.ImportDecl:
+		if r.scope.Kind != FileScope {
+			r.declareImport(s)
+		}
+	case *ast.ExprStmt:
+		r.resolveExpr(s.Expr)
+	case *ast.BlockStmt:
+		r.resolveBlock(s)
+	case *ast.IfStmt:
+		r.resolveExpr(s.Condition)
+		r.resolveBlock(s.Then)
+		if s.Else != nil {
+			r.resolveStmt(s.Else)
+		}
+	case *ast.ForStmt:
+		r.openScope(LoopScope, s)
+		r.pushLabel(s.Label, s)
+		if s.Init != nil {
+			r.resolveStmt(s.Init)
+		}
+		r.resolveExpr(s.Condition)
+		r.resolveExpr(s.Post)
+		r.resolveBlock(s.Body)
+		r.popLabel(s.Label)
+		r.closeScope()
+	case *ast.ForInStmt:
+		r.resolveExpr(s.Iterable)
+		r.openScope(LoopScope, s)
+		r.pushLabel(s.Label, s)
+		r.declare(BindingSymbol, s.Binding, s)
+		r.resolveBlock(s.Body)
+		r.popLabel(s.Label)
+		r.closeScope()
+	case *ast.LoopStmt:
+		r.openScope(LoopScope, s)
+		r.pushLabel(s.Label, s)
+		r.resolveBlock(s.Body)
+		r.popLabel(s.Label)
+		r.closeScope()
+	case *ast.BreakStmt:
+		r.resolveLabel(s.Label)
+	case *ast.ContinueStmt:
+		r.resolveLabel(s.Label)
+	case *ast.ReturnStmt:
+		r.resolveExpr(s.Value)
+	}
+}
+
+func (r *resolver) pushLabel(label *ast.Identifier, loop ast.Stmt) {
+	if label != nil {
+		symbol := &Symbol{Name: label.Name, Kind: LabelSymbol, Decl: loop, Ident: label, Scope: r.scope}
+		r.table.Defs[label] = symbol
+		r.labels = append(r.labels, symbol)
+	}
+}
+
+func (r *resolver) popLabel(label *ast.Identifier) {
+	if label != nil {
+		r.labels = r.labels[:len(r.labels)-1]
+	}
+}
+
+func (r *resolver) resolveLabel(label *ast.Identifier) {
+	if label == nil {
+		return
+	}
+	var names []string
+	for i := len(r.labels) - 1; i >= 0; i-- {
+		if r.labels[i].Name == label.Name {
+			r.use(label, r.labels[i])
+			return
+		}
+		names = append(names, r.labels[i].Name)
+	}
+	msg := fmt.Sprintf("Undefined label '%s'", label.Name)
+	if suggestion := suggest(label.Name, names); suggestion != "" {
+		msg += fmt.Sprintf(", did you mean '%s'?", suggestion)
+	}
+	r.report(errorAt(label, compiler.UndefinedName, msg))
+}
+
+func (r *resolver) declareTypeParams(typeParams []*ast.TypeParam) {
+	for _, typeParam := range typeParams {
+		r.declare(TypeParamSymbol, typeParam.Name, typeParam)
+	}
+	for _, typeParam := range typeParams {
+		r.resolveType(typeParam.Constraint)
+	}
+}
+
+// declareReceiverTypeParams declares the type arguments of a generic receiver like `Pair<K, V>`,
+// which name the type parameters of the struct in the method.
+func (r *resolver) declareReceiverTypeParams(receiver ast.TypeExpr) {
+	named, isNamed := receiver.(*ast.NamedType)
+	if !isNamed {
+		return
+	}
+	for _, typeArg := range named.TypeArgs {
+		if name, isName := typeArg.(*ast.NamedType); isName && len(name.TypeArgs) == 0 {
+			r.declare(TypeParamSymbol, name.Name, name)
+		}
+	}
+}
+
+func (r *resolver) resolveFields(fields []*ast.Field) {
+	for _, field := range fields {
+		r.resolveType(field.Type)
+		r.resolveExpr(field.Default)
+	}
+}
+
+func (r *resolver) resolveParamTypes(params []*ast.Param) {
+	for _, param := range params {
+		r.resolveType(param.Type)
+	}
+}
+
+// resolveFunction resolves a function in a new scope, holding its type parameters,
+// its receiver and parameters, and the top-level statements of its body.
+// The labels of the enclosing function aren't visible in it.
+func (r *resolver) resolveFunction(function ast.Function, typeParams []*ast.TypeParam, receiver *ast.Param, params []*ast.Param, returnType ast.TypeExpr, body ast.Node) {
+	scope := r.openScope(FunctionScope, function)
+	labels := r.labels
+	r.labels = nil
+	r.declareTypeParams(typeParams)
+	if receiver != nil {
+		r.declareReceiverTypeParams(receiver.Type)
+		params = append([]*ast.Param{receiver}, params...)
+	}
+	for _, param := range params {
+		r.resolveType(param.Type)
+		r.resolveExpr(param.Default)
+		r.declare(ParamSymbol, param.Name, param)
+	}
+	r.resolveType(returnType)
+	switch b := body.(type) {
+	case *ast.BlockStmt:
+		r.table.Scopes[b] = scope
+		r.resolveStatements(b.Statements)
+	case ast.Expr:
+		r.resolveExpr(b)
+	}
+	r.labels = labels
+	r.closeScope()
+}
+
+func (r *resolver) resolveType(typeExpr ast.TypeExpr) {
+	switch t := typeExpr.(type) {
+	case *ast.NamedType:
+		r.resolveName(t.Name)
+		for _, typeArg := range t.TypeArgs {
+			r.resolveType(typeArg)
+		}
+	case *ast.ArrayType:
+		r.resolveExpr(t.Length)
+		r.resolveType(t.Element)
+	case *ast.TupleType:
+		for _, element := range t.Elements {
+			r.resolveType(element)
+		}
+	case *ast.FuncType:
+		r.resolveParamTypes(t.Params)
+		r.resolveType(t.ReturnType)
+	case *ast.OptionalType:
+		r.resolveType(t.Elem)
+	}
+}
+
+func (r *resolver) resolveExprs(exprs []ast.Expr) {
+	for _, expr := range exprs {
+		r.resolveExpr(expr)
+	}
+}
+
+func (r *resolver) resolveExpr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		r.resolveName(e)
+	case *ast.ParenExpr:
+		r.resolveExpr(e.Expr)
+	case *ast.UnaryExpr:
+		r.resolveExpr(e.Operand)
+	case *ast.PostfixExpr:
+		r.resolveExpr(e.Operand)
+	case *ast.BinaryExpr:
+		r.resolveExpr(e.Left)
+		r.resolveExpr(e.Right)
+	case *ast.AssignExpr:
+		r.resolveExpr(e.Target)
+		r.resolveExpr(e.Value)
+	case *ast.CallExpr:
+		r.resolveExpr(e.Callee)
+		r.resolveExprs(e.Arguments)
+	case *ast.IndexExpr:
+		r.resolveExpr(e.Target)
+		r.resolveExpr(e.Index)
+	case *ast.MemberExpr:
+		r.resolveExpr(e.Target)
+		r.resolveModuleMember(e)
+	case *ast.ArrayLit:
+		r.resolveExprs(e.Elements)
+	case *ast.TupleLit:
+		r.resolveExprs(e.Elements)
+	case *ast.RangeExpr:
+		r.resolveExpr(e.Start)
+		r.resolveExpr(e.End)
+	case *ast.SpreadExpr:
+		r.resolveExpr(e.Expr)
+	case *ast.StructLit:
+		r.resolveExpr(e.Type)
+		for _, field := range e.Fields {
+			r.resolveExpr(field.Value)
+		}
+	case *ast.TypeAssertion:
+		r.resolveExpr(e.Value)
+		r.resolveType(e.Type)
+	case *ast.InstantiateExpr:
+		r.resolveExpr(e.Target)
+		for _, typeArg := range e.TypeArgs {
+			r.resolveType(typeArg)
+		}
+	case *ast.TemplateString:
+		r.resolveExprs(e.Exprs)
+	case *ast.TaggedTemplate:
+		r.resolveExpr(e.Tag)
+		r.resolveExpr(e.Template)
+	case *ast.FuncLit:
+		r.resolveFunction(e, nil, nil, e.Params, e.ReturnType, e.Body)
+	case *ast.ArrowFunc:
+		r.resolveFunction(e, nil, nil, e.Params, nil, e.Body)
+	case *ast.MatchExpr:
+		r.resolveExpr(e.Subject)
+		for _, arm := range e.Arms {
+			r.openScope(MatchArmScope, arm)
+			arm.Pattern = r.variantPatterns(arm.Pattern)
+			r.resolvePattern(arm.Pattern)
+			r.resolveExpr(arm.Guard)
+			switch body := arm.Body.(type) {
+			case *ast.BlockStmt:
+				r.resolveBlock(body)
+			case ast.Expr:
+				r.resolveExpr(body)
+			}
+			r.closeScope()
+		}
+	}
+}
+
+// resolveModuleMember binds the member of an imported module, like `vec.add`.
+func (r *resolver) resolveModuleMember(member *ast.MemberExpr) {
+	target, isName := member.Target.(*ast.Identifier)
+	if !isName {
+		return
+	}
+	module := r.table.Uses[target]
+	if module == nil || module.Kind != ModuleSymbol || module.Module == nil {
+		return
+	}
+	path := module.Decl.(*ast.ImportDecl).PathString()
+	symbol := module.Module.LookupLocal(member.Name.Name)
+	switch {
+	case symbol == nil:
+		r.report(errorAt(member.Name, compiler.UndefinedName, fmt.Sprintf(
+			"Module '%s' has no declaration named '%s'", path, member.Name.Name,
+		)))
+	case !symbol.IsPublic():
+		r.use(member.Name, symbol)
+		r.report(errorAt(member.Name, compiler.UnresolvedImport, fmt.Sprintf(
+			"'%s' is not public in module '%s', declare it with 'pub'", member.Name.Name, path,
+		)))
+	default:
+		r.use(member.Name, symbol)
+	}
+}
+
+// variantPatterns turns the bare names of a pattern which name an enum variant, like `None`,
+// into variant patterns, so that they match the variant instead of binding any value.
+func (r *resolver) variantPatterns(pattern ast.Pattern) ast.Pattern {
+	return ast.Transform(pattern, func(node ast.Node) ast.Node {
+		if binding, isBinding := node.(*ast.BindingPattern); isBinding && binding.Pattern == nil && r.variants[binding.Name.Name] {
+			return &ast.VariantPattern{Span: binding.Span, Variant: binding.Name}
+		}
+		return node
+	}).(ast.Pattern)
+}
+
+// resolvePattern declares the names bound by a pattern in the current scope,
+// and resolves the types and variants it refers to.
+func (r *resolver) resolvePattern(pattern ast.Pattern) {
+	switch p := pattern.(type) {
+	case *ast.BindingPattern:
+		r.declare(BindingSymbol, p.Name, p)
+		if p.Pattern != nil {
+			r.resolvePattern(p.Pattern)
+		}
+	case *ast.TypePattern:
+		r.resolveType(p.Type)
+		r.declare(BindingSymbol, p.Name, p)
+	case *ast.TuplePattern:
+		for _, element := range p.Elements {
+			r.resolvePattern(element)
+		}
+	case *ast.StructPattern:
+		r.resolveExpr(p.Type)
+		for _, field := range p.Fields {
+			if field.Pattern == nil {
+				r.declare(BindingSymbol, field.Name, field)
+			} else {
+				r.resolvePattern(field.Pattern)
+			}
+		}
+	case *ast.VariantPattern:
+		r.resolveExpr(p.Variant)
+		for _, element := range p.Elements {
+			r.resolvePattern(element)
+		}
+	}
+}
+
+func errorAt(node ast.Node, code compiler.DiagnosticCode, msg string) *compiler.Diagnostic {
+	span := node.NodeSpan()
+	return &compiler.Diagnostic{Type: compiler.DiagnosticError, Code: code, Pos: span.Start, End: span.End, Msg: msg}
+}
+
+func warningAt(node ast.Node, code compiler.DiagnosticCode, msg string) *compiler.Diagnostic {
+	diagnostic := errorAt(node, code, msg)
+	diagnostic.Type = compiler.DiagnosticWarning
+	return diagnostic
+}
@@ -0,0 +1,183 @@
+package resolve
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/parser"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func parseFile(source string) *ast.File {
+	fileParser := parser.CreateParser(source)
+	file := fileParser.ParseFile().Unwrap()
+	So(fileParser.Diagnostics(), ShouldBeEmpty)
+	return file
+}
+
+func messages(diagnostics []*compiler.Diagnostic) []string {
+	var msgs []string
+	for _, diagnostic := range diagnostics {
+		msgs = append(msgs, diagnostic.Msg)
+	}
+	return msgs
+}
+
+// identAt returns the identifier of the file at the start of the first occurrence of the snippet in its source.
+func identAt(file *ast.File, source, snippet string) *ast.Identifier {
+	offset := strings.Index(source, snippet)
+	var found *ast.Identifier
+	ast.Inspect(file, func(node ast.Node) bool {
+		if ident, isIdent := node.(*ast.Identifier); isIdent && ident.Start.Offset == offset {
+			found = ident
+		}
+		return found == nil
+	})
+	return found
+}
+
+func TestResolve(t *testing.T) {
+	Convey("Test bind uses to their declarations", t, func() {
+		source := `func sum(values: []int) int {
+  let total = 0
+  for value in values {
+    total += value
+  }
+  return total + offset
+}
+let offset = 1`
+		file := parseFile(source)
+		table, diagnostics := ResolveFile(file)
+		So(diagnostics, ShouldBeEmpty)
+
+		total := table.Defs[identAt(file, source, "total = 0")]
+		So(total.Name, ShouldEqual, "total")
+		So(total.Kind, ShouldEqual, VarSymbol)
+		So(len(total.Uses), ShouldEqual, 2)
+		So(table.SymbolOf(total.Uses[0]), ShouldEqual, total)
+		So(total.Scope.Kind, ShouldEqual, FunctionScope)
+
+		value := table.Uses[identAt(file, source, "value\n")]
+		So(value.Kind, ShouldEqual, BindingSymbol)
+		So(value.Scope.Kind, ShouldEqual, LoopScope)
+
+		offset := table.Module.LookupLocal("offset")
+		So(offset.Uses, ShouldResemble, []*ast.Identifier{identAt(file, source, "offset\n")})
+		So(table.Uses[identAt(file, source, "int")], ShouldEqual, table.Universe.LookupLocal("int"))
+	})
+
+	Convey("Test report undefined names with suggestions", t, func() {
+		file := parseFile(`let count = 0
+func f(values: []int) {
+  cuont += 1
+  undefinedThing()
+  let result: strng = valeus
+  outer: for v in values { break otuer }
+}`)
+		_, diagnostics := ResolveFile(file)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Undefined name 'cuont', did you mean 'count'?",
+			"Undefined name 'undefinedThing'",
+			"Undefined name 'strng', did you mean 'string'?",
+			"Undefined name 'valeus', did you mean 'values'?",
+			"Undefined label 'otuer', did you mean 'outer'?",
+		})
+		So(diagnostics[0].Code, ShouldEqual, compiler.UndefinedName)
+		So(diagnostics[0].Pos.Line, ShouldEqual, 3)
+	})
+
+	Convey("Test local variables are visible after their declaration", t, func() {
+		file := parseFile("func f() {\n  g()\n  x\n  let x = 1\n  func g() {}\n}")
+		table, diagnostics := ResolveFile(file)
+		So(messages(diagnostics), ShouldResemble, []string{"Undefined name 'x'"})
+
+		scope := table.ScopeAt(file, &compiler.Position{Offset: 15})
+		So(scope.Kind, ShouldEqual, FunctionScope)
+		So(scope.LookupAt("x", &compiler.Position{Offset: 15}), ShouldBeNil)
+		So(scope.LookupAt("g", &compiler.Position{Offset: 15}).Kind, ShouldEqual, FuncSymbol)
+		So(table.LookupAt(file, &compiler.Position{Offset: 40}, "x").Kind, ShouldEqual, VarSymbol)
+	})
+
+	Convey("Test report duplicate declarations and shadowing", t, func() {
+		file := parseFile(`struct Point {}
+func Point() {}
+func f(a: int, a: int) {
+  let b = 1
+  if a > 0 {
+    let b = 2
+  }
+  let f = (a) => a
+}
+let x = match (1, 2) { (y, y) => y }`)
+		_, diagnostics := ResolveFile(file)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Duplicate declaration of 'Point', it's already declared as a struct at 1:8",
+			"Duplicate declaration of 'a', it's already declared as a parameter at 3:8",
+			"Declaration of 'b' shadows the variable declared at 4:7",
+			"Declaration of 'a' shadows the parameter declared at 3:8",
+			"Duplicate declaration of 'y', it's already declared as a binding at 10:25",
+		})
+		So(diagnostics[0].Code, ShouldEqual, compiler.DuplicateDeclaration)
+		So(diagnostics[2].Type, ShouldEqual, compiler.DiagnosticWarning)
+		So(diagnostics[2].Code, ShouldEqual, compiler.ShadowedDeclaration)
+	})
+
+	Convey("Test resolve types, patterns and variants", t, func() {
+		source := `enum Shape<T> { Circle { radius: T }, Square(T) }
+func area<T>(shape: Shape<T>) T {
+  return match shape {
+    Shape.Circle { radius } => radius
+    Square(side) if side > limit => side
+    other => 0
+  }
+}`
+		file := parseFile(source)
+		table, diagnostics := ResolveFile(file)
+		So(messages(diagnostics), ShouldResemble, []string{"Undefined name 'limit'"})
+		radius := table.Defs[identAt(file, source, "radius }")]
+		So(radius.Kind, ShouldEqual, BindingSymbol)
+		So(radius.Scope.Kind, ShouldEqual, MatchArmScope)
+		So(len(radius.Uses), ShouldEqual, 1)
+		So(table.Uses[identAt(file, source, "T {\n  return")].Kind, ShouldEqual, TypeParamSymbol)
+	})
+}
+
+func TestResolveModule(t *testing.T) {
+	Convey("Test resolve a module of several files with imports", t, func() {
+		secondSource := "let d = plus\nlet e = a\nlet f = vec"
+		vector, _ := ResolveFile(parseFile("pub func add() {}\nfunc secret() {}"))
+		files := []*ast.File{
+			parseFile("import geometry.vector as vec\nimport geometry.vector.{add as plus}\nlet a = vec.add\nlet b = vec.secret\nlet c = vec.mul"),
+			parseFile(secondSource),
+		}
+		importer := func(decl *ast.ImportDecl) *Scope { return vector.Module }
+		table, diagnostics := ResolveModule(files, importer)
+
+		So(messages(diagnostics[0]), ShouldResemble, []string{
+			"'secret' is not public in module 'geometry.vector', declare it with 'pub'",
+			"Module 'geometry.vector' has no declaration named 'mul'",
+		})
+		// The imports are visible in their file only
+		So(messages(diagnostics[1]), ShouldResemble, []string{"Undefined name 'plus'", "Undefined name 'vec'"})
+
+		add := vector.Module.LookupLocal("add")
+		So(len(add.Uses), ShouldEqual, 2)
+		plus := table.Scopes[files[0]].LookupLocal("plus")
+		So(plus.Kind, ShouldEqual, ImportSymbol)
+		So(plus.Target, ShouldEqual, add)
+		So(table.Module.LookupLocal("a").Uses[0], ShouldEqual, identAt(files[1], secondSource, "a\n"))
+	})
+}
+
+func TestSuggest(t *testing.T) {
+	Convey("Test suggest similar names", t, func() {
+		So(editDistance("count", "cuont"), ShouldEqual, 1)
+		So(editDistance("kitten", "sitting"), ShouldEqual, 3)
+		So(suggest("lenght", []string{"height", "length", "len"}), ShouldEqual, "length")
+		So(suggest("x", []string{"y", "z"}), ShouldEqual, "")
+		So(suggest("ab", []string{"cb"}), ShouldEqual, "cb")
+		So(suggest("total", []string{"count"}), ShouldEqual, "")
+	})
+}
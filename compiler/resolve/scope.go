@@ -0,0 +1,228 @@
+package resolve
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"sort"
+)
+
+type SymbolKind int
+
+const (
+	VarSymbol SymbolKind = iota
+	ConstSymbol
+	ParamSymbol
+	BindingSymbol // A name bound by a match pattern, or by a `for x in` loop
+	FuncSymbol
+	StructSymbol
+	InterfaceSymbol
+	EnumSymbol
+	TypeParamSymbol
+	TypeSymbol // A predeclared type, like `int`
+	ModuleSymbol
+	ImportSymbol // A declaration imported by a selective import
+	LabelSymbol
+)
+
+var symbolKindNames = [...]string{
+	VarSymbol:       "variable",
+	ConstSymbol:     "constant",
+	ParamSymbol:     "parameter",
+	BindingSymbol:   "binding",
+	FuncSymbol:      "function",
+	StructSymbol:    "struct",
+	InterfaceSymbol: "interface",
+	EnumSymbol:      "enum",
+	TypeParamSymbol: "type parameter",
+	TypeSymbol:      "type",
+	ModuleSymbol:    "module",
+	ImportSymbol:    "import",
+	LabelSymbol:     "label",
+}
+
+func (k SymbolKind) String() string {
+	return symbolKindNames[k]
+}
+
+// Symbol is a declared name. The predeclared symbols of the universe have no Decl nor Ident.
+type Symbol struct {
+	Name  string
+	Kind  SymbolKind
+	Decl  ast.Node // The declaring node, like the *ast.VarDecl or the *ast.Param
+	Ident *ast.Identifier
+	Scope *Scope
+	// Uses are the identifiers referring to the symbol, in source order.
+	Uses []*ast.Identifier
+	// Target is the declaration of the imported module which an ImportSymbol refers to,
+	// it's nil if the module or the declaration isn't found.
+	Target *Symbol
+	// Module is the scope of the module a ModuleSymbol refers to, or nil if it isn't found.
+	Module *Scope
+}
+
+// IsPublic tells whether the symbol is visible from the modules importing its module.
+func (s *Symbol) IsPublic() bool {
+	decl, isDecl := s.Decl.(ast.Decl)
+	return isDecl && decl.IsPublic()
+}
+
+// isHoisted tells whether the symbol is visible in its whole scope, and not only after its declaration.
+func (s *Symbol) isHoisted() bool {
+	switch s.Kind {
+	case VarSymbol, ConstSymbol, BindingSymbol:
+		return s.Scope.Kind == ModuleScope
+	}
+	return true
+}
+
+type ScopeKind int
+
+const (
+	UniverseScope ScopeKind = iota
+	ModuleScope
+	FileScope // Holds the imports of a file
+	TypeScope // Holds the type parameters of a struct or an enum
+	FunctionScope
+	BlockScope
+	LoopScope
+	MatchArmScope
+)
+
+// Scope maps names to symbols, the names of the enclosing scopes are visible
+// unless they're shadowed.
+type Scope struct {
+	Kind     ScopeKind
+	Node     ast.Node // The node which introduces the scope, nil for the universe and the module
+	Parent   *Scope
+	Children []*Scope
+	symbols  map[string]*Symbol
+	order    []*Symbol
+}
+
+func newScope(kind ScopeKind, node ast.Node, parent *Scope) *Scope {
+	scope := &Scope{Kind: kind, Node: node, Parent: parent, symbols: map[string]*Symbol{}}
+	if parent != nil {
+		parent.Children = append(parent.Children, scope)
+	}
+	return scope
+}
+
+func (s *Scope) insert(symbol *Symbol) {
+	symbol.Scope = s
+	s.symbols[symbol.Name] = symbol
+	s.order = append(s.order, symbol)
+}
+
+// Symbols returns the symbols declared in the scope, in declaration order.
+func (s *Scope) Symbols() []*Symbol {
+	return s.order
+}
+
+// LookupLocal returns the symbol with the name declared in the scope itself, or nil.
+func (s *Scope) LookupLocal(name string) *Symbol {
+	return s.symbols[name]
+}
+
+// Lookup returns the symbol with the name in the scope or the innermost enclosing scope, or nil.
+func (s *Scope) Lookup(name string) *Symbol {
+	for scope := s; scope != nil; scope = scope.Parent {
+		if symbol := scope.symbols[name]; symbol != nil {
+			return symbol
+		}
+	}
+	return nil
+}
+
+// LookupAt is like Lookup, but leaves out the local variables declared after the position.
+func (s *Scope) LookupAt(name string, pos *compiler.Position) *Symbol {
+	for scope := s; scope != nil; scope = scope.Parent {
+		if symbol := scope.symbols[name]; symbol != nil && (symbol.isHoisted() || symbol.Ident.Start.Offset < pos.Offset) {
+			return symbol
+		}
+	}
+	return nil
+}
+
+// Names returns the sorted names visible in the scope.
+func (s *Scope) Names() []string {
+	seen := map[string]bool{}
+	var names []string
+	for scope := s; scope != nil; scope = scope.Parent {
+		for name := range scope.symbols {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Innermost returns the innermost scope enclosing the position, starting from the scope.
+func (s *Scope) Innermost(pos *compiler.Position) *Scope {
+	for _, child := range s.Children {
+		if child.Node == nil {
+			continue
+		}
+		span := child.Node.NodeSpan()
+		if span.Start.Offset <= pos.Offset && pos.Offset < span.End.Offset {
+			return child.Innermost(pos)
+		}
+	}
+	return s
+}
+
+// Table is the result of the name resolution of a module, which tools can query.
+type Table struct {
+	Universe *Scope
+	Module   *Scope
+	// Defs maps the identifiers which declare a symbol to it.
+	Defs map[*ast.Identifier]*Symbol
+	// Uses maps the identifiers which refer to a symbol to it.
+	Uses map[*ast.Identifier]*Symbol
+	// Scopes maps the nodes introducing a scope to it, like files, functions and blocks.
+	Scopes map[ast.Node]*Scope
+}
+
+// SymbolOf returns the symbol declared by or referred to by the identifier, or nil.
+func (t *Table) SymbolOf(ident *ast.Identifier) *Symbol {
+	if symbol := t.Defs[ident]; symbol != nil {
+		return symbol
+	}
+	return t.Uses[ident]
+}
+
+// ScopeAt returns the innermost scope enclosing the position of the file.
+func (t *Table) ScopeAt(file *ast.File, pos *compiler.Position) *Scope {
+	if scope := t.Scopes[file]; scope != nil {
+		return scope.Innermost(pos)
+	}
+	return t.Module
+}
+
+// LookupAt returns the symbol a name refers to at the position of the file, or nil.
+func (t *Table) LookupAt(file *ast.File, pos *compiler.Position, name string) *Symbol {
+	return t.ScopeAt(file, pos).LookupAt(name, pos)
+}
+
+// universeTypes are the names of the predeclared types, see types.Predeclared.
+var universeTypes = []string{
+	"bool", "int", "i8", "i16", "i32", "i64", "uint", "u8", "byte", "u16", "u32", "u64",
+	"f32", "f64", "float", "string", "rune",
+}
+
+// predeclaredVariants are the variants of the predeclared enums, which can be used unqualified.
+var predeclaredVariants = []string{"Some", "None", "Ok", "Err"}
+
+// CreateUniverse returns a scope with the predeclared types and enums.
+func CreateUniverse() *Scope {
+	universe := newScope(UniverseScope, nil, nil)
+	for _, name := range universeTypes {
+		universe.insert(&Symbol{Name: name, Kind: TypeSymbol})
+	}
+	for _, name := range []string{"Option", "Result"} {
+		universe.insert(&Symbol{Name: name, Kind: EnumSymbol})
+	}
+	return universe
+}
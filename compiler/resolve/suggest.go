@@ -0,0 +1,58 @@
+package resolve
+
+// suggest returns the candidate most similar to a misspelled name, or "" if none is close
+// enough. A name is close enough if its edit distance to the misspelled one is at most
+// a third of the length of the misspelled one, rounded to the nearest integer, so that
+// there are no suggestions for single character names.
+func suggest(name string, candidates []string) string {
+	maxDistance := (len([]rune(name)) + 1) / 3
+	best, bestDistance := "", maxDistance+1
+	for _, candidate := range candidates {
+		if candidate == name {
+			continue
+		}
+		if distance := editDistance(name, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and transpositions
+// of adjacent characters turning a into b (the optimal string alignment distance).
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// distances[i][j] is the distance between the first i runes of s and the first j runes of t
+	distances := make([][]int, len(s)+1)
+	for i := range distances {
+		distances[i] = make([]int, len(t)+1)
+		distances[i][0] = i
+	}
+	for j := range distances[0] {
+		distances[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			distance := minInt(distances[i-1][j]+1, distances[i][j-1]+1, distances[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				distance = minInt(distance, distances[i-2][j-2]+1)
+			}
+			distances[i][j] = distance
+		}
+	}
+	return distances[len(s)][len(t)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
@@ -3,6 +3,7 @@ package compiler
 import (
 	"fmt"
 	"mirth/shared"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
@@ -24,6 +25,14 @@ type Scanner struct {
 	// is reading the text part of the template string.
 	readingTemplateStrText bool
 	templateStrNested      int
+	// Depth of curly braces opened inside each nested interpolation,
+	// so that `${ f(() => { x }) }` ends at the last '}'.
+	interpolationBraceDepths []int
+
+	// Operators recognized by the scanner, including custom ones
+	operators *OperatorTable
+	// Start position of the token being scanned
+	tokenStart *Position
 
 	warnings []*Diagnostic
 }
@@ -43,11 +52,56 @@ func CreateScanner[S AvailableSource](source S) *Scanner {
 		offset:                 0,
 		templateStrNested:      0,
 		readingTemplateStrText: false,
+		operators:              defaultOperatorTable,
 	}
 	scanner.updatePeekCache()
 	return scanner
 }
 
+// UseOperatorTable makes the scanner recognize the custom operators declared in the table.
+func (s *Scanner) UseOperatorTable(table *OperatorTable) {
+	s.operators = table
+}
+
+// Warnings returns the warnings reported so far, which don't stop the scanning.
+func (s *Scanner) Warnings() []*Diagnostic {
+	return s.warnings
+}
+
+// Tokenize scans the whole source and collects all the tokens,
+// the last token is always an end-of-file token. A scanning error
+// doesn't stop the scanner, it's recorded and the scanner continues
+// from where the error was found.
+func (s *Scanner) Tokenize() ([]*Token, []*Diagnostic) {
+	var tokens []*Token
+	var diagnostics []*Diagnostic
+	for s.offset < len(s.source) {
+		offsetBeforeScan := s.offset
+		result := s.getNextToken()
+		if result.Ok {
+			tokens = append(tokens, result.Unwrap())
+			continue
+		}
+		// Only whitespaces are left after the last token
+		if result.Err.Code == FailedToRetrieveToken && s.offset >= len(s.source) {
+			break
+		}
+		diagnostics = append(diagnostics, result.Err)
+		// Make sure the scanner always makes progress after an error
+		if s.offset == offsetBeforeScan {
+			s.advanceRune()
+		}
+	}
+	s.tokenStart = s.getCurrentPosition()
+	tokens = append(tokens, s.makeToken(TokenTypeEndOfFile, ""))
+
+	diagnostics = append(diagnostics, s.warnings...)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Pos.Offset < diagnostics[j].Pos.Offset
+	})
+	return tokens, diagnostics
+}
+
 func (s *Scanner) getCurrentPosition() *Position {
 	return CreatePositon(
 		s.offset,
@@ -91,35 +145,27 @@ func (s *Scanner) peekForwardStepRune(step int) *UniRune {
 }
 
 func (s *Scanner) advanceRune() {
+	if isLineBreak(s.currentRune) {
+		s.line += 1
+		s.column = 1
+	} else if s.currentRune.byteLength > 0 {
+		s.column += 1
+	}
 	s.offset += s.currentRune.byteLength
 	s.updatePeekCache()
 }
 
 func (s *Scanner) advanceRuneByStep(step int) {
-	forwardOffset := s.currentRune.byteLength
-	unisegState := -1
-	contentSlice := s.source[s.offset+forwardOffset:]
-	var graphemeBytes []byte
-	for i := 0; i < step-1; i++ {
-		graphemeBytes, contentSlice, _, unisegState = uniseg.FirstGraphemeCluster(contentSlice, unisegState)
-		if len(graphemeBytes) == 0 {
-			break
-		}
-		forwardOffset += len(graphemeBytes)
+	for i := 0; i < step; i++ {
+		s.advanceRune()
 	}
-	s.offset += forwardOffset
-	s.updatePeekCache()
 }
 
 func (s *Scanner) makeToken(tokenType TokenType, value string) *Token {
-	s.column += uniseg.GraphemeClusterCount(value)
 	return &Token{
 		tokenType,
-		CreatePositon(
-			s.offset,
-			s.line,
-			s.column,
-		),
+		s.tokenStart,
+		s.getCurrentPosition(),
 		value,
 	}
 }
@@ -135,11 +181,7 @@ func (s *Scanner) createScannerErr(errCode DiagnosticCode, message string) *Diag
 func (s *Scanner) createScannerWarn(warnCode DiagnosticCode, message string) *Diagnostic {
 	return CreateWarningDiagnostic(
 		warnCode,
-		CreatePositon(
-			s.offset,
-			s.line,
-			s.column,
-		),
+		s.getCurrentPosition(),
 		message,
 	)
 }
@@ -166,7 +208,7 @@ func (s *Scanner) throwUpDiagnostic(diagnostic *Diagnostic) *ScanResult {
 
 func (s *Scanner) readLineComment() *ScanResult {
 	var comment string
-	for r := s.currentRune; !isLineBreak(r); r = s.currentRune {
+	for r := s.currentRune; s.offset < len(s.source) && !isLineBreak(r); r = s.currentRune {
 		s.advanceRune()
 		comment += r.raw
 	}
@@ -355,6 +397,14 @@ func (s *Scanner) readHexSequenceStrForRune(length int) *shared.Result[string, *
 
 func (s *Scanner) readTextContent(isEnd func(*Scanner) bool, appendContent func(string)) *shared.Result[any, *Diagnostic] {
 	for !isEnd(s) {
+		if s.offset >= len(s.source) {
+			return shared.ResultErr[any](
+				s.createScannerErr(
+					UnexpectedEndOfFile,
+					"Unexpected end of file",
+				),
+			)
+		}
 		if s.currentRune.isRune('\n') {
 			return shared.ResultErr[any](
 				s.createScannerErr(
@@ -485,6 +535,133 @@ func (s *Scanner) readString() *ScanResult {
 	)
 }
 
+// readByteTextContent works like readTextContent, but for byte literals.
+// Every escape sequence stands for exactly one byte, so `\xFF` is kept as
+// the byte 0xFF instead of being encoded as the code point U+00FF.
+func (s *Scanner) readByteTextContent(isEnd func(*Scanner) bool, appendContent func(byte)) *shared.Result[any, *Diagnostic] {
+	for !isEnd(s) {
+		if s.offset >= len(s.source) {
+			return shared.ResultErr[any](
+				s.createScannerErr(
+					UnexpectedEndOfFile,
+					"Unexpected end of file",
+				),
+			)
+		}
+		if s.currentRune.isRune('\n') {
+			return shared.ResultErr[any](
+				s.createScannerErr(
+					UnexpectedToken,
+					"Unexpected line break",
+				),
+			)
+		}
+
+		if s.currentRune.isRune('\\') {
+			if escaped, isSingleEscape := singleEscapeSymbolsRuneMap[s.nextRune.raw]; isSingleEscape {
+				appendContent(escaped[0])
+				s.advanceRuneByStep(2) // Moving over the '\' and the escaped symbol
+				continue
+			}
+			if !s.nextRune.isRune('x') {
+				return shared.ResultErr[any](
+					s.createScannerErr(
+						UnexpectedToken,
+						fmt.Sprintf(
+							"Unexpected token: invalid escape symbol '%s' in byte literal",
+							string(s.nextRune.raw),
+						),
+					),
+				)
+			}
+			s.advanceRuneByStep(2) // Moving over the '\' and the 'x'
+
+			// Read the next 2 hexadecimal digits as a raw byte
+			var value byte
+			for i := 0; i < 2; i++ {
+				if !isHexDigit(s.currentRune) {
+					return shared.ResultErr[any](
+						s.createScannerErr(
+							UnexpectedToken,
+							fmt.Sprintf(
+								"Unexpected token: invalid hexadecimal digit '%s' in byte escape sequence",
+								string(s.currentRune.raw),
+							),
+						),
+					)
+				}
+				value = value<<4 | hexDigitValue(s.currentRune.firstRune())
+				s.advanceRune()
+			}
+			appendContent(value)
+		} else if !isASCII(s.currentRune) {
+			return shared.ResultErr[any](
+				s.createScannerErr(
+					UnexpectedToken,
+					fmt.Sprintf(
+						"Unexpected token: non-ASCII character '%s' in byte literal, use '\\x' escapes instead",
+						string(s.currentRune.raw),
+					),
+				),
+			)
+		} else {
+			appendContent(s.currentRune.raw[0])
+			s.advanceRune()
+		}
+	}
+	return shared.ResultPass[*Diagnostic]()
+}
+
+func (s *Scanner) readByte() *ScanResult {
+	s.advanceRuneByStep(2) // Moving over the 'b' and the first quote
+
+	var byteContent []byte
+	readTextResult := s.readByteTextContent(func(s *Scanner) bool {
+		return s.currentRune.isRune('\'')
+	}, func(b byte) {
+		byteContent = append(byteContent, b)
+	})
+	if !readTextResult.Ok {
+		return s.throwUpDiagnostic(readTextResult.Err)
+	}
+	if len(byteContent) != 1 {
+		// Moving over the last quote, so that the scanning goes on after the literal
+		s.advanceRune()
+		err := s.createScannerErr(
+			UnexpectedToken,
+			"Unexpected token: byte literal should contain exactly one byte",
+		)
+		err.Pos, err.End = s.tokenStart, s.getCurrentPosition()
+		return s.ResultErr(err)
+	}
+
+	// Moving over the last quote
+	s.advanceRune()
+	return s.ResultOk(
+		s.makeToken(TokenTypeByte, string(byteContent)),
+	)
+}
+
+func (s *Scanner) readByteString() *ScanResult {
+	s.advanceRuneByStep(2) // Moving over the 'b' and the first quote
+
+	var byteStringContent []byte
+	readTextResult := s.readByteTextContent(func(s *Scanner) bool {
+		return s.currentRune.isRune('"')
+	}, func(b byte) {
+		byteStringContent = append(byteStringContent, b)
+	})
+	if !readTextResult.Ok {
+		return s.throwUpDiagnostic(readTextResult.Err)
+	}
+
+	// Moving over the last quote
+	s.advanceRune()
+	return s.ResultOk(
+		s.makeToken(TokenTypeByteString, string(byteStringContent)),
+	)
+}
+
 func (s *Scanner) meetTemplateInterpolationStart() bool {
 	return s.currentRune.isRune('$') && s.nextRune.isRune('{')
 }
@@ -534,7 +711,18 @@ func (s *Scanner) resultMultiRuneToken(tokenType TokenType, tokenContent string)
 	)
 }
 
+// readOperator reads the longest operator at the current offset,
+// returns nil if the source doesn't start with any known operator.
+func (s *Scanner) readOperator() *ScanResult {
+	operator := s.operators.longestMatch(s.source[s.offset:])
+	if operator == nil {
+		return nil
+	}
+	return s.resultMultiRuneToken(operator.Type, operator.Symbol)
+}
+
 func (s *Scanner) getNextToken() *ScanResult {
+	s.tokenStart = s.getCurrentPosition()
 	if s.readingTemplateStrText {
 		templateStrTextResult := s.readTemplateStrText()
 		if templateStrTextResult != nil {
@@ -543,6 +731,7 @@ func (s *Scanner) getNextToken() *ScanResult {
 	}
 
 	for s.offset < len(s.source) {
+		s.tokenStart = s.getCurrentPosition()
 		r := s.currentRune
 		switch r.raw {
 		case " ", "\t", "\r":
@@ -559,6 +748,7 @@ func (s *Scanner) getNextToken() *ScanResult {
 				s.advanceRuneByStep(2) // Moving over the '$' and the '{'
 				// Increase the nested level of template string interpolation
 				s.templateStrNested += 1
+				s.interpolationBraceDepths = append(s.interpolationBraceDepths, 0)
 				if s.templateStrNested > 5 {
 					tooManyNested := s.createScannerWarn(
 						UnexpectedToken,
@@ -570,132 +760,28 @@ func (s *Scanner) getNextToken() *ScanResult {
 					s.makeToken(TokenTypeInterplolationStart, "${"),
 				)
 			}
-		case ";":
-			return s.resultSingleRuneToken(TokenTypeSemi, r.raw)
-		case ",":
-			return s.resultSingleRuneToken(TokenTypeComma, r.raw)
-		case ":":
-			return s.resultSingleRuneToken(TokenTypeColon, r.raw)
-		case "(":
-			return s.resultSingleRuneToken(TokenTypeLeftParen, r.raw)
-		case ")":
-			return s.resultSingleRuneToken(TokenTypeRightParen, r.raw)
+			return s.readIdentifier()
 		case "{":
-			return s.resultSingleRuneToken(TokenTypeLeftCurly, r.raw)
+			if s.templateStrNested > 0 {
+				s.interpolationBraceDepths[s.templateStrNested-1] += 1
+			}
+			return s.readOperator()
 		case "}":
 			if !s.readingTemplateStrText && s.templateStrNested > 0 {
-				s.templateStrNested -= 1
-				s.readingTemplateStrText = true
-			}
-			return s.resultSingleRuneToken(TokenTypeRightCurly, r.raw)
-		case "[":
-			return s.resultSingleRuneToken(TokenTypeLeftBracket, r.raw)
-		case "]":
-			return s.resultSingleRuneToken(TokenTypeRightBracket, r.raw)
-		case ".":
-			// If here're actually two or three dots, it's regard as range operator.
-			if s.nextRune.isRune('.') {
-				if s.peekForwardStepRune(2).isRune('.') {
-					return s.resultMultiRuneToken(TokenTypeEllipsis, "...")
+				if s.interpolationBraceDepths[s.templateStrNested-1] > 0 {
+					s.interpolationBraceDepths[s.templateStrNested-1] -= 1
+				} else {
+					s.templateStrNested -= 1
+					s.interpolationBraceDepths = s.interpolationBraceDepths[:s.templateStrNested]
+					s.readingTemplateStrText = true
 				}
-				return s.resultMultiRuneToken(TokenTypeDoubleDots, "..")
 			}
-			return s.resultSingleRuneToken(TokenTypeDot, r.raw)
-		case "=":
-			if s.nextRune.isRune('=') {
-				return s.resultMultiRuneToken(TokenTypeDoubleEqual, "==")
-			} else if s.nextRune.isRune('>') {
-				return s.resultMultiRuneToken(TokenTypeArrow, "=>")
-			}
-			return s.resultSingleRuneToken(TokenTypeEqual, r.raw)
-		case "+":
-			if s.nextRune.isRune('=') {
-				return s.resultMultiRuneToken(TokenTypePlusEqual, "+=")
-			} else if s.nextRune.isRune('+') {
-				return s.resultMultiRuneToken(TokenTypeDoublePlus, "++")
-			}
-			return s.resultSingleRuneToken(TokenTypePlus, r.raw)
-		case "-":
-			if s.nextRune.isRune('=') {
-				return s.resultMultiRuneToken(TokenTypeMinusEqual, "-=")
-			} else if s.nextRune.isRune('-') {
-				return s.resultMultiRuneToken(TokenTypeDoubleMinus, "--")
-			}
-			return s.resultSingleRuneToken(TokenTypeMinus, r.raw)
-		case "*":
-			// If here're actually two stars, it's regard as power operator.
-			if s.nextRune.isRune('*') {
-				return s.resultMultiRuneToken(TokenTypeDoubleStar, "**")
-			}
-			if s.nextRune.isRune('=') {
-				return s.resultMultiRuneToken(TokenTypeStarEqual, "*=")
-			}
-			return s.resultSingleRuneToken(TokenTypeStar, r.raw)
+			return s.readOperator()
 		case "/":
-			if s.nextRune.isRune('=') {
-				return s.resultMultiRuneToken(TokenTypeSlashEqual, "/=")
-			} else if s.nextRune.isRune('/') {
+			if s.nextRune.isRune('/') {
 				return s.readLineComment()
 			}
-			return s.resultSingleRuneToken(TokenTypeSlash, r.raw)
-		case "%":
-			if s.nextRune.isRune('=') {
-				return s.resultMultiRuneToken(TokenTypePercentEqual, "%=")
-			}
-			return s.resultSingleRuneToken(TokenTypePercent, r.raw)
-		case "&":
-			if s.nextRune.isRune('&') {
-				return s.resultMultiRuneToken(TokenTypeDoubleAmpersand, "&&")
-			} else if s.nextRune.isRune('=') {
-				return s.resultMultiRuneToken(TokenTypeAmpersandEqual, "&=")
-			}
-			return s.resultSingleRuneToken(TokenTypeAmpersand, r.raw)
-		case "|":
-			if s.nextRune.isRune('|') {
-				return s.resultMultiRuneToken(TokenTypeDoubleVertical, "||")
-			} else if s.nextRune.isRune('=') {
-				return s.resultMultiRuneToken(TokenTypeVerticalEqual, "|=")
-			}
-			return s.resultSingleRuneToken(TokenTypeVertical, r.raw)
-		case "^":
-			if s.nextRune.isRune('=') {
-				return s.resultMultiRuneToken(TokenTypeCaretEqual, "^=")
-			}
-			return s.resultSingleRuneToken(TokenTypeCaret, r.raw)
-		case "~":
-			return s.resultSingleRuneToken(TokenTypeWavy, r.raw)
-		case "!":
-			if s.nextRune.isRune('=') {
-				return s.resultMultiRuneToken(TokenTypeBangEqual, "!=")
-			}
-			return s.resultSingleRuneToken(TokenTypeBang, r.raw)
-		case "<":
-			if s.nextRune.isRune('=') {
-				return s.resultMultiRuneToken(TokenTypeLeftAngleEqual, "<=")
-			} else if s.nextRune.isRune('<') {
-				if s.peekForwardStepRune(2).isRune('=') {
-					return s.resultMultiRuneToken(TokenTypeDoubleLeftAngleEqual, "<<=")
-				}
-				return s.resultMultiRuneToken(TokenTypeDoubleLeftAngle, "<<")
-			}
-			return s.resultSingleRuneToken(TokenTypeLeftAngle, r.raw)
-		case ">":
-			if s.nextRune.isRune('=') {
-				return s.resultMultiRuneToken(TokenTypeRightAngleEqual, ">=")
-			} else if s.nextRune.isRune('>') {
-				if s.peekForwardStepRune(2).isRune('=') {
-					return s.resultMultiRuneToken(TokenTypeDoubleRightAngleEqual, ">>=")
-				}
-				return s.resultMultiRuneToken(TokenTypeDoubleRightAngle, ">>")
-			}
-			return s.resultSingleRuneToken(TokenTypeRightAngle, r.raw)
-		case "?":
-			if s.nextRune.isRune('?') {
-				return s.resultMultiRuneToken(TokenTypeDoubleQuestion, "??")
-			} else if s.nextRune.isRune('.') {
-				return s.resultMultiRuneToken(TokenTypeQuestionDot, "?.")
-			}
-			return s.resultSingleRuneToken(TokenTypeQuestion, r.raw)
+			return s.readOperator()
 		case "'":
 			return s.readRune()
 		case "\"":
@@ -714,6 +800,15 @@ func (s *Scanner) getNextToken() *ScanResult {
 			if isDecimalDigit(r) {
 				return s.readNumber()
 			}
+			if operatorResult := s.readOperator(); operatorResult != nil {
+				return operatorResult
+			}
+			// Byte literals are prefixed with 'b', like b'x' and b"bytes"
+			if r.isRune('b') && s.nextRune.isRune('\'') {
+				return s.readByte()
+			} else if r.isRune('b') && s.nextRune.isRune('"') {
+				return s.readByteString()
+			}
 			return s.readIdentifier()
 		}
 	}
@@ -100,15 +100,19 @@ func TestScanPunctuations(t *testing.T) {
 		".":   TokenTypeDot,
 		"..":  TokenTypeDoubleDots,
 		"...": TokenTypeEllipsis,
+		"..=": TokenTypeDoubleDotsEqual,
 		"+":   TokenTypePlus,
 		"-":   TokenTypeMinus,
 		"*":   TokenTypeStar,
+		"**":  TokenTypeDoubleStar,
+		"**=": TokenTypeDoubleStarEqual,
 		"/":   TokenTypeSlash,
 		"%":   TokenTypePercent,
 		"&":   TokenTypeAmpersand,
 		"|":   TokenTypeVertical,
 		"^":   TokenTypeCaret,
 		"~":   TokenTypeWavy,
+		"@":   TokenTypeAlpha,
 		"=":   TokenTypeEqual,
 		"==":  TokenTypeDoubleEqual,
 		"!=":  TokenTypeBangEqual,
@@ -149,6 +153,55 @@ func TestScanPunctuations(t *testing.T) {
 	})
 }
 
+func TestScanCustomOperators(t *testing.T) {
+	table := CreateOperatorTable()
+	table.Declare("|>", PrecedenceAdditive, AssociativityLeft).Unwrap()
+	table.Declare("<=>", PrecedenceComparison, AssociativityNone).Unwrap()
+
+	Convey("Test scan custom operators with longest match", t, func() {
+		scanner := CreateScanner("xs|>sum <=> a<=b | c")
+		scanner.UseOperatorTable(table)
+		expectTokens := []struct {
+			tokenType TokenType
+			raw       string
+		}{
+			{TokenTypeIdentifier, "xs"},
+			{TokenTypeCustomOperator, "|>"},
+			{TokenTypeIdentifier, "sum"},
+			{TokenTypeCustomOperator, "<=>"},
+			{TokenTypeIdentifier, "a"},
+			{TokenTypeLeftAngleEqual, "<="},
+			{TokenTypeIdentifier, "b"},
+			{TokenTypeVertical, "|"},
+			{TokenTypeIdentifier, "c"},
+		}
+		for _, expectToken := range expectTokens {
+			token := scanner.getNextToken().Unwrap()
+			So(token.Type, ShouldEqual, expectToken.tokenType)
+			So(token.Content, ShouldEqual, expectToken.raw)
+		}
+	})
+
+	Convey("Test lookup custom operator precedence", t, func() {
+		operator, ok := table.Lookup("|>")
+		So(ok, ShouldBeTrue)
+		So(operator.Precedence, ShouldEqual, PrecedenceAdditive)
+		So(operator.Associativity, ShouldEqual, AssociativityLeft)
+
+		_, ok = CreateOperatorTable().Lookup("|>")
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("Test declare invalid custom operators", t, func() {
+		So(table.Declare("", PrecedenceAdditive, AssociativityLeft).Err, ShouldNotBeNil)
+		So(table.Declare("<$>", PrecedenceAdditive, AssociativityLeft).Err, ShouldNotBeNil)
+		So(table.Declare("//>", PrecedenceAdditive, AssociativityLeft).Err, ShouldNotBeNil)
+		So(table.Declare("**=", PrecedenceAdditive, AssociativityLeft).Err, ShouldNotBeNil)
+		So(table.Declare("|>", PrecedenceAdditive, AssociativityLeft).Err, ShouldNotBeNil)
+		So(table.Declare("+++", PrecedencePrefix, AssociativityLeft).Err, ShouldNotBeNil)
+	})
+}
+
 func TestScanLineComment(t *testing.T) {
 	source := "123 > 1.5e3\n// This is a line comment\n123"
 	expectTokenTypes := []TokenType{
@@ -300,3 +353,121 @@ func TestScanTemplateString(t *testing.T) {
 		}
 	})
 }
+
+func TestScanInterpolationBraces(t *testing.T) {
+	Convey("Test curly braces inside interpolation", t, func() {
+		tokens, diagnostics := CreateScanner("tag`a ${ {x} } b`").Tokenize()
+		So(diagnostics, ShouldBeEmpty)
+		var types []TokenType
+		for _, token := range tokens {
+			types = append(types, token.Type)
+		}
+		So(types, ShouldResemble, []TokenType{
+			TokenTypeIdentifier,
+			TokenTypeTemplateStringQuote,
+			TokenTypeTemplateStrFragment,
+			TokenTypeInterplolationStart,
+			TokenTypeLeftCurly,
+			TokenTypeIdentifier,
+			TokenTypeRightCurly,
+			TokenTypeRightCurly,
+			TokenTypeTemplateStrFragment,
+			TokenTypeTemplateStringQuote,
+			TokenTypeEndOfFile,
+		})
+	})
+}
+
+func TestTokenize(t *testing.T) {
+	Convey("Test tokenize whole source", t, func() {
+		scanner := CreateScanner("let a = 1 \n// comment at the end")
+		tokens, diagnostics := scanner.Tokenize()
+		So(diagnostics, ShouldBeEmpty)
+		So(len(tokens), ShouldEqual, 7)
+		So(tokens[5].Type, ShouldEqual, TokenTypeLineComment)
+		So(tokens[6].Type, ShouldEqual, TokenTypeEndOfFile)
+	})
+
+	Convey("Test tokenize continues after errors", t, func() {
+		scanner := CreateScanner("let a = 123e\nlet b = \"unterminated")
+		tokens, diagnostics := scanner.Tokenize()
+		So(len(diagnostics), ShouldEqual, 2)
+		So(diagnostics[0].Code, ShouldEqual, UnexpectedToken)
+		So(diagnostics[1].Code, ShouldEqual, UnexpectedEndOfFile)
+		So(tokens[len(tokens)-3].Content, ShouldEqual, "b")
+	})
+}
+
+func TestScanByteLiterals(t *testing.T) {
+	Convey("Test scan byte and byte string", t, func() {
+		scanner := CreateScanner("b'x' b'\\xFF' b\"\\x00\\xfe\\n ok\" b")
+		expectTokens := []struct {
+			tokenType TokenType
+			raw       string
+		}{
+			{TokenTypeByte, "x"},
+			{TokenTypeByte, "\xff"},
+			{TokenTypeByteString, "\x00\xfe\n ok"},
+			{TokenTypeIdentifier, "b"},
+		}
+		for _, expectToken := range expectTokens {
+			token := scanner.getNextToken().Unwrap()
+			So(token.Type, ShouldEqual, expectToken.tokenType)
+			So(token.Content, ShouldEqual, expectToken.raw)
+		}
+	})
+
+	Convey("Test scan byte literals but found non-ASCII characters", t, func() {
+		result := CreateScanner("b\"café\"").getNextToken()
+		So(result.Err, ShouldNotBeNil)
+		So(result.Err.Code, ShouldEqual, UnexpectedToken)
+		So(result.Err.Pos.Offset, ShouldEqual, 5)
+
+		result = CreateScanner("b'\\u4e16'").getNextToken()
+		So(result.Err, ShouldNotBeNil)
+		So(result.Err.Msg, ShouldEqual, "Unexpected token: invalid escape symbol 'u' in byte literal")
+	})
+
+	Convey("Test scan byte literal with more than one byte", t, func() {
+		result := CreateScanner("b'ab'").getNextToken()
+		So(result.Err, ShouldNotBeNil)
+		So(result.Err.Msg, ShouldEqual, "Unexpected token: byte literal should contain exactly one byte")
+
+		tokens, diagnostics := CreateScanner("b'ab' x").Tokenize()
+		So(len(diagnostics), ShouldEqual, 1)
+		So(diagnostics[0].Pos.Offset, ShouldEqual, 0)
+		So(diagnostics[0].End.Offset, ShouldEqual, 5)
+		So(len(tokens), ShouldEqual, 2)
+		So(tokens[0].Content, ShouldEqual, "x")
+	})
+}
+
+func TestScanTokenPositions(t *testing.T) {
+	Convey("Test token start and end positions", t, func() {
+		scanner := CreateScanner("let 世界 = \"hi\"\n  a <<= 1")
+		tokens, _ := scanner.Tokenize()
+		expectPositions := []struct {
+			raw         string
+			line        int
+			column      int
+			endColumn   int
+			startOffset int
+		}{
+			{"let", 1, 1, 4, 0},
+			{"世界", 1, 5, 7, 4},
+			{"=", 1, 8, 9, 11},
+			{"hi", 1, 10, 14, 13},
+			{"\n", 1, 14, 1, 17},
+			{"a", 2, 3, 4, 20},
+			{"<<=", 2, 5, 8, 22},
+			{"1", 2, 9, 10, 26},
+		}
+		for i, expectPosition := range expectPositions {
+			So(tokens[i].Content, ShouldEqual, expectPosition.raw)
+			So(tokens[i].Pos.Line, ShouldEqual, expectPosition.line)
+			So(tokens[i].Pos.Column, ShouldEqual, expectPosition.column)
+			So(tokens[i].End.Column, ShouldEqual, expectPosition.endColumn)
+			So(tokens[i].Pos.Offset, ShouldEqual, expectPosition.startOffset)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package syntax
+
+import (
+	"mirth/compiler"
+	"strings"
+)
+
+// Kind is the kind of a syntax node, named after the AST node it's parsed as,
+// like "BinaryExpr" and "FuncDecl".
+type Kind string
+
+// TriviaKind tells the tokens which don't matter to the parser apart from the others.
+type TriviaKind int
+
+const (
+	NotTrivia TriviaKind = iota
+	TriviaWhitespace
+	TriviaComment
+	// TriviaSkipped is source text the scanner failed to turn into tokens.
+	TriviaSkipped
+)
+
+// GreenElement is a *GreenNode or a *GreenToken.
+//
+// Green elements are immutable and only know their width, not their position,
+// so the same element can be shared by several trees. Identical tokens of a tree
+// are the same element, and editing a tree only rebuilds the path to the edit.
+type GreenElement interface {
+	Width() int
+	Text() string
+	greenElement()
+}
+
+// GreenToken is a token with its exact source text. Trivia tokens are
+// whitespace, comments and skipped text, their Type is zero for whitespace
+// and skipped text.
+type GreenToken struct {
+	Type   compiler.TokenType
+	Trivia TriviaKind
+	text   string
+}
+
+func CreateGreenToken(tokenType compiler.TokenType, trivia TriviaKind, text string) *GreenToken {
+	return &GreenToken{Type: tokenType, Trivia: trivia, text: text}
+}
+
+func (t *GreenToken) Width() int   { return len(t.text) }
+func (t *GreenToken) Text() string { return t.text }
+
+// GreenNode is an inner node of the green tree.
+type GreenNode struct {
+	Kind     Kind
+	width    int
+	children []GreenElement
+}
+
+func CreateGreenNode(kind Kind, children []GreenElement) *GreenNode {
+	width := 0
+	for _, child := range children {
+		width += child.Width()
+	}
+	return &GreenNode{Kind: kind, width: width, children: children}
+}
+
+func (n *GreenNode) Width() int { return n.width }
+
+// Children returns the child elements, which must not be modified.
+func (n *GreenNode) Children() []GreenElement {
+	return n.children
+}
+
+func (n *GreenNode) Text() string {
+	var builder strings.Builder
+	n.writeText(&builder)
+	return builder.String()
+}
+
+func (n *GreenNode) writeText(builder *strings.Builder) {
+	for _, child := range n.children {
+		if node, isNode := child.(*GreenNode); isNode {
+			node.writeText(builder)
+		} else {
+			builder.WriteString(child.Text())
+		}
+	}
+}
+
+// ReplaceChild returns a copy of the node with the child at the index replaced,
+// the other children are shared with this node.
+func (n *GreenNode) ReplaceChild(index int, child GreenElement) *GreenNode {
+	children := make([]GreenElement, len(n.children))
+	copy(children, n.children)
+	children[index] = child
+	return CreateGreenNode(n.Kind, children)
+}
+
+func (*GreenToken) greenElement() {}
+func (*GreenNode) greenElement()  {}
@@ -0,0 +1,171 @@
+package syntax
+
+import "mirth/compiler/ast"
+
+// Element is a *Node or a *Token of the red tree.
+//
+// Red elements wrap green elements with their absolute offset and their parent,
+// so the tree can be navigated in every direction. They are created on demand
+// and cached, so navigating to the same element twice returns the same pointer.
+type Element interface {
+	Parent() *Node
+	Offset() int
+	End() int
+	Text() string
+	// IndexInParent is the index of the element among the children of its parent.
+	IndexInParent() int
+	NextSibling() Element
+	PrevSibling() Element
+}
+
+type redBase struct {
+	parent *Node
+	index  int
+	offset int
+}
+
+func (b *redBase) Parent() *Node      { return b.parent }
+func (b *redBase) Offset() int        { return b.offset }
+func (b *redBase) IndexInParent() int { return b.index }
+
+func (b *redBase) NextSibling() Element {
+	if b.parent == nil || b.index+1 >= len(b.parent.Green.children) {
+		return nil
+	}
+	return b.parent.Child(b.index + 1)
+}
+
+func (b *redBase) PrevSibling() Element {
+	if b.parent == nil || b.index == 0 {
+		return nil
+	}
+	return b.parent.Child(b.index - 1)
+}
+
+// Token is a token of the red tree.
+type Token struct {
+	redBase
+	Green *GreenToken
+}
+
+func (t *Token) End() int     { return t.offset + t.Green.Width() }
+func (t *Token) Text() string { return t.Green.Text() }
+
+// Node is an inner node of the red tree.
+type Node struct {
+	redBase
+	Green    *GreenNode
+	tree     *Tree
+	children []Element // Created on demand
+}
+
+func (n *Node) Kind() Kind      { return n.Green.Kind }
+func (n *Node) End() int        { return n.offset + n.Green.Width() }
+func (n *Node) Text() string    { return n.Green.Text() }
+func (n *Node) ChildCount() int { return len(n.Green.children) }
+
+// Child returns the child element at the index.
+func (n *Node) Child(index int) Element {
+	if n.children == nil {
+		n.children = make([]Element, len(n.Green.children))
+	}
+	if n.children[index] == nil {
+		offset := n.offset
+		for _, sibling := range n.Green.children[:index] {
+			offset += sibling.Width()
+		}
+		base := redBase{parent: n, index: index, offset: offset}
+		switch green := n.Green.children[index].(type) {
+		case *GreenNode:
+			n.children[index] = &Node{redBase: base, Green: green, tree: n.tree}
+		case *GreenToken:
+			n.children[index] = &Token{redBase: base, Green: green}
+		}
+	}
+	return n.children[index]
+}
+
+// Children returns all the child elements, including the tokens and the trivia.
+func (n *Node) Children() []Element {
+	children := make([]Element, n.ChildCount())
+	for i := range children {
+		children[i] = n.Child(i)
+	}
+	return children
+}
+
+// ChildNodes returns the child nodes, leaving out the tokens.
+func (n *Node) ChildNodes() []*Node {
+	var nodes []*Node
+	for i := 0; i < n.ChildCount(); i++ {
+		if node, isNode := n.Child(i).(*Node); isNode {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Tokens returns all the tokens under the node in source order, including trivia.
+func (n *Node) Tokens() []*Token {
+	var tokens []*Token
+	for i := 0; i < n.ChildCount(); i++ {
+		switch child := n.Child(i).(type) {
+		case *Node:
+			tokens = append(tokens, child.Tokens()...)
+		case *Token:
+			tokens = append(tokens, child)
+		}
+	}
+	return tokens
+}
+
+// TokenAt returns the token covering the offset, or nil if the offset is out of the node.
+// At the boundary between two tokens, the token starting at the offset is returned.
+func (n *Node) TokenAt(offset int) *Token {
+	if offset < n.offset || offset >= n.End() {
+		return nil
+	}
+	for i := 0; i < n.ChildCount(); i++ {
+		child := n.Child(i)
+		if offset < child.Offset() || offset >= child.End() {
+			continue
+		}
+		if node, isNode := child.(*Node); isNode {
+			return node.TokenAt(offset)
+		}
+		return child.(*Token)
+	}
+	return nil
+}
+
+// Ancestors returns the parent, the parent's parent and so on up to the root.
+func (n *Node) Ancestors() []*Node {
+	var ancestors []*Node
+	for parent := n.parent; parent != nil; parent = parent.parent {
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// AST returns the typed AST node this syntax node is the source of, or nil if the
+// parser makes no node of the same kind and span.
+func (n *Node) AST() ast.Node {
+	if n.tree == nil {
+		return nil
+	}
+	n.tree.deriveAST()
+	return n.tree.astOf[n.Green]
+}
+
+// Replace returns the green root of a new tree, where this node is replaced
+// by the green node. Only the ancestors of this node are copied, every other
+// green element is shared with the current tree.
+func (n *Node) Replace(green *GreenNode) *GreenNode {
+	replaced := green
+	node := n
+	for node.parent != nil {
+		replaced = node.parent.Green.ReplaceChild(node.index, replaced)
+		node = node.parent
+	}
+	return replaced
+}
@@ -0,0 +1,252 @@
+// Package syntax builds the lossless concrete syntax trees of the source files, for the
+// formatters, the refactorings and the language server.
+//
+// The typed AST is a view over the syntax tree: every AST node is linked to the syntax node
+// with the same kind and span, and each one can be reached from the other. A tree built by
+// Build is linked to the AST it's built from. A tree over another green root, like an edited
+// one from Replace, derives its AST from its own text when it's first asked for.
+package syntax
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/parser"
+	"reflect"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tree is a lossless concrete syntax tree of a source file. Unlike the AST,
+// it keeps every token and every piece of trivia, so its text is exactly the
+// source, which is what formatters, refactorings and the language server need.
+//
+// The tree is split in two layers: the immutable green tree only knows the
+// widths of its elements and can be shared, and the red tree on top of it
+// knows the offsets and the parents.
+//
+// The AST of the tree is a view over it, see the package doc.
+type Tree struct {
+	Root *Node
+
+	// astOf and syntaxOf link the AST nodes to the syntax nodes, they're nil until the AST
+	// of a created tree is derived.
+	astOf    map[*GreenNode]ast.Node
+	syntaxOf map[ast.Node]*Node
+}
+
+// CreateTree creates a red tree over a green root, whose AST is derived from its text when
+// it's first asked for.
+func CreateTree(root *GreenNode) *Tree {
+	tree := &Tree{}
+	tree.Root = &Node{Green: root, tree: tree}
+	return tree
+}
+
+// Syntax returns the syntax node of an AST node, or nil if it's not in the tree.
+func (t *Tree) Syntax(node ast.Node) *Node {
+	t.deriveAST()
+	return t.syntaxOf[node]
+}
+
+type nodeKey struct {
+	kind   Kind
+	offset int
+	end    int
+}
+
+// deriveAST parses the text of a tree without AST, and links each AST node to the syntax node
+// with the same kind and span. The syntax nodes the parser doesn't make, like a replacement
+// which parses as another kind of node, are left without AST node.
+func (t *Tree) deriveAST() {
+	if t.astOf != nil {
+		return
+	}
+	source := []byte(t.Text())
+	tokens, _ := compiler.CreateScanner(source).Tokenize()
+	file := parser.CreateParserFromTokens(tokens, nil).ParseFile().Unwrap()
+
+	nodes := map[nodeKey]*Node{}
+	var index func(node *Node)
+	index = func(node *Node) {
+		key := nodeKey{node.Kind(), node.Offset(), node.End()}
+		if _, exists := nodes[key]; !exists {
+			nodes[key] = node
+		}
+		for _, child := range node.ChildNodes() {
+			index(child)
+		}
+	}
+	index(t.Root)
+
+	t.astOf, t.syntaxOf = map[*GreenNode]ast.Node{t.Root.Green: file}, map[ast.Node]*Node{file: t.Root}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if node == nil || node == ast.Node(file) {
+			return true
+		}
+		span := node.NodeSpan()
+		syntaxNode, exists := nodes[nodeKey{Kind(kindName(node)), span.Start.Offset, span.End.Offset}]
+		if exists && t.astOf[syntaxNode.Green] == nil {
+			t.astOf[syntaxNode.Green] = node
+			t.syntaxOf[node] = syntaxNode
+		}
+		return true
+	})
+}
+
+// Text returns the source text of the tree.
+func (t *Tree) Text() string {
+	return t.Root.Text()
+}
+
+// Build creates the syntax tree of a source from its tokens and its AST.
+// Each AST node becomes a syntax node, holding the tokens of its span which are not
+// in its children. The source between tokens, like whitespace and text skipped by
+// the scanner, becomes trivia.
+func Build(source []byte, tokens []*compiler.Token, file *ast.File) *Tree {
+	b := &builder{
+		source:    source,
+		tokens:    tokens,
+		tokenPool: map[greenTokenKey]*GreenToken{},
+		astOf:     map[*GreenNode]ast.Node{},
+	}
+	root := b.buildNode(file, len(source))
+	tree := CreateTree(root)
+	tree.astOf = b.astOf
+	tree.syntaxOf = map[ast.Node]*Node{}
+	tree.indexNodes(tree.Root)
+	return tree
+}
+
+// Parse scans and parses a source, and builds its syntax tree.
+// The diagnostics are the ones of the scanner and the parser,
+// the tree covers the whole source even if there are errors.
+func Parse(source []byte) (*Tree, []*compiler.Diagnostic) {
+	tokens, scanDiagnostics := compiler.CreateScanner(source).Tokenize()
+	fileParser := parser.CreateParserFromTokens(tokens, nil)
+	file := fileParser.ParseFile().Unwrap()
+	diagnostics := append(scanDiagnostics, fileParser.Diagnostics()...)
+	return Build(source, tokens, file), diagnostics
+}
+
+func (t *Tree) indexNodes(node *Node) {
+	if astNode, exists := t.astOf[node.Green]; exists {
+		t.syntaxOf[astNode] = node
+	}
+	for _, child := range node.ChildNodes() {
+		t.indexNodes(child)
+	}
+}
+
+type greenTokenKey struct {
+	tokenType compiler.TokenType
+	trivia    TriviaKind
+	text      string
+}
+
+type builder struct {
+	source     []byte
+	tokens     []*compiler.Token
+	tokenIndex int // Index of the next token to put in the tree
+	cursor     int // Offset of the source not in the tree yet
+	tokenPool  map[greenTokenKey]*GreenToken
+	astOf      map[*GreenNode]ast.Node
+}
+
+// newToken returns the green token, identical tokens are shared.
+func (b *builder) newToken(tokenType compiler.TokenType, trivia TriviaKind, text string) *GreenToken {
+	key := greenTokenKey{tokenType, trivia, text}
+	if token, exists := b.tokenPool[key]; exists {
+		return token
+	}
+	token := CreateGreenToken(tokenType, trivia, text)
+	b.tokenPool[key] = token
+	return token
+}
+
+// buildNode builds the green node of an AST node, whose source ends at the end offset.
+func (b *builder) buildNode(node ast.Node, end int) *GreenNode {
+	children := ast.Children(node)
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].NodeSpan().Start.Offset < children[j].NodeSpan().Start.Offset
+	})
+
+	var elements []GreenElement
+	for _, child := range children {
+		span := child.NodeSpan()
+		elements = b.appendSource(elements, span.Start.Offset)
+		elements = append(elements, b.buildNode(child, span.End.Offset))
+	}
+	elements = b.appendSource(elements, end)
+
+	green := CreateGreenNode(Kind(kindName(node)), elements)
+	b.astOf[green] = node
+	return green
+}
+
+// appendSource appends the tokens and the trivia from the cursor to the end offset.
+func (b *builder) appendSource(elements []GreenElement, end int) []GreenElement {
+	for b.cursor < end {
+		// Tokens behind the cursor overlap what is already in the tree
+		for b.tokenIndex < len(b.tokens) && b.tokens[b.tokenIndex].Pos.Offset < b.cursor {
+			b.tokenIndex++
+		}
+
+		nextTokenStart := len(b.source)
+		if b.tokenIndex < len(b.tokens) {
+			nextTokenStart = b.tokens[b.tokenIndex].Pos.Offset
+		}
+		if nextTokenStart > b.cursor {
+			elements = b.appendTrivia(elements, min(nextTokenStart, end))
+			continue
+		}
+
+		token := b.tokens[b.tokenIndex]
+		b.tokenIndex++
+		if token.End.Offset == token.Pos.Offset {
+			continue // The end of file
+		}
+		trivia := NotTrivia
+		if token.Type == compiler.TokenTypeLineComment {
+			trivia = TriviaComment
+		}
+		elements = append(elements, b.newToken(token.Type, trivia, string(b.source[token.Pos.Offset:token.End.Offset])))
+		b.cursor = token.End.Offset
+	}
+	return elements
+}
+
+// appendTrivia splits the source from the cursor to the end offset,
+// which has no token, into whitespace and skipped text.
+func (b *builder) appendTrivia(elements []GreenElement, end int) []GreenElement {
+	for b.cursor < end {
+		start := b.cursor
+		r, _ := utf8.DecodeRune(b.source[start:end])
+		isSpace := unicode.IsSpace(r)
+		for b.cursor < end {
+			r, size := utf8.DecodeRune(b.source[b.cursor:end])
+			if unicode.IsSpace(r) != isSpace {
+				break
+			}
+			b.cursor += size
+		}
+		trivia := TriviaSkipped
+		if isSpace {
+			trivia = TriviaWhitespace
+		}
+		elements = append(elements, b.newToken(0, trivia, string(b.source[start:b.cursor])))
+	}
+	return elements
+}
+
+// kindName names the kind of a syntax node after the type of its AST node.
+func kindName(node ast.Node) string {
+	return reflect.TypeOf(node).Elem().Name()
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,130 @@
+package syntax
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBuildSyntaxTree(t *testing.T) {
+	sources := []string{
+		"let a = 1 + 2 * 3\n",
+		"  // leading comment\r\nfunc add(a: int, b: int) int {\n\treturn a + b // sum\n}\n\n",
+		"struct Point { x: float; y: float }\nlet p = Point { x: 1, y: 2 }\nlet s = `(${p.x}, ${p.y})`",
+		"for i in 0..n { print(\"héllo ✓\", xs[i..]) }",
+		"let a = 123e\nlet b = (1 + \nlet c = #3\n}",
+		"",
+	}
+	for _, source := range sources {
+		Convey("Test round-trip source "+source, t, func() {
+			tree, _ := Parse([]byte(source))
+			So(tree.Text(), ShouldEqual, source)
+			So(tree.Root.Kind(), ShouldEqual, Kind("File"))
+			So(tree.Root.End(), ShouldEqual, len(source))
+
+			// Every AST node has a syntax node with the same span
+			var check func(node ast.Node)
+			check = func(node ast.Node) {
+				syntaxNode := tree.Syntax(node)
+				So(syntaxNode, ShouldNotBeNil)
+				So(syntaxNode.AST(), ShouldEqual, node)
+				if _, isFile := node.(*ast.File); !isFile {
+					So(syntaxNode.Offset(), ShouldEqual, node.NodeSpan().Start.Offset)
+					So(syntaxNode.End(), ShouldEqual, node.NodeSpan().End.Offset)
+				}
+				for _, child := range ast.Children(node) {
+					check(child)
+				}
+			}
+			check(tree.Root.AST())
+		})
+	}
+}
+
+func TestNavigateSyntaxTree(t *testing.T) {
+	Convey("Test navigate parents, siblings and tokens", t, func() {
+		source := "let total = price * count // items\n"
+		tree, diagnostics := Parse([]byte(source))
+		So(diagnostics, ShouldBeEmpty)
+
+		star := tree.Root.TokenAt(18)
+		So(star.Text(), ShouldEqual, "*")
+		So(star.Green.Type, ShouldEqual, compiler.TokenTypeStar)
+		binary := star.Parent()
+		So(binary.Kind(), ShouldEqual, Kind("BinaryExpr"))
+		So(binary.Text(), ShouldEqual, "price * count")
+		So(binary.Parent().Kind(), ShouldEqual, Kind("VarDecl"))
+		So(len(binary.Ancestors()), ShouldEqual, 2)
+
+		whitespace := star.PrevSibling().(*Token)
+		So(whitespace.Green.Trivia, ShouldEqual, TriviaWhitespace)
+		price := whitespace.PrevSibling().(*Node)
+		So(price.Text(), ShouldEqual, "price")
+		So(price.PrevSibling(), ShouldBeNil)
+		So(star.NextSibling().NextSibling().(*Node).Text(), ShouldEqual, "count")
+		So(star.NextSibling(), ShouldEqual, star.NextSibling())
+
+		comment := tree.Root.TokenAt(27)
+		So(comment.Green.Trivia, ShouldEqual, TriviaComment)
+		So(comment.Parent(), ShouldEqual, tree.Root)
+
+		declaration := tree.Syntax(binary.AST()).Parent()
+		So(declaration.AST().(*ast.VarDecl).Name.Name, ShouldEqual, "total")
+	})
+
+	Convey("Test skipped text is kept as trivia", t, func() {
+		tree, diagnostics := Parse([]byte("let a = 0o3e2"))
+		So(diagnostics, ShouldNotBeEmpty)
+		var skipped []string
+		for _, token := range tree.Root.Tokens() {
+			if token.Green.Trivia == TriviaSkipped {
+				skipped = append(skipped, token.Text())
+			}
+		}
+		So(skipped, ShouldResemble, []string{"0o3e2"})
+	})
+}
+
+func TestShareGreenTree(t *testing.T) {
+	Convey("Test identical tokens are shared", t, func() {
+		tree, _ := Parse([]byte("a + a"))
+		tokens := tree.Root.Tokens()
+		So(tokens[0].Green, ShouldEqual, tokens[4].Green)
+		So(tokens[1].Green, ShouldEqual, tokens[3].Green)
+	})
+
+	Convey("Test replace a node without touching the rest of the tree", t, func() {
+		tree, _ := Parse([]byte("let a = 1\nlet b = x + y\n"))
+		right := tree.Root.TokenAt(22).Parent()
+		So(right.Text(), ShouldEqual, "y")
+
+		replacement := CreateGreenNode("Identifier", []GreenElement{CreateGreenToken(compiler.TokenTypeIdentifier, NotTrivia, "total")})
+		edited := CreateTree(right.Replace(replacement))
+		So(edited.Text(), ShouldEqual, "let a = 1\nlet b = x + total\n")
+		So(tree.Text(), ShouldEqual, "let a = 1\nlet b = x + y\n")
+
+		// The first declaration is shared by both trees
+		So(edited.Root.Green.Children()[0], ShouldEqual, tree.Root.Green.Children()[0])
+		So(edited.Root.ChildNodes()[1].Green, ShouldNotEqual, tree.Root.ChildNodes()[1].Green)
+
+		// The edited tree derives its AST from its text
+		So(edited.Root.ChildNodes()[0].AST().(*ast.VarDecl).Name.Name, ShouldEqual, "a")
+		So(edited.Root.ChildNodes()[0].AST(), ShouldNotEqual, tree.Root.ChildNodes()[0].AST())
+		total := edited.Root.TokenAt(22).Parent()
+		So(total.AST().(*ast.Identifier).Name, ShouldEqual, "total")
+		So(edited.Syntax(total.AST()), ShouldEqual, total)
+		So(total.Parent().AST().(*ast.BinaryExpr).Right, ShouldEqual, total.AST())
+		So(edited.Root.AST().(*ast.File).Statements[1], ShouldEqual, edited.Root.ChildNodes()[1].AST())
+	})
+
+	Convey("Test leave the replacements which parse as another node without AST", t, func() {
+		tree, _ := Parse([]byte("let b = x + y\n"))
+		replacement := CreateGreenNode("Identifier", []GreenElement{CreateGreenToken(compiler.TokenTypeDecimalInteger, NotTrivia, "1")})
+		edited := CreateTree(tree.Root.TokenAt(12).Parent().Replace(replacement))
+		So(edited.Text(), ShouldEqual, "let b = x + 1\n")
+		So(edited.Root.TokenAt(12).Parent().AST(), ShouldBeNil)
+		So(edited.Root.TokenAt(8).Parent().AST().(*ast.Identifier).Name, ShouldEqual, "x")
+	})
+}
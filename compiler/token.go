@@ -19,6 +19,12 @@ const (
 	TokenTypeContinue
 	TokenTypeStruct
 	TokenTypeInterface
+	TokenTypeIn
+	TokenTypeMatch
+	TokenTypeEnum
+	TokenTypeImport
+	TokenTypePub
+	TokenTypeAs
 
 	// Punctuations
 	TokenTypeLineBreak             // \n
@@ -39,6 +45,7 @@ const (
 	TokenTypeMinus                 // -
 	TokenTypeStar                  // *
 	TokenTypeDoubleStar            // **
+	TokenTypeDoubleStarEqual       // **=
 	TokenTypeSlash                 // /
 	TokenTypePercent               // %
 	TokenTypeAlpha                 // @
@@ -70,11 +77,13 @@ const (
 	TokenTypeCaretEqual            // ^=
 	TokenTypeEllipsis              // ...
 	TokenTypeDoubleDots            // ..
+	TokenTypeDoubleDotsEqual       // ..=
 	TokenTypeQuestion              // ?
 	TokenTypeQuestionDot           // ?.
 	TokenTypeDoubleQuestion        // ??
 	TokenTypeTemplateStringQuote   // `
 	TokenTypeInterplolationStart   // ${
+	TokenTypeCustomOperator        // declared by an OperatorTable
 
 	// Literals
 	TokenTypeDecimalInteger
@@ -85,11 +94,15 @@ const (
 	TokenTypeFloat
 	TokenTypeRune
 	TokenTypeString
+	TokenTypeByte       // b'x'
+	TokenTypeByteString // b"..."
 	TokenTypeTemplateStrFragment
 	TokenTypeTrue
 	TokenTypeFalse
+	TokenTypeNil
 
 	TokenTypeLineComment
+	TokenTypeEndOfFile
 )
 
 var KeywordTokenMap = map[string]TokenType{
@@ -105,8 +118,15 @@ var KeywordTokenMap = map[string]TokenType{
 	"continue":  TokenTypeContinue,
 	"struct":    TokenTypeStruct,
 	"interface": TokenTypeInterface,
+	"in":        TokenTypeIn,
+	"match":     TokenTypeMatch,
+	"enum":      TokenTypeEnum,
+	"import":    TokenTypeImport,
+	"pub":       TokenTypePub,
+	"as":        TokenTypeAs,
 	"true":      TokenTypeTrue,
 	"false":     TokenTypeFalse,
+	"nil":       TokenTypeNil,
 }
 
 func isKeyword(s string) (TokenType, bool) {
@@ -118,6 +138,7 @@ func isKeyword(s string) (TokenType, bool) {
 // It is used to represent a word, a number, a string, etc.
 type Token struct {
 	Type    TokenType
-	Pos     *Position
+	Pos     *Position // Position of the first rune
+	End     *Position // Position right after the last rune
 	Content string
 }
@@ -21,77 +21,90 @@ func _() {
 	_ = x[TokenTypeContinue-11]
 	_ = x[TokenTypeStruct-12]
 	_ = x[TokenTypeInterface-13]
-	_ = x[TokenTypeLineBreak-14]
-	_ = x[TokenTypeSemi-15]
-	_ = x[TokenTypeComma-16]
-	_ = x[TokenTypeColon-17]
-	_ = x[TokenTypeLeftParen-18]
-	_ = x[TokenTypeRightParen-19]
-	_ = x[TokenTypeLeftCurly-20]
-	_ = x[TokenTypeRightCurly-21]
-	_ = x[TokenTypeLeftBracket-22]
-	_ = x[TokenTypeRightBracket-23]
-	_ = x[TokenTypeDot-24]
-	_ = x[TokenTypeEqual-25]
-	_ = x[TokenTypeDoubleEqual-26]
-	_ = x[TokenTypeBangEqual-27]
-	_ = x[TokenTypePlus-28]
-	_ = x[TokenTypeMinus-29]
-	_ = x[TokenTypeStar-30]
-	_ = x[TokenTypeDoubleStar-31]
-	_ = x[TokenTypeSlash-32]
-	_ = x[TokenTypePercent-33]
-	_ = x[TokenTypeAlpha-34]
-	_ = x[TokenTypeWavy-35]
-	_ = x[TokenTypeCaret-36]
-	_ = x[TokenTypeAmpersand-37]
-	_ = x[TokenTypeBang-38]
-	_ = x[TokenTypeVertical-39]
-	_ = x[TokenTypeLeftAngle-40]
-	_ = x[TokenTypeRightAngle-41]
-	_ = x[TokenTypeDoubleLeftAngle-42]
-	_ = x[TokenTypeDoubleRightAngle-43]
-	_ = x[TokenTypeDoubleAmpersand-44]
-	_ = x[TokenTypeDoubleVertical-45]
-	_ = x[TokenTypeLeftAngleEqual-46]
-	_ = x[TokenTypeRightAngleEqual-47]
-	_ = x[TokenTypeArrow-48]
-	_ = x[TokenTypeDoublePlus-49]
-	_ = x[TokenTypeDoubleMinus-50]
-	_ = x[TokenTypePlusEqual-51]
-	_ = x[TokenTypeMinusEqual-52]
-	_ = x[TokenTypeStarEqual-53]
-	_ = x[TokenTypeSlashEqual-54]
-	_ = x[TokenTypePercentEqual-55]
-	_ = x[TokenTypeDoubleLeftAngleEqual-56]
-	_ = x[TokenTypeDoubleRightAngleEqual-57]
-	_ = x[TokenTypeAmpersandEqual-58]
-	_ = x[TokenTypeVerticalEqual-59]
-	_ = x[TokenTypeCaretEqual-60]
-	_ = x[TokenTypeEllipsis-61]
-	_ = x[TokenTypeDoubleDots-62]
-	_ = x[TokenTypeQuestion-63]
-	_ = x[TokenTypeQuestionDot-64]
-	_ = x[TokenTypeDoubleQuestion-65]
-	_ = x[TokenTypeTemplateStringQuote-66]
-	_ = x[TokenTypeInterplolationStart-67]
-	_ = x[TokenTypeDecimalInteger-68]
-	_ = x[TokenTypeOctalInteger-69]
-	_ = x[TokenTypeHexadecimalInteger-70]
-	_ = x[TokenTypeBinaryInteger-71]
-	_ = x[TokenTypeExponent-72]
-	_ = x[TokenTypeFloat-73]
-	_ = x[TokenTypeRune-74]
-	_ = x[TokenTypeString-75]
-	_ = x[TokenTypeTemplateStrFragment-76]
-	_ = x[TokenTypeTrue-77]
-	_ = x[TokenTypeFalse-78]
-	_ = x[TokenTypeLineComment-79]
+	_ = x[TokenTypeIn-14]
+	_ = x[TokenTypeMatch-15]
+	_ = x[TokenTypeEnum-16]
+	_ = x[TokenTypeImport-17]
+	_ = x[TokenTypePub-18]
+	_ = x[TokenTypeAs-19]
+	_ = x[TokenTypeLineBreak-20]
+	_ = x[TokenTypeSemi-21]
+	_ = x[TokenTypeComma-22]
+	_ = x[TokenTypeColon-23]
+	_ = x[TokenTypeLeftParen-24]
+	_ = x[TokenTypeRightParen-25]
+	_ = x[TokenTypeLeftCurly-26]
+	_ = x[TokenTypeRightCurly-27]
+	_ = x[TokenTypeLeftBracket-28]
+	_ = x[TokenTypeRightBracket-29]
+	_ = x[TokenTypeDot-30]
+	_ = x[TokenTypeEqual-31]
+	_ = x[TokenTypeDoubleEqual-32]
+	_ = x[TokenTypeBangEqual-33]
+	_ = x[TokenTypePlus-34]
+	_ = x[TokenTypeMinus-35]
+	_ = x[TokenTypeStar-36]
+	_ = x[TokenTypeDoubleStar-37]
+	_ = x[TokenTypeDoubleStarEqual-38]
+	_ = x[TokenTypeSlash-39]
+	_ = x[TokenTypePercent-40]
+	_ = x[TokenTypeAlpha-41]
+	_ = x[TokenTypeWavy-42]
+	_ = x[TokenTypeCaret-43]
+	_ = x[TokenTypeAmpersand-44]
+	_ = x[TokenTypeBang-45]
+	_ = x[TokenTypeVertical-46]
+	_ = x[TokenTypeLeftAngle-47]
+	_ = x[TokenTypeRightAngle-48]
+	_ = x[TokenTypeDoubleLeftAngle-49]
+	_ = x[TokenTypeDoubleRightAngle-50]
+	_ = x[TokenTypeDoubleAmpersand-51]
+	_ = x[TokenTypeDoubleVertical-52]
+	_ = x[TokenTypeLeftAngleEqual-53]
+	_ = x[TokenTypeRightAngleEqual-54]
+	_ = x[TokenTypeArrow-55]
+	_ = x[TokenTypeDoublePlus-56]
+	_ = x[TokenTypeDoubleMinus-57]
+	_ = x[TokenTypePlusEqual-58]
+	_ = x[TokenTypeMinusEqual-59]
+	_ = x[TokenTypeStarEqual-60]
+	_ = x[TokenTypeSlashEqual-61]
+	_ = x[TokenTypePercentEqual-62]
+	_ = x[TokenTypeDoubleLeftAngleEqual-63]
+	_ = x[TokenTypeDoubleRightAngleEqual-64]
+	_ = x[TokenTypeAmpersandEqual-65]
+	_ = x[TokenTypeVerticalEqual-66]
+	_ = x[TokenTypeCaretEqual-67]
+	_ = x[TokenTypeEllipsis-68]
+	_ = x[TokenTypeDoubleDots-69]
+	_ = x[TokenTypeDoubleDotsEqual-70]
+	_ = x[TokenTypeQuestion-71]
+	_ = x[TokenTypeQuestionDot-72]
+	_ = x[TokenTypeDoubleQuestion-73]
+	_ = x[TokenTypeTemplateStringQuote-74]
+	_ = x[TokenTypeInterplolationStart-75]
+	_ = x[TokenTypeCustomOperator-76]
+	_ = x[TokenTypeDecimalInteger-77]
+	_ = x[TokenTypeOctalInteger-78]
+	_ = x[TokenTypeHexadecimalInteger-79]
+	_ = x[TokenTypeBinaryInteger-80]
+	_ = x[TokenTypeExponent-81]
+	_ = x[TokenTypeFloat-82]
+	_ = x[TokenTypeRune-83]
+	_ = x[TokenTypeString-84]
+	_ = x[TokenTypeByte-85]
+	_ = x[TokenTypeByteString-86]
+	_ = x[TokenTypeTemplateStrFragment-87]
+	_ = x[TokenTypeTrue-88]
+	_ = x[TokenTypeFalse-89]
+	_ = x[TokenTypeNil-90]
+	_ = x[TokenTypeLineComment-91]
+	_ = x[TokenTypeEndOfFile-92]
 }
 
-const _TokenType_name = "TokenTypeIdentifierTokenTypeLetTokenTypeConstTokenTypeFuncTokenTypeIfTokenTypeElseTokenTypeForTokenTypeLoopTokenTypeReturnTokenTypeBreakTokenTypeContinueTokenTypeStructTokenTypeInterfaceTokenTypeLineBreakTokenTypeSemiTokenTypeCommaTokenTypeColonTokenTypeLeftParenTokenTypeRightParenTokenTypeLeftCurlyTokenTypeRightCurlyTokenTypeLeftBracketTokenTypeRightBracketTokenTypeDotTokenTypeEqualTokenTypeDoubleEqualTokenTypeBangEqualTokenTypePlusTokenTypeMinusTokenTypeStarTokenTypeDoubleStarTokenTypeSlashTokenTypePercentTokenTypeAlphaTokenTypeWavyTokenTypeCaretTokenTypeAmpersandTokenTypeBangTokenTypeVerticalTokenTypeLeftAngleTokenTypeRightAngleTokenTypeDoubleLeftAngleTokenTypeDoubleRightAngleTokenTypeDoubleAmpersandTokenTypeDoubleVerticalTokenTypeLeftAngleEqualTokenTypeRightAngleEqualTokenTypeArrowTokenTypeDoublePlusTokenTypeDoubleMinusTokenTypePlusEqualTokenTypeMinusEqualTokenTypeStarEqualTokenTypeSlashEqualTokenTypePercentEqualTokenTypeDoubleLeftAngleEqualTokenTypeDoubleRightAngleEqualTokenTypeAmpersandEqualTokenTypeVerticalEqualTokenTypeCaretEqualTokenTypeEllipsisTokenTypeDoubleDotsTokenTypeQuestionTokenTypeQuestionDotTokenTypeDoubleQuestionTokenTypeTemplateStringQuoteTokenTypeInterplolationStartTokenTypeDecimalIntegerTokenTypeOctalIntegerTokenTypeHexadecimalIntegerTokenTypeBinaryIntegerTokenTypeExponentTokenTypeFloatTokenTypeRuneTokenTypeStringTokenTypeTemplateStrFragmentTokenTypeTrueTokenTypeFalseTokenTypeLineComment"
+const _TokenType_name = "TokenTypeIdentifierTokenTypeLetTokenTypeConstTokenTypeFuncTokenTypeIfTokenTypeElseTokenTypeForTokenTypeLoopTokenTypeReturnTokenTypeBreakTokenTypeContinueTokenTypeStructTokenTypeInterfaceTokenTypeInTokenTypeMatchTokenTypeEnumTokenTypeImportTokenTypePubTokenTypeAsTokenTypeLineBreakTokenTypeSemiTokenTypeCommaTokenTypeColonTokenTypeLeftParenTokenTypeRightParenTokenTypeLeftCurlyTokenTypeRightCurlyTokenTypeLeftBracketTokenTypeRightBracketTokenTypeDotTokenTypeEqualTokenTypeDoubleEqualTokenTypeBangEqualTokenTypePlusTokenTypeMinusTokenTypeStarTokenTypeDoubleStarTokenTypeDoubleStarEqualTokenTypeSlashTokenTypePercentTokenTypeAlphaTokenTypeWavyTokenTypeCaretTokenTypeAmpersandTokenTypeBangTokenTypeVerticalTokenTypeLeftAngleTokenTypeRightAngleTokenTypeDoubleLeftAngleTokenTypeDoubleRightAngleTokenTypeDoubleAmpersandTokenTypeDoubleVerticalTokenTypeLeftAngleEqualTokenTypeRightAngleEqualTokenTypeArrowTokenTypeDoublePlusTokenTypeDoubleMinusTokenTypePlusEqualTokenTypeMinusEqualTokenTypeStarEqualTokenTypeSlashEqualTokenTypePercentEqualTokenTypeDoubleLeftAngleEqualTokenTypeDoubleRightAngleEqualTokenTypeAmpersandEqualTokenTypeVerticalEqualTokenTypeCaretEqualTokenTypeEllipsisTokenTypeDoubleDotsTokenTypeDoubleDotsEqualTokenTypeQuestionTokenTypeQuestionDotTokenTypeDoubleQuestionTokenTypeTemplateStringQuoteTokenTypeInterplolationStartTokenTypeCustomOperatorTokenTypeDecimalIntegerTokenTypeOctalIntegerTokenTypeHexadecimalIntegerTokenTypeBinaryIntegerTokenTypeExponentTokenTypeFloatTokenTypeRuneTokenTypeStringTokenTypeByteTokenTypeByteStringTokenTypeTemplateStrFragmentTokenTypeTrueTokenTypeFalseTokenTypeNilTokenTypeLineCommentTokenTypeEndOfFile"
 
-var _TokenType_index = [...]uint16{0, 19, 31, 45, 58, 69, 82, 94, 107, 122, 136, 153, 168, 186, 204, 217, 231, 245, 263, 282, 300, 319, 339, 360, 372, 386, 406, 424, 437, 451, 464, 483, 497, 513, 527, 540, 554, 572, 585, 602, 620, 639, 663, 688, 712, 735, 758, 782, 796, 815, 835, 853, 872, 890, 909, 930, 959, 989, 1012, 1034, 1053, 1070, 1089, 1106, 1126, 1149, 1177, 1205, 1228, 1249, 1276, 1298, 1315, 1329, 1342, 1357, 1385, 1398, 1412, 1432}
+var _TokenType_index = [...]uint16{0, 19, 31, 45, 58, 69, 82, 94, 107, 122, 136, 153, 168, 186, 197, 211, 224, 239, 251, 262, 280, 293, 307, 321, 339, 358, 376, 395, 415, 436, 448, 462, 482, 500, 513, 527, 540, 559, 583, 597, 613, 627, 640, 654, 672, 685, 702, 720, 739, 763, 788, 812, 835, 858, 882, 896, 915, 935, 953, 972, 990, 1009, 1030, 1059, 1089, 1112, 1134, 1153, 1170, 1189, 1213, 1230, 1250, 1273, 1301, 1329, 1352, 1375, 1396, 1423, 1445, 1462, 1476, 1489, 1504, 1517, 1536, 1564, 1577, 1591, 1603, 1623, 1641}
 
 func (i TokenType) String() string {
 	i -= 1
@@ -0,0 +1,171 @@
+package types
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"strings"
+)
+
+// expectation tells where an expected type comes from, like a type annotation,
+// so that a mismatch can point at it too.
+type expectation struct {
+	node ast.Node
+	msg  string
+}
+
+// Assignable tells whether a value of type from can be used where a value of type to is expected.
+// The invalid type is assignable to and from every type, since its errors are already reported.
+func Assignable(from, to Type) bool {
+	if from == Invalid || to == Invalid {
+		return true
+	}
+	if from == Void || to == Void {
+		return false
+	}
+	if Identical(from, to) {
+		return true
+	}
+	if iface, isInterface := to.(*Interface); isInterface {
+		return Implements(from, iface)
+	}
+	// A value and nil can be used as an optional value, but an optional value must be checked first
+	if optional, isOptional := to.(*Optional); isOptional {
+		if fromOptional, isOptional := from.(*Optional); isOptional {
+			return Assignable(fromOptional.Elem, optional.Elem)
+		}
+		return from == Nil || Assignable(from, optional.Elem)
+	}
+	return false
+}
+
+// assign checks an expression against the type it's assigned to, and reports a mismatch.
+// expected is nil if any type is allowed.
+func (c *checker) assign(expr ast.Expr, expected Type, origin *expectation) Type {
+	found := c.value(expr, expected)
+	if expected != nil {
+		c.checkAssignable(expr, found, expected, origin)
+	}
+	return found
+}
+
+// checkAssignable reports a value of type found which can't be assigned to the expected type.
+func (c *checker) checkAssignable(expr ast.Node, found, expected Type, origin *expectation) bool {
+	if Assignable(found, expected) {
+		if value, isExpr := expr.(ast.Expr); isExpr {
+			c.checkOverflow(value, expected)
+		}
+		return true
+	}
+	var diagnostic *compiler.Diagnostic
+	if optional, isOptional := found.(*Optional); isOptional && Assignable(optional.Elem, expected) {
+		diagnostic = c.errorf(expr, compiler.TypeMismatch,
+			"Type mismatch: expected '%s', found '%s' which may be nil, check it or give it a default with '??'", expected, found)
+	} else if iface, isInterface := expected.(*Interface); isInterface {
+		diagnostic = c.errorf(expr, compiler.TypeMismatch, "Type mismatch: %s", notImplemented(found, iface))
+	} else {
+		diagnostic = c.errorf(expr, compiler.TypeMismatch, "Type mismatch: expected '%s', found '%s'", expected, found)
+	}
+	if origin != nil && origin.node != nil {
+		span := origin.node.NodeSpan()
+		diagnostic.Notes = append(diagnostic.Notes, &compiler.Note{Pos: span.Start, End: span.End, Msg: origin.msg})
+	}
+	return false
+}
+
+// notImplemented describes why a type doesn't implement an interface, listing each
+// missing method and each method with another signature.
+func notImplemented(t Type, iface *Interface) string {
+	var reasons []string
+	for _, mismatch := range MissingMethods(t, iface) {
+		reasons = append(reasons, mismatch.String())
+	}
+	return fmt.Sprintf("'%s' doesn't implement '%s', %s", t, iface, strings.Join(reasons, ", "))
+}
+
+// value checks an expression whose value is used, which rules out the calls of functions returning nothing.
+func (c *checker) value(expr ast.Expr, expected Type) Type {
+	t := c.expr(expr, expected)
+	if t == Void {
+		c.errorf(expr, compiler.InvalidOperation, "Expression doesn't have a value, its function doesn't return anything")
+		return Invalid
+	}
+	return t
+}
+
+func isInteger(t Type) bool {
+	basic, isBasic := t.(*Basic)
+	return isBasic && basic.Kind >= IntKind && basic.Kind <= Uint64Kind
+}
+
+func isFloat(t Type) bool {
+	basic, isBasic := t.(*Basic)
+	return isBasic && (basic.Kind == Float32Kind || basic.Kind == Float64Kind)
+}
+
+func isNumeric(t Type) bool {
+	return isInteger(t) || isFloat(t)
+}
+
+// isOrdered tells whether the values of a type can be compared with `<`.
+func isOrdered(t Type) bool {
+	return isNumeric(t) || t == String || t == Rune
+}
+
+// isUntypedConstant tells whether an expression is a numeric constant made of literals and
+// untyped constants only, whose type is given by the context, like `1` in `let x: u8 = 1` or `-(2 * N)`.
+func (c *checker) isUntypedConstant(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLiteral:
+		switch e.Kind {
+		case compiler.TokenTypeDecimalInteger,
+			compiler.TokenTypeOctalInteger,
+			compiler.TokenTypeHexadecimalInteger,
+			compiler.TokenTypeBinaryInteger,
+			compiler.TokenTypeExponent,
+			compiler.TokenTypeFloat:
+			return true
+		}
+	case *ast.Identifier:
+		return c.untypedConstant(e) != nil
+	case *ast.ParenExpr:
+		return c.isUntypedConstant(e.Expr)
+	case *ast.UnaryExpr:
+		return c.isUntypedConstant(e.Operand)
+	case *ast.BinaryExpr:
+		return c.isUntypedConstant(e.Left) && c.isUntypedConstant(e.Right)
+	}
+	return false
+}
+
+// mentions tells whether a type refers to one of the type parameters.
+func mentions(t Type, typeParams map[*TypeParam]bool) bool {
+	switch t := t.(type) {
+	case *TypeParam:
+		return typeParams[t]
+	case *Array:
+		return mentions(t.Elem, typeParams)
+	case *Range:
+		return mentions(t.Elem, typeParams)
+	case *Optional:
+		return mentions(t.Elem, typeParams)
+	case *Tuple:
+		return mentionsAny(t.Elems, typeParams)
+	case *Func:
+		return mentionsAny(t.Params, typeParams) || (t.Result != nil && mentions(t.Result, typeParams))
+	case *Enum:
+		return mentionsAny(t.TypeArgs, typeParams)
+	case *Struct:
+		return mentionsAny(t.TypeArgs, typeParams)
+	}
+	return false
+}
+
+func mentionsAny(types []Type, typeParams map[*TypeParam]bool) bool {
+	for _, t := range types {
+		if mentions(t, typeParams) {
+			return true
+		}
+	}
+	return false
+}
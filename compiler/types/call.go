@@ -0,0 +1,380 @@
+package types
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/resolve"
+	"mirth/shared"
+)
+
+// call checks a function call, or a conversion like `u8(x)` if the callee is a type.
+// The type arguments of a generic function are inferred from the arguments, and then
+// from the expected result type.
+func (c *checker) call(e *ast.CallExpr, expected Type) Type {
+	if target, isType := c.typeExpr(e.Callee); isType {
+		c.info.Types[e.Callee] = target
+		return c.conversion(e, target)
+	}
+	callee := c.value(e.Callee, nil)
+	optional, isOptional := callee.(*Optional)
+	if !isOptional {
+		return c.callOf(e, callee, expected)
+	}
+	if !c.chains[e.Callee] {
+		c.possiblyNil(e.Callee, callee, "before calling it")
+		for _, arg := range e.Arguments {
+			c.value(arg, nil)
+		}
+		return Invalid
+	}
+	// A call in an optional chain, like `a?.f()`
+	if expectedOptional, isOptional := expected.(*Optional); isOptional {
+		expected = expectedOptional.Elem
+	}
+	result := c.callOf(e, optional.Elem, expected)
+	if _, isOptional := result.(*Optional); !isOptional {
+		c.chains[e] = true
+	}
+	return optionalOf(result)
+}
+
+// callOf checks a call whose callee has the type callee.
+func (c *checker) callOf(e *ast.CallExpr, callee Type, expected Type) Type {
+	signature, isFunc := callee.(*Func)
+	if !isFunc {
+		for _, arg := range e.Arguments {
+			c.value(arg, nil)
+		}
+		if callee != Invalid {
+			c.errorf(e.Callee, compiler.InvalidOperation, "Type '%s' can't be called, it's not a function", callee)
+		}
+		return Invalid
+	}
+	if !c.checkArgumentCount(e, signature) {
+		for _, arg := range e.Arguments {
+			c.value(arg, nil)
+		}
+		if len(signature.TypeParams) > 0 {
+			return Invalid
+		}
+		return resultOf(signature)
+	}
+
+	args := make([]Type, len(e.Arguments))
+	if len(signature.TypeParams) > 0 {
+		instance := c.inferCall(e, signature, args, expected)
+		if instance == nil {
+			return Invalid
+		}
+		signature = instance
+		c.info.Types[e.Callee] = instance
+	} else {
+		for i, arg := range e.Arguments {
+			args[i] = c.value(arg, paramOf(signature, i, arg))
+		}
+	}
+
+	params := c.paramDecls(e.Callee)
+	for i, arg := range e.Arguments {
+		var origin *expectation
+		if len(params) > 0 {
+			param := params[minInt(i, len(params)-1)]
+			if param.Type != nil {
+				origin = &expectation{param.Type, fmt.Sprintf("Expected because of the type of the parameter '%s'", param.Name.Name)}
+			}
+		}
+		c.checkAssignable(arg, args[i], paramOf(signature, i, arg), origin)
+	}
+	return resultOf(signature)
+}
+
+// taggedTemplate checks a tagged template, which calls its tag with the fragments of the
+// template and the values of its expressions, like `func(fragments: []string, values: []T)`
+// or `func(fragments: []string, ...values: T)`. The type argument of a generic tag is
+// inferred from the values.
+func (c *checker) taggedTemplate(e *ast.TaggedTemplate) Type {
+	tag := c.value(e.Tag, nil)
+	signature, isFunc := tag.(*Func)
+	var elem Type
+	if isFunc {
+		elem = templateValues(signature)
+	}
+	values := make([]Type, len(e.Template.Exprs))
+	for i, value := range e.Template.Exprs {
+		values[i] = c.value(value, shared.Ternary(isFunc && len(signature.TypeParams) == 0, elem, nil))
+	}
+	c.info.Types[e.Template] = String
+
+	switch {
+	case !isFunc:
+		if tag != Invalid {
+			c.errorf(e.Tag, compiler.InvalidOperation, "Template tag must be a function, found '%s'", tag)
+		}
+		return Invalid
+	case elem == nil:
+		c.errorf(e.Tag, compiler.InvalidOperation,
+			"Template tag must take the fragments and the values like 'func([]string, []T)' or 'func([]string, ...T)', found '%s'", signature)
+		return shared.Ternary[Type](len(signature.TypeParams) > 0, Invalid, resultOf(signature))
+	case len(signature.TypeParams) > 0:
+		// The values are unified with their parameter like the arguments of a variadic parameter
+		args := append([]Type{templateFragments}, values...)
+		subst, err := unifyArgs(signature.TypeParams, &Func{Params: signature.Params, Variadic: true}, args, nil)
+		if err != nil {
+			c.errorf(e, compiler.CannotInferType, "%s", capitalize(err.Error()))
+			return Invalid
+		}
+		for _, typeParam := range signature.TypeParams {
+			if _, inferred := subst[typeParam]; !inferred {
+				c.errorf(e, compiler.CannotInferType, "Can't infer the type argument of '%s' of '%s', give it explicitly", typeParam.Name, signature)
+				return Invalid
+			}
+			c.checkConstraint(e, typeParam, subst[typeParam])
+		}
+		signature = instantiateFunc(signature, subst)
+		elem = templateValues(signature)
+		c.info.Types[e.Tag] = signature
+	}
+	for i, value := range e.Template.Exprs {
+		c.checkAssignable(value, values[i], elem, nil)
+	}
+	return resultOf(signature)
+}
+
+// templateFragments is the type of the fragments a template tag is called with.
+var templateFragments = &Array{Elem: String, Length: -1}
+
+// templateValues returns the type of the values a template tag takes, or nil if the tag
+// doesn't take the fragments and the values.
+func templateValues(tag *Func) Type {
+	if len(tag.Params) != 2 || !Identical(tag.Params[0], templateFragments) {
+		return nil
+	}
+	if values, isArray := tag.Params[1].(*Array); isArray && values.Length < 0 {
+		return values.Elem
+	}
+	return nil
+}
+
+// paramOf returns the type of the parameter of an argument. The arguments beyond the
+// parameters of a variadic function have the type of the variadic element, but an
+// array is spread into the variadic parameter, like `f(...values)`.
+func paramOf(signature *Func, i int, arg ast.Expr) Type {
+	if signature.Variadic && i >= len(signature.Params)-1 {
+		variadic := signature.Params[len(signature.Params)-1]
+		if _, isSpread := arg.(*ast.SpreadExpr); isSpread {
+			return variadic
+		}
+		return variadic.(*Array).Elem
+	}
+	return signature.Params[i]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// paramDecls returns the parameter declarations of the called function, if it's a declared function.
+func (c *checker) paramDecls(callee ast.Expr) []*ast.Param {
+	ident, isName := callee.(*ast.Identifier)
+	if !isName {
+		return nil
+	}
+	symbol := c.table.Uses[ident]
+	if symbol != nil && symbol.Kind == resolve.ImportSymbol {
+		symbol = symbol.Target
+	}
+	if symbol == nil || symbol.Kind != resolve.FuncSymbol {
+		return nil
+	}
+	if decl, isFunc := symbol.Decl.(*ast.FuncDecl); isFunc {
+		return decl.Params
+	}
+	return nil
+}
+
+// checkArgumentCount reports a call which gives too few or too many arguments.
+func (c *checker) checkArgumentCount(e *ast.CallExpr, signature *Func) bool {
+	count, required := len(e.Arguments), signature.RequiredParams()
+	expected := ""
+	switch {
+	case signature.Variadic && count < required:
+		expected = fmt.Sprintf("at least %d", required)
+	case signature.Variadic:
+		return true
+	case count >= required && count <= len(signature.Params):
+		return true
+	case signature.Defaults > 0:
+		expected = fmt.Sprintf("%d to %d", required, len(signature.Params))
+	default:
+		expected = fmt.Sprint(required)
+	}
+	c.errorf(e, compiler.ArgumentCount, "Wrong number of arguments: expected %s, found %d", expected, count)
+	return false
+}
+
+// deferral tells when the type of an argument of a generic call is checked: the untyped
+// constants take the type arguments given by the other arguments, and then the lambdas
+// without parameter types take them from the arguments checked before.
+func (c *checker) deferral(arg ast.Expr) int {
+	var params []*ast.Param
+	switch a := arg.(type) {
+	case *ast.ArrowFunc:
+		params = a.Params
+	case *ast.FuncLit:
+		params = a.Params
+	}
+	for _, param := range params {
+		if param.Type == nil {
+			return 2
+		}
+	}
+	if c.isUntypedConstant(arg) {
+		return 1
+	}
+	return 0
+}
+
+// inferCall infers the type arguments of a call to a generic function, checking the
+// arguments and recording their types in args, and returns the instantiated signature
+// or nil. The arguments whose type depends on the type arguments, like the lambdas
+// without parameter types, are checked last against the type arguments known by then.
+func (c *checker) inferCall(e *ast.CallExpr, signature *Func, args []Type, expected Type) *Func {
+	typeParams := map[*TypeParam]bool{}
+	for _, typeParam := range signature.TypeParams {
+		typeParams[typeParam] = true
+	}
+	deferred := map[int][]int{}
+	for i, arg := range e.Arguments {
+		if pass := c.deferral(arg); pass > 0 {
+			deferred[pass] = append(deferred[pass], i)
+			continue
+		}
+		var hint Type
+		if param := paramOf(signature, i, arg); !mentions(param, typeParams) {
+			hint = param
+		}
+		args[i] = c.value(arg, hint)
+	}
+
+	subst, err := c.unifyCall(e, signature, args, expected)
+	for pass := 1; pass <= 2 && err == nil; pass++ {
+		if len(deferred[pass]) == 0 {
+			continue
+		}
+		unknown := map[*TypeParam]bool{}
+		for typeParam := range c.unknown {
+			unknown[typeParam] = true
+		}
+		for _, typeParam := range signature.TypeParams {
+			if _, inferred := subst[typeParam]; !inferred {
+				unknown[typeParam] = true
+			}
+		}
+		enclosing := c.unknown
+		c.unknown = unknown
+		for _, i := range deferred[pass] {
+			args[i] = c.value(e.Arguments[i], Subst(paramOf(signature, i, e.Arguments[i]), subst))
+		}
+		c.unknown = enclosing
+		subst, err = c.unifyCall(e, signature, args, expected)
+	}
+	if err != nil {
+		for i, arg := range e.Arguments {
+			if args[i] == nil {
+				c.value(arg, nil)
+			}
+		}
+		c.errorf(e, compiler.CannotInferType, "%s", capitalize(err.Error()))
+		return nil
+	}
+
+	for _, typeParam := range signature.TypeParams {
+		typeArg, inferred := subst[typeParam]
+		if !inferred && hasInvalid(args) {
+			return nil // The argument whose type is unknown is already reported
+		}
+		if !inferred {
+			c.errorf(e, compiler.CannotInferType, "Can't infer the type argument of '%s' of '%s', give it explicitly", typeParam.Name, signature)
+			return nil
+		}
+		c.checkConstraint(e, typeParam, typeArg)
+	}
+	return instantiateFunc(signature, subst)
+}
+
+func hasInvalid(types []Type) bool {
+	for _, t := range types {
+		if t == Invalid {
+			return true
+		}
+	}
+	return false
+}
+
+// unifyCall unifies the parameters of a generic function with the argument types known so far,
+// and with the expected result type unless it contradicts the arguments.
+func (c *checker) unifyCall(e *ast.CallExpr, signature *Func, args []Type, expected Type) (Substitution, error) {
+	unified := make([]Type, len(args))
+	for i, arg := range args {
+		unified[i] = arg
+		// A spread array gives the elements of the variadic parameter
+		if _, isSpread := e.Arguments[i].(*ast.SpreadExpr); isSpread && arg != nil {
+			if array, isArray := arg.(*Array); isArray {
+				unified[i] = array.Elem
+			}
+		}
+	}
+	if expected != nil && !mentions(expected, c.unknown) {
+		if subst, err := unifyArgs(signature.TypeParams, signature, unified, expected); err == nil {
+			return subst, nil
+		}
+	}
+	return unifyArgs(signature.TypeParams, signature, unified, nil)
+}
+
+// conversion checks a conversion of a value to a type, like `f64(count)`. The numeric
+// types convert to each other and to runes, and strings convert to and from byte arrays.
+func (c *checker) conversion(e *ast.CallExpr, target Type) Type {
+	if len(e.Arguments) != 1 {
+		for _, arg := range e.Arguments {
+			c.value(arg, nil)
+		}
+		c.errorf(e, compiler.ArgumentCount, "Conversion to '%s' needs one argument, found %d", target, len(e.Arguments))
+		return target
+	}
+	var hint Type
+	if isNumeric(target) {
+		hint = target
+	}
+	from := c.value(e.Arguments[0], hint)
+	if !convertible(from, target) {
+		c.errorf(e, compiler.InvalidOperation, "Can't convert '%s' to '%s'", from, target)
+	} else {
+		c.checkOverflow(e.Arguments[0], target)
+	}
+	return target
+}
+
+func convertible(from, to Type) bool {
+	if Assignable(from, to) {
+		return true
+	}
+	bytes := &Array{Elem: Uint8, Length: -1}
+	switch {
+	case isNumeric(from) && isNumeric(to):
+		return true
+	case from == Rune:
+		return isInteger(to) || to == String
+	case to == Rune:
+		return isInteger(from)
+	case to == String:
+		return Identical(from, bytes)
+	case from == String:
+		return Identical(to, bytes)
+	}
+	return false
+}
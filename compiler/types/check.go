@@ -0,0 +1,499 @@
+package types
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/resolve"
+	"mirth/shared"
+	"strings"
+)
+
+// Info records the results of the type checking, for the later phases and the tools.
+type Info struct {
+	// Types maps the checked expressions to their types.
+	Types map[ast.Expr]Type
+	// Symbols maps the declared symbols to their types. The type of a struct,
+	// an interface, an enum or a type parameter symbol is the declared type itself.
+	Symbols map[*resolve.Symbol]Type
+	// Constants maps the constant symbols to their values, nil if the value is invalid.
+	Constants map[*resolve.Symbol]*Constant
+}
+
+func CreateInfo() *Info {
+	return &Info{Types: map[ast.Expr]Type{}, Symbols: map[*resolve.Symbol]Type{}, Constants: map[*resolve.Symbol]*Constant{}}
+}
+
+// TypeOf returns the type of a checked expression, or nil.
+func (info *Info) TypeOf(expr ast.Expr) Type {
+	return info.Types[expr]
+}
+
+// function is the context of the function whose body is being checked.
+type function struct {
+	// result is the result type, nil if the function doesn't return a value
+	result     Type
+	resultNode ast.Node // The return type annotation, if any
+	// inferResult is true for the arrow lambdas whose result type is inferred from their first return.
+	inferResult bool
+}
+
+type checker struct {
+	info        *Info
+	table       *resolve.Table
+	files       []*ast.File
+	diagnostics [][]*compiler.Diagnostic
+	file        int // The index of the file being checked
+	function    *function
+	// fileOfDecl maps the top-level declarations to the index of their file.
+	fileOfDecl map[ast.Node]int
+	// inferring are the top-level variables whose type is being inferred, to report cycles.
+	inferring map[*resolve.Symbol]bool
+	// methodDecls maps the generic structs to the declarations of their methods by name.
+	methodDecls map[*Struct]map[string]*ast.FuncDecl
+	methods     map[*ast.FuncDecl]*Func
+	// unknown are the type parameters of the call being inferred, which the deferred arguments can't rely on.
+	unknown map[*TypeParam]bool
+	// narrowed are the variables of optional type known not to be nil, see ifStmt.
+	narrowed narrowing
+	// chains are the links of the optional chains like `a?.b`, whose type is optional because
+	// the chain stops at nil. The links after them, like `.c` in `a?.b.c`, are part of the chain.
+	chains map[ast.Expr]bool
+}
+
+// CheckFile resolves the names of a file which is a module on its own, and type checks it.
+func CheckFile(file *ast.File) (*Info, []*compiler.Diagnostic) {
+	table, diagnostics := resolve.ResolveFile(file)
+	info := CreateInfo()
+	return info, append(diagnostics, Check([]*ast.File{file}, table, info)[0]...)
+}
+
+// Check type checks the files of a module, whose names are resolved by the table.
+// info gathers the results of all the modules of a program, the imported modules
+// should be checked beforehand with the same info. The diagnostics are returned by
+// file, in the order of the files.
+func Check(files []*ast.File, table *resolve.Table, info *Info) [][]*compiler.Diagnostic {
+	c := &checker{
+		info:        info,
+		table:       table,
+		files:       files,
+		diagnostics: make([][]*compiler.Diagnostic, len(files)),
+		fileOfDecl:  map[ast.Node]int{},
+		inferring:   map[*resolve.Symbol]bool{},
+		methodDecls: map[*Struct]map[string]*ast.FuncDecl{},
+		methods:     map[*ast.FuncDecl]*Func{},
+		unknown:     map[*TypeParam]bool{},
+		chains:      map[ast.Expr]bool{},
+	}
+	var statements []ast.Stmt
+	for i, file := range files {
+		for _, stmt := range file.Statements {
+			c.fileOfDecl[stmt] = i
+			statements = append(statements, stmt)
+		}
+	}
+	c.declare(statements)
+	for i, file := range files {
+		c.file = i
+		for _, stmt := range file.Statements {
+			c.stmt(stmt)
+		}
+	}
+	return c.diagnostics
+}
+
+func (c *checker) report(diagnostic *compiler.Diagnostic) {
+	c.diagnostics[c.file] = append(c.diagnostics[c.file], diagnostic)
+}
+
+func (c *checker) errorf(node ast.Node, code compiler.DiagnosticCode, format string, args ...any) *compiler.Diagnostic {
+	diagnostic := errorAt(node, code, fmt.Sprintf(format, args...))
+	c.report(diagnostic)
+	return diagnostic
+}
+
+// inFileOf runs f with the diagnostics reported to the file of a top-level declaration.
+func (c *checker) inFileOf(decl ast.Node, f func()) {
+	file, isTopLevel := c.fileOfDecl[decl]
+	if !isTopLevel {
+		f()
+		return
+	}
+	previous := c.file
+	c.file = file
+	f()
+	c.file = previous
+}
+
+// declare declares the types and the function signatures of a statement list, in
+// steps so that they may refer to each other: first the types without their members,
+// then their members, and then the signatures of the functions and methods.
+func (c *checker) declare(statements []ast.Stmt) {
+	for _, stmt := range statements {
+		switch decl := stmt.(type) {
+		case *ast.StructDecl:
+			s := &Struct{Name: decl.Name.Name, TypeParams: c.newTypeParams(decl.TypeParams)}
+			c.defineSymbol(decl.Name, s)
+			c.methodDecls[s] = map[string]*ast.FuncDecl{}
+		case *ast.InterfaceDecl:
+			c.defineSymbol(decl.Name, &Interface{Name: decl.Name.Name})
+		case *ast.EnumDecl:
+			enum, _ := newEnum(decl)
+			for i, typeParam := range decl.TypeParams {
+				c.defineSymbol(typeParam.Name, enum.TypeParams[i])
+			}
+			c.defineSymbol(decl.Name, enum)
+		}
+	}
+	for _, stmt := range statements {
+		c.inFileOf(stmt, func() {
+			switch decl := stmt.(type) {
+			case *ast.StructDecl:
+				c.declareFields(decl)
+			case *ast.InterfaceDecl:
+				c.declareMethods(decl)
+			case *ast.EnumDecl:
+				enum := c.symbolType(decl.Name).(*Enum)
+				c.lowerConstraints(decl.TypeParams, enum.TypeParams)
+				for _, diagnostic := range declareVariants(enum, decl, c.lowerType, c.namedConstant) {
+					c.report(diagnostic)
+				}
+			}
+		})
+	}
+	for _, stmt := range statements {
+		if decl, isInterface := stmt.(*ast.InterfaceDecl); isInterface {
+			c.inFileOf(decl, func() {
+				c.checkEmbedding(decl)
+			})
+		}
+	}
+	for _, stmt := range statements {
+		if decl, isFunc := stmt.(*ast.FuncDecl); isFunc {
+			c.inFileOf(decl, func() {
+				c.declareFunc(decl)
+			})
+		}
+	}
+}
+
+func (c *checker) defineSymbol(ident *ast.Identifier, t Type) {
+	if symbol := c.table.Defs[ident]; symbol != nil {
+		c.info.Symbols[symbol] = t
+	}
+}
+
+func (c *checker) symbolType(ident *ast.Identifier) Type {
+	return c.info.Symbols[c.table.Defs[ident]]
+}
+
+// newTypeParams declares type parameters, their constraints are lowered by lowerConstraints.
+func (c *checker) newTypeParams(decls []*ast.TypeParam) []*TypeParam {
+	var typeParams []*TypeParam
+	for _, decl := range decls {
+		typeParam := &TypeParam{Name: decl.Name.Name}
+		c.defineSymbol(decl.Name, typeParam)
+		typeParams = append(typeParams, typeParam)
+	}
+	return typeParams
+}
+
+func (c *checker) lowerConstraints(decls []*ast.TypeParam, typeParams []*TypeParam) {
+	for i, decl := range decls {
+		if decl.Constraint != nil {
+			typeParams[i].Constraint = c.lowerType(decl.Constraint)
+		}
+	}
+}
+
+func (c *checker) declareFields(decl *ast.StructDecl) {
+	s := c.symbolType(decl.Name).(*Struct)
+	c.lowerConstraints(decl.TypeParams, s.TypeParams)
+	for _, fieldDecl := range decl.Fields {
+		fieldType := c.lowerType(fieldDecl.Type)
+		name := ""
+		if fieldDecl.Embedded {
+			embedded, isStruct := fieldType.(*Struct)
+			if !isStruct {
+				if fieldType != Invalid {
+					c.errorf(fieldDecl, compiler.InvalidOperation, "Only structs can be embedded, '%s' is not a struct", fieldType)
+				}
+				continue
+			}
+			name = embedded.Name
+			s.Embedded = append(s.Embedded, name)
+		} else {
+			name = fieldDecl.Name.Name
+		}
+		if _, exists := s.Field(name); exists {
+			c.errorf(fieldDecl, compiler.DuplicateDeclaration, "Duplicate field '%s' in struct '%s'", name, s.Name)
+			continue
+		}
+		s.Fields = append(s.Fields, &Field{Name: name, Type: fieldType, Default: fieldDecl.Default != nil})
+	}
+}
+
+func (c *checker) declareMethods(decl *ast.InterfaceDecl) {
+	iface := c.symbolType(decl.Name).(*Interface)
+	for _, embeddedDecl := range decl.Embedded {
+		embedded := c.lowerType(embeddedDecl)
+		if embeddedInterface, isInterface := embedded.(*Interface); isInterface {
+			iface.Embedded = append(iface.Embedded, embeddedInterface)
+		} else if embedded != Invalid {
+			c.errorf(embeddedDecl, compiler.InvalidOperation, "Only interfaces can be embedded in an interface, '%s' is not an interface", embedded)
+		}
+	}
+	for _, method := range decl.Methods {
+		if _, exists := iface.Method(method.Name.Name); exists {
+			c.errorf(method.Name, compiler.DuplicateDeclaration, "Duplicate method '%s' in interface '%s'", method.Name.Name, iface.Name)
+			continue
+		}
+		iface.Methods = append(iface.Methods, &Method{
+			Name:      method.Name.Name,
+			Signature: c.signature(nil, method.Params, method.ReturnType),
+		})
+	}
+}
+
+// checkEmbedding reports an interface which embeds itself, or which gets two methods
+// with the same name and different signatures from its embedded interfaces.
+func (c *checker) checkEmbedding(decl *ast.InterfaceDecl) {
+	iface := c.symbolType(decl.Name).(*Interface)
+	if path := embeddingCycle(iface, iface, nil); path != nil {
+		names := []string{iface.Name}
+		for _, embedded := range path {
+			names = append(names, embedded.Name)
+		}
+		c.errorf(decl.Name, compiler.InvalidOperation, "Interface '%s' embeds itself: %s", iface.Name, strings.Join(names, " -> "))
+		return
+	}
+
+	signatures := map[string]*Func{}
+	origins := map[string]string{}
+	for _, method := range iface.Methods {
+		signatures[method.Name], origins[method.Name] = method.Signature, iface.Name
+	}
+	for i, embedded := range iface.Embedded {
+		for _, method := range embedded.MethodSet() {
+			signature, exists := signatures[method.Name]
+			if !exists {
+				signatures[method.Name], origins[method.Name] = method.Signature, embedded.Name
+				continue
+			}
+			if !Identical(signature, method.Signature) {
+				c.errorf(decl.Embedded[i], compiler.DuplicateDeclaration, "Method '%s' of '%s' conflicts with the method '%s' of '%s'",
+					methodString(method.Name, method.Signature), embedded.Name, methodString(method.Name, signature), origins[method.Name],
+				)
+			}
+		}
+	}
+}
+
+// embeddingCycle returns the path of embedded interfaces from iface back to target, or nil.
+func embeddingCycle(iface, target *Interface, visited map[*Interface]bool) []*Interface {
+	if visited == nil {
+		visited = map[*Interface]bool{}
+	}
+	visited[iface] = true
+	for _, embedded := range iface.Embedded {
+		if embedded == target {
+			return []*Interface{embedded}
+		}
+		if visited[embedded] {
+			continue
+		}
+		if path := embeddingCycle(embedded, target, visited); path != nil {
+			return append([]*Interface{embedded}, path...)
+		}
+	}
+	return nil
+}
+
+// declareFunc declares the signature of a function, or adds a method to its struct.
+func (c *checker) declareFunc(decl *ast.FuncDecl) {
+	if decl.Receiver != nil {
+		c.declareReceiverTypeParams(decl.Receiver.Type)
+	}
+	typeParams := c.newTypeParams(decl.TypeParams)
+	c.lowerConstraints(decl.TypeParams, typeParams)
+	signature := c.signature(typeParams, decl.Params, decl.ReturnType)
+	c.methods[decl] = signature
+	if decl.Receiver == nil {
+		c.defineSymbol(decl.Name, signature)
+		return
+	}
+
+	receiverType := c.lowerType(decl.Receiver.Type)
+	c.defineSymbol(decl.Receiver.Name, receiverType)
+	s, isStruct := receiverType.(*Struct)
+	switch {
+	case receiverType == Invalid:
+	case !isStruct:
+		c.errorf(decl.Receiver.Type, compiler.InvalidOperation, "Methods can only be declared on structs, found '%s'", receiverType)
+	case c.methodDecls[s.Origin()] == nil:
+		c.errorf(decl.Receiver.Type, compiler.InvalidOperation, "Methods of '%s' must be declared in its module", s)
+	default:
+		origin := s.Origin()
+		if _, exists := origin.Field(decl.Name.Name); exists {
+			c.errorf(decl.Name, compiler.DuplicateDeclaration, "Struct '%s' already has a field named '%s'", origin.Name, decl.Name.Name)
+		} else if c.methodDecls[origin][decl.Name.Name] != nil {
+			c.errorf(decl.Name, compiler.DuplicateDeclaration, "Struct '%s' already has a method named '%s'", origin.Name, decl.Name.Name)
+		} else {
+			origin.Methods = append(origin.Methods, &Method{Name: decl.Name.Name, Signature: signature})
+			c.methodDecls[origin][decl.Name.Name] = decl
+		}
+	}
+}
+
+// declareReceiverTypeParams binds the type arguments of a generic receiver like `Pair<K, V>`
+// to the type parameters of the struct, so that the methods share them with the fields.
+func (c *checker) declareReceiverTypeParams(receiver ast.TypeExpr) {
+	named, isNamed := receiver.(*ast.NamedType)
+	if !isNamed {
+		return
+	}
+	symbol := c.table.Uses[named.Name]
+	if symbol == nil {
+		return
+	}
+	t, _ := c.typeOfSymbol(symbol)
+	s, isStruct := t.(*Struct)
+	if !isStruct || len(s.TypeParams) != len(named.TypeArgs) {
+		return
+	}
+	for i, typeArg := range named.TypeArgs {
+		if name, isName := typeArg.(*ast.NamedType); isName && len(name.TypeArgs) == 0 {
+			c.defineSymbol(name.Name, s.TypeParams[i])
+		}
+	}
+}
+
+// signature lowers the type of a function from its parameters and its return type.
+func (c *checker) signature(typeParams []*TypeParam, params []*ast.Param, returnType ast.TypeExpr) *Func {
+	signature := &Func{TypeParams: typeParams}
+	for _, param := range params {
+		var paramType Type = Invalid
+		if param.Type != nil {
+			paramType = c.lowerType(param.Type)
+		}
+		if param.Variadic {
+			signature.Variadic = true
+			paramType = variadicType(paramType)
+		} else if param.Default != nil {
+			signature.Defaults++
+		}
+		signature.Params = append(signature.Params, paramType)
+	}
+	if returnType != nil {
+		signature.Result = c.lowerType(returnType)
+	}
+	return signature
+}
+
+// variadicType returns the array type holding the arguments of a variadic parameter, which
+// is annotated with the type of its elements like `...parts: string`.
+func variadicType(elem Type) Type {
+	return &Array{Elem: elem, Length: -1}
+}
+
+// typeOfSymbol returns the type a symbol names, and whether it names a type.
+func (c *checker) typeOfSymbol(symbol *resolve.Symbol) (Type, bool) {
+	if symbol.Kind == resolve.ImportSymbol {
+		if symbol.Target == nil {
+			return Invalid, true // The loader reports it
+		}
+		symbol = symbol.Target
+	}
+	switch symbol.Kind {
+	case resolve.TypeSymbol:
+		return Predeclared[symbol.Name], true
+	case resolve.StructSymbol, resolve.InterfaceSymbol, resolve.EnumSymbol, resolve.TypeParamSymbol:
+		if symbol.Decl == nil {
+			return Predeclared[symbol.Name], true
+		}
+		if t := c.info.Symbols[symbol]; t != nil {
+			return t, true
+		}
+		return Invalid, true
+	}
+	return nil, false
+}
+
+// lowerType turns a type annotation into a type, the names are looked up in the symbol table.
+func (c *checker) lowerType(typeExpr ast.TypeExpr) Type {
+	switch t := typeExpr.(type) {
+	case *ast.NamedType:
+		symbol := c.table.Uses[t.Name]
+		if symbol == nil {
+			return Invalid // The resolver reports it
+		}
+		named, isType := c.typeOfSymbol(symbol)
+		if !isType {
+			c.errorf(t.Name, compiler.InvalidOperation, "'%s' is a %s, not a type", t.Name.Name, symbol.Kind)
+			return Invalid
+		}
+		typeArgs := make([]Type, len(t.TypeArgs))
+		for i, typeArg := range t.TypeArgs {
+			typeArgs[i] = c.lowerType(typeArg)
+		}
+		return c.instantiate(t, named, typeArgs)
+	case *ast.ArrayType:
+		array := &Array{Elem: c.lowerType(t.Element), Length: -1}
+		if t.Length != nil {
+			length, diagnostic := c.constant(t.Length)
+			switch {
+			case length != nil && length.Kind == IntConstant && length.Int.IsInt64() && length.Int.Sign() >= 0:
+				array.Length = length.Int.Int64()
+			case length != nil || (diagnostic != nil && diagnostic.Code == compiler.NotConstant):
+				c.errorf(t.Length, compiler.InvalidOperation, "Array length must be a non-negative integer constant")
+			case diagnostic != nil:
+				c.report(diagnostic)
+			}
+		}
+		return array
+	case *ast.TupleType:
+		elems := make([]Type, len(t.Elements))
+		for i, elem := range t.Elements {
+			elems[i] = c.lowerType(elem)
+		}
+		return &Tuple{Elems: elems}
+	case *ast.FuncType:
+		return c.signature(nil, t.Params, t.ReturnType)
+	case *ast.OptionalType:
+		return optionalOf(c.lowerType(t.Elem))
+	default:
+		return Invalid
+	}
+}
+
+// instantiate instantiates a generic struct or enum with type arguments,
+// which must be given for each type parameter.
+func (c *checker) instantiate(node ast.Node, generic Type, typeArgs []Type) Type {
+	var typeParams []*TypeParam
+	switch t := generic.(type) {
+	case *Struct:
+		typeParams = t.TypeParams
+	case *Enum:
+		typeParams = t.TypeParams
+	}
+	if len(typeArgs) != len(typeParams) {
+		if len(typeParams) == 0 {
+			c.errorf(node, compiler.ArgumentCount, "Type '%s' has no type parameters", generic)
+		} else {
+			c.errorf(node, compiler.ArgumentCount, "Type '%s' needs %d type %s, found %d",
+				generic, len(typeParams), shared.Ternary(len(typeParams) == 1, "argument", "arguments"), len(typeArgs),
+			)
+		}
+		return Invalid
+	}
+	if len(typeArgs) == 0 {
+		return generic
+	}
+	switch t := generic.(type) {
+	case *Struct:
+		return t.Instantiate(typeArgs)
+	case *Enum:
+		return t.Instantiate(typeArgs)
+	}
+	return generic
+}
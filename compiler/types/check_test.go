@@ -0,0 +1,377 @@
+package types
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/parser"
+	"mirth/compiler/resolve"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// checkSource type checks a source file, and returns the types of its variables by name.
+func checkSource(source string) (map[string]string, []*compiler.Diagnostic) {
+	file := parser.CreateParser(source).ParseFile().Unwrap()
+	info, diagnostics := CheckFile(file)
+	vars := map[string]string{}
+	for symbol, t := range info.Symbols {
+		if symbol.Kind == resolve.VarSymbol || symbol.Kind == resolve.ConstSymbol || symbol.Kind == resolve.BindingSymbol {
+			vars[symbol.Name] = t.String()
+		}
+	}
+	return vars, diagnostics
+}
+
+func messages(diagnostics []*compiler.Diagnostic) []string {
+	var msgs []string
+	for _, diagnostic := range diagnostics {
+		msgs = append(msgs, diagnostic.Msg)
+	}
+	return msgs
+}
+
+func TestCheckInference(t *testing.T) {
+	Convey("Test infer the types of the variables from their values", t, func() {
+		vars, diagnostics := checkSource(`
+let a = 1
+let b: u8 = 2
+let c = 1.5
+let d = b"hi"
+let e = [1, 2, 3]
+let f = (1, "x")
+let g = b + 1
+let h: f32 = 1 + 2.5
+let i = "abc"[0]
+let j = 0..10
+let k = a < 3 && true
+let l = e[1..]
+let m = 'x'`)
+		So(diagnostics, ShouldBeEmpty)
+		So(vars, ShouldResemble, map[string]string{
+			"a": "int", "b": "u8", "c": "f64", "d": "[]u8", "e": "[]int", "f": "(int, string)", "g": "u8",
+			"h": "f32", "i": "u8", "j": "Range<int>", "k": "bool", "l": "[]int", "m": "rune",
+		})
+	})
+
+	Convey("Test infer the type arguments of generic calls", t, func() {
+		vars, diagnostics := checkSource(`
+func identity<T>(x: T) T { return x }
+func map<T, U>(xs: []T, f: func(T) U) []U { return [] }
+let small: u8 = 1
+let a = identity(small)
+let b = identity(3)
+let c = map([1, 2], (x) => x > 1)
+let d = map(["a"], (s) => { return 1.5 })
+let e: Option<u8> = Some(1)
+let f: Option<int> = None
+let g = Some("x")
+let h = identity<f32>(1)`)
+		So(diagnostics, ShouldBeEmpty)
+		So(vars["a"], ShouldEqual, "u8")
+		So(vars["b"], ShouldEqual, "int")
+		So(vars["c"], ShouldEqual, "[]bool")
+		So(vars["d"], ShouldEqual, "[]f64")
+		So(vars["e"], ShouldEqual, "Option<u8>")
+		So(vars["f"], ShouldEqual, "Option<int>")
+		So(vars["g"], ShouldEqual, "Option<string>")
+		So(vars["h"], ShouldEqual, "f32")
+	})
+
+	Convey("Test infer the lambda parameters from the expected type", t, func() {
+		vars, diagnostics := checkSource(`
+let positive: func(int) bool = (x) => x > 0
+let double = (x: f64) => x * 2.0
+let twice = double(1.0)`)
+		So(diagnostics, ShouldBeEmpty)
+		So(vars["positive"], ShouldEqual, "func(int) bool")
+		So(vars["double"], ShouldEqual, "func(f64) f64")
+		So(vars["twice"], ShouldEqual, "f64")
+
+		_, diagnostics = checkSource("let f = (x) => x")
+		So(messages(diagnostics), ShouldResemble, []string{"Can't infer the type of the parameter 'x', annotate it"})
+	})
+
+	Convey("Test type the variadic parameters as arrays of their annotated elements", t, func() {
+		vars, diagnostics := checkSource(`
+func join(sep: string, ...parts: string) string { return parts[0] + sep }
+func count(...xs: []int) int { return xs[0][0] }
+let words = ["a", "b"]
+let a = join(", ", "x", "y")
+let b = join(", ", ...words)
+let c = count([1], [2, 3])
+let f: func(...[]int) int = count`)
+		So(diagnostics, ShouldBeEmpty)
+		So(vars["a"], ShouldEqual, "string")
+		So(vars["c"], ShouldEqual, "int")
+		So(vars["f"], ShouldEqual, "func(...[]int) int")
+
+		_, diagnostics = checkSource("func count(...xs: []int) int { return 0 }\nlet c = count(1, 2)")
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Type mismatch: expected '[]int', found 'int'",
+			"Type mismatch: expected '[]int', found 'int'",
+		})
+	})
+
+	Convey("Test check the tagged templates against the parameters of their tag", t, func() {
+		vars, diagnostics := checkSource("" +
+			"func sql(fragments: []string, values: []int) string { return fragments[0] }\n" +
+			"func html(fragments: []string, ...values: string) string { return values[0] }\n" +
+			"func list<T>(fragments: []string, values: []T) []T { return values }\n" +
+			"let id = 1\n" +
+			"let a = sql`SELECT * FROM users WHERE id = ${id} OR id = ${2}`\n" +
+			"let b = html`<p>${\"x\"}</p>`\n" +
+			"let c = list`${1.5}, ${2.5}`")
+		So(diagnostics, ShouldBeEmpty)
+		So(vars["a"], ShouldEqual, "string")
+		So(vars["b"], ShouldEqual, "string")
+		So(vars["c"], ShouldEqual, "[]f64")
+
+		_, diagnostics = checkSource("" +
+			"func sql(fragments: []string, values: []int) string { return fragments[0] }\n" +
+			"func upper(s: string) string { return s }\n" +
+			"func list<T>(fragments: []string, values: []T) []T { return values }\n" +
+			"let a = sql`id = ${\"1\"}`\n" +
+			"let b = upper`x`\n" +
+			"let c = list`${1}, ${\"2\"}`\n" +
+			"let d = 1`x`")
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Type mismatch: expected 'int', found 'string'",
+			"Template tag must take the fragments and the values like 'func([]string, []T)' or 'func([]string, ...T)', found 'func(string) string'",
+			"Type argument of 'T' is inferred as both 'int' and 'string'",
+			"Template tag must be a function, found 'int'",
+		})
+	})
+
+	Convey("Test infer the top-level variables used before their declaration", t, func() {
+		vars, diagnostics := checkSource("func f() string { return name }\nlet name = \"mirth\"")
+		So(diagnostics, ShouldBeEmpty)
+		So(vars["name"], ShouldEqual, "string")
+
+		_, diagnostics = checkSource("let a = b + 1\nlet b = a")
+		So(messages(diagnostics), ShouldResemble, []string{"Initialization cycle, the value of 'a' refers to itself"})
+	})
+}
+
+func TestCheckStructs(t *testing.T) {
+	Convey("Test check struct literals, fields and methods", t, func() {
+		vars, diagnostics := checkSource(`
+struct Point { x: f64; y: f64 = 0.0 }
+struct Box<T> { value: T }
+struct Named { Point; name: string }
+func (p: Point) norm() f64 { return p.x * p.x + p.y * p.y }
+func (b: Box<T>) get() T { return b.value }
+let p = Point { x: 1.0 }
+let n = p.norm()
+let box = Box { value: "s" }
+let value = box.get()
+let typed: Box<u8> = Box { value: 1 }
+let named = Named { Point: p, name: "origin" }
+let x = named.x`)
+		So(diagnostics, ShouldBeEmpty)
+		So(vars["n"], ShouldEqual, "f64")
+		So(vars["box"], ShouldEqual, "Box<string>")
+		So(vars["value"], ShouldEqual, "string")
+		So(vars["typed"], ShouldEqual, "Box<u8>")
+		So(vars["x"], ShouldEqual, "f64")
+	})
+
+	Convey("Test report invalid struct literals and members", t, func() {
+		_, diagnostics := checkSource(`
+struct Point { x: f64; y: f64 = 0.0 }
+let p = Point { y: 1.0, z: 2.0 }
+let q = p.z
+let r = Point { x: "1" }`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"'Point' has no field 'z'",
+			"Missing field 'x' in the literal of 'Point'",
+			"Type 'Point' has no field or method 'z'",
+			"Type mismatch: expected 'f64', found 'string'",
+		})
+		So(diagnostics[2].Code, ShouldEqual, compiler.UnknownMember)
+	})
+
+	Convey("Test assign the structs to the interfaces they implement", t, func() {
+		_, diagnostics := checkSource(`
+interface Shape { area() f64 }
+struct Circle { r: f64 }
+struct Square { side: f64 }
+func (c: Circle) area() f64 { return c.r * c.r * 3.14 }
+let a: Shape = Circle { r: 1.0 }
+let b: Shape = Square { side: 1.0 }
+let total = a.area()`)
+		So(messages(diagnostics), ShouldResemble, []string{"Type mismatch: 'Square' doesn't implement 'Shape', missing method 'area() f64'"})
+	})
+}
+
+func TestCheckInterfaces(t *testing.T) {
+	Convey("Test list the missing and mismatched methods", t, func() {
+		_, diagnostics := checkSource(`
+interface Shape { area() f64; name() string; scale(f: f64) }
+struct Square { side: f64 }
+func (s: Square) area() int { return 1 }
+func (s: Square) name() string { return "square" }
+func describe(s: Shape) string { return s.name() }
+let d = describe(Square { side: 1.0 })`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Type mismatch: 'Square' doesn't implement 'Shape', method 'area() int' should be 'area() f64', missing method 'scale(f64)'",
+		})
+		So(diagnostics[0].Notes[0].Msg, ShouldEqual, "Expected because of the type of the parameter 's'")
+	})
+
+	Convey("Test satisfy the methods of the embedded interfaces", t, func() {
+		_, diagnostics := checkSource(`
+interface Named { name() string }
+interface Shape { Named; area() f64 }
+struct Square { side: f64 }
+func (s: Square) area() f64 { return s.side * s.side }
+func (s: Square) name() string { return "square" }
+let s: Shape = Square { side: 1.0 }
+let n: Named = s
+let label = s.name()`)
+		So(diagnostics, ShouldBeEmpty)
+
+		_, diagnostics = checkSource(`
+interface Named { name() string }
+interface Shape { Named; area() f64 }
+struct Circle { r: f64 }
+func (c: Circle) area() f64 { return c.r }
+let s: Shape = Circle { r: 1.0 }`)
+		So(messages(diagnostics), ShouldResemble, []string{"Type mismatch: 'Circle' doesn't implement 'Shape', missing method 'name() string'"})
+	})
+
+	Convey("Test report the invalid embeddings", t, func() {
+		_, diagnostics := checkSource(`
+interface A { B; a() }
+interface B { A; b() }`)
+		So(messages(diagnostics), ShouldResemble, []string{"Interface 'A' embeds itself: A -> B -> A", "Interface 'B' embeds itself: B -> A -> B"})
+
+		_, diagnostics = checkSource("struct Point { x: f64 }\ninterface Shape { Point }")
+		So(messages(diagnostics), ShouldResemble, []string{"Only interfaces can be embedded in an interface, 'Point' is not an interface"})
+
+		_, diagnostics = checkSource(`
+interface Sized { size() int }
+interface Measured { size() f64 }
+interface Shape { Sized; Measured }`)
+		So(messages(diagnostics), ShouldResemble, []string{"Method 'size() f64' of 'Measured' conflicts with the method 'size() int' of 'Sized'"})
+	})
+
+	Convey("Test check the type assertions and the type switches", t, func() {
+		vars, diagnostics := checkSource(`
+interface Shape { area() f64 }
+struct Circle { r: f64 }
+struct Square { side: f64 }
+func (c: Circle) area() f64 { return c.r * c.r * 3.14 }
+func (s: Square) area() f64 { return s.side * s.side }
+let shape: Shape = Circle { r: 1.0 }
+let circle = shape.(Circle)
+let size = match shape {
+  c: Circle => c.r
+  sq: Square => sq.side
+  _ => 0.0
+}`)
+		So(diagnostics, ShouldBeEmpty)
+		So(vars["circle"], ShouldEqual, "Circle")
+		So(vars["c"], ShouldEqual, "Circle")
+		So(vars["sq"], ShouldEqual, "Square")
+		So(vars["size"], ShouldEqual, "f64")
+
+		_, diagnostics = checkSource(`
+interface Shape { area() f64 }
+struct Point { x: f64 }
+let shape: Shape
+let p = shape.(Point)
+let n = 1
+let m = n.(int)`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Impossible type assertion: 'Point' doesn't implement 'Shape', missing method 'area() f64'",
+			"Type assertion needs an interface value, found 'int'",
+		})
+	})
+}
+
+func TestCheckErrors(t *testing.T) {
+	Convey("Test report mismatches with the span of the expected type", t, func() {
+		_, diagnostics := checkSource("let name: string = 1")
+		So(len(diagnostics), ShouldEqual, 1)
+		So(diagnostics[0].Code, ShouldEqual, compiler.TypeMismatch)
+		So(diagnostics[0].Msg, ShouldEqual, "Type mismatch: expected 'string', found 'int'")
+		So(diagnostics[0].Pos.Column, ShouldEqual, 20)
+		So(len(diagnostics[0].Notes), ShouldEqual, 1)
+		So(diagnostics[0].Notes[0].Msg, ShouldEqual, "Expected because of the type annotation")
+		So(diagnostics[0].Notes[0].Pos.Column, ShouldEqual, 11)
+		So(diagnostics[0].Notes[0].End.Column, ShouldEqual, 17)
+
+		_, diagnostics = checkSource("func add(x: int, y: int) int { return x + y }\nlet sum = add(1, \"2\")")
+		So(messages(diagnostics), ShouldResemble, []string{"Type mismatch: expected 'int', found 'string'"})
+		So(diagnostics[0].Notes[0].Msg, ShouldEqual, "Expected because of the type of the parameter 'y'")
+		So(diagnostics[0].Notes[0].Pos.Line, ShouldEqual, 1)
+	})
+
+	Convey("Test report invalid expressions", t, func() {
+		cases := []struct {
+			source string
+			code   compiler.DiagnosticCode
+			msg    string
+		}{
+			{"let a = 1 + \"x\"", compiler.InvalidOperation, "Operator '+' needs operands of the same type, found 'int' and 'string'"},
+			{"let a = \"x\" * \"y\"", compiler.InvalidOperation, "Operator '*' is not defined on 'string'"},
+			{"let a = !1", compiler.TypeMismatch, "Type mismatch: expected 'bool', found 'int'"},
+			{"let a = 1.5 << 2", compiler.InvalidOperation, "Operator '<<' needs integers, found 'f64'"},
+			{"func f(x: int) int { return x }\nlet a = f()", compiler.ArgumentCount, "Wrong number of arguments: expected 1, found 0"},
+			{"func f(x: int = 1) int { return x }\nlet a = f(1, 2)", compiler.ArgumentCount, "Wrong number of arguments: expected 0 to 1, found 2"},
+			{"func f() {}\nlet a = f()", compiler.InvalidOperation, "Expression doesn't have a value, its function doesn't return anything"},
+			{"let a = int", compiler.InvalidOperation, "'int' is a type, not a value"},
+			{"let a = 1\nlet b = a()", compiler.InvalidOperation, "Type 'int' can't be called, it's not a function"},
+			{"let a = []", compiler.CannotInferType, "Can't infer the type of an empty array, annotate it"},
+			{"let a: [3]int = [1, 2]", compiler.TypeMismatch, "Array literal has 2 elements, but '[3]int' has 3"},
+			{"let a = string(1.5)", compiler.InvalidOperation, "Can't convert 'f64' to 'string'"},
+			{"let a = None", compiler.CannotInferType, "Can't infer the type arguments of 'Option.None', give them explicitly like 'Option<T>.None'"},
+			{"func f() int { return }", compiler.TypeMismatch, "Missing return value, the function returns 'int'"},
+			{"func f() { return 1 }", compiler.TypeMismatch, "Unexpected return value, the function doesn't return anything"},
+			{"func f() { for x in 1 {} }", compiler.InvalidOperation, "Can't iterate over 'int', only over arrays, strings and ranges"},
+			{"func f() { if 1 {} }", compiler.TypeMismatch, "Type mismatch: expected 'bool', found 'int'"},
+			{"let a: Option = None", compiler.ArgumentCount, "Type 'Option' needs 1 type argument, found 0"},
+		}
+		for _, c := range cases {
+			_, diagnostics := checkSource(c.source)
+			So(messages(diagnostics), ShouldResemble, []string{c.msg})
+			So(diagnostics[0].Code, ShouldEqual, c.code)
+			So(diagnostics[0].Code, ShouldEqual, c.code)
+			So(diagnostics[0].Msg, ShouldEqual, c.msg)
+		}
+	})
+}
+
+func TestCheckMatchTypes(t *testing.T) {
+	Convey("Test type the patterns against the subject", t, func() {
+		vars, diagnostics := checkSource(`
+enum Shape { Circle(f64), Rect { w: f64, h: f64 } }
+let shape = Shape.Circle(1.0)
+let area = match shape {
+  Circle(r) => r * r
+  Shape.Rect { w, h } => w * h
+}
+let pair = (1, "one")
+let name = match pair { (n, s) => s }`)
+		So(diagnostics, ShouldBeEmpty)
+		So(vars["area"], ShouldEqual, "f64")
+		So(vars["r"], ShouldEqual, "f64")
+		So(vars["w"], ShouldEqual, "f64")
+		So(vars["name"], ShouldEqual, "string")
+	})
+
+	Convey("Test report the arms and patterns of another type", t, func() {
+		_, diagnostics := checkSource(`
+let n = 1
+let a = match n {
+  0 => "zero"
+  "one" => 1
+}`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Pattern of type 'string' doesn't match values of type 'int'",
+			"Type mismatch: expected 'string', found 'int'",
+			"Non-exhaustive match, pattern '..=-1' is not covered by any arm, add an arm for it or a '_' arm",
+		})
+		So(diagnostics[1].Notes[0].Msg, ShouldEqual, "Expected because of the type of the first arm")
+	})
+}
@@ -0,0 +1,465 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/resolve"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type ConstantKind int
+
+const (
+	IntConstant ConstantKind = iota
+	FloatConstant
+	StringConstant
+	BoolConstant
+)
+
+// Constant is the value of a constant expression, computed at compile time. The numbers
+// have an arbitrary precision, they're only bounded when converted to a sized type, see Fits.
+// The runes and the bytes are integer constants.
+type Constant struct {
+	Kind  ConstantKind
+	Int   *big.Int
+	Float *big.Rat
+	Text  string
+	Bool  bool
+}
+
+func (c *Constant) String() string {
+	switch c.Kind {
+	case IntConstant:
+		return c.Int.String()
+	case FloatConstant:
+		f, _ := c.Float.Float64()
+		text := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".eIN") {
+			text += ".0"
+		}
+		return text
+	case StringConstant:
+		return strconv.Quote(c.Text)
+	default:
+		return strconv.FormatBool(c.Bool)
+	}
+}
+
+// text returns the value interpolated in a template string.
+func (c *Constant) text() string {
+	if c.Kind == StringConstant {
+		return c.Text
+	}
+	return c.String()
+}
+
+// rat returns the value of a numeric constant as a fraction.
+func (c *Constant) rat() *big.Rat {
+	if c.Kind == IntConstant {
+		return new(big.Rat).SetInt(c.Int)
+	}
+	return c.Float
+}
+
+func (c *Constant) isNumeric() bool {
+	return c.Kind == IntConstant || c.Kind == FloatConstant
+}
+
+// bounds are the ranges of the sized integer types, the runes are 32-bit code points.
+var bounds = map[BasicKind][2]*big.Int{
+	IntKind:    {big.NewInt(math.MinInt64), big.NewInt(math.MaxInt64)},
+	Int8Kind:   {big.NewInt(math.MinInt8), big.NewInt(math.MaxInt8)},
+	Int16Kind:  {big.NewInt(math.MinInt16), big.NewInt(math.MaxInt16)},
+	Int32Kind:  {big.NewInt(math.MinInt32), big.NewInt(math.MaxInt32)},
+	Int64Kind:  {big.NewInt(math.MinInt64), big.NewInt(math.MaxInt64)},
+	UintKind:   {big.NewInt(0), new(big.Int).SetUint64(math.MaxUint64)},
+	Uint8Kind:  {big.NewInt(0), big.NewInt(math.MaxUint8)},
+	Uint16Kind: {big.NewInt(0), big.NewInt(math.MaxUint16)},
+	Uint32Kind: {big.NewInt(0), big.NewInt(math.MaxUint32)},
+	Uint64Kind: {big.NewInt(0), new(big.Int).SetUint64(math.MaxUint64)},
+	RuneKind:   {big.NewInt(0), big.NewInt(utf8.MaxRune)},
+}
+
+// Fits tells whether a numeric constant can be converted to a type without overflowing it.
+// A fraction converted to an integer type is truncated, and only its integer part must fit.
+// The constants of the other types always fit.
+func (c *Constant) Fits(t Type) bool {
+	basic, isBasic := t.(*Basic)
+	if !isBasic || !c.isNumeric() {
+		return true
+	}
+	switch basic.Kind {
+	case Float32Kind:
+		f, _ := c.rat().Float32()
+		return !math.IsInf(float64(f), 0)
+	case Float64Kind:
+		f, _ := c.rat().Float64()
+		return !math.IsInf(f, 0)
+	}
+	bound, isSized := bounds[basic.Kind]
+	if !isSized {
+		return true
+	}
+	value := c.Int
+	if c.Kind == FloatConstant {
+		value = new(big.Int).Quo(c.Float.Num(), c.Float.Denom())
+	}
+	return value.Cmp(bound[0]) >= 0 && value.Cmp(bound[1]) <= 0
+}
+
+// ConstantLookup returns the value of the constant a name refers to, or a NotConstant
+// diagnostic if the name isn't a constant. Both are nil if the constant is invalid and
+// its error is already reported.
+type ConstantLookup func(ident *ast.Identifier) (*Constant, *compiler.Diagnostic)
+
+// maxShift bounds the shifts and the exponents of the constants, which would
+// otherwise take an unbounded memory.
+const maxShift = 1 << 16
+
+// EvalConstant folds a constant expression made of literals, names of constants, and the
+// arithmetic, bitwise, comparison and logical operators, and of string concatenations and
+// template strings. lookup gives the values of the names, they're not constants if it's nil.
+// The diagnostic is nil if the error is already reported.
+func EvalConstant(expr ast.Expr, lookup ConstantLookup) (*Constant, *compiler.Diagnostic) {
+	switch e := expr.(type) {
+	case *ast.BasicLiteral:
+		return literalConstant(e)
+	case *ast.ParenExpr:
+		return EvalConstant(e.Expr, lookup)
+	case *ast.Identifier:
+		if lookup == nil {
+			return nil, notConstant(e, fmt.Sprintf("'%s' is not a constant", e.Name))
+		}
+		return lookup(e)
+	case *ast.UnaryExpr:
+		operand, diagnostic := EvalConstant(e.Operand, lookup)
+		if operand == nil {
+			return nil, diagnostic
+		}
+		return unaryConstant(e, operand)
+	case *ast.BinaryExpr:
+		left, diagnostic := EvalConstant(e.Left, lookup)
+		if left == nil {
+			return nil, diagnostic
+		}
+		right, diagnostic := EvalConstant(e.Right, lookup)
+		if right == nil {
+			return nil, diagnostic
+		}
+		return binaryConstant(e, left, right)
+	case *ast.TemplateString:
+		var text strings.Builder
+		for i, fragment := range e.Fragments {
+			text.WriteString(fragment.Value)
+			if i < len(e.Exprs) {
+				value, diagnostic := EvalConstant(e.Exprs[i], lookup)
+				if value == nil {
+					return nil, diagnostic
+				}
+				text.WriteString(value.text())
+			}
+		}
+		return &Constant{Kind: StringConstant, Text: text.String()}, nil
+	case *ast.CallExpr:
+		return nil, notConstant(e, "Function calls are not constant")
+	}
+	return nil, notConstant(expr, "Expression is not constant")
+}
+
+func notConstant(node ast.Node, msg string) *compiler.Diagnostic {
+	return errorAt(node, compiler.NotConstant, msg)
+}
+
+func literalConstant(literal *ast.BasicLiteral) (*Constant, *compiler.Diagnostic) {
+	switch literal.Kind {
+	case compiler.TokenTypeDecimalInteger,
+		compiler.TokenTypeOctalInteger,
+		compiler.TokenTypeHexadecimalInteger,
+		compiler.TokenTypeBinaryInteger:
+		if value, isValid := new(big.Int).SetString(literal.Value, 0); isValid {
+			return &Constant{Kind: IntConstant, Int: value}, nil
+		}
+	case compiler.TokenTypeExponent, compiler.TokenTypeFloat:
+		if value, isValid := new(big.Rat).SetString(strings.ReplaceAll(literal.Value, "_", "")); isValid {
+			return &Constant{Kind: FloatConstant, Float: value}, nil
+		}
+	case compiler.TokenTypeString:
+		return &Constant{Kind: StringConstant, Text: literal.Value}, nil
+	case compiler.TokenTypeTrue, compiler.TokenTypeFalse:
+		return &Constant{Kind: BoolConstant, Bool: literal.Kind == compiler.TokenTypeTrue}, nil
+	case compiler.TokenTypeRune:
+		if utf8.RuneCountInString(literal.Value) == 1 {
+			r, _ := utf8.DecodeRuneInString(literal.Value)
+			return &Constant{Kind: IntConstant, Int: big.NewInt(int64(r))}, nil
+		}
+	case compiler.TokenTypeByte:
+		if len(literal.Value) == 1 {
+			return &Constant{Kind: IntConstant, Int: big.NewInt(int64(literal.Value[0]))}, nil
+		}
+	}
+	return nil, notConstant(literal, fmt.Sprintf("Literal '%s' is not a constant", literal.Value))
+}
+
+func unaryConstant(e *ast.UnaryExpr, operand *Constant) (*Constant, *compiler.Diagnostic) {
+	switch {
+	case e.Operator.Type == compiler.TokenTypeMinus && operand.Kind == IntConstant:
+		return &Constant{Kind: IntConstant, Int: new(big.Int).Neg(operand.Int)}, nil
+	case e.Operator.Type == compiler.TokenTypeMinus && operand.Kind == FloatConstant:
+		return &Constant{Kind: FloatConstant, Float: new(big.Rat).Neg(operand.Float)}, nil
+	case e.Operator.Type == compiler.TokenTypePlus && operand.isNumeric():
+		return operand, nil
+	case e.Operator.Type == compiler.TokenTypeWavy && operand.Kind == IntConstant:
+		return &Constant{Kind: IntConstant, Int: new(big.Int).Not(operand.Int)}, nil
+	case e.Operator.Type == compiler.TokenTypeBang && operand.Kind == BoolConstant:
+		return &Constant{Kind: BoolConstant, Bool: !operand.Bool}, nil
+	}
+	return nil, notConstant(e, fmt.Sprintf("Operator '%s' is not constant on %s", e.Operator.Content, operand))
+}
+
+func binaryConstant(e *ast.BinaryExpr, left, right *Constant) (*Constant, *compiler.Diagnostic) {
+	operator := e.Operator.Type
+	switch {
+	case left.Kind == BoolConstant && right.Kind == BoolConstant:
+		switch operator {
+		case compiler.TokenTypeDoubleAmpersand:
+			return boolConstant(left.Bool && right.Bool), nil
+		case compiler.TokenTypeDoubleVertical:
+			return boolConstant(left.Bool || right.Bool), nil
+		case compiler.TokenTypeDoubleEqual:
+			return boolConstant(left.Bool == right.Bool), nil
+		case compiler.TokenTypeBangEqual:
+			return boolConstant(left.Bool != right.Bool), nil
+		}
+	case left.Kind == StringConstant && right.Kind == StringConstant:
+		if operator == compiler.TokenTypePlus {
+			return &Constant{Kind: StringConstant, Text: left.Text + right.Text}, nil
+		}
+		if comparison, isComparison := compare(operator, strings.Compare(left.Text, right.Text)); isComparison {
+			return boolConstant(comparison), nil
+		}
+	case left.Kind == IntConstant && right.Kind == IntConstant:
+		return intBinary(e, left.Int, right.Int)
+	case left.isNumeric() && right.isNumeric():
+		// An integer operand is converted to a float, like `2 * 1.5`
+		return floatBinary(e, left, right)
+	}
+	return nil, notConstant(e, fmt.Sprintf("Operator '%s' is not constant on %s and %s", e.Operator.Content, left, right))
+}
+
+func boolConstant(value bool) *Constant {
+	return &Constant{Kind: BoolConstant, Bool: value}
+}
+
+// compare returns the result of a comparison operator from the result of a three-way comparison.
+func compare(operator compiler.TokenType, cmp int) (bool, bool) {
+	switch operator {
+	case compiler.TokenTypeDoubleEqual:
+		return cmp == 0, true
+	case compiler.TokenTypeBangEqual:
+		return cmp != 0, true
+	case compiler.TokenTypeLeftAngle:
+		return cmp < 0, true
+	case compiler.TokenTypeLeftAngleEqual:
+		return cmp <= 0, true
+	case compiler.TokenTypeRightAngle:
+		return cmp > 0, true
+	case compiler.TokenTypeRightAngleEqual:
+		return cmp >= 0, true
+	}
+	return false, false
+}
+
+func intBinary(e *ast.BinaryExpr, left, right *big.Int) (*Constant, *compiler.Diagnostic) {
+	if comparison, isComparison := compare(e.Operator.Type, left.Cmp(right)); isComparison {
+		return boolConstant(comparison), nil
+	}
+	result := new(big.Int)
+	switch e.Operator.Type {
+	case compiler.TokenTypePlus:
+		result.Add(left, right)
+	case compiler.TokenTypeMinus:
+		result.Sub(left, right)
+	case compiler.TokenTypeStar:
+		result.Mul(left, right)
+	case compiler.TokenTypeSlash, compiler.TokenTypePercent:
+		if right.Sign() == 0 {
+			return nil, errorAt(e, compiler.InvalidOperation, "Division by zero in a constant expression")
+		}
+		if e.Operator.Type == compiler.TokenTypeSlash {
+			result.Quo(left, right)
+		} else {
+			result.Rem(left, right)
+		}
+	case compiler.TokenTypeAmpersand:
+		result.And(left, right)
+	case compiler.TokenTypeVertical:
+		result.Or(left, right)
+	case compiler.TokenTypeCaret:
+		result.Xor(left, right)
+	case compiler.TokenTypeDoubleLeftAngle, compiler.TokenTypeDoubleRightAngle, compiler.TokenTypeDoubleStar:
+		if right.Sign() < 0 || right.Cmp(big.NewInt(maxShift)) > 0 {
+			return nil, errorAt(e.Right, compiler.InvalidOperation, fmt.Sprintf(
+				"Operand %s of '%s' must be between 0 and %d in a constant expression", right, e.Operator.Content, maxShift,
+			))
+		}
+		switch e.Operator.Type {
+		case compiler.TokenTypeDoubleLeftAngle:
+			result.Lsh(left, uint(right.Int64()))
+		case compiler.TokenTypeDoubleRightAngle:
+			result.Rsh(left, uint(right.Int64()))
+		default:
+			result.Exp(left, right, nil)
+		}
+	default:
+		return nil, notConstant(e, fmt.Sprintf("Operator '%s' is not constant on %s and %s", e.Operator.Content, left, right))
+	}
+	return &Constant{Kind: IntConstant, Int: result}, nil
+}
+
+func floatBinary(e *ast.BinaryExpr, left, right *Constant) (*Constant, *compiler.Diagnostic) {
+	l, r := left.rat(), right.rat()
+	if comparison, isComparison := compare(e.Operator.Type, l.Cmp(r)); isComparison {
+		return boolConstant(comparison), nil
+	}
+	result := new(big.Rat)
+	switch e.Operator.Type {
+	case compiler.TokenTypePlus:
+		result.Add(l, r)
+	case compiler.TokenTypeMinus:
+		result.Sub(l, r)
+	case compiler.TokenTypeStar:
+		result.Mul(l, r)
+	case compiler.TokenTypeSlash:
+		if r.Sign() == 0 {
+			return nil, errorAt(e, compiler.InvalidOperation, "Division by zero in a constant expression")
+		}
+		result.Quo(l, r)
+	case compiler.TokenTypeDoubleStar:
+		// Only the integer exponents keep the result exact
+		if right.Kind != IntConstant || right.Int.CmpAbs(big.NewInt(maxShift)) > 0 {
+			return nil, notConstant(e, fmt.Sprintf("Exponent %s is not constant, it must be an integer between -%d and %d", right, maxShift, maxShift))
+		}
+		exponent := new(big.Int).Abs(right.Int)
+		result.SetFrac(new(big.Int).Exp(l.Num(), exponent, nil), new(big.Int).Exp(l.Denom(), exponent, nil))
+		if right.Int.Sign() < 0 {
+			if result.Sign() == 0 {
+				return nil, errorAt(e, compiler.InvalidOperation, "Division by zero in a constant expression")
+			}
+			result.Inv(result)
+		}
+	default:
+		return nil, notConstant(e, fmt.Sprintf("Operator '%s' is not constant on %s and %s", e.Operator.Content, left, right))
+	}
+	return &Constant{Kind: FloatConstant, Float: result}, nil
+}
+
+// constantsByName returns a lookup of the constants declared in a tree by their names,
+// for the phases which run before the names are resolved. The errors of the constants
+// are reported by the checker.
+func constantsByName(node ast.Node) ConstantLookup {
+	decls := map[string]*ast.VarDecl{}
+	ast.Inspect(node, func(node ast.Node) bool {
+		if decl, isVar := node.(*ast.VarDecl); isVar && decl.IsConst() && decl.Value != nil {
+			if _, exists := decls[decl.Name.Name]; !exists {
+				decls[decl.Name.Name] = decl
+			}
+		}
+		return true
+	})
+	values := map[string]*Constant{}
+	evaluating := map[string]bool{}
+	var lookup ConstantLookup
+	lookup = func(ident *ast.Identifier) (*Constant, *compiler.Diagnostic) {
+		decl, exists := decls[ident.Name]
+		if !exists {
+			return nil, notConstant(ident, fmt.Sprintf("'%s' is not a constant", ident.Name))
+		}
+		if value, evaluated := values[ident.Name]; evaluated {
+			return value, nil
+		}
+		if evaluating[ident.Name] {
+			return nil, nil
+		}
+		evaluating[ident.Name] = true
+		value, _ := EvalConstant(decl.Value, lookup)
+		delete(evaluating, ident.Name)
+		values[ident.Name] = value
+		return value, nil
+	}
+	return lookup
+}
+
+// constant evaluates a constant expression, whose names must refer to constants.
+func (c *checker) constant(expr ast.Expr) (*Constant, *compiler.Diagnostic) {
+	return EvalConstant(expr, c.namedConstant)
+}
+
+// namedConstant returns the value of the constant a name refers to. A top-level constant
+// used before its declaration is checked first, like a variable, see topLevelVar.
+func (c *checker) namedConstant(ident *ast.Identifier) (*Constant, *compiler.Diagnostic) {
+	symbol := c.table.Uses[ident]
+	if symbol != nil && symbol.Kind == resolve.ImportSymbol {
+		symbol = symbol.Target
+	}
+	if symbol == nil {
+		return nil, nil // The resolver reports it
+	}
+	if symbol.Kind != resolve.ConstSymbol {
+		return nil, notConstant(ident, fmt.Sprintf("'%s' is a %s, not a constant", ident.Name, symbol.Kind))
+	}
+	if _, checked := c.info.Symbols[symbol]; !checked {
+		if decl, isVar := symbol.Decl.(*ast.VarDecl); isVar && symbol.Scope.Kind == resolve.ModuleScope {
+			c.topLevelVar(decl, symbol)
+		}
+	}
+	return c.info.Constants[symbol], nil
+}
+
+// untypedConstant returns the value of the numeric constant a name refers to if the constant
+// has no type annotation, so that it takes the type of its context like a literal, or nil.
+func (c *checker) untypedConstant(ident *ast.Identifier) *Constant {
+	symbol := c.table.Uses[ident]
+	if symbol != nil && symbol.Kind == resolve.ImportSymbol {
+		symbol = symbol.Target
+	}
+	if symbol == nil || symbol.Kind != resolve.ConstSymbol {
+		return nil
+	}
+	decl, isVar := symbol.Decl.(*ast.VarDecl)
+	value := c.info.Constants[symbol]
+	if !isVar || decl.Type != nil || value == nil || !value.isNumeric() {
+		return nil
+	}
+	return value
+}
+
+// checkOverflow reports a constant expression whose value doesn't fit in the numeric type it's converted to.
+func (c *checker) checkOverflow(expr ast.Expr, t Type) {
+	if !isNumeric(t) && t != Rune {
+		return
+	}
+	if value, _ := c.constant(expr); value != nil && !value.Fits(t) {
+		c.errorf(expr, compiler.ConstantOverflow, "Constant %s overflows '%s'", value, t)
+	}
+}
+
+// checkDivision reports a division or a remainder by zero whose operands are both constant,
+// which can't be folded, in any expression and not only in the constant declarations.
+func (c *checker) checkDivision(e *ast.BinaryExpr) {
+	if e.Operator.Type != compiler.TokenTypeSlash && e.Operator.Type != compiler.TokenTypePercent {
+		return
+	}
+	left, _ := c.constant(e.Left)
+	right, _ := c.constant(e.Right)
+	if left == nil || right == nil {
+		return
+	}
+	if _, diagnostic := binaryConstant(e, left, right); diagnostic != nil && diagnostic.Code == compiler.InvalidOperation {
+		c.report(diagnostic)
+	}
+}
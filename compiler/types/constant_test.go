@@ -0,0 +1,172 @@
+package types
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/parser"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// constantsOf checks a source file, and returns the values of its constants by name.
+func constantsOf(source string) (map[string]string, []*compiler.Diagnostic) {
+	file := parser.CreateParser(source).ParseFile().Unwrap()
+	info, diagnostics := CheckFile(file)
+	values := map[string]string{}
+	for symbol, value := range info.Constants {
+		if value != nil {
+			values[symbol.Name] = value.String()
+		}
+	}
+	return values, diagnostics
+}
+
+func TestEvalConstant(t *testing.T) {
+	Convey("Test fold the constant expressions with an arbitrary precision", t, func() {
+		cases := []struct {
+			source string
+			value  string
+		}{
+			{"1 << 100 >> 98", "4"},
+			{"2 ** 64", "18446744073709551616"},
+			{"-7 / 2", "-3"},
+			{"-7 % 2", "-1"},
+			{"0xF0 | 0x0F & 0x3C", "252"},
+			{"~0 ^ 5", "-6"},
+			{"1 + 0.5", "1.5"},
+			{"1.0 / 3 * 3 == 1.0", "true"},
+			{"2.0 ** -2", "0.25"},
+			{"\"ab\" + \"cd\"", "\"abcd\""},
+			{"\"a\" < \"b\" && !(1 >= 2)", "true"},
+			{"`${1 + 1} and ${\"two\"}`", "\"2 and two\""},
+			{"'a' + 1", "98"},
+		}
+		for _, c := range cases {
+			file := parser.CreateParser("let x = " + c.source).ParseFile().Unwrap()
+			value, diagnostic := EvalConstant(file.Statements[0].(*ast.VarDecl).Value, nil)
+			So(diagnostic, ShouldBeNil)
+			So(value.String(), ShouldEqual, c.value)
+		}
+	})
+
+	Convey("Test report the expressions which are not constant", t, func() {
+		cases := []struct {
+			source string
+			code   compiler.DiagnosticCode
+			msg    string
+		}{
+			{"f(1)", compiler.NotConstant, "Function calls are not constant"},
+			{"1 + n", compiler.NotConstant, "'n' is not a constant"},
+			{"1 / 0", compiler.InvalidOperation, "Division by zero in a constant expression"},
+			{"1 << -1", compiler.InvalidOperation, "Operand -1 of '<<' must be between 0 and 65536 in a constant expression"},
+			{"\"a\" - \"b\"", compiler.NotConstant, "Operator '-' is not constant on \"a\" and \"b\""},
+		}
+		for _, c := range cases {
+			file := parser.CreateParser("let x = " + c.source).ParseFile().Unwrap()
+			value, diagnostic := EvalConstant(file.Statements[0].(*ast.VarDecl).Value, nil)
+			So(value, ShouldBeNil)
+			So(diagnostic.Code, ShouldEqual, c.code)
+			So(diagnostic.Msg, ShouldEqual, c.msg)
+		}
+	})
+
+	Convey("Test tell whether a constant fits in a type", t, func() {
+		So((&Constant{Kind: IntConstant, Int: bounds[Uint8Kind][1]}).Fits(Uint8), ShouldBeTrue)
+		So((&Constant{Kind: IntConstant, Int: bounds[Int8Kind][0]}).Fits(Uint8), ShouldBeFalse)
+		So((&Constant{Kind: IntConstant, Int: bounds[Uint64Kind][1]}).Fits(Int64), ShouldBeFalse)
+		So((&Constant{Kind: IntConstant, Int: bounds[Uint64Kind][1]}).Fits(Float32), ShouldBeTrue)
+		So((&Constant{Kind: BoolConstant}).Fits(Uint8), ShouldBeTrue)
+	})
+}
+
+func TestCheckConstants(t *testing.T) {
+	Convey("Test evaluate the constants of a file", t, func() {
+		values, diagnostics := constantsOf(`
+func area() f64 { return PI * 2.0 }
+const PI = 3.14159
+const SIZE = 4
+const CELLS = SIZE * SIZE
+const MASK: u8 = 0xFF
+const NAME = "grid"
+const TITLE = ` + "`${NAME} of ${CELLS}`" + `
+let grid: [CELLS]int = [0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0]
+let small: u8 = SIZE
+let half: f32 = PI / 2.0`)
+		So(diagnostics, ShouldBeEmpty)
+		So(values, ShouldResemble, map[string]string{
+			"PI": "3.14159", "SIZE": "4", "CELLS": "16", "MASK": "255", "NAME": "\"grid\"", "TITLE": "\"grid of 16\"",
+		})
+	})
+
+	Convey("Test use the constants as enum discriminants and tuple indexes", t, func() {
+		vars, diagnostics := checkSource(`
+const BASE = 1 << 4
+enum Flag { Read = BASE, Write, Exec = BASE * 4 }
+const SECOND = 1
+let pair = (1, "one")
+let name = pair[SECOND]`)
+		So(diagnostics, ShouldBeEmpty)
+		So(vars["name"], ShouldEqual, "string")
+
+		file := parser.CreateParser("const BASE = 1 << 4\nenum Flag { Read = BASE, Write, Exec = BASE * 4 }").ParseFile().Unwrap()
+		enums, diagnostics := DeclareEnums(file)
+		So(diagnostics, ShouldBeEmpty)
+		var discriminants []string
+		for _, variant := range enums["Flag"].Variants {
+			discriminants = append(discriminants, variant.Discriminant.String())
+		}
+		So(discriminants, ShouldResemble, []string{"16", "17", "64"})
+	})
+
+	Convey("Test report the overflows when a constant is converted to a sized type", t, func() {
+		cases := []struct {
+			source string
+			msg    string
+		}{
+			{"let a: u8 = 256", "Constant 256 overflows 'u8'"},
+			{"let a: i8 = -129", "Constant -129 overflows 'i8'"},
+			{"const BIG = 1 << 64\nlet a = BIG", "Constant 18446744073709551616 overflows 'int'"},
+			{"const BIG = 1 << 64\nlet a: u64 = BIG - 1\nlet b: u64 = BIG", "Constant 18446744073709551616 overflows 'u64'"},
+			{"const LIMIT: u16 = 70000", "Constant 70000 overflows 'u16'"},
+			{"let a = u8(300)", "Constant 300 overflows 'u8'"},
+			{"let a: u8 = 1\nlet b = a + 1000", "Constant 1000 overflows 'u8'"},
+			{"func f(x: i16) {}\nfunc g() { f(40000) }", "Constant 40000 overflows 'i16'"},
+			{"let a: f32 = 1e40", "Constant 1e+40 overflows 'f32'"},
+		}
+		for _, c := range cases {
+			_, diagnostics := checkSource(c.source)
+			So(messages(diagnostics), ShouldResemble, []string{c.msg})
+			So(diagnostics[0].Code, ShouldEqual, compiler.ConstantOverflow)
+		}
+
+		// The intermediate results have an arbitrary precision
+		_, diagnostics := checkSource("let a: u8 = 1000 - 999\nconst HUGE = 1 << 200\nconst ONE = HUGE >> 200")
+		So(diagnostics, ShouldBeEmpty)
+	})
+
+	Convey("Test report the constants whose value is not constant", t, func() {
+		_, diagnostics := checkSource("func f() int { return 1 }\nconst A = f()\nlet n = 2\nconst B = n + 1\nconst C = 1 / 0")
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Function calls are not constant",
+			"'n' is a variable, not a constant",
+			"Division by zero in a constant expression",
+		})
+		So(diagnostics[0].Code, ShouldEqual, compiler.NotConstant)
+
+		_, diagnostics = checkSource("let n = 4\nlet a: [n]int = [1, 2, 3, 4]")
+		So(messages(diagnostics), ShouldResemble, []string{"Array length must be a non-negative integer constant"})
+	})
+
+	Convey("Test report the divisions by zero folded outside the constant declarations", t, func() {
+		_, diagnostics := checkSource("const ZERO = 0\nlet h = 10 / 0\nlet m = 10 % ZERO\nlet f = 1.5 / 0\nfunc g(n: int) int { return n / 0 + (1 + 1) / 2 }")
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Division by zero in a constant expression",
+			"Division by zero in a constant expression",
+			"Division by zero in a constant expression",
+		})
+		So(diagnostics[0].Code, ShouldEqual, compiler.InvalidOperation)
+		So(diagnostics[0].Pos.Line, ShouldEqual, 2)
+		So(diagnostics[1].Pos.Line, ShouldEqual, 3)
+	})
+}
@@ -0,0 +1,225 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"strconv"
+)
+
+// Field is a field of a struct, or of the payload of an enum variant.
+// The fields of a tuple payload are named by their index, like `0`.
+// A field with a default value may be left out of the literals.
+type Field struct {
+	Name    string
+	Type    Type
+	Default bool
+}
+
+// Variant is a variant of an enum. Tuple tells a tuple payload from a struct
+// payload, and a variant without payload has no fields. Every variant has a
+// discriminant, which is explicit for C-like variants like `Red = 1`.
+type Variant struct {
+	Name         string
+	Fields       []*Field
+	Tuple        bool
+	Discriminant *big.Int
+}
+
+// Enum is a tagged union, a value is one of the variants with its payload.
+// The instances of a generic enum share its variants, and have a type
+// argument for each type parameter.
+type Enum struct {
+	Name       string
+	TypeParams []*TypeParam
+	TypeArgs   []Type
+	Variants   []*Variant
+	origin     *Enum // The generic enum of an instance
+}
+
+func (e *Enum) String() string {
+	if len(e.TypeArgs) == 0 {
+		return e.Name
+	}
+	return fmt.Sprintf("%s<%s>", e.Name, typeList(e.TypeArgs))
+}
+
+// Variant returns the variant with the name, or nil.
+func (e *Enum) Variant(name string) *Variant {
+	for _, variant := range e.Origin().Variants {
+		if variant.Name == name {
+			return variant
+		}
+	}
+	return nil
+}
+
+// Instantiate returns the instance of a generic enum for the type arguments.
+// The variants of an instance are the ones of its origin, see Origin.
+func (e *Enum) Instantiate(typeArgs []Type) *Enum {
+	return &Enum{
+		Name:       e.Name,
+		TypeParams: e.TypeParams,
+		TypeArgs:   typeArgs,
+		Variants:   e.Variants,
+		origin:     e.Origin(),
+	}
+}
+
+// Origin returns the generic enum of an instance, or the enum itself.
+func (e *Enum) Origin() *Enum {
+	if e.origin != nil {
+		return e.origin
+	}
+	return e
+}
+
+// FieldTypes returns the types of the payload fields of a variant,
+// with the type arguments of the instance.
+func (e *Enum) FieldTypes(variant *Variant) []Type {
+	subst := Substitution{}
+	for i, typeArg := range e.TypeArgs {
+		if i < len(e.TypeParams) {
+			subst[e.TypeParams[i]] = typeArg
+		}
+	}
+	fieldTypes := make([]Type, len(variant.Fields))
+	for i, field := range variant.Fields {
+		fieldTypes[i] = Subst(field.Type, subst)
+	}
+	return fieldTypes
+}
+
+var (
+	optionT = &TypeParam{Name: "T"}
+	resultT = &TypeParam{Name: "T"}
+	resultE = &TypeParam{Name: "E"}
+
+	// Option is the predeclared enum of optional values, `Option.Some(value)` or `Option.None`.
+	Option = &Enum{
+		Name:       "Option",
+		TypeParams: []*TypeParam{optionT},
+		Variants: []*Variant{
+			{Name: "Some", Fields: []*Field{{Name: "0", Type: optionT}}, Tuple: true, Discriminant: big.NewInt(0)},
+			{Name: "None", Discriminant: big.NewInt(1)},
+		},
+	}
+	// Result is the predeclared enum of the results of fallible operations,
+	// `Result.Ok(value)` or `Result.Err(error)`, like shared.Result on the Go side.
+	Result = &Enum{
+		Name:       "Result",
+		TypeParams: []*TypeParam{resultT, resultE},
+		Variants: []*Variant{
+			{Name: "Ok", Fields: []*Field{{Name: "0", Type: resultT}}, Tuple: true, Discriminant: big.NewInt(0)},
+			{Name: "Err", Fields: []*Field{{Name: "0", Type: resultE}}, Tuple: true, Discriminant: big.NewInt(1)},
+		},
+	}
+)
+
+// DeclareEnum lowers an enum declaration. The variants without an explicit
+// discriminant are numbered from the previous variant, starting at 0.
+// The discriminants must be integer constants, but they can't refer to named constants.
+func DeclareEnum(decl *ast.EnumDecl) (*Enum, []*compiler.Diagnostic) {
+	return lowerEnum(decl, nil)
+}
+
+// lowerEnum lowers an enum declaration whose discriminants may refer to the constants of lookup.
+func lowerEnum(decl *ast.EnumDecl, lookup ConstantLookup) (*Enum, []*compiler.Diagnostic) {
+	enum, typeParams := newEnum(decl)
+	for i, typeParam := range decl.TypeParams {
+		if typeParam.Constraint != nil {
+			enum.TypeParams[i].Constraint = LowerType(typeParam.Constraint, typeParams)
+		}
+	}
+	diagnostics := declareVariants(enum, decl, func(typeExpr ast.TypeExpr) Type {
+		return LowerType(typeExpr, typeParams)
+	}, lookup)
+	return enum, diagnostics
+}
+
+// newEnum returns an enum without variants for a declaration, with its type parameters by name.
+func newEnum(decl *ast.EnumDecl) (*Enum, map[string]*TypeParam) {
+	enum := &Enum{Name: decl.Name.Name}
+	typeParams := map[string]*TypeParam{}
+	for _, typeParam := range decl.TypeParams {
+		param := &TypeParam{Name: typeParam.Name.Name}
+		typeParams[param.Name] = param
+		enum.TypeParams = append(enum.TypeParams, param)
+	}
+	return enum, typeParams
+}
+
+// declareVariants adds the variants of a declaration to its enum, lowering the payload types with lower.
+// The discriminants are constant expressions, whose names are looked up with lookup.
+func declareVariants(enum *Enum, decl *ast.EnumDecl, lower func(ast.TypeExpr) Type, lookup ConstantLookup) []*compiler.Diagnostic {
+	var diagnostics []*compiler.Diagnostic
+	discriminant := big.NewInt(0)
+	variantOfDiscriminant := map[string]string{}
+	for _, variantDecl := range decl.Variants {
+		name := variantDecl.Name.Name
+		if enum.Variant(name) != nil {
+			diagnostics = append(diagnostics, errorAt(variantDecl.Name, compiler.DuplicateDeclaration, fmt.Sprintf(
+				"Duplicate variant '%s' in enum '%s'", name, enum.Name,
+			)))
+			continue
+		}
+
+		variant := &Variant{Name: name, Tuple: variantDecl.Tuple != nil}
+		for i, fieldType := range variantDecl.Tuple {
+			variant.Fields = append(variant.Fields, &Field{Name: strconv.Itoa(i), Type: lower(fieldType)})
+		}
+		for _, field := range variantDecl.Fields {
+			if field.Name == nil {
+				continue // An embedded struct, which the checker reports
+			}
+			variant.Fields = append(variant.Fields, &Field{Name: field.Name.Name, Type: lower(field.Type), Default: field.Default != nil})
+		}
+
+		if variantDecl.Discriminant != nil {
+			value, diagnostic := EvalConstant(variantDecl.Discriminant, lookup)
+			switch {
+			case value != nil && value.Kind == IntConstant:
+				discriminant = value.Int
+			case value != nil || (diagnostic != nil && diagnostic.Code == compiler.NotConstant):
+				diagnostics = append(diagnostics, errorAt(variantDecl.Discriminant, compiler.InvalidDiscriminant, fmt.Sprintf(
+					"Discriminant of variant '%s' must be an integer constant", name,
+				)))
+			case diagnostic != nil:
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		}
+		if previous, isUsed := variantOfDiscriminant[discriminant.String()]; isUsed {
+			diagnostics = append(diagnostics, errorAt(variantDecl, compiler.InvalidDiscriminant, fmt.Sprintf(
+				"Discriminant %s of variant '%s' is already used by variant '%s'", discriminant, name, previous,
+			)))
+		}
+		variantOfDiscriminant[discriminant.String()] = name
+		variant.Discriminant = discriminant
+		discriminant = new(big.Int).Add(discriminant, big.NewInt(1))
+		enum.Variants = append(enum.Variants, variant)
+	}
+	return diagnostics
+}
+
+// DeclareEnums lowers all the enum declarations of a tree, and returns them by name.
+// The discriminants may refer to the constants declared in the tree.
+func DeclareEnums(node ast.Node) (map[string]*Enum, []*compiler.Diagnostic) {
+	enums := map[string]*Enum{}
+	lookup := constantsByName(node)
+	var diagnostics []*compiler.Diagnostic
+	ast.Inspect(node, func(node ast.Node) bool {
+		if decl, isEnum := node.(*ast.EnumDecl); isEnum {
+			enum, enumDiagnostics := lowerEnum(decl, lookup)
+			enums[enum.Name] = enum
+			diagnostics = append(diagnostics, enumDiagnostics...)
+		}
+		return true
+	})
+	return enums, diagnostics
+}
+
+func errorAt(node ast.Node, code compiler.DiagnosticCode, msg string) *compiler.Diagnostic {
+	span := node.NodeSpan()
+	return &compiler.Diagnostic{Type: compiler.DiagnosticError, Code: code, Pos: span.Start, End: span.End, Msg: msg}
+}
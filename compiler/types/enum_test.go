@@ -0,0 +1,69 @@
+package types
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/parser"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func declareEnum(source string) (*Enum, []*compiler.Diagnostic) {
+	file := parser.CreateParser(source).ParseFile().Unwrap()
+	return DeclareEnum(file.Statements[0].(*ast.EnumDecl))
+}
+
+func TestDeclareEnum(t *testing.T) {
+	Convey("Test declare enum with payloads", t, func() {
+		enum, diagnostics := declareEnum("enum Tree<T> {\n  Leaf\n  Node(Tree<T>, T, Tree<T>)\n  Tagged { tag: string, value: []T }\n}")
+		So(diagnostics, ShouldBeEmpty)
+		So(enum.String(), ShouldEqual, "Tree")
+		node := enum.Variant("Node")
+		So(node.Tuple, ShouldBeTrue)
+		So(node.Fields[1].Name, ShouldEqual, "1")
+		So(node.Fields[1].Type, ShouldEqual, enum.TypeParams[0])
+
+		instance := enum.Instantiate([]Type{Int})
+		So(instance.String(), ShouldEqual, "Tree<int>")
+		So(Identical(instance, enum.Instantiate([]Type{Int})), ShouldBeTrue)
+		So(Identical(instance, enum.Instantiate([]Type{String})), ShouldBeFalse)
+		tagged := instance.FieldTypes(instance.Variant("Tagged"))
+		So(tagged[0], ShouldEqual, String)
+		So(tagged[1].String(), ShouldEqual, "[]int")
+	})
+
+	Convey("Test number discriminants", t, func() {
+		enum, diagnostics := declareEnum("enum Level { Low = -1, Mid, High = 0x10, Max }")
+		So(diagnostics, ShouldBeEmpty)
+		var discriminants []string
+		for _, variant := range enum.Variants {
+			discriminants = append(discriminants, variant.Discriminant.String())
+		}
+		So(discriminants, ShouldResemble, []string{"-1", "0", "16", "17"})
+	})
+
+	Convey("Test report invalid enum declarations", t, func() {
+		_, diagnostics := declareEnum("enum E {\n  A = 1\n  B = 0\n  C\n  A\n  D = f()\n}")
+		So(len(diagnostics), ShouldEqual, 3)
+		So(diagnostics[0].Msg, ShouldEqual, "Discriminant 1 of variant 'C' is already used by variant 'A'")
+		So(diagnostics[1].Code, ShouldEqual, compiler.DuplicateDeclaration)
+		So(diagnostics[1].Msg, ShouldEqual, "Duplicate variant 'A' in enum 'E'")
+		So(diagnostics[2].Msg, ShouldEqual, "Discriminant of variant 'D' must be an integer constant")
+	})
+
+	Convey("Test report invalid enum declarations when checking", t, func() {
+		_, diagnostics := checkSource("enum E { A, A }\nfunc f() {\n  enum F { X = 1, Y = 0, Z }\n}")
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Duplicate variant 'A' in enum 'E'",
+			"Discriminant 1 of variant 'Z' is already used by variant 'X'",
+		})
+	})
+
+	Convey("Test predeclared generic enums", t, func() {
+		So(Predeclared["Option"], ShouldEqual, Option)
+		result := Result.Instantiate([]Type{Int, String})
+		So(result.FieldTypes(result.Variant("Err"))[0], ShouldEqual, String)
+		So(Option.Variant("None").Fields, ShouldBeEmpty)
+	})
+}
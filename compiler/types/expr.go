@@ -0,0 +1,1100 @@
+package types
+
+import (
+	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/resolve"
+	"mirth/shared"
+	"strings"
+)
+
+// expr checks an expression and records its type. expected is the type the context
+// expects, or nil: it gives their type to the numeric literals and to the parameters
+// of the arrow lambdas, but the caller checks that the result is assignable to it.
+func (c *checker) expr(expr ast.Expr, expected Type) Type {
+	t := c.exprType(expr, expected)
+	c.info.Types[expr] = t
+	return t
+}
+
+func (c *checker) exprType(expr ast.Expr, expected Type) Type {
+	switch e := expr.(type) {
+	case *ast.BasicLiteral:
+		return literalType(e, expected)
+	case *ast.Identifier:
+		return c.ident(e, expected)
+	case *ast.ParenExpr:
+		return c.expr(e.Expr, expected)
+	case *ast.UnaryExpr:
+		return c.unary(e, e.Operator, e.Operand, expected)
+	case *ast.PostfixExpr:
+		return c.unary(e, e.Operator, e.Operand, expected)
+	case *ast.BinaryExpr:
+		return c.binary(e, expected)
+	case *ast.AssignExpr:
+		return c.assignment(e)
+	case *ast.CallExpr:
+		return c.call(e, expected)
+	case *ast.IndexExpr:
+		return c.index(e)
+	case *ast.MemberExpr:
+		return c.member(e, expected)
+	case *ast.InstantiateExpr:
+		return c.instantiateExpr(e)
+	case *ast.TypeAssertion:
+		value := c.value(e.Value, nil)
+		target := c.lowerType(e.Type)
+		c.checkAssertion(e.Value, value, e.Type, target)
+		return target
+	case *ast.ArrayLit:
+		return c.arrayLit(e, expected)
+	case *ast.TupleLit:
+		expectedTuple, _ := expected.(*Tuple)
+		elems := make([]Type, len(e.Elements))
+		for i, element := range e.Elements {
+			var expectedElem Type
+			if expectedTuple != nil && len(expectedTuple.Elems) == len(e.Elements) {
+				expectedElem = expectedTuple.Elems[i]
+			}
+			elems[i] = c.value(element, expectedElem)
+		}
+		return &Tuple{Elems: elems}
+	case *ast.RangeExpr:
+		return c.rangeExpr(e, expected)
+	case *ast.SpreadExpr:
+		return c.value(e.Expr, nil)
+	case *ast.StructLit:
+		return c.structLit(e, expected)
+	case *ast.TemplateString:
+		for _, interpolated := range e.Exprs {
+			c.value(interpolated, nil)
+		}
+		return String
+	case *ast.TaggedTemplate:
+		return c.taggedTemplate(e)
+	case *ast.FuncLit:
+		expectedFunc, _ := expected.(*Func)
+		return c.funcLit(e, e.Params, e.ReturnType, e.Body, expectedFunc)
+	case *ast.ArrowFunc:
+		expectedFunc, _ := expected.(*Func)
+		return c.funcLit(e, e.Params, nil, e.Body, expectedFunc)
+	case *ast.MatchExpr:
+		return c.match(e, expected)
+	default:
+		return Invalid
+	}
+}
+
+func resultOf(signature *Func) Type {
+	if signature.Result == nil {
+		return Void
+	}
+	return signature.Result
+}
+
+// literalType returns the type of a literal, the numeric literals take the expected numeric type
+// and nil the expected optional type. A byte string is an array of bytes.
+func literalType(literal *ast.BasicLiteral, expected Type) Type {
+	switch literal.Kind {
+	case compiler.TokenTypeDecimalInteger,
+		compiler.TokenTypeOctalInteger,
+		compiler.TokenTypeHexadecimalInteger,
+		compiler.TokenTypeBinaryInteger:
+		if isNumeric(expected) {
+			return expected
+		}
+		return Int
+	case compiler.TokenTypeExponent, compiler.TokenTypeFloat:
+		if isFloat(expected) {
+			return expected
+		}
+		return Float64
+	case compiler.TokenTypeString:
+		return String
+	case compiler.TokenTypeRune:
+		return Rune
+	case compiler.TokenTypeByte:
+		return Uint8
+	case compiler.TokenTypeByteString:
+		return &Array{Elem: Uint8, Length: -1}
+	case compiler.TokenTypeTrue, compiler.TokenTypeFalse:
+		return Bool
+	case compiler.TokenTypeNil:
+		if _, isOptional := expected.(*Optional); isOptional {
+			return expected
+		}
+		return Nil
+	}
+	return Invalid
+}
+
+// ident returns the type of the value a name refers to.
+func (c *checker) ident(ident *ast.Identifier, expected Type) Type {
+	symbol := c.table.Uses[ident]
+	if symbol == nil {
+		// An unqualified variant, otherwise the resolver reported an undefined name
+		if enum, variant := c.variantNamed(ident, expected); variant != nil {
+			return c.variantValue(ident, enum, variant, expected)
+		}
+		return Invalid
+	}
+	t := c.symbolValue(ident, symbol)
+	// An untyped constant takes the expected numeric type, like a literal
+	if value := c.untypedConstant(ident); value != nil {
+		if (value.Kind == IntConstant && isNumeric(expected)) || (value.Kind == FloatConstant && isFloat(expected)) {
+			return expected
+		}
+	}
+	return t
+}
+
+// symbolValue returns the type of the value of a symbol, node is the node referring to it.
+func (c *checker) symbolValue(node ast.Node, symbol *resolve.Symbol) Type {
+	if symbol.Kind == resolve.ImportSymbol {
+		if symbol.Target == nil {
+			return Invalid
+		}
+		symbol = symbol.Target
+	}
+	if t, isNarrowed := c.narrowed[symbol]; isNarrowed {
+		return t
+	}
+	switch symbol.Kind {
+	case resolve.TypeSymbol, resolve.StructSymbol, resolve.InterfaceSymbol, resolve.EnumSymbol, resolve.TypeParamSymbol, resolve.ModuleSymbol:
+		c.errorf(node, compiler.InvalidOperation, "'%s' is a %s, not a value", symbol.Name, symbol.Kind)
+		return Invalid
+	case resolve.VarSymbol, resolve.ConstSymbol:
+		if t := c.info.Symbols[symbol]; t != nil {
+			return t
+		}
+		if decl, isVar := symbol.Decl.(*ast.VarDecl); isVar && symbol.Scope.Kind == resolve.ModuleScope {
+			return c.topLevelVar(decl, symbol)
+		}
+		return Invalid
+	}
+	if t := c.info.Symbols[symbol]; t != nil {
+		return t
+	}
+	return Invalid
+}
+
+// topLevelVar checks a top-level variable when its type is needed before its declaration is met.
+func (c *checker) topLevelVar(decl *ast.VarDecl, symbol *resolve.Symbol) Type {
+	if c.inferring[symbol] {
+		c.inFileOf(decl, func() {
+			c.errorf(decl.Name, compiler.CannotInferType, "Initialization cycle, the value of '%s' refers to itself", decl.Name.Name)
+		})
+		c.info.Symbols[symbol] = Invalid
+		return Invalid
+	}
+	function, narrowed := c.function, c.narrowed
+	c.function, c.narrowed = nil, nil
+	c.inFileOf(decl, func() {
+		c.varDecl(decl)
+	})
+	c.function, c.narrowed = function, narrowed
+	return c.info.Symbols[symbol]
+}
+
+// typeExpr returns the type an expression names, like `Point` or `Box<int>`, and whether it names one.
+func (c *checker) typeExpr(expr ast.Expr) (Type, bool) {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		if symbol := c.table.Uses[e]; symbol != nil {
+			return c.typeOfSymbol(symbol)
+		}
+	case *ast.MemberExpr:
+		// A type of an imported module, like `geometry.Point`
+		if target, isName := e.Target.(*ast.Identifier); isName {
+			if module := c.table.Uses[target]; module != nil && module.Kind == resolve.ModuleSymbol {
+				if symbol := c.table.Uses[e.Name]; symbol != nil {
+					return c.typeOfSymbol(symbol)
+				}
+			}
+		}
+	case *ast.InstantiateExpr:
+		if generic, isType := c.typeExpr(e.Target); isType {
+			typeArgs := make([]Type, len(e.TypeArgs))
+			for i, typeArg := range e.TypeArgs {
+				typeArgs[i] = c.lowerType(typeArg)
+			}
+			t := c.instantiate(e, generic, typeArgs)
+			c.info.Types[e] = t
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// variantNamed returns the enum variant an unqualified name refers to: a variant of the
+// expected enum, or else the only variant with the name of the enums in scope.
+func (c *checker) variantNamed(ident *ast.Identifier, expected Type) (*Enum, *Variant) {
+	if enum, isEnum := expected.(*Enum); isEnum && enum.Variant(ident.Name) != nil {
+		return enum, enum.Variant(ident.Name)
+	}
+	var found *Enum
+	for _, candidate := range c.enumsInScope(ident) {
+		if candidate.Variant(ident.Name) != nil {
+			if found != nil {
+				c.errorf(ident, compiler.UndefinedName, "Variant '%s' is ambiguous, qualify it like '%s.%s'", ident.Name, found.Name, ident.Name)
+				return nil, nil
+			}
+			found = candidate
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+	return found, found.Variant(ident.Name)
+}
+
+// enumsInScope returns the enums visible where a name is used.
+func (c *checker) enumsInScope(ident *ast.Identifier) []*Enum {
+	var enums []*Enum
+	for scope := c.scopeOf(ident); scope != nil; scope = scope.Parent {
+		for _, symbol := range scope.Symbols() {
+			if t, isType := c.typeOfSymbol(symbol); isType {
+				if enum, isEnum := t.(*Enum); isEnum {
+					enums = append(enums, enum)
+				}
+			}
+		}
+	}
+	return enums
+}
+
+func (c *checker) scopeOf(node ast.Node) *resolve.Scope {
+	return c.table.ScopeAt(c.files[c.file], node.NodeSpan().Start)
+}
+
+// variantValue returns the type of a variant used as a value: the enum for a variant
+// without payload, or the function building it for a tuple payload. The type arguments
+// of a generic enum which isn't instantiated are inferred from the expected type, or
+// from the arguments of the call building the variant.
+func (c *checker) variantValue(node ast.Node, enum *Enum, variant *Variant, expected Type) Type {
+	if len(enum.TypeArgs) == 0 && len(enum.TypeParams) > 0 {
+		if expectedEnum, isEnum := expected.(*Enum); isEnum && expectedEnum.Origin() == enum.Origin() {
+			enum = expectedEnum
+		}
+	}
+	generic := len(enum.TypeArgs) == 0 && len(enum.TypeParams) > 0
+	switch {
+	case len(variant.Fields) > 0 && !variant.Tuple:
+		c.errorf(node, compiler.InvalidOperation, "Variant '%s.%s' has fields, build it with a struct literal like '%s.%s { ... }'",
+			enum.Name, variant.Name, enum.Name, variant.Name,
+		)
+		return Invalid
+	case variant.Tuple:
+		signature := &Func{Params: enum.FieldTypes(variant), Result: enum}
+		if generic {
+			signature.TypeParams = enum.TypeParams
+			signature.Result = enum.Instantiate(typeParamsAsTypes(enum.TypeParams))
+		}
+		return signature
+	case generic && expected == Invalid:
+		return Invalid // The expected type is already reported
+	case generic:
+		c.errorf(node, compiler.CannotInferType, "Can't infer the type arguments of '%s.%s', give them explicitly like '%s<%s>.%s'",
+			enum.Name, variant.Name, enum.Name, typeList(typeParamsAsTypes(enum.TypeParams)), variant.Name,
+		)
+		return Invalid
+	default:
+		return enum
+	}
+}
+
+func typeParamsAsTypes(typeParams []*TypeParam) []Type {
+	types := make([]Type, len(typeParams))
+	for i, typeParam := range typeParams {
+		types[i] = typeParam
+	}
+	return types
+}
+
+func (c *checker) unary(node ast.Expr, operator *compiler.Token, operand ast.Expr, expected Type) Type {
+	switch operator.Type {
+	case compiler.TokenTypeBang:
+		c.assign(operand, Bool, nil)
+		return Bool
+	case compiler.TokenTypeMinus, compiler.TokenTypePlus:
+		t := c.value(operand, expected)
+		if !isNumeric(t) && t != Invalid {
+			c.errorf(node, compiler.InvalidOperation, "Operator '%s' is not defined on '%s'", operator.Content, t)
+			return Invalid
+		}
+		return t
+	case compiler.TokenTypeWavy:
+		t := c.value(operand, expected)
+		if !isInteger(t) && t != Invalid {
+			c.errorf(node, compiler.InvalidOperation, "Operator '%s' is not defined on '%s'", operator.Content, t)
+			return Invalid
+		}
+		return t
+	case compiler.TokenTypeDoublePlus, compiler.TokenTypeDoubleMinus:
+		t := c.value(operand, nil)
+		if !isNumeric(t) && t != Invalid {
+			c.errorf(node, compiler.InvalidOperation, "Operator '%s' is not defined on '%s'", operator.Content, t)
+			return Invalid
+		}
+		return t
+	default:
+		// A custom operator, whose operands may have any type
+		c.value(operand, nil)
+		return Invalid
+	}
+}
+
+func (c *checker) binary(e *ast.BinaryExpr, expected Type) Type {
+	var left, right Type
+	switch e.Operator.Type {
+	case compiler.TokenTypeDoubleAmpersand, compiler.TokenTypeDoubleVertical:
+		// The right operand is only evaluated if the left one is true for `&&`, false for `||`
+		c.assign(e.Left, Bool, nil)
+		whenTrue, whenFalse := c.nilChecks(e.Left)
+		before := c.narrowed
+		c.narrowed = before.with(shared.Ternary(e.Operator.Type == compiler.TokenTypeDoubleAmpersand, whenTrue, whenFalse))
+		c.assign(e.Right, Bool, nil)
+		c.narrowed = before.without(c.assignedIn(e.Right))
+		return Bool
+	case compiler.TokenTypeDoubleQuestion:
+		return c.nilDefault(e, expected)
+	case compiler.TokenTypeDoubleLeftAngle, compiler.TokenTypeDoubleRightAngle:
+		left = c.value(e.Left, expected)
+		right = c.value(e.Right, nil)
+		for _, operand := range []struct {
+			expr ast.Expr
+			t    Type
+		}{{e.Left, left}, {e.Right, right}} {
+			if !isInteger(operand.t) && operand.t != Invalid {
+				c.errorf(operand.expr, compiler.InvalidOperation, "Operator '%s' needs integers, found '%s'", e.Operator.Content, operand.t)
+				return Invalid
+			}
+		}
+		return left
+	}
+
+	// The operands have the same type, an untyped constant takes the type of the other operand
+	var hint Type
+	if isArithmetic(e.Operator.Type) {
+		hint = expected
+	}
+	if c.isUntypedConstant(e.Left) && !c.isUntypedConstant(e.Right) {
+		right = c.value(e.Right, hint)
+		left = c.value(e.Left, right)
+	} else {
+		left = c.value(e.Left, hint)
+		right = c.value(e.Right, left)
+	}
+	if e.Operator.Type == compiler.TokenTypeCustomOperator {
+		return Invalid
+	}
+	isEquality := e.Operator.Type == compiler.TokenTypeDoubleEqual || e.Operator.Type == compiler.TokenTypeBangEqual
+	if isEquality && (isNil(e.Left) || isNil(e.Right)) {
+		operand, operandType := e.Left, left
+		if isNil(e.Left) {
+			operand, operandType = e.Right, right
+		}
+		if symbol, _ := c.optionalVar(operand); symbol == nil && !canBeNil(operandType) {
+			c.errorf(operand, compiler.InvalidOperation,
+				"Type '%s' can't be nil, only the optional types like '%s?' can be compared with nil", operandType, operandType)
+		}
+		return Bool
+	}
+	if left == Invalid || right == Invalid {
+		return c.binaryResult(e.Operator.Type, Invalid)
+	}
+	if !Identical(left, right) {
+		c.errorf(e, compiler.InvalidOperation, "Operator '%s' needs operands of the same type, found '%s' and '%s'", e.Operator.Content, left, right)
+		return c.binaryResult(e.Operator.Type, Invalid)
+	}
+
+	defined := false
+	switch e.Operator.Type {
+	case compiler.TokenTypeDoubleEqual, compiler.TokenTypeBangEqual:
+		defined = isComparable(left)
+	case compiler.TokenTypeLeftAngle, compiler.TokenTypeRightAngle, compiler.TokenTypeLeftAngleEqual, compiler.TokenTypeRightAngleEqual:
+		defined = isOrdered(left)
+	case compiler.TokenTypePlus:
+		defined = isNumeric(left) || left == String
+	case compiler.TokenTypeMinus, compiler.TokenTypeStar, compiler.TokenTypeSlash, compiler.TokenTypeDoubleStar:
+		defined = isNumeric(left)
+	case compiler.TokenTypePercent, compiler.TokenTypeAmpersand, compiler.TokenTypeVertical, compiler.TokenTypeCaret:
+		defined = isInteger(left)
+	}
+	if !defined {
+		c.errorf(e, compiler.InvalidOperation, "Operator '%s' is not defined on '%s'", e.Operator.Content, left)
+		return c.binaryResult(e.Operator.Type, Invalid)
+	}
+	c.checkDivision(e)
+	// An untyped constant operand is converted to the type of the other operand
+	if leftUntyped, rightUntyped := c.isUntypedConstant(e.Left), c.isUntypedConstant(e.Right); leftUntyped != rightUntyped {
+		if leftUntyped {
+			c.checkOverflow(e.Left, left)
+		} else {
+			c.checkOverflow(e.Right, right)
+		}
+	}
+	return c.binaryResult(e.Operator.Type, left)
+}
+
+// nilDefault checks `value ?? default`, whose value is optional. The default is used when the
+// value is nil: its type is the type of the value without nil, or the optional type itself.
+func (c *checker) nilDefault(e *ast.BinaryExpr, expected Type) Type {
+	var hint Type
+	if expected != nil {
+		hint = optionalOf(expected)
+	}
+	left := c.value(e.Left, hint)
+	if symbol, _ := c.optionalVar(e.Left); symbol != nil {
+		left = c.info.Symbols[symbol] // A narrowed variable keeps its declared type, the default is then unused
+	}
+	optional, isOptional := left.(*Optional)
+	if !isOptional {
+		if left != Invalid {
+			c.errorf(e.Left, compiler.InvalidOperation, "Operator '??' needs an optional value on its left, found '%s'", left)
+		}
+		c.value(e.Right, nil)
+		return left
+	}
+	right := c.value(e.Right, optional.Elem)
+	if _, isOptional := right.(*Optional); isOptional || right == Nil {
+		c.checkAssignable(e.Right, right, left, nil)
+		return left
+	}
+	c.checkAssignable(e.Right, right, optional.Elem, nil)
+	return optional.Elem
+}
+
+// canBeNil tells whether a value of a type can be nil.
+func canBeNil(t Type) bool {
+	_, isOptional := t.(*Optional)
+	return isOptional || t == Nil || t == Invalid
+}
+
+// binaryResult returns the type of a binary expression whose operands have the type operand.
+func (c *checker) binaryResult(operator compiler.TokenType, operand Type) Type {
+	switch operator {
+	case compiler.TokenTypeDoubleEqual, compiler.TokenTypeBangEqual,
+		compiler.TokenTypeLeftAngle, compiler.TokenTypeRightAngle,
+		compiler.TokenTypeLeftAngleEqual, compiler.TokenTypeRightAngleEqual:
+		return Bool
+	}
+	return operand
+}
+
+func isArithmetic(operator compiler.TokenType) bool {
+	switch operator {
+	case compiler.TokenTypePlus, compiler.TokenTypeMinus, compiler.TokenTypeStar, compiler.TokenTypeSlash,
+		compiler.TokenTypePercent, compiler.TokenTypeDoubleStar,
+		compiler.TokenTypeAmpersand, compiler.TokenTypeVertical, compiler.TokenTypeCaret:
+		return true
+	}
+	return false
+}
+
+// isComparable tells whether the values of a type can be compared with `==`.
+func isComparable(t Type) bool {
+	switch t := t.(type) {
+	case *Basic:
+		return t != Void
+	case *Func:
+		return false
+	case *Array:
+		return isComparable(t.Elem)
+	case *Tuple:
+		for _, elem := range t.Elems {
+			if !isComparable(elem) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compoundOperators maps the compound assignment operators to their binary operator.
+var compoundOperators = map[compiler.TokenType]compiler.TokenType{
+	compiler.TokenTypePlusEqual:             compiler.TokenTypePlus,
+	compiler.TokenTypeMinusEqual:            compiler.TokenTypeMinus,
+	compiler.TokenTypeStarEqual:             compiler.TokenTypeStar,
+	compiler.TokenTypeDoubleStarEqual:       compiler.TokenTypeDoubleStar,
+	compiler.TokenTypeSlashEqual:            compiler.TokenTypeSlash,
+	compiler.TokenTypePercentEqual:          compiler.TokenTypePercent,
+	compiler.TokenTypeDoubleLeftAngleEqual:  compiler.TokenTypeDoubleLeftAngle,
+	compiler.TokenTypeDoubleRightAngleEqual: compiler.TokenTypeDoubleRightAngle,
+	compiler.TokenTypeAmpersandEqual:        compiler.TokenTypeAmpersand,
+	compiler.TokenTypeVerticalEqual:         compiler.TokenTypeVertical,
+	compiler.TokenTypeCaretEqual:            compiler.TokenTypeCaret,
+}
+
+// assignment checks `target = value`, and the compound assignments like `target += value`
+// as the binary expression `target + value` assigned to the target.
+func (c *checker) assignment(e *ast.AssignExpr) Type {
+	binaryOperator, isCompound := compoundOperators[e.Operator.Type]
+	if !isCompound {
+		// A variable of optional type is assigned nil or not, it's narrowed after the assignment if it's not
+		symbol, elem := c.optionalVar(e.Target)
+		narrowed := c.narrowed
+		if symbol != nil {
+			c.narrowed = narrowed.without(map[*resolve.Symbol]bool{symbol: true})
+		}
+		target := c.value(e.Target, nil)
+		c.narrowed = narrowed
+		value := c.assign(e.Value, target, &expectation{e.Target, "Expected because of the type of the assignment target"})
+		if symbol != nil {
+			c.narrowed = c.narrowed.without(map[*resolve.Symbol]bool{symbol: true})
+			if !canBeNil(value) {
+				c.narrowed = c.narrowed.with(narrowing{symbol: elem})
+			}
+		}
+		return target
+	}
+	operator := *e.Operator
+	operator.Type = binaryOperator
+	operator.Content = operator.Content[:len(operator.Content)-1]
+	binary := &ast.BinaryExpr{Span: e.Span, Operator: &operator, Left: e.Target, Right: e.Value}
+	result := c.binary(binary, nil)
+	target := c.info.Types[e.Target]
+	c.checkAssignable(e, result, target, nil)
+	return target
+}
+
+func (c *checker) index(e *ast.IndexExpr) Type {
+	target := c.value(e.Target, nil)
+	if optional, isOptional := target.(*Optional); isOptional {
+		if !c.chains[e.Target] {
+			c.possiblyNil(e.Target, target, "before indexing it")
+			c.value(e.Index, nil)
+			return Invalid
+		}
+		result := c.indexOf(e, optional.Elem)
+		if _, isOptional := result.(*Optional); !isOptional {
+			c.chains[e] = true
+		}
+		return optionalOf(result)
+	}
+	return c.indexOf(e, target)
+}
+
+// indexOf returns the type of an index expression, or of a slice, on a target of type target.
+func (c *checker) indexOf(e *ast.IndexExpr, target Type) Type {
+	if rangeExpr, isRange := e.Index.(*ast.RangeExpr); isRange {
+		c.rangeExpr(rangeExpr, Int)
+		c.info.Types[rangeExpr] = &Range{Elem: Int}
+		switch t := target.(type) {
+		case *Array:
+			return &Array{Elem: t.Elem, Length: -1}
+		case *Basic:
+			if t == String || t == Invalid {
+				return t
+			}
+		}
+		c.errorf(e.Target, compiler.InvalidOperation, "Type '%s' can't be sliced", target)
+		return Invalid
+	}
+
+	if tuple, isTuple := target.(*Tuple); isTuple {
+		if index, _ := c.constant(e.Index); index != nil && index.Kind == IntConstant {
+			c.expr(e.Index, Int)
+			if !index.Int.IsInt64() || index.Int.Int64() < 0 || index.Int.Int64() >= int64(len(tuple.Elems)) {
+				c.errorf(e.Index, compiler.InvalidOperation, "Index %s is out of the tuple '%s'", index, tuple)
+				return Invalid
+			}
+			return tuple.Elems[index.Int.Int64()]
+		}
+		c.value(e.Index, Int)
+		c.errorf(e.Index, compiler.InvalidOperation, "Tuple index must be an integer constant")
+		return Invalid
+	}
+
+	index := c.value(e.Index, Int)
+	if !isInteger(index) && index != Invalid {
+		c.errorf(e.Index, compiler.TypeMismatch, "Index must be an integer, found '%s'", index)
+	}
+	switch t := target.(type) {
+	case *Array:
+		return t.Elem
+	case *Basic:
+		if t == String {
+			return Uint8
+		}
+		if t == Invalid {
+			return Invalid
+		}
+	}
+	c.errorf(e.Target, compiler.InvalidOperation, "Type '%s' can't be indexed", target)
+	return Invalid
+}
+
+// member returns the type of a member access: a variant of an enum type, a declaration of
+// an imported module, or a field or a method of a value. The arrays and the strings have
+// a `length`.
+func (c *checker) member(e *ast.MemberExpr, expected Type) Type {
+	if owner, isType := c.typeExpr(e.Target); isType {
+		c.info.Types[e.Target] = owner
+		enum, isEnum := owner.(*Enum)
+		if !isEnum {
+			if owner != Invalid {
+				c.errorf(e.Name, compiler.UnknownMember, "Type '%s' has no member '%s'", owner, e.Name.Name)
+			}
+			return Invalid
+		}
+		variant := enum.Variant(e.Name.Name)
+		if variant == nil {
+			c.errorf(e.Name, compiler.UnknownMember, "Enum '%s' has no variant '%s'", enum.Name, e.Name.Name)
+			return Invalid
+		}
+		return c.variantValue(e, enum, variant, expected)
+	}
+	if target, isName := e.Target.(*ast.Identifier); isName {
+		if module := c.table.Uses[target]; module != nil && module.Kind == resolve.ModuleSymbol {
+			if symbol := c.table.Uses[e.Name]; symbol != nil {
+				return c.symbolValue(e, symbol)
+			}
+			return Invalid // The resolver reports it
+		}
+	}
+
+	// `a?.b` is nil if a is nil, and so are the links which follow it in the chain, like `.c` in `a?.b.c`
+	target := c.value(e.Target, nil)
+	optional, isOptional := target.(*Optional)
+	if isOptional {
+		if !e.Optional && !c.chains[e.Target] {
+			c.possiblyNil(e.Target, target, fmt.Sprintf("or use '?.' to access '%s'", e.Name.Name))
+			return Invalid
+		}
+		target = optional.Elem
+	}
+	if memberType, exists := memberOf(target, e.Name.Name); exists {
+		if !isOptional {
+			return memberType
+		}
+		if _, isOptional := memberType.(*Optional); !isOptional {
+			c.chains[e] = true
+		}
+		return optionalOf(memberType)
+	}
+	if target != Invalid {
+		c.errorf(e.Name, compiler.UnknownMember, "Type '%s' has no field or method '%s'", target, e.Name.Name)
+	}
+	return Invalid
+}
+
+// memberOf returns the type of a field or a method of a value.
+func memberOf(t Type, name string) (Type, bool) {
+	switch t := t.(type) {
+	case *Struct:
+		if fieldType, exists := t.Field(name); exists {
+			return fieldType, true
+		}
+	case *Array:
+		if name == "length" {
+			return Int, true
+		}
+	case *Basic:
+		if t == String && name == "length" {
+			return Int, true
+		}
+	}
+	if signature, exists := methodOf(t, name); exists {
+		return signature, true
+	}
+	return nil, false
+}
+
+// checkAssertion reports a type assertion or a type pattern on a value which isn't an
+// interface, or whose type can't be held by the interface since it doesn't implement it.
+func (c *checker) checkAssertion(valueNode ast.Node, value Type, typeNode ast.Node, target Type) {
+	if value == Invalid || target == Invalid {
+		return
+	}
+	iface, isInterface := value.(*Interface)
+	if !isInterface {
+		c.errorf(valueNode, compiler.InvalidOperation, "Type assertion needs an interface value, found '%s'", value)
+		return
+	}
+	if _, isInterface := target.(*Interface); isInterface {
+		return // Any interface may be implemented by the held value
+	}
+	if !Implements(target, iface) {
+		c.errorf(typeNode, compiler.TypeMismatch, "Impossible type assertion: %s", notImplemented(target, iface))
+	}
+}
+
+// instantiateExpr returns the type of a generic function given its type arguments, like `identity<int>`.
+func (c *checker) instantiateExpr(e *ast.InstantiateExpr) Type {
+	if t, isType := c.typeExpr(e); isType {
+		c.errorf(e, compiler.InvalidOperation, "Type '%s' is not a value", t)
+		return Invalid
+	}
+	target := c.value(e.Target, nil)
+	typeArgs := make([]Type, len(e.TypeArgs))
+	for i, typeArg := range e.TypeArgs {
+		typeArgs[i] = c.lowerType(typeArg)
+	}
+	signature, isFunc := target.(*Func)
+	if !isFunc || len(signature.TypeParams) == 0 {
+		if target != Invalid {
+			c.errorf(e, compiler.InvalidOperation, "Type arguments given to '%s', which is not generic", target)
+		}
+		return Invalid
+	}
+	if len(typeArgs) != len(signature.TypeParams) {
+		c.errorf(e, compiler.ArgumentCount, "Function needs %d type arguments, found %d", len(signature.TypeParams), len(typeArgs))
+		return Invalid
+	}
+	subst := Substitution{}
+	for i, typeParam := range signature.TypeParams {
+		subst[typeParam] = typeArgs[i]
+		c.checkConstraint(e.TypeArgs[i], typeParam, typeArgs[i])
+	}
+	return instantiateFunc(signature, subst)
+}
+
+// instantiateFunc substitutes the type parameters of a generic function.
+func instantiateFunc(signature *Func, subst Substitution) *Func {
+	instance := Subst(signature, subst).(*Func)
+	instance.TypeParams = nil
+	return instance
+}
+
+// checkConstraint reports a type argument which doesn't satisfy the constraint of its type parameter.
+func (c *checker) checkConstraint(node ast.Node, typeParam *TypeParam, typeArg Type) {
+	if iface, isInterface := typeParam.Constraint.(*Interface); isInterface && !Assignable(typeArg, iface) {
+		c.errorf(node, compiler.TypeMismatch, "Type '%s' doesn't satisfy the constraint of '%s': %s", typeArg, typeParam.Name, notImplemented(typeArg, iface))
+	} else if typeParam.Constraint != nil && !Assignable(typeArg, typeParam.Constraint) {
+		c.errorf(node, compiler.TypeMismatch, "Type '%s' doesn't satisfy the constraint '%s' of '%s'", typeArg, typeParam.Constraint, typeParam.Name)
+	}
+}
+
+func (c *checker) arrayLit(e *ast.ArrayLit, expected Type) Type {
+	expectedArray, _ := expected.(*Array)
+	var elem Type
+	if expectedArray != nil {
+		elem = expectedArray.Elem
+	}
+	for _, element := range e.Elements {
+		if spread, isSpread := element.(*ast.SpreadExpr); isSpread {
+			var expectedSpread Type
+			if elem != nil {
+				expectedSpread = &Array{Elem: elem, Length: -1}
+			}
+			spreadType := c.value(spread.Expr, expectedSpread)
+			c.info.Types[spread] = spreadType
+			spreadArray, isArray := spreadType.(*Array)
+			switch {
+			case spreadType == Invalid:
+			case !isArray:
+				c.errorf(spread.Expr, compiler.TypeMismatch, "Only arrays can be spread, found '%s'", spreadType)
+			case elem == nil:
+				elem = spreadArray.Elem
+			default:
+				c.checkAssignable(spread.Expr, spreadArray.Elem, elem, nil)
+			}
+			continue
+		}
+		if elem == nil {
+			elem = c.value(element, nil)
+		} else {
+			c.assign(element, elem, nil)
+		}
+	}
+	if elem == nil {
+		c.errorf(e, compiler.CannotInferType, "Can't infer the type of an empty array, annotate it")
+		return Invalid
+	}
+	if expectedArray != nil && expectedArray.Length >= 0 {
+		if int64(len(e.Elements)) != expectedArray.Length {
+			c.errorf(e, compiler.TypeMismatch, "Array literal has %d elements, but '%s' has %d", len(e.Elements), expectedArray, expectedArray.Length)
+		}
+		return expectedArray
+	}
+	return &Array{Elem: elem, Length: -1}
+}
+
+func (c *checker) rangeExpr(e *ast.RangeExpr, expected Type) Type {
+	var elem Type
+	if expectedRange, isRange := expected.(*Range); isRange {
+		elem = expectedRange.Elem
+	} else if isInteger(expected) {
+		elem = expected
+	}
+	var bounds []ast.Expr
+	for _, bound := range []ast.Expr{e.Start, e.End} {
+		if bound != nil {
+			bounds = append(bounds, bound)
+		}
+	}
+	// An untyped constant bound takes the type of the other bound
+	if elem == nil && len(bounds) == 2 && c.isUntypedConstant(bounds[0]) && !c.isUntypedConstant(bounds[1]) {
+		bounds[0], bounds[1] = bounds[1], bounds[0]
+	}
+	for _, bound := range bounds {
+		if elem == nil {
+			elem = c.value(bound, nil)
+		} else {
+			c.assign(bound, elem, nil)
+		}
+	}
+	if elem == nil {
+		elem = Int
+	}
+	if !isInteger(elem) && elem != Rune && elem != Invalid {
+		c.errorf(e, compiler.InvalidOperation, "Range bounds must be integers or runes, found '%s'", elem)
+		return Invalid
+	}
+	return &Range{Elem: elem}
+}
+
+// structLit checks a struct literal, or a literal of an enum variant with a struct payload.
+// The type arguments of a generic struct are inferred from the expected type or from the fields.
+func (c *checker) structLit(e *ast.StructLit, expected Type) Type {
+	var fields []*Field
+	var result Type
+	var typeParams []*TypeParam
+	subst := Substitution{}
+	name := ""
+
+	owner, isType := c.typeExpr(e.Type)
+	if !isType {
+		// A variant with a struct payload, like `Shape.Circle { radius: 1.0 }`
+		var variantType Type = Invalid
+		enum, variant := c.variantOfExpr(e.Type, expected)
+		if variant != nil {
+			if len(variant.Fields) > 0 && variant.Tuple || len(variant.Fields) == 0 {
+				c.errorf(e.Type, compiler.InvalidOperation, "Variant '%s.%s' has no named fields", enum.Name, variant.Name)
+			} else {
+				fields, result, name = variant.Fields, enum, enum.Name+"."+variant.Name
+				if len(enum.TypeArgs) == 0 {
+					typeParams = enum.TypeParams
+				} else {
+					for i, typeParam := range enum.TypeParams {
+						subst[typeParam] = enum.TypeArgs[i]
+					}
+				}
+				variantType = enum
+			}
+		}
+		if result == nil {
+			for _, field := range e.Fields {
+				c.value(field.Value, nil)
+			}
+			c.info.Types[e.Type] = variantType
+			return Invalid
+		}
+	} else {
+		c.info.Types[e.Type] = owner
+		s, isStruct := owner.(*Struct)
+		if !isStruct {
+			if owner != Invalid {
+				c.errorf(e.Type, compiler.InvalidOperation, "Type '%s' is not a struct", owner)
+			}
+			for _, field := range e.Fields {
+				c.value(field.Value, nil)
+			}
+			return Invalid
+		}
+		fields, result, name = s.Origin().Fields, s, s.Name
+		if len(s.TypeArgs) == 0 {
+			typeParams = s.TypeParams
+		} else {
+			subst = s.subst()
+		}
+	}
+
+	// The type arguments of a generic type come from the expected instance, or from the field values
+	if len(typeParams) > 0 {
+		switch expected := expected.(type) {
+		case *Struct:
+			if s, isStruct := result.(*Struct); isStruct && expected.Origin() == s.Origin() {
+				subst = expected.subst()
+			}
+		case *Enum:
+			if enum, isEnum := result.(*Enum); isEnum && expected.Origin() == enum.Origin() {
+				for i, typeParam := range enum.TypeParams {
+					subst[typeParam] = expected.TypeArgs[i]
+				}
+			}
+		}
+	}
+
+	given := map[string]bool{}
+	fieldTypes := map[*ast.FieldValue]Type{}
+	for _, fieldValue := range e.Fields {
+		var field *Field
+		for _, candidate := range fields {
+			if candidate.Name == fieldValue.Name.Name {
+				field = candidate
+			}
+		}
+		switch {
+		case field == nil:
+			c.errorf(fieldValue.Name, compiler.UnknownMember, "'%s' has no field '%s'", name, fieldValue.Name.Name)
+			c.value(fieldValue.Value, nil)
+			continue
+		case given[field.Name]:
+			c.errorf(fieldValue.Name, compiler.DuplicateDeclaration, "Field '%s' is given twice", field.Name)
+		}
+		given[field.Name] = true
+		expectedField := Subst(field.Type, subst)
+		if len(typeParams) > 0 && len(subst) < len(typeParams) {
+			// The field types are unified with the type parameters after all the values are checked
+			fieldTypes[fieldValue] = c.value(fieldValue.Value, nil)
+			continue
+		}
+		c.assign(fieldValue.Value, expectedField, nil)
+	}
+	for _, field := range fields {
+		if !given[field.Name] && !field.Default {
+			c.errorf(e, compiler.InvalidOperation, "Missing field '%s' in the literal of '%s'", field.Name, name)
+		}
+	}
+
+	if len(typeParams) > 0 && len(subst) < len(typeParams) {
+		var params, args []Type
+		for _, fieldValue := range e.Fields {
+			if fieldType, exists := fieldTypes[fieldValue]; exists {
+				for _, field := range fields {
+					if field.Name == fieldValue.Name.Name {
+						params = append(params, field.Type)
+						args = append(args, fieldType)
+					}
+				}
+			}
+		}
+		inferred, err := unifyArgs(typeParams, &Func{Params: params}, args, nil)
+		if err != nil {
+			c.errorf(e, compiler.CannotInferType, "%s", capitalize(err.Error()))
+			return Invalid
+		}
+		for _, typeParam := range typeParams {
+			if _, exists := inferred[typeParam]; !exists {
+				c.errorf(e, compiler.CannotInferType, "Can't infer the type argument of '%s' of '%s', give it explicitly", typeParam.Name, name)
+				return Invalid
+			}
+			c.checkConstraint(e, typeParam, inferred[typeParam])
+		}
+		for fieldValue, fieldType := range fieldTypes {
+			for _, field := range fields {
+				if field.Name == fieldValue.Name.Name {
+					c.checkAssignable(fieldValue.Value, fieldType, Subst(field.Type, inferred), nil)
+				}
+			}
+		}
+		subst = inferred
+	}
+	if len(typeParams) > 0 {
+		args := make([]Type, len(typeParams))
+		for i, typeParam := range typeParams {
+			args[i] = subst[typeParam]
+		}
+		switch r := result.(type) {
+		case *Struct:
+			return r.Instantiate(args)
+		case *Enum:
+			return r.Instantiate(args)
+		}
+	}
+	return result
+}
+
+// variantOfExpr returns the variant an expression refers to, like `Shape.Circle` or `Circle`.
+func (c *checker) variantOfExpr(expr ast.Expr, expected Type) (*Enum, *Variant) {
+	switch e := expr.(type) {
+	case *ast.MemberExpr:
+		owner, isType := c.typeExpr(e.Target)
+		if !isType {
+			return nil, nil
+		}
+		c.info.Types[e.Target] = owner
+		enum, isEnum := owner.(*Enum)
+		if !isEnum {
+			if owner != Invalid {
+				c.errorf(e.Name, compiler.UnknownMember, "Type '%s' has no member '%s'", owner, e.Name.Name)
+			}
+			return nil, nil
+		}
+		if variant := enum.Variant(e.Name.Name); variant != nil {
+			if expectedEnum, isEnum := expected.(*Enum); isEnum && len(enum.TypeArgs) == 0 && expectedEnum.Origin() == enum.Origin() {
+				return expectedEnum, variant
+			}
+			return enum, variant
+		}
+		c.errorf(e.Name, compiler.UnknownMember, "Enum '%s' has no variant '%s'", enum.Name, e.Name.Name)
+	case *ast.Identifier:
+		if c.table.Uses[e] == nil {
+			return c.variantNamed(e, expected)
+		}
+		c.errorf(e, compiler.InvalidOperation, "'%s' is not a type nor an enum variant", e.Name)
+	}
+	return nil, nil
+}
+
+// funcLit checks an anonymous function or an arrow lambda. The types of the parameters without
+// annotations, and the result type of an arrow lambda, come from the expected function type.
+func (c *checker) funcLit(node ast.Expr, params []*ast.Param, returnType ast.TypeExpr, body ast.Node, expected *Func) Type {
+	signature := &Func{}
+	for i, param := range params {
+		var paramType Type
+		switch {
+		case param.Type != nil:
+			paramType = c.lowerType(param.Type)
+		case expected != nil && i < len(expected.Params) && !mentions(expected.Params[i], c.unknown):
+			paramType = expected.Params[i]
+		default:
+			c.errorf(param, compiler.CannotInferType, "Can't infer the type of the parameter '%s', annotate it", param.Name.Name)
+			paramType = Invalid
+		}
+		if param.Variadic && param.Type != nil {
+			signature.Variadic = true
+			paramType = variadicType(paramType)
+		}
+		if param.Default != nil {
+			signature.Defaults++
+			c.assign(param.Default, paramType, nil)
+		}
+		c.defineSymbol(param.Name, paramType)
+		signature.Params = append(signature.Params, paramType)
+	}
+
+	context := &function{}
+	if returnType != nil {
+		context.result, context.resultNode = c.lowerType(returnType), returnType
+	} else if _, isArrow := node.(*ast.ArrowFunc); isArrow {
+		if expected != nil && expected.Result != nil && !mentions(expected.Result, c.unknown) {
+			context.result = expected.Result
+		} else {
+			context.inferResult = true
+		}
+	}
+
+	// The narrowing doesn't hold in a function, which may be called after the variables are assigned nil
+	enclosing, unknown, narrowed := c.function, c.unknown, c.narrowed
+	c.function, c.unknown, c.narrowed = context, map[*TypeParam]bool{}, nil
+	switch b := body.(type) {
+	case *ast.BlockStmt:
+		c.statements(b.Statements)
+	case ast.Expr:
+		if context.inferResult {
+			context.result = c.expr(b, nil)
+			if context.result == Void {
+				context.result = nil
+			}
+		} else if context.result != nil {
+			c.assign(b, context.result, nil)
+		} else {
+			c.expr(b, nil)
+		}
+	}
+	// The variables the function assigns aren't narrowed after it, since it may be called anytime
+	c.function, c.unknown, c.narrowed = enclosing, unknown, narrowed.without(c.assignedIn(body))
+	signature.Result = context.result
+	return signature
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,238 @@
+package types
+
+import (
+	"fmt"
+	"mirth/shared"
+)
+
+// Substitution maps type parameters to their type arguments.
+type Substitution map[*TypeParam]Type
+
+// Subst replaces the type parameters in a type by their type arguments.
+func Subst(t Type, subst Substitution) Type {
+	switch t := t.(type) {
+	case *TypeParam:
+		if arg, exists := subst[t]; exists {
+			return arg
+		}
+		return t
+	case *Array:
+		return &Array{Elem: Subst(t.Elem, subst), Length: t.Length}
+	case *Tuple:
+		elems := make([]Type, len(t.Elems))
+		for i, elem := range t.Elems {
+			elems[i] = Subst(elem, subst)
+		}
+		return &Tuple{Elems: elems}
+	case *Func:
+		params := make([]Type, len(t.Params))
+		for i, param := range t.Params {
+			params[i] = Subst(param, subst)
+		}
+		var result Type
+		if t.Result != nil {
+			result = Subst(t.Result, subst)
+		}
+		return &Func{TypeParams: t.TypeParams, Params: params, Variadic: t.Variadic, Result: result, Defaults: t.Defaults}
+	case *Range:
+		return &Range{Elem: Subst(t.Elem, subst)}
+	case *Optional:
+		return optionalOf(Subst(t.Elem, subst))
+	case *Struct:
+		if len(t.TypeArgs) == 0 {
+			return t
+		}
+		args := make([]Type, len(t.TypeArgs))
+		for i, arg := range t.TypeArgs {
+			args[i] = Subst(arg, subst)
+		}
+		return t.Instantiate(args)
+	case *Enum:
+		if len(t.TypeArgs) == 0 {
+			return t
+		}
+		args := make([]Type, len(t.TypeArgs))
+		for i, arg := range t.TypeArgs {
+			args[i] = Subst(arg, subst)
+		}
+		return t.Instantiate(args)
+	case *Named:
+		if len(t.TypeArgs) == 0 {
+			return t
+		}
+		args := make([]Type, len(t.TypeArgs))
+		for i, arg := range t.TypeArgs {
+			args[i] = Subst(arg, subst)
+		}
+		return &Named{Name: t.Name, TypeArgs: args}
+	default:
+		return t
+	}
+}
+
+// Infer infers the type arguments of a call to a generic function, by unifying
+// the type of each parameter with the type of its argument. The arguments beyond
+// the parameters of a variadic function are unified with the variadic element.
+// An argument type is nil if it's unknown yet, like the type of an arrow lambda
+// whose parameters are not annotated, so that it doesn't take part.
+func Infer(typeParams []*TypeParam, signature *Func, args []Type) *shared.Result[Substitution, error] {
+	subst, err := unifyArgs(typeParams, signature, args, nil)
+	if err != nil {
+		return shared.ResultErr[Substitution](err)
+	}
+	for _, typeParam := range typeParams {
+		if _, inferred := subst[typeParam]; !inferred {
+			return shared.ResultErr[Substitution](fmt.Errorf(
+				"can't infer the type argument of '%s', give it explicitly", typeParam.Name,
+			))
+		}
+	}
+	return shared.ResultOk[Substitution, error](subst)
+}
+
+// unifyArgs unifies the parameter types of a signature with the argument types, and then
+// its result type with the expected one if it isn't nil. Some type parameters may be left
+// out of the substitution.
+func unifyArgs(typeParams []*TypeParam, signature *Func, args []Type, expected Type) (Substitution, error) {
+	u := &unifier{typeParams: map[*TypeParam]bool{}, subst: Substitution{}}
+	for _, typeParam := range typeParams {
+		u.typeParams[typeParam] = true
+	}
+	for i, arg := range args {
+		var param Type
+		switch {
+		case signature.Variadic && i >= len(signature.Params)-1:
+			param = signature.Params[len(signature.Params)-1].(*Array).Elem
+		case i < len(signature.Params):
+			param = signature.Params[i]
+		default:
+			continue // The checker reports the extra arguments
+		}
+		if arg == nil {
+			continue
+		}
+		if err := u.unify(param, arg); err != nil {
+			return nil, err
+		}
+	}
+	if expected != nil && signature.Result != nil {
+		if err := u.unify(signature.Result, expected); err != nil {
+			return nil, err
+		}
+	}
+	return u.subst, nil
+}
+
+type unifier struct {
+	typeParams map[*TypeParam]bool // The type parameters being inferred
+	subst      Substitution
+}
+
+// unify matches the structure of a parameter type against an argument type,
+// binding the type parameters it meets.
+func (u *unifier) unify(param, arg Type) error {
+	if typeParam, isTypeParam := param.(*TypeParam); isTypeParam && u.typeParams[typeParam] {
+		if arg == Nil {
+			return nil // nil doesn't tell the type, only that it's optional
+		}
+		if bound, exists := u.subst[typeParam]; exists {
+			if !Identical(bound, arg) && arg != Invalid {
+				return fmt.Errorf(
+					"type argument of '%s' is inferred as both '%s' and '%s'", typeParam.Name, bound, arg,
+				)
+			}
+			return nil
+		}
+		u.subst[typeParam] = arg
+		return nil
+	}
+
+	if arg == Invalid {
+		return nil // Its error is already reported
+	}
+	mismatch := fmt.Errorf("can't match '%s' with '%s'", arg, param)
+	switch param := param.(type) {
+	case *Array:
+		arg, isArray := arg.(*Array)
+		if !isArray {
+			return mismatch
+		}
+		return u.unify(param.Elem, arg.Elem)
+	case *Tuple:
+		arg, isTuple := arg.(*Tuple)
+		if !isTuple || len(param.Elems) != len(arg.Elems) {
+			return mismatch
+		}
+		for i := range param.Elems {
+			if err := u.unify(param.Elems[i], arg.Elems[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Func:
+		arg, isFunc := arg.(*Func)
+		if !isFunc || len(param.Params) != len(arg.Params) {
+			return mismatch
+		}
+		for i := range param.Params {
+			if arg.Params[i] == nil {
+				continue
+			}
+			if err := u.unify(param.Params[i], arg.Params[i]); err != nil {
+				return err
+			}
+		}
+		if param.Result != nil && arg.Result != nil {
+			return u.unify(param.Result, arg.Result)
+		}
+		return nil
+	case *Range:
+		arg, isRange := arg.(*Range)
+		if !isRange {
+			return mismatch
+		}
+		return u.unify(param.Elem, arg.Elem)
+	case *Optional:
+		// A value is wrapped in the optional type, nil binds nothing
+		if arg == Nil {
+			return nil
+		}
+		if argOptional, isOptional := arg.(*Optional); isOptional {
+			return u.unify(param.Elem, argOptional.Elem)
+		}
+		return u.unify(param.Elem, arg)
+	case *Enum:
+		arg, isEnum := arg.(*Enum)
+		if !isEnum || param.Origin() != arg.Origin() {
+			return mismatch
+		}
+		return u.unifyLists(param.TypeArgs, arg.TypeArgs, mismatch)
+	case *Struct:
+		arg, isStruct := arg.(*Struct)
+		if !isStruct || param.Origin() != arg.Origin() {
+			return mismatch
+		}
+		return u.unifyLists(param.TypeArgs, arg.TypeArgs, mismatch)
+	case *Named:
+		arg, isNamed := arg.(*Named)
+		if !isNamed || param.Name != arg.Name {
+			return mismatch
+		}
+		return u.unifyLists(param.TypeArgs, arg.TypeArgs, mismatch)
+	default:
+		// Other types are only checked for assignability by the checker
+		return nil
+	}
+}
+
+func (u *unifier) unifyLists(params, args []Type, mismatch error) error {
+	if len(params) != len(args) {
+		return mismatch
+	}
+	for i := range params {
+		if err := u.unify(params[i], args[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInfer(t *testing.T) {
+	Convey("Type argument inference", t, func() {
+		T := &TypeParam{Name: "T"}
+		U := &TypeParam{Name: "U"}
+
+		Convey("infers from direct and nested arguments", func() {
+			// func first<T>(items []T) T
+			signature := &Func{Params: []Type{&Array{Elem: T, Length: -1}}, Result: T}
+			result := Infer([]*TypeParam{T}, signature, []Type{&Array{Elem: String, Length: -1}})
+			So(result.Ok, ShouldBeTrue)
+			So(result.Unwrap()[T], ShouldEqual, String)
+			So(Subst(signature, result.Unwrap()).String(), ShouldEqual, "func([]string) string")
+		})
+
+		Convey("infers through generic types and function arguments", func() {
+			// func map<T, U>(box Box<T>, f func(T) U) Box<U>
+			signature := &Func{
+				Params: []Type{
+					&Named{Name: "Box", TypeArgs: []Type{T}},
+					&Func{Params: []Type{T}, Result: U},
+				},
+				Result: &Named{Name: "Box", TypeArgs: []Type{U}},
+			}
+			args := []Type{
+				&Named{Name: "Box", TypeArgs: []Type{Int}},
+				&Func{Params: []Type{nil}, Result: Bool},
+			}
+			result := Infer([]*TypeParam{T, U}, signature, args)
+			So(result.Ok, ShouldBeTrue)
+			So(Subst(signature.Result, result.Unwrap()).String(), ShouldEqual, "Box<bool>")
+		})
+
+		Convey("unifies variadic arguments with the element type", func() {
+			signature := &Func{Params: []Type{&Array{Elem: T, Length: -1}}, Variadic: true}
+			So(signature.String(), ShouldEqual, "func(...T)")
+			result := Infer([]*TypeParam{T}, signature, []Type{Float64, Float64})
+			So(result.Ok, ShouldBeTrue)
+			So(result.Unwrap()[T], ShouldEqual, Float64)
+		})
+
+		Convey("reports conflicts and uninferable parameters", func() {
+			signature := &Func{Params: []Type{T, T}}
+			result := Infer([]*TypeParam{T}, signature, []Type{Int, String})
+			So(result.Ok, ShouldBeFalse)
+			So(result.Err.Error(), ShouldEqual, "type argument of 'T' is inferred as both 'int' and 'string'")
+
+			result = Infer([]*TypeParam{T, U}, &Func{Params: []Type{T}, Result: U}, []Type{Int})
+			So(result.Ok, ShouldBeFalse)
+			So(result.Err.Error(), ShouldEqual, "can't infer the type argument of 'U', give it explicitly")
+		})
+	})
+}
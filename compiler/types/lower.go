@@ -0,0 +1,61 @@
+package types
+
+import (
+	"mirth/compiler/ast"
+)
+
+// LowerType turns a type annotation into a type. It only resolves the predeclared
+// types and the type parameters in scope, the other names are kept as Named types
+// for the checker to resolve. The length of an array must be a constant expression
+// without names, otherwise the array is dynamically sized.
+func LowerType(typeExpr ast.TypeExpr, typeParams map[string]*TypeParam) Type {
+	switch t := typeExpr.(type) {
+	case *ast.NamedType:
+		typeArgs := make([]Type, len(t.TypeArgs))
+		for i, typeArg := range t.TypeArgs {
+			typeArgs[i] = LowerType(typeArg, typeParams)
+		}
+		if typeParam, exists := typeParams[t.Name.Name]; exists && len(typeArgs) == 0 {
+			return typeParam
+		}
+		if predeclared, exists := Predeclared[t.Name.Name]; exists {
+			if enum, isEnum := predeclared.(*Enum); isEnum && len(typeArgs) > 0 {
+				return enum.Instantiate(typeArgs)
+			}
+			return predeclared
+		}
+		return &Named{Name: t.Name.Name, TypeArgs: typeArgs}
+	case *ast.ArrayType:
+		array := &Array{Elem: LowerType(t.Element, typeParams), Length: -1}
+		if t.Length != nil {
+			if length, _ := EvalConstant(t.Length, nil); length != nil && length.Kind == IntConstant && length.Int.IsInt64() {
+				array.Length = length.Int.Int64()
+			}
+		}
+		return array
+	case *ast.TupleType:
+		elems := make([]Type, len(t.Elements))
+		for i, elem := range t.Elements {
+			elems[i] = LowerType(elem, typeParams)
+		}
+		return &Tuple{Elems: elems}
+	case *ast.OptionalType:
+		return optionalOf(LowerType(t.Elem, typeParams))
+	case *ast.FuncType:
+		signature := &Func{}
+		for _, param := range t.Params {
+			paramType := LowerType(param.Type, typeParams)
+			if param.Variadic {
+				signature.Variadic = true
+				paramType = variadicType(paramType)
+			}
+			signature.Params = append(signature.Params, paramType)
+		}
+		if t.ReturnType != nil {
+			signature.Result = LowerType(t.ReturnType, typeParams)
+		}
+		return signature
+	default:
+		return Invalid
+	}
+}
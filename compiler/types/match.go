@@ -0,0 +1,700 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CheckMatch checks that the arms of a match expression cover all the values of
+// its subject, and reports the arms which can't be reached because the arms
+// before them already match all their values. An arm with a guard doesn't cover
+// anything, since its guard may be false.
+//
+// subject is the type of the subject, or nil if it's unknown. Then the values
+// are guessed from the patterns: integers are 64 bits wide, and strings or
+// floats are only covered by a wildcard or a binding. enums are the enums in
+// scope by name, for the variant patterns, the predeclared ones are always known.
+func CheckMatch(match *ast.MatchExpr, subject Type, enums map[string]*Enum) []*compiler.Diagnostic {
+	c := &matchChecker{enums: enums}
+	var diagnostics []*compiler.Diagnostic
+	var rows [][]*space
+	for _, arm := range match.Arms {
+		row := []*space{c.lowerPattern(arm.Pattern)}
+		if _, reachable := c.useful(rows, row, []Type{subject}); !reachable {
+			span := arm.Pattern.NodeSpan()
+			diagnostics = append(diagnostics, &compiler.Diagnostic{
+				Type: compiler.DiagnosticWarning,
+				Code: compiler.UnreachablePattern,
+				Pos:  span.Start,
+				End:  span.End,
+				Msg:  "Unreachable match arm, the previous arms already match all its values",
+			})
+		}
+		if arm.Guard == nil {
+			rows = append(rows, row)
+		}
+	}
+
+	if witness, missing := c.useful(rows, []*space{wildcard}, []Type{subject}); missing {
+		diagnostics = append(diagnostics, &compiler.Diagnostic{
+			Type: compiler.DiagnosticError,
+			Code: compiler.NonExhaustiveMatch,
+			Pos:  match.Start,
+			End:  match.Subject.NodeSpan().End,
+			Msg: fmt.Sprintf(
+				"Non-exhaustive match, pattern '%s' is not covered by any arm, add an arm for it or a '_' arm",
+				witness[0],
+			),
+		})
+	}
+	return diagnostics
+}
+
+type matchChecker struct {
+	enums map[string]*Enum
+}
+
+// enumNamed returns the enum in scope with the name, or nil.
+func (c *matchChecker) enumNamed(name string) *Enum {
+	if enum, exists := c.enums[name]; exists {
+		return enum
+	}
+	enum, _ := Predeclared[name].(*Enum)
+	return enum
+}
+
+// variantOf resolves the path of a variant, like `Option.Some`, or `Some`
+// if no other enum in scope has a variant with the same name.
+func (c *matchChecker) variantOf(path ast.Expr) (*Enum, *Variant) {
+	switch p := path.(type) {
+	case *ast.MemberExpr:
+		if enumName, isName := p.Target.(*ast.Identifier); isName {
+			if enum := c.enumNamed(enumName.Name); enum != nil {
+				return enum, enum.Variant(p.Name.Name)
+			}
+		}
+	case *ast.Identifier:
+		var candidates []*Enum
+		for _, predeclared := range []*Enum{Option, Result} {
+			if c.enums[predeclared.Name] == nil {
+				candidates = append(candidates, predeclared)
+			}
+		}
+		for _, enum := range c.enums {
+			candidates = append(candidates, enum)
+		}
+		var found *Enum
+		for _, enum := range candidates {
+			if enum.Variant(p.Name) != nil {
+				if found != nil {
+					return nil, nil // Ambiguous
+				}
+				found = enum
+			}
+		}
+		if found != nil {
+			return found, found.Variant(p.Name)
+		}
+	}
+	return nil, nil
+}
+
+// ctorOfVariant returns the constructor of a variant, with all its fields.
+func ctorOfVariant(enum *Enum, variant *Variant) *ctor {
+	c := &ctor{kind: variantCtor, name: enum.Name, variant: variant.Name, arity: len(variant.Fields)}
+	if !variant.Tuple && len(variant.Fields) > 0 {
+		for _, field := range variant.Fields {
+			c.fields = append(c.fields, field.Name)
+		}
+	}
+	return c
+}
+
+type ctorKind int
+
+const (
+	boolCtor    ctorKind = iota
+	intCtor              // Integers and runes, as an inclusive range of values
+	opaqueCtor           // Strings and floats, which are only equal to the same literal
+	productCtor          // Tuples and structs, which have a single constructor
+	variantCtor          // Enum variants, with their payload fields
+)
+
+// ctor is a value constructor, the head of a space.
+type ctor struct {
+	kind ctorKind
+
+	value bool
+
+	// lo or hi is nil if the range is open on that side
+	lo, hi *big.Int
+	isRune bool
+	// Whether the range starts or ends with the values of the type,
+	// so that it's printed as an open range
+	openLo, openHi bool
+
+	text string
+
+	// name is empty for a tuple, or the name of the enum of a variant.
+	// fields is the order of the struct fields in args, it's nil for tuples.
+	name    string
+	variant string
+	fields  []string
+	arity   int
+}
+
+// space is the set of values matched by a pattern, the wildcard space has no constructor.
+type space struct {
+	ctor *ctor
+	args []*space
+}
+
+var wildcard = &space{}
+
+func wildcards(count int) []*space {
+	spaces := make([]*space, count)
+	for i := range spaces {
+		spaces[i] = wildcard
+	}
+	return spaces
+}
+
+func (s *space) String() string {
+	if s.ctor == nil {
+		return "_"
+	}
+	c := s.ctor
+	switch c.kind {
+	case boolCtor:
+		return strconv.FormatBool(c.value)
+	case intCtor:
+		if c.openLo && c.openHi {
+			return "_"
+		}
+		format := func(value *big.Int) string {
+			if c.isRune {
+				return strconv.QuoteRune(rune(value.Int64()))
+			}
+			return value.String()
+		}
+		switch {
+		case c.lo.Cmp(c.hi) == 0:
+			return format(c.lo)
+		case c.openLo:
+			return "..=" + format(c.hi)
+		case c.openHi:
+			return format(c.lo) + ".."
+		default:
+			return format(c.lo) + "..=" + format(c.hi)
+		}
+	case opaqueCtor:
+		return c.text
+	default:
+		name := c.name
+		if c.kind == variantCtor {
+			name += "." + c.variant
+			if c.fields == nil {
+				if c.arity == 0 {
+					return name
+				}
+				elements := make([]string, len(s.args))
+				for i, arg := range s.args {
+					elements[i] = arg.String()
+				}
+				return fmt.Sprintf("%s(%s)", name, strings.Join(elements, ", "))
+			}
+		}
+		if name == "" {
+			elements := make([]string, len(s.args))
+			for i, arg := range s.args {
+				elements[i] = arg.String()
+			}
+			return fmt.Sprintf("(%s)", strings.Join(elements, ", "))
+		}
+		var fields []string
+		for i, arg := range s.args {
+			if arg.ctor != nil {
+				fields = append(fields, fmt.Sprintf("%s: %s", c.fields[i], arg))
+			}
+		}
+		if len(fields) == 0 {
+			return name + " {}"
+		}
+		return fmt.Sprintf("%s { %s }", name, strings.Join(fields, ", "))
+	}
+}
+
+// lowerPattern turns a pattern into the space of the values it matches.
+func (c *matchChecker) lowerPattern(pattern ast.Pattern) *space {
+	switch p := pattern.(type) {
+	case *ast.BindingPattern:
+		if p.Pattern == nil {
+			return wildcard
+		}
+		return c.lowerPattern(p.Pattern)
+	case *ast.LiteralPattern:
+		return &space{ctor: lowerLiteral(p.Value)}
+	case *ast.RangePattern:
+		var start, end *ctor
+		if p.Start != nil {
+			start = lowerLiteral(p.Start)
+		}
+		if p.End != nil {
+			end = lowerLiteral(p.End)
+		}
+		if (start != nil && start.kind != intCtor) || (end != nil && end.kind != intCtor) {
+			// Float ranges are only compared with themselves
+			return &space{ctor: &ctor{kind: opaqueCtor, text: patternText(p)}}
+		}
+		rangeCtor := &ctor{kind: intCtor}
+		if start != nil {
+			rangeCtor.lo, rangeCtor.isRune = start.lo, start.isRune
+		}
+		if end != nil {
+			rangeCtor.hi, rangeCtor.isRune = end.hi, end.isRune
+			if !p.Inclusive {
+				rangeCtor.hi = new(big.Int).Sub(rangeCtor.hi, big.NewInt(1))
+			}
+		}
+		return &space{ctor: rangeCtor}
+	case *ast.TypePattern:
+		// The types held by an interface are not known, like the strings
+		return &space{ctor: &ctor{kind: opaqueCtor, text: "_: " + LowerType(p.Type, nil).String()}}
+	case *ast.TuplePattern:
+		args := make([]*space, len(p.Elements))
+		for i, element := range p.Elements {
+			args[i] = c.lowerPattern(element)
+		}
+		return &space{ctor: &ctor{kind: productCtor, arity: len(args)}, args: args}
+	case *ast.StructPattern:
+		structCtor := &ctor{kind: productCtor, name: exprText(p.Type), fields: []string{}}
+		if enum, variant := c.variantOf(p.Type); variant != nil {
+			structCtor = &ctor{kind: variantCtor, name: enum.Name, variant: variant.Name, fields: []string{}}
+		}
+		var args []*space
+		for _, field := range p.Fields {
+			structCtor.fields = append(structCtor.fields, field.Name.Name)
+			if field.Pattern == nil {
+				args = append(args, wildcard)
+			} else {
+				args = append(args, c.lowerPattern(field.Pattern))
+			}
+		}
+		structCtor.arity = len(args)
+		return &space{ctor: structCtor, args: args}
+	case *ast.VariantPattern:
+		enum, variant := c.variantOf(p.Variant)
+		if variant == nil {
+			// An unknown variant, or a constant, which is only equal to itself
+			return &space{ctor: &ctor{kind: opaqueCtor, text: exprText(p.Variant)}}
+		}
+		args := make([]*space, len(p.Elements))
+		for i, element := range p.Elements {
+			args[i] = c.lowerPattern(element)
+		}
+		return &space{ctor: &ctor{kind: variantCtor, name: enum.Name, variant: variant.Name, arity: len(args)}, args: args}
+	default:
+		return wildcard
+	}
+}
+
+// lowerLiteral returns the constructor of the value of a literal, which may be a negated number.
+func lowerLiteral(expr ast.Expr) *ctor {
+	operand, negative := expr, false
+	if unary, isUnary := expr.(*ast.UnaryExpr); isUnary {
+		operand, negative = unary.Operand, true
+	}
+	literal, isLiteral := operand.(*ast.BasicLiteral)
+	if !isLiteral {
+		return &ctor{kind: opaqueCtor, text: exprText(expr)}
+	}
+
+	var value *big.Int
+	isRune := false
+	switch literal.Kind {
+	case compiler.TokenTypeTrue, compiler.TokenTypeFalse:
+		return &ctor{kind: boolCtor, value: literal.Kind == compiler.TokenTypeTrue}
+	case compiler.TokenTypeDecimalInteger,
+		compiler.TokenTypeOctalInteger,
+		compiler.TokenTypeHexadecimalInteger,
+		compiler.TokenTypeBinaryInteger:
+		value, _ = new(big.Int).SetString(literal.Value, 0)
+	case compiler.TokenTypeByte:
+		if len(literal.Value) == 1 {
+			value = big.NewInt(int64(literal.Value[0]))
+		}
+	case compiler.TokenTypeRune:
+		// A grapheme cluster of several code points is not a single integer
+		if utf8.RuneCountInString(literal.Value) == 1 {
+			r, _ := utf8.DecodeRuneInString(literal.Value)
+			value, isRune = big.NewInt(int64(r)), true
+		}
+	}
+	if value == nil {
+		return &ctor{kind: opaqueCtor, text: exprText(expr)}
+	}
+	if negative {
+		value.Neg(value)
+	}
+	return &ctor{kind: intCtor, lo: value, hi: value, isRune: isRune}
+}
+
+func exprText(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return e.Name
+	case *ast.MemberExpr:
+		return exprText(e.Target) + "." + e.Name.Name
+	case *ast.UnaryExpr:
+		return e.Operator.Content + exprText(e.Operand)
+	case *ast.BasicLiteral:
+		switch e.Kind {
+		case compiler.TokenTypeString:
+			return strconv.Quote(e.Value)
+		case compiler.TokenTypeRune:
+			return "'" + e.Value + "'"
+		default:
+			return e.Value
+		}
+	default:
+		return fmt.Sprintf("%T", expr)
+	}
+}
+
+func patternText(p *ast.RangePattern) string {
+	var text string
+	if p.Start != nil {
+		text = exprText(p.Start)
+	}
+	text += p.Operator.Content
+	if p.End != nil {
+		text += exprText(p.End)
+	}
+	return text
+}
+
+// useful tells whether a row of spaces q matches some values which are matched by
+// none of the rows, and returns such values as a witness. It's the usefulness
+// algorithm of "Warnings for pattern matching" by Luc Maranget, with integer
+// ranges split into disjoint ranges like the Rust compiler does.
+// types are the types of the columns, or nil for the unknown ones.
+func (c *matchChecker) useful(rows [][]*space, q []*space, types []Type) ([]*space, bool) {
+	if len(q) == 0 {
+		return []*space{}, len(rows) == 0
+	}
+	var heads []*ctor
+	for _, row := range rows {
+		if row[0].ctor != nil {
+			heads = append(heads, row[0].ctor)
+		}
+	}
+
+	if head := q[0].ctor; head != nil {
+		var candidates []*ctor
+		switch head.kind {
+		case intCtor:
+			for _, piece := range splitIntRange(append(heads, head), types[0]) {
+				if covers(head, piece) {
+					candidates = append(candidates, piece)
+				}
+			}
+		case productCtor:
+			candidates = []*ctor{productSignature(heads, head)}
+		case variantCtor:
+			candidates = []*ctor{head}
+			if enum := c.enumNamed(head.name); enum != nil && enum.Variant(head.variant) != nil {
+				candidates = []*ctor{ctorOfVariant(enum, enum.Variant(head.variant))}
+			}
+		default:
+			candidates = []*ctor{head}
+		}
+		for _, candidate := range candidates {
+			if witness, isUseful := c.usefulSpecialized(rows, q, types, candidate); isUseful {
+				return witness, true
+			}
+		}
+		return nil, false
+	}
+
+	all, complete := c.signature(heads, types[0])
+	if complete {
+		for _, candidate := range all {
+			if witness, isUseful := c.usefulSpecialized(rows, q, types, candidate); isUseful {
+				return witness, true
+			}
+		}
+		return nil, false
+	}
+
+	// Only the rows starting with a wildcard match the values of the missing constructors
+	var defaultRows [][]*space
+	for _, row := range rows {
+		if row[0].ctor == nil {
+			defaultRows = append(defaultRows, row[1:])
+		}
+	}
+	witness, isUseful := c.useful(defaultRows, q[1:], types[1:])
+	if !isUseful {
+		return nil, false
+	}
+	head := wildcard
+	// Some constructors are missing, show the first one
+	for _, missing := range all {
+		if !coveredByAny(heads, missing) {
+			head = &space{ctor: missing, args: wildcards(missing.arity)}
+			break
+		}
+	}
+	return append([]*space{head}, witness...), true
+}
+
+// usefulSpecialized checks the usefulness of q among the rows starting with
+// constructor c, which are unfolded into the arguments of c.
+func (c *matchChecker) usefulSpecialized(rows [][]*space, q []*space, types []Type, ctor *ctor) ([]*space, bool) {
+	argTypes := c.argTypesOf(ctor, types[0])
+	var specialized [][]*space
+	for _, row := range rows {
+		if args, matches := specialize(row[0], ctor); matches {
+			specialized = append(specialized, append(args, row[1:]...))
+		}
+	}
+	args, _ := specialize(q[0], ctor)
+	witness, isUseful := c.useful(specialized, append(args, q[1:]...), append(argTypes, types[1:]...))
+	if !isUseful {
+		return nil, false
+	}
+	head := &space{ctor: ctor, args: witness[:ctor.arity]}
+	return append([]*space{head}, witness[ctor.arity:]...), true
+}
+
+// specialize returns the arguments of a space for constructor c,
+// or false if the space doesn't match the values of c.
+func specialize(s *space, c *ctor) ([]*space, bool) {
+	if s.ctor == nil {
+		return wildcards(c.arity), true
+	}
+	if !covers(s.ctor, c) {
+		return nil, false
+	}
+	if c.fields == nil {
+		if len(s.args) != c.arity {
+			// Like `Option.Some` without payload, the checker reports it
+			return wildcards(c.arity), true
+		}
+		return s.args, true
+	}
+	// Struct patterns list different fields, so they are aligned to the fields of c
+	args := wildcards(c.arity)
+	for i, field := range c.fields {
+		for j, own := range s.ctor.fields {
+			if own == field {
+				args[i] = s.args[j]
+			}
+		}
+	}
+	return args, true
+}
+
+// covers tells whether constructor a matches all the values of constructor b.
+func covers(a, b *ctor) bool {
+	if a.kind != b.kind {
+		return false
+	}
+	switch a.kind {
+	case boolCtor:
+		return a.value == b.value
+	case intCtor:
+		return (a.lo == nil || (b.lo != nil && a.lo.Cmp(b.lo) <= 0)) &&
+			(a.hi == nil || (b.hi != nil && b.hi.Cmp(a.hi) <= 0))
+	case opaqueCtor:
+		return a.text == b.text
+	case variantCtor:
+		return a.name == b.name && a.variant == b.variant
+	default:
+		return a.name == b.name && (a.name != "" || a.arity == b.arity)
+	}
+}
+
+func coveredByAny(heads []*ctor, c *ctor) bool {
+	for _, head := range heads {
+		if covers(head, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// signature returns the constructors of the values of a column, split so that each
+// one is either covered by a head or not, and tells whether the heads cover them all.
+func (c *matchChecker) signature(heads []*ctor, t Type) ([]*ctor, bool) {
+	if len(heads) == 0 {
+		return nil, false
+	}
+	switch heads[0].kind {
+	case boolCtor:
+		all := []*ctor{{kind: boolCtor, value: true}, {kind: boolCtor, value: false}}
+		return all, coveredByAny(heads, all[0]) && coveredByAny(heads, all[1])
+	case intCtor:
+		all := splitIntRange(heads, t)
+		for _, c := range all {
+			if !coveredByAny(heads, c) {
+				return all, false
+			}
+		}
+		return all, true
+	case productCtor:
+		return []*ctor{productSignature(heads, heads[0])}, true
+	case variantCtor:
+		enum := c.enumNamed(heads[0].name)
+		if enum == nil {
+			return nil, false
+		}
+		var all []*ctor
+		complete := true
+		for _, variant := range enum.Origin().Variants {
+			all = append(all, ctorOfVariant(enum, variant))
+			complete = complete && coveredByAny(heads, all[len(all)-1])
+		}
+		return all, complete
+	default:
+		return nil, false
+	}
+}
+
+// productSignature returns the constructor of a column of tuples or structs like head.
+// The fields of a struct are all the fields listed by the struct patterns of the column.
+func productSignature(heads []*ctor, head *ctor) *ctor {
+	if head.name == "" {
+		return head
+	}
+	c := &ctor{kind: productCtor, name: head.name}
+	seen := map[string]bool{}
+	for _, other := range append([]*ctor{head}, heads...) {
+		if other.kind != productCtor || other.name != head.name {
+			continue
+		}
+		for _, field := range other.fields {
+			if !seen[field] {
+				seen[field] = true
+				c.fields = append(c.fields, field)
+			}
+		}
+	}
+	c.arity = len(c.fields)
+	return c
+}
+
+func (c *matchChecker) argTypesOf(ctor *ctor, t Type) []Type {
+	argTypes := make([]Type, ctor.arity)
+	if tuple, isTuple := t.(*Tuple); isTuple && ctor.kind == productCtor && ctor.name == "" && len(tuple.Elems) == ctor.arity {
+		copy(argTypes, tuple.Elems)
+	}
+	if ctor.kind == variantCtor {
+		enum, isEnum := t.(*Enum)
+		if !isEnum || enum.Name != ctor.name {
+			enum = c.enumNamed(ctor.name)
+		}
+		if enum != nil && enum.Variant(ctor.variant) != nil {
+			copy(argTypes, enum.FieldTypes(enum.Variant(ctor.variant)))
+		}
+	}
+	return argTypes
+}
+
+// intDomain returns the smallest and largest values of an integer type,
+// an unknown type is a 64 bits integer, or a rune if isRune.
+func intDomain(t Type, isRune bool) (*big.Int, *big.Int) {
+	signed := func(bits uint) (*big.Int, *big.Int) {
+		max := new(big.Int).Lsh(big.NewInt(1), bits-1)
+		return new(big.Int).Neg(max), max.Sub(max, big.NewInt(1))
+	}
+	unsigned := func(bits uint) (*big.Int, *big.Int) {
+		max := new(big.Int).Lsh(big.NewInt(1), bits)
+		return big.NewInt(0), max.Sub(max, big.NewInt(1))
+	}
+	basic, _ := t.(*Basic)
+	if basic == nil {
+		if isRune {
+			basic = Rune
+		} else {
+			basic = Int
+		}
+	}
+	switch basic.Kind {
+	case Int8Kind:
+		return signed(8)
+	case Int16Kind:
+		return signed(16)
+	case Int32Kind:
+		return signed(32)
+	case Uint8Kind:
+		return unsigned(8)
+	case Uint16Kind:
+		return unsigned(16)
+	case Uint32Kind:
+		return unsigned(32)
+	case UintKind, Uint64Kind:
+		return unsigned(64)
+	case RuneKind:
+		return big.NewInt(0), big.NewInt(utf8.MaxRune)
+	default:
+		return signed(64)
+	}
+}
+
+// splitIntRange splits the values of an integer type into ranges, such that
+// each range of the constructors either contains a range or doesn't overlap it.
+func splitIntRange(ctors []*ctor, t Type) []*ctor {
+	isRune := false
+	for _, c := range ctors {
+		isRune = isRune || (c.kind == intCtor && c.isRune)
+	}
+	min, max := intDomain(t, isRune)
+	cuts := []*big.Int{min}
+	addCut := func(cut *big.Int) {
+		if cut.Cmp(min) > 0 && cut.Cmp(max) <= 0 {
+			cuts = append(cuts, cut)
+		}
+	}
+	for _, c := range ctors {
+		if c.kind != intCtor {
+			continue
+		}
+		if c.lo != nil {
+			addCut(c.lo)
+		}
+		if c.hi != nil {
+			addCut(new(big.Int).Add(c.hi, big.NewInt(1)))
+		}
+	}
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i].Cmp(cuts[j]) < 0 })
+
+	var pieces []*ctor
+	for i, cut := range cuts {
+		if i > 0 && cut.Cmp(cuts[i-1]) == 0 {
+			continue
+		}
+		hi := max
+		for _, next := range cuts[i+1:] {
+			if next.Cmp(cut) != 0 {
+				hi = new(big.Int).Sub(next, big.NewInt(1))
+				break
+			}
+		}
+		pieces = append(pieces, &ctor{
+			kind:   intCtor,
+			lo:     cut,
+			hi:     hi,
+			isRune: isRune,
+			openLo: cut.Cmp(min) == 0,
+			openHi: hi.Cmp(max) == 0,
+		})
+	}
+	return pieces
+}
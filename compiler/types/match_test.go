@@ -0,0 +1,159 @@
+package types
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/parser"
+	"mirth/compiler/resolve"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// checkMatch parses declarations followed by a match expression,
+// and checks the match against the subject type.
+func checkMatch(source string, subject Type) []*compiler.Diagnostic {
+	file := parser.CreateParser(source).ParseFile().Unwrap()
+	enums, _ := DeclareEnums(file)
+	match := file.Statements[len(file.Statements)-1].(*ast.ExprStmt).Expr.(*ast.MatchExpr)
+	return CheckMatch(match, subject, enums)
+}
+
+func TestCheckMatch(t *testing.T) {
+	Convey("Test exhaustive matches", t, func() {
+		So(checkMatch("match b {\n  true => 1\n  false => 0\n}", Bool), ShouldBeEmpty)
+		So(checkMatch("match n {\n  ..0 => -1\n  0 => 0\n  1.. => 1\n}", nil), ShouldBeEmpty)
+		So(checkMatch("match n {\n  0..128 => 1\n  128..=255 => 2\n}", Uint8), ShouldBeEmpty)
+		So(checkMatch("match p {\n  (true, _) => 1\n  (_, true) => 2\n  (false, false) => 3\n}", nil), ShouldBeEmpty)
+		So(checkMatch("match s {\n  \"a\" => 1\n  other => 2\n}", String), ShouldBeEmpty)
+		So(checkMatch("match p {\n  Point { x: 0 } => 1\n  Point { y } => y\n}", nil), ShouldBeEmpty)
+	})
+
+	Convey("Test report the first missing pattern", t, func() {
+		cases := []struct {
+			source  string
+			subject Type
+			missing string
+		}{
+			{"match b {\n  true => 1\n}", Bool, "false"},
+			{"match n {\n  ..0 => 0\n  0..10 => 1\n  11.. => 2\n}", Int, "10"},
+			{"match n {\n  1.. => 1\n}", Int, "..=0"},
+			{"match n {\n  0..=100 => 1\n}", Uint8, "101.."},
+			{"match c {\n  'a'..='z' => 1\n}", nil, "..='`'"},
+			{"match p {\n  (true, false) => 1\n  (false, _) => 2\n}", &Tuple{Elems: []Type{Bool, Bool}}, "(true, true)"},
+			{"match p {\n  Point { x: 0, y: true } => 1\n  Point { y: false } => 2\n}", nil, "Point { x: ..=-1, y: true }"},
+			{"match s {\n  \"a\" => 1\n}", String, "_"},
+			{"match s {\n  n if n > 0 => 1\n}", nil, "_"},
+			{"match shape {\n  c: Circle => 1\n  s: Square => 2\n}", nil, "_"},
+		}
+		for _, c := range cases {
+			diagnostics := checkMatch(c.source, c.subject)
+			So(len(diagnostics), ShouldEqual, 1)
+			So(diagnostics[0].Code, ShouldEqual, compiler.NonExhaustiveMatch)
+			So(diagnostics[0].Msg, ShouldEqual,
+				"Non-exhaustive match, pattern '"+c.missing+"' is not covered by any arm, add an arm for it or a '_' arm")
+		}
+	})
+
+	Convey("Test report unreachable arms", t, func() {
+		diagnostics := checkMatch("match n {\n  0..10 => 1\n  5 => 2\n  x @ 9..=12 => 3\n  _ => 4\n  7 => 5\n}", nil)
+		So(len(diagnostics), ShouldEqual, 2)
+		So(diagnostics[0].Type, ShouldEqual, compiler.DiagnosticWarning)
+		So(diagnostics[0].Code, ShouldEqual, compiler.UnreachablePattern)
+		So(diagnostics[0].Pos.Line, ShouldEqual, 3)
+		So(diagnostics[1].Pos.Line, ShouldEqual, 6)
+
+		// A guarded arm doesn't cover the next arms
+		So(checkMatch("match n {\n  x if x > 0 => 1\n  _ => 2\n}", nil), ShouldBeEmpty)
+		// An empty range matches nothing
+		So(checkMatch("match n {\n  5..5 => 1\n  _ => 2\n}", nil)[0].Code, ShouldEqual, compiler.UnreachablePattern)
+	})
+}
+
+func TestCheckMatchEnums(t *testing.T) {
+	shapes := "enum Shape {\n  Circle { radius: f64 }\n  Rect(f64, f64)\n  Empty\n}\n"
+
+	Convey("Test exhaustive matches of enum variants", t, func() {
+		So(checkMatch(shapes+"match s {\n  Shape.Circle { radius } => radius\n  Shape.Rect(w, h) => w * h\n  Shape.Empty => 0\n}", nil), ShouldBeEmpty)
+		So(checkMatch("match o {\n  Some(true) => 1\n  Option.Some(false) => 2\n  Option.None => 3\n}", nil), ShouldBeEmpty)
+	})
+
+	Convey("Test report missing variants and payloads", t, func() {
+		cases := []struct {
+			source  string
+			subject Type
+			missing string
+		}{
+			{shapes + "match s {\n  Shape.Circle { radius: r } => r\n  Shape.Empty => 0\n}", nil, "Shape.Rect(_, _)"},
+			{shapes + "match s {\n  Shape.Rect(_, _) => 1\n  Shape.Empty => 0\n}", nil, "Shape.Circle {}"},
+			{"match r {\n  Result.Ok(_) => 1\n}", nil, "Result.Err(_)"},
+			{"match o {\n  Option.Some(true) => 1\n  Option.None => 2\n}", Option.Instantiate([]Type{Bool}), "Option.Some(false)"},
+			{"enum Color {\n  Red = 1\n  Green\n  Blue = 4\n}\nmatch c {\n  Color.Red => 1\n  Color.Blue => 2\n}", nil, "Color.Green"},
+		}
+		for _, c := range cases {
+			diagnostics := checkMatch(c.source, c.subject)
+			So(len(diagnostics), ShouldEqual, 1)
+			So(diagnostics[0].Msg, ShouldEqual,
+				"Non-exhaustive match, pattern '"+c.missing+"' is not covered by any arm, add an arm for it or a '_' arm")
+		}
+	})
+
+	Convey("Test report unreachable variant arms", t, func() {
+		diagnostics := checkMatch("match o {\n  Option.Some(_) => 1\n  Option.None => 2\n  Option.Some(3) => 3\n}", nil)
+		So(len(diagnostics), ShouldEqual, 1)
+		So(diagnostics[0].Code, ShouldEqual, compiler.UnreachablePattern)
+		So(diagnostics[0].Pos.Line, ShouldEqual, 4)
+	})
+}
+
+func TestCheckMatches(t *testing.T) {
+	Convey("Test check the match expressions with the types of their subjects", t, func() {
+		_, diagnostics := checkSource(`
+let x: u8 = 1
+let y = true
+let a = match x {
+  0..=127 => match y {
+    true => 0
+  }
+  128..=255 => 2
+}
+let b = match x {
+  0..=127 => 1
+  129..=255 => 2
+}`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Non-exhaustive match, pattern 'false' is not covered by any arm, add an arm for it or a '_' arm",
+			"Non-exhaustive match, pattern '128' is not covered by any arm, add an arm for it or a '_' arm",
+		})
+		So(diagnostics[0].Pos.Line, ShouldEqual, 5)
+	})
+
+	Convey("Test match the bare unit variants instead of binding them", t, func() {
+		_, diagnostics := checkSource(`
+enum Command { Quit, Move(int), Write(string) }
+func f(o: Option<int>, c: Command) int {
+  let a = match o {
+    None => 0
+    Some(x) => x
+  }
+  return a + match c {
+    Quit => 0
+    Move(n) => n
+  }
+}`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Non-exhaustive match, pattern 'Command.Write(_)' is not covered by any arm, add an arm for it or a '_' arm",
+		})
+		So(diagnostics[0].Pos.Line, ShouldEqual, 8)
+	})
+
+	Convey("Test check the matches on the enums declared in the other files of the module", t, func() {
+		files := []*ast.File{
+			parser.CreateParser("enum Kind { A, B }").ParseFile().Unwrap(),
+			parser.CreateParser("func f(k: Kind) int {\n  return match k {\n    Kind.A => 1\n    Kind.B => 2\n  }\n}").ParseFile().Unwrap(),
+		}
+		table, _ := resolve.ResolveModule(files, nil)
+		diagnostics := Check(files, table, CreateInfo())
+		So(diagnostics[1], ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,214 @@
+package types
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/resolve"
+)
+
+// narrowing maps the variables of optional type which are known not to be nil at a point
+// of the code to their type without nil. A narrowing is never modified, it's replaced.
+type narrowing map[*resolve.Symbol]Type
+
+// with returns the narrowing extended by other.
+func (n narrowing) with(other narrowing) narrowing {
+	if len(other) == 0 {
+		return n
+	}
+	narrowed := narrowing{}
+	for symbol, t := range n {
+		narrowed[symbol] = t
+	}
+	for symbol, t := range other {
+		narrowed[symbol] = t
+	}
+	return narrowed
+}
+
+// without returns the narrowing without the variables which are assigned again.
+func (n narrowing) without(symbols map[*resolve.Symbol]bool) narrowing {
+	narrowed := narrowing{}
+	for symbol, t := range n {
+		if !symbols[symbol] {
+			narrowed[symbol] = t
+		}
+	}
+	return narrowed
+}
+
+// join returns the variables narrowed at the end of all the paths, which meet after a branch.
+func join(paths []narrowing) narrowing {
+	joined := narrowing{}
+	for symbol, t := range paths[0] {
+		everywhere := true
+		for _, path := range paths[1:] {
+			other, exists := path[symbol]
+			everywhere = everywhere && exists && Identical(t, other)
+		}
+		if everywhere {
+			joined[symbol] = t
+		}
+	}
+	return joined
+}
+
+func isNil(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return isNil(e.Expr)
+	case *ast.BasicLiteral:
+		return e.Kind == compiler.TokenTypeNil
+	}
+	return false
+}
+
+// optionalVar returns the variable of optional type an expression names, with its type
+// without nil, or nil if the expression isn't such a variable.
+func (c *checker) optionalVar(expr ast.Expr) (*resolve.Symbol, Type) {
+	for {
+		paren, isParen := expr.(*ast.ParenExpr)
+		if !isParen {
+			break
+		}
+		expr = paren.Expr
+	}
+	ident, isName := expr.(*ast.Identifier)
+	if !isName {
+		return nil, nil
+	}
+	symbol := c.table.Uses[ident]
+	if symbol == nil || (symbol.Kind != resolve.VarSymbol && symbol.Kind != resolve.ParamSymbol && symbol.Kind != resolve.BindingSymbol) {
+		return nil, nil
+	}
+	if optional, isOptional := c.info.Symbols[symbol].(*Optional); isOptional {
+		return symbol, optional.Elem
+	}
+	return nil, nil
+}
+
+// nilChecks returns the variables which are not nil when a condition is true, and when it's
+// false. They come from the comparisons of variables with nil, like `x != nil`, combined
+// with `&&`, `||` and `!`.
+func (c *checker) nilChecks(cond ast.Expr) (whenTrue, whenFalse narrowing) {
+	switch e := cond.(type) {
+	case *ast.ParenExpr:
+		return c.nilChecks(e.Expr)
+	case *ast.UnaryExpr:
+		if e.Operator.Type == compiler.TokenTypeBang {
+			whenTrue, whenFalse = c.nilChecks(e.Operand)
+			return whenFalse, whenTrue
+		}
+	case *ast.BinaryExpr:
+		switch e.Operator.Type {
+		case compiler.TokenTypeDoubleAmpersand:
+			leftTrue, _ := c.nilChecks(e.Left)
+			rightTrue, _ := c.nilChecks(e.Right)
+			return leftTrue.with(rightTrue), nil
+		case compiler.TokenTypeDoubleVertical:
+			_, leftFalse := c.nilChecks(e.Left)
+			_, rightFalse := c.nilChecks(e.Right)
+			return nil, leftFalse.with(rightFalse)
+		case compiler.TokenTypeDoubleEqual, compiler.TokenTypeBangEqual:
+			operand := e.Left
+			if isNil(e.Left) {
+				operand = e.Right
+			} else if !isNil(e.Right) {
+				return nil, nil
+			}
+			symbol, elem := c.optionalVar(operand)
+			if symbol == nil {
+				return nil, nil
+			}
+			if e.Operator.Type == compiler.TokenTypeBangEqual {
+				return narrowing{symbol: elem}, nil
+			}
+			return nil, narrowing{symbol: elem}
+		}
+	}
+	return nil, nil
+}
+
+// assignedIn returns the variables assigned in a node, which may be nil again after it.
+func (c *checker) assignedIn(node ast.Node) map[*resolve.Symbol]bool {
+	assigned := map[*resolve.Symbol]bool{}
+	ast.Inspect(node, func(node ast.Node) bool {
+		if assign, isAssign := node.(*ast.AssignExpr); isAssign && assign.Operator.Type == compiler.TokenTypeEqual {
+			if symbol, _ := c.optionalVar(assign.Target); symbol != nil {
+				assigned[symbol] = true
+			}
+		}
+		return true
+	})
+	return assigned
+}
+
+// ifStmt checks an if statement whose branches narrow the variables compared with nil in
+// its condition. After it, a variable stays narrowed if it's narrowed at the end of every
+// branch which doesn't jump away, like x after `if x == nil { return }`.
+func (c *checker) ifStmt(s *ast.IfStmt) {
+	c.assign(s.Condition, Bool, nil)
+	whenTrue, whenFalse := c.nilChecks(s.Condition)
+	before := c.narrowed
+	var paths []narrowing
+
+	c.narrowed = before.with(whenTrue)
+	c.statements(s.Then.Statements)
+	if !terminates(s.Then) {
+		paths = append(paths, c.narrowed)
+	}
+	c.narrowed = before.with(whenFalse)
+	if s.Else != nil {
+		c.stmt(s.Else)
+	}
+	if s.Else == nil || !terminates(s.Else) {
+		paths = append(paths, c.narrowed)
+	}
+
+	if len(paths) == 0 {
+		c.narrowed = before // The code after the statement is unreachable
+		return
+	}
+	c.narrowed = join(paths)
+}
+
+// enterLoop forgets the variables assigned in a loop, which may be nil again on the next
+// iteration, and after the loop.
+func (c *checker) enterLoop(loop ast.Stmt) {
+	c.narrowed = c.narrowed.without(c.assignedIn(loop))
+}
+
+// loopBody checks the body of a loop with the variables its condition narrows. What's
+// narrowed in the body isn't narrowed after it, since the body may not run.
+func (c *checker) loopBody(body *ast.BlockStmt, condition narrowing) {
+	after := c.narrowed
+	c.narrowed = after.with(condition)
+	c.statements(body.Statements)
+	c.narrowed = after
+}
+
+// terminates tells whether a statement always jumps away, with a return, a break or a continue.
+func terminates(stmt ast.Stmt) bool {
+	switch s := stmt.(type) {
+	case *ast.ReturnStmt, *ast.BreakStmt, *ast.ContinueStmt:
+		return true
+	case *ast.BlockStmt:
+		for _, stmt := range s.Statements {
+			if terminates(stmt) {
+				return true
+			}
+		}
+	case *ast.IfStmt:
+		return s.Else != nil && terminates(s.Then) && terminates(s.Else)
+	}
+	return false
+}
+
+// possiblyNil reports a value of an optional type which is used as if it weren't nil,
+// action tells how it's used.
+func (c *checker) possiblyNil(expr ast.Expr, t Type, action string) {
+	if ident, isName := expr.(*ast.Identifier); isName {
+		c.errorf(expr, compiler.PossiblyNil, "'%s' may be nil, check it with 'if %s != nil' %s", ident.Name, ident.Name, action)
+		return
+	}
+	c.errorf(expr, compiler.PossiblyNil, "Value of type '%s' may be nil, check it %s", t, action)
+}
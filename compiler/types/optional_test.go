@@ -0,0 +1,174 @@
+package types
+
+import (
+	"mirth/compiler"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheckOptionals(t *testing.T) {
+	Convey("Test assign the values and nil to the optional types", t, func() {
+		vars, diagnostics := checkSource(`
+struct Node { value: int; next: Node? }
+func first<T>(x: T?, fallback: T) T { return x ?? fallback }
+let none: int? = nil
+let some: int? = 1
+let node = Node { value: 1, next: nil }
+let value = first(none, 2)
+let inferred = first(nil, "s")
+let f: (func() int)? = nil`)
+		So(diagnostics, ShouldBeEmpty)
+		So(vars["none"], ShouldEqual, "int?")
+		So(vars["value"], ShouldEqual, "int")
+		So(vars["inferred"], ShouldEqual, "string")
+		So(vars["f"], ShouldEqual, "(func() int)?")
+	})
+
+	Convey("Test type the optional chains and the defaults", t, func() {
+		vars, diagnostics := checkSource(`
+struct Node { value: int; next: Node?; items: []int }
+func (n: Node) double() int { return n.value * 2 }
+let node: Node? = nil
+let next = node?.next
+let value = node?.next?.value
+let doubled = node?.double()
+let count = node?.items.length
+let item = node?.items[0]
+let orZero = node?.value ?? 0
+let orNext = node?.next ?? next`)
+		So(diagnostics, ShouldBeEmpty)
+		So(vars["next"], ShouldEqual, "Node?")
+		So(vars["value"], ShouldEqual, "int?")
+		So(vars["doubled"], ShouldEqual, "int?")
+		So(vars["count"], ShouldEqual, "int?")
+		So(vars["item"], ShouldEqual, "int?")
+		So(vars["orZero"], ShouldEqual, "int")
+		So(vars["orNext"], ShouldEqual, "Node?")
+	})
+
+	Convey("Test narrow the variables compared with nil", t, func() {
+		_, diagnostics := checkSource(`
+struct Node { value: int; next: Node? }
+func length(n: Node?) int {
+	if n == nil { return 0 }
+	return 1 + length(n.next)
+}
+func sum(a: int?, b: int?) int {
+	if a != nil && b != nil { return a + b }
+	if !(a == nil || b == nil) { return a * b }
+	if a != nil { return a } else if b != nil { return b }
+	return 0
+}
+func total(n: Node?) int {
+	let current = n
+	let total = 0
+	for current != nil {
+		total += current.value
+		current = current.next
+	}
+	return total
+}
+func last(n: Node) int {
+	let current: Node? = n
+	current.value = 1
+	return current.value
+}`)
+		So(messages(diagnostics), ShouldBeEmpty)
+	})
+
+	Convey("Test report the values which may be nil", t, func() {
+		_, diagnostics := checkSource(`
+struct Node { value: int; next: Node? }
+func f(n: Node?, values: ([]int)?, call: (func() int)?) {
+	let a = n.value
+	let b = values[0]
+	let c = call()
+	let d = n?.next.value
+	if n != nil {
+		n = n.next
+		let e = n.value
+	}
+	if n != nil {
+		let g = () => n.value
+	}
+	loop {
+		if n == nil { break }
+		let h = n.value
+		n = nil
+	}
+}`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"'n' may be nil, check it with 'if n != nil' or use '?.' to access 'value'",
+			"'values' may be nil, check it with 'if values != nil' before indexing it",
+			"'call' may be nil, check it with 'if call != nil' before calling it",
+			"Value of type 'Node?' may be nil, check it or use '?.' to access 'value'",
+			"'n' may be nil, check it with 'if n != nil' or use '?.' to access 'value'",
+			"'n' may be nil, check it with 'if n != nil' or use '?.' to access 'value'",
+		})
+		So(diagnostics[0].Code, ShouldEqual, compiler.PossiblyNil)
+	})
+
+	Convey("Test give a default to the narrowed variables", t, func() {
+		vars, diagnostics := checkSource(`
+func f(n: int?) int {
+	if n != nil {
+		return n ?? 0
+	}
+	return 0
+}
+func g() {
+	let m: int? = 1
+	let a = m ?? 0
+}`)
+		So(diagnostics, ShouldBeEmpty)
+		So(vars["a"], ShouldEqual, "int")
+	})
+
+	Convey("Test forget the narrowing of the variables assigned in a closure", t, func() {
+		_, diagnostics := checkSource(`
+struct Node { value: int; next: Node? }
+func f(n: Node?) int {
+	if n != nil {
+		let reset = () => { n = nil }
+		reset()
+		return n.value
+	}
+	if n != nil {
+		func clear() { n = nil }
+		clear()
+		return n.value
+	}
+	if n != nil {
+		let read = () => { let m = n }
+		read()
+		return n.value
+	}
+	return 0
+}`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"'n' may be nil, check it with 'if n != nil' or use '?.' to access 'value'",
+			"'n' may be nil, check it with 'if n != nil' or use '?.' to access 'value'",
+		})
+		So(diagnostics[0].Pos.Line, ShouldEqual, 7)
+	})
+
+	Convey("Test report the invalid uses of nil", t, func() {
+		_, diagnostics := checkSource(`
+let a = nil
+let b: int = nil
+let n: int? = nil
+let c: int = n
+let d = 1 ?? 2
+let e = 1 == nil
+let f = n ?? "s"`)
+		So(messages(diagnostics), ShouldResemble, []string{
+			"Can't infer the type of 'a' from nil, annotate it with an optional type",
+			"Type mismatch: expected 'int', found 'nil'",
+			"Type mismatch: expected 'int', found 'int?' which may be nil, check it or give it a default with '??'",
+			"Operator '??' needs an optional value on its left, found 'int'",
+			"Type 'int' can't be nil, only the optional types like 'int?' can be compared with nil",
+			"Type mismatch: expected 'int', found 'string'",
+		})
+	})
+}
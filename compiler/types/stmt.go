@@ -0,0 +1,435 @@
+package types
+
+import (
+	"mirth/compiler"
+	"mirth/compiler/ast"
+	"mirth/compiler/resolve"
+)
+
+func (c *checker) stmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.VarDecl:
+		c.varDecl(s)
+	case *ast.ExprStmt:
+		if match, isMatch := s.Expr.(*ast.MatchExpr); isMatch {
+			// The arms of a match statement don't need to agree on a type
+			c.info.Types[match] = c.matchExpr(match, nil, false)
+			return
+		}
+		c.expr(s.Expr, nil)
+	case *ast.BlockStmt:
+		c.statements(s.Statements)
+	case *ast.IfStmt:
+		c.ifStmt(s)
+	case *ast.ForStmt:
+		if s.Init != nil {
+			c.stmt(s.Init)
+		}
+		c.enterLoop(s)
+		var whenTrue narrowing
+		if s.Condition != nil {
+			c.assign(s.Condition, Bool, nil)
+			whenTrue, _ = c.nilChecks(s.Condition)
+		}
+		if s.Post != nil {
+			c.expr(s.Post, nil)
+		}
+		c.loopBody(s.Body, whenTrue)
+	case *ast.ForInStmt:
+		c.defineSymbol(s.Binding, c.elementOf(s.Iterable))
+		c.enterLoop(s)
+		c.loopBody(s.Body, nil)
+	case *ast.LoopStmt:
+		c.enterLoop(s)
+		c.loopBody(s.Body, nil)
+	case *ast.ReturnStmt:
+		c.returnStmt(s)
+	case *ast.FuncDecl:
+		c.funcBody(s)
+	case *ast.StructDecl:
+		c.fieldDefaults(s)
+	}
+}
+
+// statements checks a list of statements, whose types and functions are declared beforehand.
+func (c *checker) statements(statements []ast.Stmt) {
+	c.declare(statements)
+	for _, stmt := range statements {
+		c.stmt(stmt)
+	}
+}
+
+// varDecl checks a variable declaration, whose type is the annotation or else the type of its value.
+// A top-level variable may already be checked when a function refers to it, see topLevelVar.
+func (c *checker) varDecl(decl *ast.VarDecl) {
+	symbol := c.table.Defs[decl.Name]
+	if symbol != nil {
+		if _, checked := c.info.Symbols[symbol]; checked {
+			return
+		}
+		c.inferring[symbol] = true
+		defer delete(c.inferring, symbol)
+	}
+
+	var t Type
+	reported := len(c.diagnostics[c.file])
+	switch {
+	case decl.Type != nil:
+		t = c.lowerType(decl.Type)
+		if decl.Value != nil {
+			c.assign(decl.Value, t, &expectation{decl.Type, "Expected because of the type annotation"})
+		}
+	case decl.Value != nil:
+		t = c.value(decl.Value, nil)
+		if t == Nil {
+			c.errorf(decl.Value, compiler.CannotInferType, "Can't infer the type of '%s' from nil, annotate it with an optional type", decl.Name.Name)
+			t = Invalid
+		}
+		if !decl.IsConst() {
+			c.checkOverflow(decl.Value, t) // An untyped constant keeps its precision
+		}
+	default:
+		c.errorf(decl.Name, compiler.CannotInferType, "Can't infer the type of '%s', annotate it or give it a value", decl.Name.Name)
+		t = Invalid
+	}
+	if symbol != nil && c.info.Symbols[symbol] == Invalid {
+		return // An initialization cycle, already reported
+	}
+	c.defineSymbol(decl.Name, t)
+	if optional, isOptional := t.(*Optional); isOptional && decl.Value != nil && !canBeNil(c.info.Types[decl.Value]) {
+		c.narrowed = c.narrowed.with(narrowing{symbol: optional.Elem})
+	}
+	if decl.IsConst() && symbol != nil {
+		if len(c.diagnostics[c.file]) > reported {
+			c.info.Constants[symbol] = nil // The value is invalid, and its errors are reported
+			return
+		}
+		c.constDecl(decl, symbol)
+	}
+}
+
+// constDecl evaluates the value of a constant declaration, whose type is checked.
+func (c *checker) constDecl(decl *ast.VarDecl, symbol *resolve.Symbol) {
+	value, diagnostic := c.constant(decl.Value)
+	if diagnostic != nil {
+		c.report(diagnostic)
+	}
+	c.info.Constants[symbol] = value
+}
+
+// elementOf returns the type of the items of a `for in` loop: the elements of an array,
+// the runes of a string, or the values of a range.
+func (c *checker) elementOf(iterable ast.Expr) Type {
+	t := c.value(iterable, nil)
+	switch t := t.(type) {
+	case *Array:
+		return t.Elem
+	case *Range:
+		return t.Elem
+	case *Basic:
+		if t == String {
+			return Rune
+		}
+		if t == Invalid {
+			return Invalid
+		}
+	}
+	c.errorf(iterable, compiler.InvalidOperation, "Can't iterate over '%s', only over arrays, strings and ranges", t)
+	return Invalid
+}
+
+func (c *checker) returnStmt(s *ast.ReturnStmt) {
+	context := c.function
+	switch {
+	case context == nil:
+		if s.Value != nil {
+			c.expr(s.Value, nil)
+		}
+	case context.inferResult:
+		// The first return of a lambda gives its result type
+		context.inferResult = false
+		if s.Value != nil {
+			context.result = c.value(s.Value, nil)
+		}
+	case s.Value == nil:
+		if context.result != nil {
+			diagnostic := c.errorf(s, compiler.TypeMismatch, "Missing return value, the function returns '%s'", context.result)
+			c.noteExpectation(diagnostic, context.resultNode, "Return type declared here")
+		}
+	case context.result == nil:
+		c.expr(s.Value, nil)
+		c.errorf(s.Value, compiler.TypeMismatch, "Unexpected return value, the function doesn't return anything")
+	default:
+		c.assign(s.Value, context.result, &expectation{context.resultNode, "Expected because of the return type"})
+	}
+}
+
+func (c *checker) noteExpectation(diagnostic *compiler.Diagnostic, node ast.Node, msg string) {
+	if node != nil {
+		span := node.NodeSpan()
+		diagnostic.Notes = append(diagnostic.Notes, &compiler.Note{Pos: span.Start, End: span.End, Msg: msg})
+	}
+}
+
+// funcBody checks the body of a function or a method, whose signature is already declared.
+func (c *checker) funcBody(decl *ast.FuncDecl) {
+	signature := c.methods[decl]
+	if signature == nil || decl.Body == nil {
+		return
+	}
+	for i, param := range decl.Params {
+		c.defineSymbol(param.Name, signature.Params[i])
+		if param.Default != nil {
+			c.assign(param.Default, signature.Params[i], &expectation{param.Type, "Expected because of the type of the parameter"})
+		}
+	}
+	enclosing, unknown, narrowed := c.function, c.unknown, c.narrowed
+	c.function = &function{result: signature.Result, resultNode: decl.ReturnType}
+	c.unknown, c.narrowed = map[*TypeParam]bool{}, nil
+	c.statements(decl.Body.Statements)
+	c.function, c.unknown, c.narrowed = enclosing, unknown, narrowed.without(c.assignedIn(decl.Body))
+}
+
+// fieldDefaults checks the default values of the fields of a struct.
+func (c *checker) fieldDefaults(decl *ast.StructDecl) {
+	s, isStruct := c.symbolType(decl.Name).(*Struct)
+	if !isStruct {
+		return
+	}
+	for _, fieldDecl := range decl.Fields {
+		if fieldDecl.Default == nil || fieldDecl.Name == nil {
+			continue
+		}
+		for _, field := range s.Fields {
+			if field.Name == fieldDecl.Name.Name {
+				c.assign(fieldDecl.Default, field.Type, &expectation{fieldDecl.Type, "Expected because of the type of the field"})
+			}
+		}
+	}
+}
+
+// match checks a match expression whose value is used: the arms must all have
+// the expected type, or else the type of the first arm.
+func (c *checker) match(e *ast.MatchExpr, expected Type) Type {
+	return c.matchExpr(e, expected, true)
+}
+
+func (c *checker) matchExpr(e *ast.MatchExpr, expected Type, isValue bool) Type {
+	subject := c.value(e.Subject, nil)
+	result := expected
+	var origin *expectation
+	for _, arm := range e.Arms {
+		c.pattern(arm.Pattern, subject)
+		if arm.Guard != nil {
+			c.assign(arm.Guard, Bool, nil)
+		}
+		armType := c.armBody(arm.Body, result)
+		if !isValue {
+			continue
+		}
+		if result == nil {
+			result = armType
+			origin = &expectation{arm.Body, "Expected because of the type of the first arm"}
+			continue
+		}
+		c.checkAssignable(arm.Body, armType, result, origin)
+	}
+	if subject == Invalid {
+		subject = nil // The values are guessed from the patterns, see CheckMatch
+	}
+	for _, diagnostic := range CheckMatch(e, subject, c.enumsAt(e.Start)) {
+		c.report(diagnostic)
+	}
+	if result == nil || !isValue {
+		return Void
+	}
+	return result
+}
+
+// enumsAt returns the enums in scope at a position of the file being checked, by name, the
+// imported ones included. An enum imported with an alias is also known by its own name,
+// which its variant patterns are reported with.
+func (c *checker) enumsAt(pos *compiler.Position) map[string]*Enum {
+	enums := map[string]*Enum{}
+	var aliased []*Enum
+	for scope := c.table.ScopeAt(c.files[c.file], pos); scope != nil; scope = scope.Parent {
+		for _, symbol := range scope.Symbols() {
+			target := symbol
+			if symbol.Kind == resolve.ImportSymbol {
+				target = symbol.Target
+			}
+			if _, isShadowed := enums[symbol.Name]; isShadowed || target == nil || target.Kind != resolve.EnumSymbol {
+				continue
+			}
+			if enum, isEnum := c.info.Symbols[target].(*Enum); isEnum {
+				enums[symbol.Name] = enum
+				aliased = append(aliased, enum)
+			}
+		}
+	}
+	for _, enum := range aliased {
+		if _, isShadowed := enums[enum.Name]; !isShadowed {
+			enums[enum.Name] = enum
+		}
+	}
+	return enums
+}
+
+// armBody checks the body of a match arm and returns its type. The value of a block
+// is its last statement, and a block ending with a jump like `return` may have any type.
+func (c *checker) armBody(body ast.Node, expected Type) Type {
+	switch b := body.(type) {
+	case ast.Expr:
+		return c.expr(b, expected)
+	case *ast.BlockStmt:
+		if len(b.Statements) == 0 {
+			return Void
+		}
+		c.declare(b.Statements)
+		last := len(b.Statements) - 1
+		for _, stmt := range b.Statements[:last] {
+			c.stmt(stmt)
+		}
+		switch s := b.Statements[last].(type) {
+		case *ast.ExprStmt:
+			return c.expr(s.Expr, expected)
+		case *ast.ReturnStmt, *ast.BreakStmt, *ast.ContinueStmt:
+			c.stmt(s)
+			return Invalid
+		default:
+			c.stmt(s)
+		}
+	}
+	return Void
+}
+
+// pattern checks that a pattern matches values of the subject type, and gives their types to its bindings.
+func (c *checker) pattern(pattern ast.Pattern, subject Type) {
+	switch p := pattern.(type) {
+	case *ast.BindingPattern:
+		c.defineSymbol(p.Name, subject)
+		if p.Pattern != nil {
+			c.pattern(p.Pattern, subject)
+		}
+	case *ast.LiteralPattern:
+		c.checkPatternType(p, c.value(p.Value, subject), subject)
+	case *ast.RangePattern:
+		for _, bound := range []ast.Expr{p.Start, p.End} {
+			if bound != nil {
+				c.checkPatternType(bound, c.value(bound, subject), subject)
+			}
+		}
+	case *ast.TypePattern:
+		target := c.lowerType(p.Type)
+		c.checkAssertion(p, subject, p.Type, target)
+		c.defineSymbol(p.Name, target)
+	case *ast.TuplePattern:
+		tuple, isTuple := subject.(*Tuple)
+		if !isTuple || len(tuple.Elems) != len(p.Elements) {
+			if subject != Invalid {
+				c.errorf(p, compiler.TypeMismatch, "Tuple pattern of %d elements doesn't match values of type '%s'", len(p.Elements), subject)
+			}
+			for _, element := range p.Elements {
+				c.pattern(element, Invalid)
+			}
+			return
+		}
+		for i, element := range p.Elements {
+			c.pattern(element, tuple.Elems[i])
+		}
+	case *ast.StructPattern:
+		c.structPattern(p, subject)
+	case *ast.VariantPattern:
+		enum, variant := c.variantOfExpr(p.Variant, subject)
+		if variant == nil || !c.checkVariantSubject(p.Variant, enum, subject) {
+			for _, element := range p.Elements {
+				c.pattern(element, Invalid)
+			}
+			return
+		}
+		if p.Elements == nil {
+			return
+		}
+		if !variant.Tuple || len(variant.Fields) != len(p.Elements) {
+			c.errorf(p, compiler.ArgumentCount, "Variant '%s.%s' has %d fields, the pattern has %d", enum.Name, variant.Name, len(variant.Fields), len(p.Elements))
+			for _, element := range p.Elements {
+				c.pattern(element, Invalid)
+			}
+			return
+		}
+		fieldTypes := enum.FieldTypes(variant)
+		for i, element := range p.Elements {
+			c.pattern(element, fieldTypes[i])
+		}
+	}
+}
+
+func (c *checker) checkPatternType(node ast.Node, t, subject Type) {
+	if !Assignable(t, subject) {
+		c.errorf(node, compiler.TypeMismatch, "Pattern of type '%s' doesn't match values of type '%s'", t, subject)
+	}
+}
+
+// checkVariantSubject reports a variant pattern whose enum isn't the subject type.
+func (c *checker) checkVariantSubject(node ast.Node, enum *Enum, subject Type) bool {
+	if subject == Invalid {
+		return true
+	}
+	if subjectEnum, isEnum := subject.(*Enum); !isEnum || subjectEnum.Origin() != enum.Origin() {
+		c.errorf(node, compiler.TypeMismatch, "Variant of '%s' doesn't match values of type '%s'", enum.Name, subject)
+		return false
+	}
+	return true
+}
+
+// structPattern checks the fields of a struct pattern, or of a variant with a struct payload.
+func (c *checker) structPattern(p *ast.StructPattern, subject Type) {
+	fieldType := func(name string) (Type, bool) { return nil, false }
+	owner := ""
+	if t, isType := c.typeExpr(p.Type); isType {
+		c.info.Types[p.Type] = t
+		s, isStruct := t.(*Struct)
+		switch {
+		case t == Invalid:
+		case !isStruct:
+			c.errorf(p.Type, compiler.TypeMismatch, "Type '%s' is not a struct", t)
+		default:
+			if subjectStruct, isStruct := subject.(*Struct); isStruct && subjectStruct.Origin() == s.Origin() {
+				s = subjectStruct
+			} else if subject != Invalid {
+				c.errorf(p.Type, compiler.TypeMismatch, "Pattern of type '%s' doesn't match values of type '%s'", s, subject)
+			}
+			fieldType, owner = s.Field, s.Name
+		}
+	} else if enum, variant := c.variantOfExpr(p.Type, subject); variant != nil && c.checkVariantSubject(p.Type, enum, subject) {
+		if variant.Tuple {
+			c.errorf(p.Type, compiler.TypeMismatch, "Variant '%s.%s' has no named fields", enum.Name, variant.Name)
+		} else {
+			fieldTypes := enum.FieldTypes(variant)
+			fieldType = func(name string) (Type, bool) {
+				for i, field := range variant.Fields {
+					if field.Name == name {
+						return fieldTypes[i], true
+					}
+				}
+				return nil, false
+			}
+			owner = enum.Name + "." + variant.Name
+		}
+	}
+
+	for _, field := range p.Fields {
+		t, exists := fieldType(field.Name.Name)
+		if !exists {
+			if owner != "" {
+				c.errorf(field.Name, compiler.UnknownMember, "'%s' has no field '%s'", owner, field.Name.Name)
+			}
+			t = Invalid
+		}
+		if field.Pattern == nil {
+			c.defineSymbol(field.Name, t)
+		} else {
+			c.pattern(field.Pattern, t)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Method is a method of a struct, or a method required by an interface.
+// The receiver isn't one of the parameters of its signature.
+type Method struct {
+	Name      string
+	Signature *Func
+}
+
+// Struct is a struct type. The instances of a generic struct share its fields
+// and methods, and have a type argument for each type parameter.
+type Struct struct {
+	Name       string
+	TypeParams []*TypeParam
+	TypeArgs   []Type
+	Fields     []*Field
+	// Embedded are the names of the fields holding an embedded struct,
+	// whose fields and methods are promoted.
+	Embedded []string
+	Methods  []*Method
+	origin   *Struct // The generic struct of an instance
+}
+
+func (s *Struct) String() string {
+	if len(s.TypeArgs) == 0 {
+		return s.Name
+	}
+	return fmt.Sprintf("%s<%s>", s.Name, typeList(s.TypeArgs))
+}
+
+// Instantiate returns the instance of a generic struct for the type arguments.
+func (s *Struct) Instantiate(typeArgs []Type) *Struct {
+	origin := s.Origin()
+	return &Struct{
+		Name:       origin.Name,
+		TypeParams: origin.TypeParams,
+		TypeArgs:   typeArgs,
+		origin:     origin,
+	}
+}
+
+// Origin returns the generic struct of an instance, or the struct itself.
+func (s *Struct) Origin() *Struct {
+	if s.origin != nil {
+		return s.origin
+	}
+	return s
+}
+
+func (s *Struct) subst() Substitution {
+	subst := Substitution{}
+	for i, typeArg := range s.TypeArgs {
+		if i < len(s.TypeParams) {
+			subst[s.TypeParams[i]] = typeArg
+		}
+	}
+	return subst
+}
+
+// Field returns the type of the field with the name, with the type arguments of the instance.
+// The fields of the embedded structs are looked up after the own fields.
+func (s *Struct) Field(name string) (Type, bool) {
+	origin := s.Origin()
+	for _, field := range origin.Fields {
+		if field.Name == name {
+			return Subst(field.Type, s.subst()), true
+		}
+	}
+	for _, embedded := range s.embedded() {
+		if fieldType, exists := embedded.Field(name); exists {
+			return fieldType, true
+		}
+	}
+	return nil, false
+}
+
+// embedded returns the embedded structs, with the type arguments of the instance.
+func (s *Struct) embedded() []*Struct {
+	origin := s.Origin()
+	var structs []*Struct
+	for _, name := range origin.Embedded {
+		for _, field := range origin.Fields {
+			if field.Name != name {
+				continue
+			}
+			if embedded, isStruct := Subst(field.Type, s.subst()).(*Struct); isStruct && embedded.Origin() != origin {
+				structs = append(structs, embedded)
+			}
+		}
+	}
+	return structs
+}
+
+// Method returns the signature of the method with the name, with the type arguments of the instance.
+// The methods of the embedded structs are looked up after the own methods.
+func (s *Struct) Method(name string) (*Func, bool) {
+	origin := s.Origin()
+	for _, method := range origin.Methods {
+		if method.Name == name {
+			return Subst(method.Signature, s.subst()).(*Func), true
+		}
+	}
+	for _, embedded := range s.embedded() {
+		if signature, exists := embedded.Method(name); exists {
+			return signature, true
+		}
+	}
+	return nil, false
+}
+
+// Interface is an interface type, the set of the types which have all its methods.
+// Methods are its own methods, the methods of the embedded interfaces are added to them.
+type Interface struct {
+	Name     string
+	Methods  []*Method
+	Embedded []*Interface
+}
+
+func (i *Interface) String() string { return i.Name }
+
+// MethodSet returns all the methods of an interface: its own methods, and then the methods
+// of the embedded interfaces which have another name.
+func (i *Interface) MethodSet() []*Method {
+	var methods []*Method
+	names := map[string]bool{}
+	visited := map[*Interface]bool{}
+	var collect func(iface *Interface)
+	collect = func(iface *Interface) {
+		if visited[iface] {
+			return // An embedding cycle, which the checker reports
+		}
+		visited[iface] = true
+		for _, method := range iface.Methods {
+			if !names[method.Name] {
+				names[method.Name] = true
+				methods = append(methods, method)
+			}
+		}
+		for _, embedded := range iface.Embedded {
+			collect(embedded)
+		}
+	}
+	collect(i)
+	return methods
+}
+
+// Method returns the signature of the method with the name.
+func (i *Interface) Method(name string) (*Func, bool) {
+	for _, method := range i.MethodSet() {
+		if method.Name == name {
+			return method.Signature, true
+		}
+	}
+	return nil, false
+}
+
+// MethodMismatch is a method of an interface which a type doesn't have,
+// or has with another signature.
+type MethodMismatch struct {
+	Method *Method
+	Found  *Func // nil if the method is missing
+}
+
+func (m *MethodMismatch) String() string {
+	if m.Found == nil {
+		return fmt.Sprintf("missing method '%s'", methodString(m.Method.Name, m.Method.Signature))
+	}
+	return fmt.Sprintf("method '%s' should be '%s'",
+		methodString(m.Method.Name, m.Found), methodString(m.Method.Name, m.Method.Signature),
+	)
+}
+
+// methodString prints a method like it's declared, `area() f64`.
+func methodString(name string, signature *Func) string {
+	return name + strings.TrimPrefix(signature.String(), "func")
+}
+
+// MissingMethods returns the methods of an interface which a type doesn't have with
+// an identical signature, in the order of the interface.
+func MissingMethods(t Type, iface *Interface) []*MethodMismatch {
+	var mismatches []*MethodMismatch
+	for _, method := range iface.MethodSet() {
+		signature, exists := methodOf(t, method.Name)
+		switch {
+		case !exists:
+			mismatches = append(mismatches, &MethodMismatch{Method: method})
+		case !Identical(signature, method.Signature):
+			mismatches = append(mismatches, &MethodMismatch{Method: method, Found: signature})
+		}
+	}
+	return mismatches
+}
+
+// Implements tells whether a type has all the methods of an interface, with identical
+// signatures. The types implement the interfaces implicitly, without declaring it.
+func Implements(t Type, iface *Interface) bool {
+	return len(MissingMethods(t, iface)) == 0
+}
+
+// methodOf returns the signature of the method of a type, the methods of a type
+// parameter are the ones of its constraint.
+func methodOf(t Type, name string) (*Func, bool) {
+	switch t := t.(type) {
+	case *Struct:
+		return t.Method(name)
+	case *Interface:
+		return t.Method(name)
+	case *TypeParam:
+		if constraint, isInterface := t.Constraint.(*Interface); isInterface {
+			return constraint.Method(name)
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,266 @@
+package types
+
+import (
+	"fmt"
+	"mirth/shared"
+	"strings"
+)
+
+// Type is implemented by all the types.
+type Type interface {
+	String() string
+}
+
+type BasicKind int
+
+const (
+	InvalidKind BasicKind = iota
+	BoolKind
+	IntKind
+	Int8Kind
+	Int16Kind
+	Int32Kind
+	Int64Kind
+	UintKind
+	Uint8Kind
+	Uint16Kind
+	Uint32Kind
+	Uint64Kind
+	Float32Kind
+	Float64Kind
+	StringKind
+	RuneKind
+	VoidKind
+	NilKind
+)
+
+// Basic is a predeclared type, like `int` and `string`.
+type Basic struct {
+	Kind BasicKind
+	Name string
+}
+
+func (b *Basic) String() string { return b.Name }
+
+var (
+	// Invalid is the type of erroneous expressions, it's compatible with every type
+	// so that a single error isn't reported again by every expression using it.
+	Invalid = &Basic{InvalidKind, "invalid"}
+	Bool    = &Basic{BoolKind, "bool"}
+	Int     = &Basic{IntKind, "int"}
+	Int8    = &Basic{Int8Kind, "i8"}
+	Int16   = &Basic{Int16Kind, "i16"}
+	Int32   = &Basic{Int32Kind, "i32"}
+	Int64   = &Basic{Int64Kind, "i64"}
+	Uint    = &Basic{UintKind, "uint"}
+	Uint8   = &Basic{Uint8Kind, "u8"}
+	Uint16  = &Basic{Uint16Kind, "u16"}
+	Uint32  = &Basic{Uint32Kind, "u32"}
+	Uint64  = &Basic{Uint64Kind, "u64"}
+	Float32 = &Basic{Float32Kind, "f32"}
+	Float64 = &Basic{Float64Kind, "f64"}
+	String  = &Basic{StringKind, "string"}
+	Rune    = &Basic{RuneKind, "rune"}
+	// Void is the type of the calls to functions which don't return a value.
+	Void = &Basic{VoidKind, "void"}
+	// Nil is the type of `nil` when no optional type is expected, it's assignable to the optional types.
+	Nil = &Basic{NilKind, "nil"}
+)
+
+// Predeclared maps the names of the predeclared types to them,
+// `byte` is an alias of `u8` and `float` an alias of `f64`.
+// The generic enums are not instantiated.
+var Predeclared = map[string]Type{
+	"bool":   Bool,
+	"int":    Int,
+	"i8":     Int8,
+	"i16":    Int16,
+	"i32":    Int32,
+	"i64":    Int64,
+	"uint":   Uint,
+	"u8":     Uint8,
+	"byte":   Uint8,
+	"u16":    Uint16,
+	"u32":    Uint32,
+	"u64":    Uint64,
+	"f32":    Float32,
+	"f64":    Float64,
+	"float":  Float64,
+	"string": String,
+	"rune":   Rune,
+	"Option": Option,
+	"Result": Result,
+}
+
+// Array is `[]Elem` for a dynamically sized array, or `[Length]Elem` if Length isn't negative.
+type Array struct {
+	Elem   Type
+	Length int64
+}
+
+func (a *Array) String() string {
+	if a.Length < 0 {
+		return "[]" + a.Elem.String()
+	}
+	return fmt.Sprintf("[%d]%s", a.Length, a.Elem)
+}
+
+// Func is the type of a function. The last parameter of a variadic
+// function is an array, holding the variadic arguments. Result is nil
+// if the function doesn't return a value. A generic function has type
+// parameters, which are inferred or given explicitly at the call sites.
+type Func struct {
+	TypeParams []*TypeParam
+	Params     []Type
+	Variadic   bool
+	Result     Type
+	// Defaults is the number of parameters with a default value, before the variadic one.
+	Defaults int
+}
+
+// RequiredParams returns the number of arguments a call must give at least.
+func (f *Func) RequiredParams() int {
+	return len(f.Params) - f.Defaults - shared.Ternary(f.Variadic, 1, 0)
+}
+
+func (f *Func) String() string {
+	params := make([]string, len(f.Params))
+	for i, param := range f.Params {
+		params[i] = param.String()
+		if f.Variadic && i == len(f.Params)-1 {
+			params[i] = "..." + param.(*Array).Elem.String()
+		}
+	}
+	signature := fmt.Sprintf("func(%s)", strings.Join(params, ", "))
+	if len(f.TypeParams) > 0 {
+		typeParams := make([]Type, len(f.TypeParams))
+		for i, typeParam := range f.TypeParams {
+			typeParams[i] = typeParam
+		}
+		signature = fmt.Sprintf("func<%s>(%s)", typeList(typeParams), strings.Join(params, ", "))
+	}
+	if f.Result != nil {
+		signature += " " + f.Result.String()
+	}
+	return signature
+}
+
+// Range is the type of the ranges `start..end` of integers or runes.
+type Range struct {
+	Elem Type
+}
+
+func (r *Range) String() string {
+	return fmt.Sprintf("Range<%s>", r.Elem)
+}
+
+// Tuple is the type of tuples `(A, B)`.
+type Tuple struct {
+	Elems []Type
+}
+
+func (t *Tuple) String() string {
+	return fmt.Sprintf("(%s)", typeList(t.Elems))
+}
+
+// Optional is the type `Elem?` of the values which are either an Elem or nil.
+// Elem is never optional itself.
+type Optional struct {
+	Elem Type
+}
+
+func (o *Optional) String() string {
+	if _, isFunc := o.Elem.(*Func); isFunc {
+		return fmt.Sprintf("(%s)?", o.Elem)
+	}
+	return o.Elem.String() + "?"
+}
+
+// optionalOf returns the optional type of t, which is t if it's already optional.
+// The invalid type and void stay as they are.
+func optionalOf(t Type) Type {
+	if _, isOptional := t.(*Optional); isOptional || t == Invalid || t == Void {
+		return t
+	}
+	return &Optional{Elem: t}
+}
+
+// TypeParam is a type parameter of a generic function or struct.
+// Constraint is nil if any type is allowed.
+type TypeParam struct {
+	Name       string
+	Constraint Type
+}
+
+func (t *TypeParam) String() string { return t.Name }
+
+// Named is a declared type, like a struct, with its type arguments if it's generic.
+type Named struct {
+	Name     string
+	TypeArgs []Type
+}
+
+func (n *Named) String() string {
+	if len(n.TypeArgs) == 0 {
+		return n.Name
+	}
+	return fmt.Sprintf("%s<%s>", n.Name, typeList(n.TypeArgs))
+}
+
+func typeList(types []Type) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// Identical tells whether two types are the same type.
+func Identical(a, b Type) bool {
+	if a == b {
+		return true
+	}
+	switch a := a.(type) {
+	case *Array:
+		b, isArray := b.(*Array)
+		return isArray && a.Length == b.Length && Identical(a.Elem, b.Elem)
+	case *Range:
+		b, isRange := b.(*Range)
+		return isRange && Identical(a.Elem, b.Elem)
+	case *Optional:
+		b, isOptional := b.(*Optional)
+		return isOptional && Identical(a.Elem, b.Elem)
+	case *Tuple:
+		b, isTuple := b.(*Tuple)
+		return isTuple && identicalLists(a.Elems, b.Elems)
+	case *Func:
+		b, isFunc := b.(*Func)
+		if !isFunc || a.Variadic != b.Variadic || !identicalLists(a.Params, b.Params) {
+			return false
+		}
+		return (a.Result == nil) == (b.Result == nil) && (a.Result == nil || Identical(a.Result, b.Result))
+	case *Enum:
+		b, isEnum := b.(*Enum)
+		return isEnum && a.Origin() == b.Origin() && identicalLists(a.TypeArgs, b.TypeArgs)
+	case *Struct:
+		b, isStruct := b.(*Struct)
+		return isStruct && a.Origin() == b.Origin() && identicalLists(a.TypeArgs, b.TypeArgs)
+	case *Named:
+		b, isNamed := b.(*Named)
+		return isNamed && a.Name == b.Name && identicalLists(a.TypeArgs, b.TypeArgs)
+	default:
+		return false
+	}
+}
+
+func identicalLists(a, b []Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Identical(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
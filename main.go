@@ -1,9 +1,58 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"mirth/compiler"
+	"mirth/compiler/module"
+	"os"
+	"path/filepath"
 )
 
 func main() {
-	fmt.Println("Hello, Mirth!")
+	printEscapes := flag.Bool("m", false, "print the captures of the closures and the escape decisions")
+	root := flag.String("root", ".", "root directory of the project")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: mirth [flags] <module>\n\nChecks the module like `app.main` with the modules it imports.\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	program := module.Load(context.Background(), module.CreateResolver(*root), flag.Arg(0), nil)
+	if program.Fatal != nil {
+		fmt.Fprintln(os.Stderr, program.Fatal)
+		os.Exit(1)
+	}
+	relative := func(path string) string {
+		if rel, err := filepath.Rel(*root, path); err == nil {
+			return rel
+		}
+		return path
+	}
+	failed := false
+	for _, mod := range program.Order {
+		for _, file := range mod.Files {
+			for _, diagnostic := range file.Diagnostics {
+				fmt.Fprintf(os.Stderr, "%s %s\n", relative(file.Path), diagnostic.Render(file.Source))
+				failed = failed || diagnostic.Type == compiler.DiagnosticError
+			}
+		}
+	}
+	if *printEscapes {
+		for _, mod := range program.Order {
+			for _, file := range mod.Files {
+				for _, decision := range program.Escapes.Decisions(file.AST) {
+					fmt.Printf("%s:%s\n", relative(file.Path), decision)
+				}
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
 }